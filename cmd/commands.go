@@ -0,0 +1,151 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/controller"
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/tracing"
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/version"
+)
+
+func newRunCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "run",
+		Short: "Run the controller to keep the dashboards in grafana in sync",
+		Args:  cobra.NoArgs,
+		RunE:  runController,
+	}
+}
+
+func runController(cmd *cobra.Command, args []string) error {
+	klog.InfoS("starting grafana-dashboard-loader", "version", version.Version, "commit", version.GitCommit)
+	if metricsAddr != "" {
+		go metrics.Serve(metricsAddr)
+	} else {
+		klog.InfoS("the metrics are not exposed since the metrics address is empty")
+	}
+
+	shutdown := setUpTracing()
+	defer shutdown()
+
+	// the controller drains the in-flight syncs within the shutdown grace period once it is terminated
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	controller.RunGrafanaDashboardController(ctx)
+	return nil
+}
+
+func newSyncCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Apply all the dashboards once and exit with an error if any of them failed, e.g. in a job or an init container",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shutdown := setUpTracing()
+			defer shutdown()
+			return controller.SyncOnce(context.Background())
+		},
+	}
+	cmd.Flags().BoolVar(&controller.SyncClusterObjects, "cluster", controller.SyncClusterObjects,
+		"List the dashboard configmaps, secrets and GrafanaDashboards in the cluster, disable it to only sync the local sources.")
+	return cmd
+}
+
+func newExportCommand() *cobra.Command {
+	output := ""
+	opts := controller.ExportOptions{Namespace: os.Getenv("POD_NAMESPACE")}
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Dump the dashboards in grafana into a directory which can be loaded by --dashboard-dir, or as configmaps",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output == "" {
+				return fmt.Errorf("--output is required")
+			}
+			exported, err := controller.ExportDashboards(context.Background(), output, opts)
+			fmt.Fprintf(cmd.OutOrStdout(), "exported %v dashboards to %v\n", exported, output)
+			return err
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", output, "The directory to write the dashboards to.")
+	cmd.Flags().StringVar(&opts.OrgID, "org-id", opts.OrgID, "The grafana organization to export, the default org is used by default.")
+	cmd.Flags().StringSliceVar(&opts.Folders, "folder", opts.Folders,
+		"Only export the dashboards in the folders, \"General\" is the general folder.")
+	cmd.Flags().StringSliceVar(&opts.Tags, "tag", opts.Tags, "Only export the dashboards which have all the tags.")
+	cmd.Flags().BoolVar(&opts.Configmaps, "configmaps", opts.Configmaps,
+		"Write every dashboard as a configmap manifest with the labels and annotations to load it into the same folder.")
+	cmd.Flags().StringVar(&opts.Namespace, "namespace", opts.Namespace, "The namespace of the configmap manifests.")
+	return cmd
+}
+
+func newValidateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate [path...]",
+		Short: "Check the local dashboard files or directories, the --dashboard-dir is checked by default",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				if controller.DashboardDir == "" {
+					return fmt.Errorf("no dashboard file or directory to validate")
+				}
+				args = []string{controller.DashboardDir}
+			}
+			problems := controller.ValidateDashboards(args)
+			for _, problem := range problems {
+				fmt.Fprintln(cmd.ErrOrStderr(), problem)
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("found %v problems in the dashboards", len(problems))
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "all the dashboards are valid")
+			return nil
+		},
+	}
+}
+
+func newRestoreCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <archive>",
+		Short: "Save the dashboards of a backup archive into grafana, the existing dashboards are overwritten",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			restored, err := controller.RestoreBackup(context.Background(), args[0])
+			fmt.Fprintf(cmd.OutOrStdout(), "restored %v dashboards from %v\n", restored, args[0])
+			return err
+		},
+	}
+}
+
+func newVersionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print the version of the loader",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Fprintln(cmd.OutOrStdout(), version.Get())
+			return nil
+		},
+	}
+}
+
+// setUpTracing exports the traces once the otlp endpoint is configured, the returned func flushes them
+func setUpTracing() func() {
+	if !tracing.Enabled() {
+		return func() {}
+	}
+	shutdown, err := tracing.Setup(context.Background())
+	if err != nil {
+		klog.Fatal("Failed to set up tracing", "error", err)
+	}
+	return func() { shutdown(context.Background()) }
+}
@@ -6,33 +6,349 @@ package main
 import (
 	"flag"
 	"os"
-	"os/signal"
-	"syscall"
 
-	"github.com/spf13/pflag"
-	"k8s.io/klog"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
 
 	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/controller"
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/features"
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/logging"
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
 )
 
+// metricsAddr is where /metrics and /version are served, empty disables them
+var metricsAddr = ":3002"
+
 func main() {
+	rootCmd := &cobra.Command{
+		Use:   "grafana-dashboard-loader",
+		Short: "Load the dashboards from the configmaps and other sources into grafana",
+		// the controller is run without a sub command to keep the existing deployments working
+		RunE:          runController,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
 
 	klogFlags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 	klog.InitFlags(klogFlags)
-	flagset := pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	// the options are shared by all the sub commands so that they talk to grafana in the same way
+	flagset := rootCmd.PersistentFlags()
 	flagset.AddGoFlagSet(klogFlags)
+	features.DefaultMutableFeatureGate.AddFlag(flagset)
+	flagset.StringVar(&logging.Format, "log-format", logging.Format,
+		"The format of the logs, text or json, the verbosity is set by -v.")
+	flagset.StringVar(&controller.ConfigFile, "config", controller.ConfigFile,
+		"The yaml or json config file of the grafana connection, selectors, folders and retries, it is reloaded once changed and overrides the flags.")
+	flagset.DurationVar(&controller.ConfigPollInterval, "config-poll-interval", controller.ConfigPollInterval,
+		"The interval to check the config file for changes.")
+	flagset.DurationVar(&controller.ResyncPeriod, "resync-period", controller.ResyncPeriod,
+		"The period to re-deliver all the dashboard configmaps, unchanged configmaps are not applied again.")
+	flagset.DurationVar(&controller.UpdateDebounceWindow, "update-debounce-window", controller.UpdateDebounceWindow,
+		"How long the updates of a dashboard configmap are coalesced before the last one is applied, 0 applies every update at once.")
+	flagset.IntVar(&controller.SyncWorkers, "sync-workers", controller.SyncWorkers,
+		"The number of the dashboard objects synced at the same time, the events of the same object are handled in order, 1 syncs the objects one by one.")
+	flagset.DurationVar(&controller.WatchFailureThreshold, "watch-failure-threshold", controller.WatchFailureThreshold,
+		"How long the watch of a resource may keep failing before a warning event is posted on the loader pod named by the POD_NAME env.")
+	flagset.DurationVar(&controller.ShutdownGracePeriod, "shutdown-grace-period", controller.ShutdownGracePeriod,
+		"How long the in-flight syncs may take to finish on SIGTERM before they are cancelled, keep it below the termination grace period of the pod.")
+	flagset.DurationVar(&controller.SyncTimeout, "sync-timeout", controller.SyncTimeout,
+		"The timeout to apply or delete the dashboards of a configmap including the retries.")
+	flagset.DurationVar(&util.RequestTimeout, "grafana-request-timeout", util.RequestTimeout,
+		"The timeout of every single request to grafana, 0 means no timeout.")
+	flagset.Float32Var(&util.RequestQPS, "grafana-qps", util.RequestQPS,
+		"The maximum rate of the requests to grafana, 0 means no limit.")
+	flagset.IntVar(&util.RequestBurst, "grafana-burst", util.RequestBurst,
+		"The maximum burst of the requests to grafana.")
+	flagset.IntVar(&util.ConditionalCacheSize, "grafana-conditional-cache-size", util.ConditionalCacheSize,
+		"The number of grafana GET responses kept with their ETag or Last-Modified header to get them again by conditional requests, 0 disables it.")
+	flagset.DurationVar(&util.MaxRetryAfter, "grafana-max-retry-after", util.MaxRetryAfter,
+		"The maximum wait to honor the Retry-After header of grafana.")
+	flagset.IntVar(&controller.BreakerFailureThreshold, "breaker-failure-threshold", controller.BreakerFailureThreshold,
+		"The number of consecutive grafana failures to pause the calls to grafana, 0 disables the circuit breaker.")
+	flagset.DurationVar(&controller.BreakerCooldown, "breaker-cooldown", controller.BreakerCooldown,
+		"How long the calls to grafana are paused before /api/health is probed again.")
+	flagset.DurationVar(&controller.GrafanaReadyTimeout, "grafana-ready-timeout", controller.GrafanaReadyTimeout,
+		"How long /api/health of grafana is probed before the first sync, the dashboards are applied with the retries after it, 0 does not wait.")
+	flagset.DurationVar(&controller.GrafanaFailbackInterval, "grafana-failback-interval", controller.GrafanaFailbackInterval,
+		"How often the preferred grafana replicas of the config file are probed to move the requests back to them after a failover, 0 disables the failback.")
+	flagset.DurationVar(&controller.GrafanaResetCheckInterval, "grafana-reset-check-interval", controller.GrafanaResetCheckInterval,
+		"How often the sentinel dashboard is checked to apply all the dashboards again once grafana is reinstalled without its database, 0 disables the check.")
+	flagset.IntVar(&util.MaxIdleConns, "grafana-max-idle-conns", util.MaxIdleConns,
+		"The maximum number of idle connections to grafana.")
+	flagset.IntVar(&util.MaxIdleConnsPerHost, "grafana-max-idle-conns-per-host", util.MaxIdleConnsPerHost,
+		"The maximum number of idle connections to every grafana host.")
+	flagset.DurationVar(&util.IdleConnTimeout, "grafana-idle-conn-timeout", util.IdleConnTimeout,
+		"How long an idle connection to grafana is kept.")
+	flagset.DurationVar(&util.TLSHandshakeTimeout, "grafana-tls-handshake-timeout", util.TLSHandshakeTimeout,
+		"The timeout of the tls handshake with grafana.")
+	flagset.BoolVar(&util.DisableHTTP2, "grafana-disable-http2", util.DisableHTTP2,
+		"Use HTTP/1.1 only to connect to grafana.")
+	flagset.StringVar(&util.ProxyURL, "grafana-proxy-url", util.ProxyURL,
+		"The proxy to connect to grafana, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are used by default.")
+	flagset.StringVar(&util.NoProxy, "grafana-no-proxy", util.NoProxy,
+		"The comma separated hosts to connect without the --grafana-proxy-url.")
+	flagset.StringVar(&util.ClientCertFile, "grafana-client-cert", util.ClientCertFile,
+		"The client certificate file to present to grafana, it is reloaded once the file is changed.")
+	flagset.StringVar(&util.ClientKeyFile, "grafana-client-key", util.ClientKeyFile,
+		"The key file of the grafana client certificate.")
+	flagset.StringVar(&util.CAFile, "grafana-ca-file", util.CAFile,
+		"The ca bundle to verify grafana, the system roots are used by default.")
+	flagset.BoolVar(&controller.DryRun, "dry-run", controller.DryRun,
+		"Only log the folders and dashboards which would be created, updated or deleted in grafana.")
+	flagset.StringSliceVar(&controller.IncludeNamespaces, "include-namespaces", controller.IncludeNamespaces,
+		"The namespaces the dashboards are loaded from when all the namespaces are watched, e.g. \"team-*\", empty means all of them.")
+	flagset.StringSliceVar(&controller.ExcludeNamespaces, "exclude-namespaces", controller.ExcludeNamespaces,
+		"The namespaces the dashboards are never loaded from, e.g. \"openshift-*\", they win over the included namespaces.")
+	flagset.StringVar(&controller.NamespaceSelector, "namespace-selector", controller.NamespaceSelector,
+		"The label selector of the namespaces the dashboards are loaded from, e.g. \"grafana-dashboards=enabled\".")
+	flagset.BoolVar(&controller.TenantOrgs, "tenant-orgs", controller.TenantOrgs,
+		"Apply the dashboards of every namespace into the grafana organization of the namespace, the organization is "+
+			"created when it does not exist, the observability.open-cluster-management.io/dashboard-org-id annotation overrides it.")
+	flagset.StringToStringVar(&controller.TenantOrgNames, "tenant-org-names", controller.TenantOrgNames,
+		"The names of the grafana organizations of the tenants keyed by the namespace, e.g. \"team-a=Team A\", the default is the namespace name.")
+	flagset.StringVar(&controller.TenantFolder, "tenant-folder", controller.TenantFolder,
+		"The folder of the dashboards without a folder annotation in the organization of the tenant.")
+	flagset.BoolVar(&controller.TeamSync, "team-sync", controller.TeamSync,
+		"Maintain a grafana team per namespace with the users bound to the team roles in the namespace, "+
+			"and grant the team the permission on the folders of the dashboards of the namespace.")
+	flagset.DurationVar(&controller.TeamSyncInterval, "team-sync-interval", controller.TeamSyncInterval,
+		"How often the grafana teams are synced with the role bindings.")
+	flagset.StringSliceVar(&controller.TeamRoles, "team-roles", controller.TeamRoles,
+		"The roles or cluster roles whose users and openshift groups in the namespace are the members of its team.")
+	flagset.StringVar(&controller.TeamPermission, "team-permission", controller.TeamPermission,
+		"The permission of the team on the folders of the dashboards of the namespace, it is View, Edit or Admin.")
+	flagset.BoolVar(&controller.Sharding, "sharding", controller.Sharding,
+		"Spread the namespaces across the loader replicas by consistent hashing, every replica holds a lease "+
+			"and only applies the dashboards of its namespaces.")
+	flagset.StringVar(&controller.ShardIdentity, "shard-identity", controller.ShardIdentity,
+		"The name of the replica, empty means the hostname.")
+	flagset.StringVar(&controller.ShardLeaseNamespace, "shard-lease-namespace", controller.ShardLeaseNamespace,
+		"The namespace of the leases of the replicas, empty means the namespace of the loader.")
+	flagset.DurationVar(&controller.ShardLeaseDuration, "shard-lease-duration", controller.ShardLeaseDuration,
+		"How long the namespaces of a replica are kept after its lease is last renewed.")
+	flagset.BoolVar(&controller.GrafanaCloud, "grafana-cloud", controller.GrafanaCloud,
+		"The grafana is a grafana cloud stack, which has a single organization and no server admin api.")
+	flagset.BoolVar(&controller.NamespaceFolders, "namespace-folders", controller.NamespaceFolders,
+		"Put the dashboards without a folder annotation into the folder of their namespace, it is the "+
+			"observability.open-cluster-management.io/dashboard-folder annotation of the namespace or the namespace name.")
+	flagset.BoolVar(&controller.WatchManagedClusters, "watch-managed-clusters", controller.WatchManagedClusters,
+		"Watch the ManagedClusters to apply the configmaps with the observability.open-cluster-management.io/dashboard-managed-clusters "+
+			"annotation once per selected managed cluster, into the folder named after the cluster by default.")
+	flagset.BoolVar(&controller.NonEditableDashboards, "non-editable-dashboards", controller.NonEditableDashboards,
+		"Make the dashboards non-editable in grafana unless the configmap has the dashboard-editable annotation.")
+	flagset.BoolVar(&controller.SkipUnchangedDashboards, "skip-unchanged-dashboards", controller.SkipUnchangedDashboards,
+		"Get the stored dashboard before it is saved and skip the save when it is the same, so that the resyncs do not add new versions to the dashboard history.")
+	flagset.BoolVar(&controller.LogDashboardDiff, "log-dashboard-diff", controller.LogDashboardDiff,
+		"Fetch the current version of every dashboard before it is updated to log the panels and queries which are changed.")
+	flagset.BoolVar(&controller.ProvisionedByTag, "provisioned-by-tag", controller.ProvisionedByTag,
+		"Tag the dashboards with provisioned-by:grafana-dashboard-loader/<version>.")
+	flagset.StringSliceVar(&controller.ProtectedDashboardUIDs, "protected-dashboard-uids", controller.ProtectedDashboardUIDs,
+		"The uids of the dashboards which are never deleted, e.g. when a configmap with the same uids is deleted.")
+	flagset.StringSliceVar(&controller.ProtectedFolders, "protected-folders", controller.ProtectedFolders,
+		"The titles or uids of the folders which the loader never saves dashboards into and never deletes together with their dashboards.")
+	flagset.BoolVar(&controller.ProtectStockDashboards, "protect-stock-dashboards", controller.ProtectStockDashboards,
+		"Never delete the dashboards applied from the grafana-dashboard-acm configmaps in the open-cluster-management-observability namespace.")
+	flagset.BoolVar(&controller.AdoptDashboards, "adopt-dashboards", controller.AdoptDashboards,
+		"Adopt the existing dashboard which has the same title in the folder but is not managed by the loader instead of failing with name-exists, "+
+			"the configmap annotation observability.open-cluster-management.io/dashboard-adopt overrides it.")
+	flagset.BoolVar(&controller.OwnershipTags, "ownership-tags", controller.OwnershipTags,
+		"Tag the dashboards with source-namespace:, source-name: and source-key: of their configmaps and describe the folders created by the loader.")
+	flagset.BoolVar(&controller.MigrateDashboards, "migrate-dashboards", controller.MigrateDashboards,
+		"Upgrade the legacy rows, the graph and singlestat panels and the old template variables of the dashboards before they are posted.")
+	flagset.BoolVar(&controller.BlockAngularPanels, "block-angular-panels", controller.BlockAngularPanels,
+		"Do not apply the dashboards with the angular panels, e.g. graph or singlestat, which are not rendered by grafana 11 and later.")
+	flagset.StringVar(&controller.PolicyMode, "policy-mode", controller.PolicyMode,
+		"What is done with the dashboards which use the banned panels or datasources, reject does not apply them and "+
+			"sanitize replaces the banned panels with text panels and removes the banned queries and variables.")
+	flagset.StringArrayVar(&controller.FreezeWindows, "freeze-window", controller.FreezeWindows,
+		"A change freeze during which the dashboards are neither applied nor deleted, e.g. \"0 22 * * 5 60h\" is a cron expression of the start and the duration, it can be repeated.")
+	flagset.StringSliceVar(&controller.BannedPanelTypes, "banned-panel-types", controller.BannedPanelTypes,
+		"The panel plugins the dashboards must not use, they can be set in the policy section of the config file too.")
+	flagset.StringSliceVar(&controller.BannedDatasourceTypes, "banned-datasource-types", controller.BannedDatasourceTypes,
+		"The datasource plugins the panels, the queries and the variables of the dashboards must not use.")
+	flagset.DurationVar(&controller.MinRefreshInterval, "min-refresh-interval", controller.MinRefreshInterval,
+		"Raise the auto refresh of the dashboards to at least this interval, e.g. 30s, and remove the shorter ones from the time picker, 0 means no limit.")
+	flagset.DurationVar(&controller.MaxTimeRange, "max-time-range", controller.MaxTimeRange,
+		"Shorten the relative default time range of the dashboards to at most this duration, e.g. 168h, 0 means no limit.")
+	flagset.IntVar(&controller.MaxDashboardSize, "max-dashboard-size", controller.MaxDashboardSize,
+		"The limit in bytes of the request body of grafana or the proxy in front of it, the larger dashboards are not posted, 0 means no limit.")
+	flagset.BoolVar(&controller.ValidatePromQL, "validate-promql", controller.ValidatePromQL,
+		"Parse the queries of the prometheus targets and do not apply the dashboards with invalid queries, it is checked by the validate command too.")
+	flagset.StringSliceVar(&controller.InvalidPromQLSelectors, "invalid-promql-selectors", controller.InvalidPromQLSelectors,
+		"The metric selectors which the queries must not match when --validate-promql is set, e.g. the metrics which are not collected.")
+	flagset.StringSliceVar(&controller.LintRules, "lint-rules", controller.LintRules,
+		"The lint rules to run on every dashboard, the findings are posted as events and the "+
+			"status.observability.open-cluster-management.io/dashboard-lint annotation, one or more of "+
+			"template-datasource-rule, panel-datasource-rule, panel-description-rule and target-rate-interval-rule.")
+	flagset.BoolVar(&controller.LintBlockOnError, "lint-block-on-error", controller.LintBlockOnError,
+		"Do not apply the dashboards with the findings of the template-datasource-rule or the panel-datasource-rule.")
+	flagset.StringVar(&controller.DefaultFolder, "default-folder", controller.DefaultFolder,
+		"The folder of the dashboards without a folder annotation, e.g. \"{{ .ClusterName }} {{ .Namespace }}\".")
+	flagset.StringVar(&controller.ClusterName, "cluster-name", os.Getenv("CLUSTER_NAME"),
+		"The cluster name used by the {{ .ClusterName }} variable of the folder templates.")
+	flagset.BoolVar(&controller.WatchSecrets, "watch-secrets", controller.WatchSecrets,
+		"Also load the dashboards from the secrets which have the same labels as the dashboard configmaps.")
+	flagset.BoolVar(&controller.WatchGrafanaDashboards, "watch-grafana-dashboards", controller.WatchGrafanaDashboards,
+		"Also load the dashboards from the spec.json of the grafana-operator GrafanaDashboard resources.")
+	flagset.BoolVar(&controller.MetadataInformer, "metadata-informer", controller.MetadataInformer,
+		"Watch the metadata of the configmaps only and get a configmap once it is changed, so that the content of the configmaps is not kept in memory.")
+	flagset.StringVar(&controller.ConfigmapLabelSelector, "configmap-label-selector", controller.ConfigmapLabelSelector,
+		"The label selector sent with the list and the watch of the configmaps, e.g. grafana-custom-dashboard, the configmaps which do not match it are not seen, empty watches all the configmaps.")
+	flagset.Float32Var(&controller.KubeAPIQPS, "kube-api-qps", controller.KubeAPIQPS,
+		"The maximum rate of the requests to the kubernetes api server.")
+	flagset.IntVar(&controller.KubeAPIBurst, "kube-api-burst", controller.KubeAPIBurst,
+		"The maximum burst of the requests to the kubernetes api server.")
+	flagset.StringVar(&controller.KubeAPIContentType, "kube-api-content-type", controller.KubeAPIContentType,
+		"The content type of the requests to the kubernetes api server, e.g. application/vnd.kubernetes.protobuf, empty uses json.")
+	flagset.DurationVar(&controller.WatchTimeout, "watch-timeout", controller.WatchTimeout,
+		"How long every watch of the informers lasts before it is established again, 0 keeps the client-go default between 5 and 10 minutes.")
+	flagset.StringSliceVar(&controller.MigrateUIDsFrom, "migrate-uids-from", controller.MigrateUIDsFrom,
+		"The uid strategies which the dashboards may have been applied by before, name, hash, embedded or legacy for the uids with dots "+
+			"generated by the former versions, the dashboard found under a former uid is given the current uid in place.")
+	flagset.StringVar(&controller.UIDStrategy, "uid-strategy", controller.UIDStrategy,
+		"How the uid of the dashboards is chosen unless the configmap annotation observability.open-cluster-management.io/dashboard-uid-strategy says otherwise: "+
+			"name uses the embedded uid or the one generated from the configmap name and namespace, hash uses a hash of the namespace, the name and the data key, "+
+			"embedded uses the embedded uid only.")
+	flagset.Int64Var(&controller.ListPageSize, "list-page-size", controller.ListPageSize,
+		"The number of objects got by every list request of the configmaps, the secrets and the GrafanaDashboards, 0 lists all of them at once.")
+	flagset.StringVar(&controller.SidecarLabel, "sidecar-label", controller.SidecarLabel,
+		"Also load the configmaps with the k8s-sidecar dashboard label, e.g. grafana_dashboard.")
+	flagset.StringVar(&controller.SidecarLabelValue, "sidecar-label-value", controller.SidecarLabelValue,
+		"The value of the k8s-sidecar dashboard label to match, empty matches any value.")
+	flagset.StringVar(&controller.SidecarFolderAnnotation, "sidecar-folder-annotation", controller.SidecarFolderAnnotation,
+		"The annotation of the k8s-sidecar dashboards which decides the folder.")
+	flagset.StringVar(&controller.DashboardDir, "dashboard-dir", controller.DashboardDir,
+		"The local directory to load the *.json dashboards from, the sub directories are used as the folders.")
+	flagset.DurationVar(&controller.DashboardDirPollInterval, "dashboard-dir-poll-interval", controller.DashboardDirPollInterval,
+		"The interval to scan the dashboard directory for changes.")
+	flagset.StringVar(&controller.GitRepository, "git-repository", controller.GitRepository,
+		"The git repository to load the dashboards from, the git binary is required and shipped in the image.")
+	flagset.StringVar(&controller.GitRef, "git-ref", controller.GitRef,
+		"The branch, tag or commit of the git repository to sync.")
+	flagset.StringVar(&controller.GitPath, "git-path", controller.GitPath,
+		"The directory in the git repository which contains the dashboards.")
+	flagset.DurationVar(&controller.GitPollInterval, "git-poll-interval", controller.GitPollInterval,
+		"The interval to fetch the git repository for new commits.")
+	flagset.StringVar(&controller.GitCheckoutDir, "git-checkout-dir", controller.GitCheckoutDir,
+		"The directory to check out the git repository, a temp directory is used by default.")
+	flagset.StringVar(&controller.GrafanaServiceSelector, "grafana-service-selector", controller.GrafanaServiceSelector,
+		"The label selector of the grafana services to apply the dashboards to instead of the grafana url, empty disables the discovery.")
+	flagset.StringVar(&controller.GrafanaServiceNamespace, "grafana-service-namespace", controller.GrafanaServiceNamespace,
+		"The namespace of the grafana services, empty means the namespace of the loader.")
+	flagset.StringVar(&controller.GrafanaServicePort, "grafana-service-port", controller.GrafanaServicePort,
+		"The name of the port of the grafana services.")
+	flagset.StringVar(&controller.GrafanaServiceScheme, "grafana-service-scheme", controller.GrafanaServiceScheme,
+		"The scheme of the discovered grafana urls, http or https.")
+	flagset.BoolVar(&controller.GrafanaServiceEndpoints, "grafana-service-endpoints", controller.GrafanaServiceEndpoints,
+		"Apply the dashboards to every ready endpoint of the grafana services, for the grafana replicas which do not share a database.")
+	flagset.StringToStringVar(&controller.DashboardInputs, "dashboard-inputs", controller.DashboardInputs,
+		"The values of the __inputs of the dashboards exported for sharing keyed by the input name, e.g. DS_PROMETHEUS=Observatorium.")
+	flagset.StringToStringVar(&controller.DatasourceInputs, "datasource-inputs", controller.DatasourceInputs,
+		"The datasources of the datasource __inputs keyed by the plugin id, e.g. prometheus=Observatorium.")
+	flagset.StringVar(&controller.GrafanaComURL, "grafana-com-url", controller.GrafanaComURL,
+		"The url to download the dashboards referred by the *.grafana-com data keys.")
+	flagset.IntVar(&controller.DownloadCacheSize, "download-cache-size", controller.DownloadCacheSize,
+		"The number of the downloaded grafana.com and remote dashboards kept in memory for each kind of reference, 0 disables the cache.")
+	flagset.StringVar(&controller.OCIArtifact, "oci-artifact", controller.OCIArtifact,
+		"The oci artifact pushed by oras to load the dashboards from, e.g. quay.io/org/dashboards:v1.")
+	flagset.DurationVar(&controller.OCIPollInterval, "oci-poll-interval", controller.OCIPollInterval,
+		"The interval to check the oci artifact for a new digest.")
+	flagset.BoolVar(&controller.OCIInsecure, "oci-insecure", controller.OCIInsecure,
+		"Use plain http to pull the oci artifact.")
+	flagset.StringVar(&controller.OCIUsername, "oci-username", controller.OCIUsername,
+		"The username to pull the oci artifact.")
+	flagset.StringVar(&metricsAddr, "metrics-addr", metricsAddr,
+		"The address to expose /metrics and /version on, it is port 3002 of all the interfaces by default, empty disables them.")
+	flagset.StringVar(&controller.BackupDir, "backup-dir", controller.BackupDir,
+		"The directory, e.g. a mounted pvc, to back up the managed dashboards into, empty disables the backups.")
+	flagset.DurationVar(&controller.BackupInterval, "backup-interval", controller.BackupInterval,
+		"The interval to back up the managed dashboards.")
+	flagset.IntVar(&controller.BackupRetention, "backup-retention", controller.BackupRetention,
+		"The number of the latest backup archives to keep.")
+	flagset.BoolVar(&controller.ReportMCOStatus, "report-mco-status", controller.ReportMCOStatus,
+		"Report the counts of the synced and failed dashboards as the GrafanaDashboardsSynced condition of the MultiClusterObservability resource.")
+	flagset.StringVar(&controller.MCOName, "mco-name", controller.MCOName,
+		"The MultiClusterObservability resource to report to, the only one in the cluster is used by default.")
+	flagset.DurationVar(&controller.MCOStatusInterval, "mco-status-interval", controller.MCOStatusInterval,
+		"The interval to refresh the GrafanaDashboardsSynced condition.")
+	flagset.BoolVar(&controller.ReportSyncStatus, "report-sync-status", controller.ReportSyncStatus,
+		"Maintain a DashboardSyncReport resource listing the managed dashboards in every namespace of the dashboard sources, the CRD is required.")
+	flagset.StringVar(&controller.SyncReportName, "sync-report-name", controller.SyncReportName,
+		"The name of the DashboardSyncReport resources.")
+	flagset.DurationVar(&controller.SyncReportInterval, "sync-report-interval", controller.SyncReportInterval,
+		"The interval to refresh the DashboardSyncReport resources.")
+	flagset.StringVar(&controller.StatusConfigmap, "status-configmap", controller.StatusConfigmap,
+		"The configmap in the namespace of the loader to summarize the managed dashboards and their last results into, empty disables it.")
+	flagset.StringVar(&controller.RetryQueueConfigmap, "retry-queue-configmap", controller.RetryQueueConfigmap,
+		"The configmap in the namespace of the loader to persist the dashboards which still need to be applied or deleted into, so that they are retried after a restart, empty keeps them in memory only.")
+	flagset.DurationVar(&controller.RetryQueueInterval, "retry-queue-interval", controller.RetryQueueInterval,
+		"The interval to retry the failed deletions of the dashboards.")
+	flagset.StringSliceVar(&controller.WebhookURLs, "webhook-url", controller.WebhookURLs,
+		"The urls to post the json notifications of the failed applies, deletions and grafana side changes of the dashboards to.")
+	flagset.StringSliceVar(&controller.SlackWebhookURLs, "slack-webhook-url", controller.SlackWebhookURLs,
+		"The slack incoming webhooks to post the notifications to.")
+	flagset.DurationVar(&controller.WebhookTimeout, "webhook-timeout", controller.WebhookTimeout,
+		"The timeout to deliver a notification to a webhook.")
+	flagset.StringVar(&controller.AuditLogFile, "audit-log-file", controller.AuditLogFile,
+		"The file to append the audit records of all the grafana changes to, one json per line.")
+	flagset.StringVar(&controller.AuditWebhookURL, "audit-webhook-url", controller.AuditWebhookURL,
+		"The endpoint to post the audit record of every grafana change to.")
+	flagset.StringVar(&controller.AdminAddr, "admin-addr", controller.AdminAddr,
+		"The address of the admin api to trigger resyncs and list the managed dashboards, the ADMIN_TOKEN is required.")
+	flagset.StringVar(&controller.AdmissionAddr, "admission-addr", controller.AdmissionAddr,
+		"The address of the validating admission webhook which rejects the invalid dashboard configmaps, e.g. :9443.")
+	flagset.StringVar(&controller.AdmissionCertFile, "admission-cert", controller.AdmissionCertFile,
+		"The serving certificate of the admission webhook.")
+	flagset.StringVar(&controller.AdmissionKeyFile, "admission-key", controller.AdmissionKeyFile,
+		"The key of the serving certificate of the admission webhook.")
+	flagset.BoolVar(&controller.EnableDebug, "enable-debug", controller.EnableDebug,
+		"Expose the pprof handlers and the goroutine and heap dump trigger on the admin api.")
+	flagset.StringVar(&controller.DebugDumpDir, "debug-dump-dir", controller.DebugDumpDir,
+		"The directory to write the goroutine and heap dumps to.")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		// the password is not a flag so that it does not show up in the pod spec
+		controller.OCIPassword = os.Getenv("OCI_PASSWORD")
+		controller.AdminToken = os.Getenv("ADMIN_TOKEN")
+		if err := logging.Setup(); err != nil {
+			return err
+		}
+		if err := controller.ValidatePolicyMode(controller.PolicyMode); err != nil {
+			return err
+		}
+		if err := controller.ValidateConfigmapLabelSelector(controller.ConfigmapLabelSelector); err != nil {
+			return err
+		}
+		if err := controller.ValidateKubeAPIContentType(controller.KubeAPIContentType); err != nil {
+			return err
+		}
+		if err := controller.ValidateUIDStrategy(controller.UIDStrategy); err != nil {
+			return err
+		}
+		if err := controller.ValidateMigrateUIDsFrom(controller.MigrateUIDsFrom); err != nil {
+			return err
+		}
+		if err := controller.ValidateFreezeWindows(controller.FreezeWindows); err != nil {
+			return err
+		}
+		if err := controller.ValidateNamespaceFilter(); err != nil {
+			return err
+		}
+		if err := controller.ValidateTeamPermission(controller.TeamPermission); err != nil {
+			return err
+		}
+		if controller.ConfigFile != "" {
+			if _, err := controller.LoadConfig(controller.ConfigFile); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 
-	// use a channel to synchronize the finalization for a graceful shutdown
-	stop := make(chan struct{})
-	defer close(stop)
-
-	controller.RunGrafanaDashboardController(stop)
-
-	// use a channel to handle OS signals to terminate and gracefully shut
-	// down processing
-	sigTerm := make(chan os.Signal, 1)
-	signal.Notify(sigTerm, syscall.SIGTERM)
-	signal.Notify(sigTerm, syscall.SIGINT)
-	<-sigTerm
-
+	rootCmd.AddCommand(newRunCommand(), newSyncCommand(), newExportCommand(), newValidateCommand(),
+		newRestoreCommand(), newVersionCommand())
+	if err := rootCmd.Execute(); err != nil {
+		klog.Error(err)
+		klog.Flush()
+		os.Exit(1)
+	}
 }
@@ -3,12 +3,23 @@ module github.com/open-cluster-management/grafana-dashboard-loader
 go 1.16
 
 require (
+	github.com/go-logr/logr v0.2.0
+	github.com/prometheus/client_golang v1.8.0
+	github.com/prometheus/prometheus v1.8.2-0.20201119142752-3ad25a6dc3d9
+	github.com/spf13/cobra v1.1.1
 	github.com/spf13/pflag v1.0.5
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.0.1
+	go.opentelemetry.io/otel/sdk v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
+	golang.org/x/net v0.0.0-20201110031124-69a78807bb2b
 	gopkg.in/yaml.v2 v2.3.0
 	k8s.io/api v0.19.4
 	k8s.io/apimachinery v0.19.4
 	k8s.io/client-go v0.19.4
-	k8s.io/klog v1.0.0
+	k8s.io/component-base v0.19.4
+	k8s.io/klog/v2 v2.4.0
+	sigs.k8s.io/yaml v1.2.0
 )
 
 // Resolves CVE-2020-14040
@@ -0,0 +1,136 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// AdminAddr is the address of the admin api, empty means disabled
+	AdminAddr = ""
+	// AdminToken is the bearer token required by the admin api
+	AdminToken = ""
+)
+
+var (
+	resyncLock sync.Mutex
+	// resyncFuncs re-deliver the objects of the watched sources
+	resyncFuncs []func()
+)
+
+// registerInformerResync makes a resync re-deliver all the objects in the informer cache to the handler
+func registerInformerResync(informer cache.SharedIndexInformer, handler cache.ResourceEventHandler) {
+	resyncLock.Lock()
+	defer resyncLock.Unlock()
+	resyncFuncs = append(resyncFuncs, func() {
+		for _, obj := range informer.GetStore().List() {
+			handler.OnUpdate(obj, obj)
+		}
+	})
+}
+
+// resyncAll applies all the dashboards again, the watched sources are re-delivered at once
+// while the polled sources are applied on their next poll
+func resyncAll() {
+	for _, state := range allSyncStates() {
+		state.reset()
+	}
+	redeliverAll()
+	klog.Info("all the dashboards are resynced")
+}
+
+// redeliverAll delivers all the watched objects again, the ones which are synced already are skipped
+func redeliverAll() {
+	resyncLock.Lock()
+	funcs := append([]func(){}, resyncFuncs...)
+	resyncLock.Unlock()
+	for _, f := range funcs {
+		f()
+	}
+}
+
+// serveAdmin serves the admin api on the address until the process exits
+func serveAdmin(addr string) {
+	if AdminToken == "" {
+		klog.Error("the admin api is disabled since ADMIN_TOKEN is not set")
+		return
+	}
+	err := http.ListenAndServe(addr, newAdminHandler())
+	if err != nil {
+		klog.Error("failed to serve the admin api ", "error ", err)
+	}
+}
+
+func newAdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/resync", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		klog.Info("resync is triggered by the admin api")
+		go resyncAll()
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "resync triggered"})
+	})
+	mux.HandleFunc("/api/v1/dashboards", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "only GET is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, listDashboardStatuses())
+	})
+	mux.HandleFunc("/api/v1/dashboards/rollback", handleRollback)
+	if EnableDebug {
+		registerDebugHandlers(mux)
+	}
+	return authenticated(mux)
+}
+
+// authenticated rejects the requests without the admin bearer token
+func authenticated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(AdminToken)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// listDashboardStatuses returns the last sync of every managed configmap sorted by the source and name
+func listDashboardStatuses() []dashboardStatus {
+	statuses := []dashboardStatus{}
+	for _, state := range allSyncStates() {
+		statuses = append(statuses, state.listStatuses()...)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Source != statuses[j].Source {
+			return statuses[i].Source < statuses[j].Source
+		}
+		if statuses[i].Namespace != statuses[j].Namespace {
+			return statuses[i].Namespace < statuses[j].Namespace
+		}
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		klog.Error("failed to write the response ", "error ", err)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAdminAuthentication(t *testing.T) {
+	defer func(token string) { AdminToken = token }(AdminToken)
+	AdminToken = "secret"
+	handler := newAdminHandler()
+
+	testCaseList := []struct {
+		name     string
+		method   string
+		path     string
+		header   string
+		expected int
+	}{
+		{"no token", "GET", "/api/v1/dashboards", "", http.StatusUnauthorized},
+
+		{"wrong token", "GET", "/api/v1/dashboards", "Bearer wrong", http.StatusUnauthorized},
+
+		{"list dashboards", "GET", "/api/v1/dashboards", "Bearer secret", http.StatusOK},
+
+		{"resync with GET", "GET", "/api/v1/resync", "Bearer secret", http.StatusMethodNotAllowed},
+
+		{"unknown path", "GET", "/api/v1/unknown", "Bearer secret", http.StatusNotFound},
+	}
+
+	for _, c := range testCaseList {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, w.Code, c.expected)
+		}
+	}
+
+	// the admin api is closed without a token
+	AdminToken = ""
+	req := httptest.NewRequest("GET", "/api/v1/dashboards", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("case (empty token) output: (%v) is not the expected: (%v)", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAdminDashboardsAndResync(t *testing.T) {
+	defer func(token string) { AdminToken = token }(AdminToken)
+	AdminToken = "secret"
+	handler := newAdminHandler()
+
+	state := newSyncState("admin-test")
+	synced := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "synced", Namespace: "test"},
+		Data:       map[string]string{"synced.json": "{}"},
+	}
+	failed := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "failed", Namespace: "test"},
+		Data:       map[string]string{"failed.json": "{}"},
+	}
+	state.markSynced(synced, nil)
+	state.markFailed(failed, nil, fmt.Errorf("grafana is down"))
+
+	req := httptest.NewRequest("GET", "/api/v1/dashboards", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	statuses := []dashboardStatus{}
+	err := json.Unmarshal(w.Body.Bytes(), &statuses)
+	if err != nil {
+		t.Fatalf("failed to parse the dashboards: %v", err)
+	}
+	found := map[string]dashboardStatus{}
+	for _, status := range statuses {
+		if status.Source == "admin-test" {
+			found[status.Name] = status
+		}
+	}
+	if !found["synced"].Synced || found["synced"].Dashboards[0] != "synced.json" {
+		t.Errorf("the synced configmap is not listed: %v", found["synced"])
+	}
+	if found["failed"].Synced || found["failed"].Error != "grafana is down" {
+		t.Errorf("the failed configmap is not listed: %v", found["failed"])
+	}
+
+	resynced := make(chan struct{}, 1)
+	resyncLock.Lock()
+	resyncFuncs = append(resyncFuncs, func() { resynced <- struct{}{} })
+	resyncLock.Unlock()
+	defer func(funcs []func()) {
+		resyncLock.Lock()
+		resyncFuncs = funcs
+		resyncLock.Unlock()
+	}(resyncFuncs[:len(resyncFuncs)-1])
+
+	req = httptest.NewRequest("POST", "/api/v1/resync", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("case (resync) output: (%v) is not the expected: (%v)", w.Code, http.StatusAccepted)
+	}
+	select {
+	case <-resynced:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the sources are not resynced")
+	}
+	if state.isSynced(synced) {
+		t.Errorf("the configmap should be applied again after resync")
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// maxAdmissionReviewSize bounds the request body, the configmap is at most maxConfigmapSize
+const maxAdmissionReviewSize = 4 * maxConfigmapSize
+
+var (
+	// AdmissionAddr is the address of the validating admission webhook, empty means disabled
+	AdmissionAddr = ""
+	// AdmissionCertFile is the serving certificate of the webhook
+	AdmissionCertFile = ""
+	// AdmissionKeyFile is the key of the serving certificate
+	AdmissionKeyFile = ""
+)
+
+// serveAdmission serves the admission webhook on the address until the process exits
+func serveAdmission(addr string) {
+	if AdmissionCertFile == "" || AdmissionKeyFile == "" {
+		klog.Error("the admission webhook is disabled since the serving certificate is not set")
+		return
+	}
+	err := http.ListenAndServeTLS(addr, AdmissionCertFile, AdmissionKeyFile, newAdmissionHandler())
+	if err != nil {
+		klog.ErrorS(err, "failed to serve the admission webhook")
+	}
+}
+
+func newAdmissionHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/validate-configmap", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxAdmissionReviewSize))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read the request: %v", err), http.StatusBadRequest)
+			return
+		}
+		review := admissionv1.AdmissionReview{}
+		if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+			http.Error(w, "the request is not an AdmissionReview", http.StatusBadRequest)
+			return
+		}
+		review.Response = reviewConfigmap(review.Request)
+		review.Response.UID = review.Request.UID
+		review.Request = nil
+		writeJSON(w, http.StatusOK, review)
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// reviewConfigmap rejects the dashboard configmaps which the loader cannot apply,
+// the other configmaps and the deletions are always allowed
+func reviewConfigmap(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	allowed := &admissionv1.AdmissionResponse{Allowed: true}
+	if req.Operation == admissionv1.Delete || req.Kind.Kind != "ConfigMap" {
+		return allowed
+	}
+	cm := &corev1.ConfigMap{}
+	if err := json.Unmarshal(req.Object.Raw, cm); err != nil {
+		return &admissionv1.AdmissionResponse{Result: &metav1.Status{
+			Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
+			Message: fmt.Sprintf("failed to decode the configmap: %v", err),
+		}}
+	}
+	if !isDesiredDashboardConfigmap(cm) {
+		return allowed
+	}
+
+	problems, warnings := validateDashboardConfigmap(cm)
+	if len(problems) == 0 {
+		allowed.Warnings = warnings
+		return allowed
+	}
+	klog.InfoS("the dashboard configmap is rejected", "configmap", klog.KObj(cm), "problems", problems)
+	return &admissionv1.AdmissionResponse{
+		Warnings: warnings,
+		Result: &metav1.Status{
+			Status: metav1.StatusFailure, Code: http.StatusUnprocessableEntity, Reason: metav1.StatusReasonInvalid,
+			Message: fmt.Sprintf("the dashboards are invalid: %v", strings.Join(problems, "; ")),
+		},
+	}
+}
+
+// validateDashboardConfigmap checks the size, the dashboards and the folder annotations of the configmap,
+// the referred dashboards are not downloaded at admission time and are checked by the loader instead
+func validateDashboardConfigmap(cm *corev1.ConfigMap) ([]string, []string) {
+	problems, warnings := []string{}, []string{}
+	size := 0
+	for key, value := range cm.Data {
+		size += len(key) + len(value)
+	}
+	for key, value := range cm.BinaryData {
+		size += len(key) + len(value)
+	}
+	if err := checkConfigmapSize(size); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if names := targetNames(); len(names) > 0 {
+		// the targets are warned only since the discovered ones may show up later
+		for _, name := range unknownTargetNames(cm, names) {
+			warnings = append(warnings, fmt.Sprintf("%v: the grafana target %v is not configured", grafanaInstanceKey, name))
+		}
+	}
+
+	dashboards := map[string]string{}
+	for key, value := range cm.Data {
+		if isGrafanaComReference(key) || isRemoteReference(key) {
+			continue
+		}
+		dashboard, err := resolveDashboardInputs(cm, value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%v: %v", key, err))
+			continue
+		}
+		dashboards[key] = dashboard
+	}
+	for key, value := range cm.BinaryData {
+		if !isGzipDashboard(key, value) {
+			continue
+		}
+		dashboard, err := gunzip(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%v: %v", key, err))
+			continue
+		}
+		if dashboard, err = resolveDashboardInputs(cm, dashboard); err != nil {
+			problems = append(problems, fmt.Sprintf("%v: %v", key, err))
+			continue
+		}
+		dashboards[key] = dashboard
+	}
+	keys := []string{}
+	for key := range dashboards {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := validateDashboard(dashboards[key]); err != nil {
+			problems = append(problems, fmt.Sprintf("%v: %v", key, err))
+			continue
+		}
+		dashboard := map[string]interface{}{}
+		json.Unmarshal([]byte(dashboards[key]), &dashboard)
+		// the violations are rejected by validateDashboard unless they are sanitized
+		if hasPolicy() && activeSettings().policyMode == policyModeSanitize {
+			for _, violation := range enforcePolicy(dashboard, false) {
+				warnings = append(warnings, fmt.Sprintf("%v: %v", key, violation))
+			}
+		}
+		for _, change := range applyGuardrails(dashboard) {
+			warnings = append(warnings, fmt.Sprintf("%v: %v", key, change))
+		}
+		if err := checkDashboardSize(dashboard); err != nil {
+			problems = append(problems, fmt.Sprintf("%v: %v", key, err))
+		}
+		if warning := angularPanelWarning(dashboard); warning != "" && BlockAngularPanels {
+			problems = append(problems, fmt.Sprintf("%v: %v", key, warning))
+		} else if warning != "" {
+			warnings = append(warnings, fmt.Sprintf("%v: %v", key, warning))
+		}
+		for _, finding := range lintDashboard(dashboard) {
+			warnings = append(warnings, fmt.Sprintf("%v: %v", key, finding))
+		}
+	}
+
+	return append(problems, validateFolderAnnotations(cm)...), warnings
+}
+
+// validateFolderAnnotations checks the folder templates and that the per key folders refer to the dashboards of the configmap
+func validateFolderAnnotations(cm *corev1.ConfigMap) []string {
+	problems := []string{}
+	if isPerClusterConfigmap(cm) {
+		// the folders of the per cluster dashboards are checked as they are rendered for a managed cluster
+		if _, err := matchedManagedClusters(cm); err != nil {
+			problems = append(problems, err.Error())
+		}
+		cm = clusterConfigmap(cm, "cluster")
+	}
+	annotations := cm.GetAnnotations()
+	keys := []string{}
+	for key := range annotations {
+		if key == customFolderKey || strings.HasPrefix(key, dashboardFolderKeyPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := validateFolderTitle(cm, annotations[key]); err != nil {
+			problems = append(problems, fmt.Sprintf("%v: %v", key, err))
+		}
+		if dataKey := strings.TrimPrefix(key, dashboardFolderKeyPrefix); dataKey != key {
+			_, inData := cm.Data[dataKey]
+			_, inBinaryData := cm.BinaryData[dataKey]
+			if !inData && !inBinaryData {
+				problems = append(problems, fmt.Sprintf("%v: there is no dashboard %v in the configmap", key, dataKey))
+			}
+		}
+	}
+	return problems
+}
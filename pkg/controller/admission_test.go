@@ -0,0 +1,100 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestAdmissionWebhook(t *testing.T) {
+	valid := `{"title": "a", "schemaVersion": 27, "panels": []}`
+	configmapOf := func(data map[string]string, annotations map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: annotations,
+				Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+			Data: data,
+		}
+	}
+
+	testCaseList := []struct {
+		name      string
+		operation admissionv1.Operation
+		cm        *corev1.ConfigMap
+		allowed   bool
+		expected  string
+	}{
+		{"valid", admissionv1.Create, configmapOf(map[string]string{"a.json": valid}, nil), true, ""},
+
+		{"not a dashboard", admissionv1.Create, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "test"},
+			Data:       map[string]string{"a.json": "{"},
+		}, true, ""},
+
+		{"deleted", admissionv1.Delete, configmapOf(map[string]string{"a.json": "{"}, nil), true, ""},
+
+		{"invalid json", admissionv1.Create, configmapOf(map[string]string{"a.json": "{"}, nil), false,
+			"a.json: invalid dashboard json"},
+
+		{"schema mismatch", admissionv1.Update, configmapOf(map[string]string{"a.json": `{"title": "a", "panels": []}`}, nil), false,
+			"a.json: the dashboard does not match the grafana schema: schemaVersion: missing required field"},
+
+		{"too large", admissionv1.Create, configmapOf(map[string]string{"a.json": valid, "b.txt": strings.Repeat("a", maxConfigmapSize)}, nil), false,
+			"larger than the 1.0MiB limit of a configmap"},
+
+		{"folder template", admissionv1.Create,
+			configmapOf(map[string]string{"a.json": valid}, map[string]string{customFolderKey: "{{ .Namespace }} dashboards"}), true, ""},
+
+		{"invalid folder template", admissionv1.Create,
+			configmapOf(map[string]string{"a.json": valid}, map[string]string{customFolderKey: "{{ .Unknown }}"}), false,
+			customFolderKey + ": failed to execute folder template"},
+
+		{"folder of missing dashboard", admissionv1.Create,
+			configmapOf(map[string]string{"a.json": valid}, map[string]string{dashboardFolderKeyPrefix + "b.json": "SLOs"}), false,
+			"there is no dashboard b.json in the configmap"},
+
+		{"referred dashboard", admissionv1.Create,
+			configmapOf(map[string]string{"a.json": valid, "b.grafana-com": "not checked"}, nil), true, ""},
+	}
+
+	server := httptest.NewServer(newAdmissionHandler())
+	defer server.Close()
+	for _, c := range testCaseList {
+		review := admissionv1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+			Request: &admissionv1.AdmissionRequest{
+				UID:       "uid",
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+				Operation: c.operation,
+				Object:    runtime.RawExtension{Object: c.cm},
+			},
+		}
+		body, _ := json.Marshal(review)
+		resp, err := http.Post(server.URL+"/validate-configmap", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("case (%v) failed to post the review: %v", c.name, err)
+		}
+		output := admissionv1.AdmissionReview{}
+		json.NewDecoder(resp.Body).Decode(&output)
+		resp.Body.Close()
+		if output.Response == nil || output.Response.UID != "uid" {
+			t.Fatalf("case (%v) output: (%v) is not a response to the review", c.name, output)
+		}
+		if output.Response.Allowed != c.allowed {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output.Response.Allowed, c.allowed)
+		}
+		if c.expected != "" && (output.Response.Result == nil || !strings.Contains(output.Response.Result.Message, c.expected)) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output.Response.Result, c.expected)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+// dashboardAdoptKey is the annotation to adopt, "true", or not, "false", the existing dashboards of the configmap
+const dashboardAdoptKey = "observability.open-cluster-management.io/dashboard-adopt"
+
+// AdoptDashboards takes over the dashboard which has the same title in the folder but is not managed by the loader,
+// instead of failing with name-exists the dashboard is updated with the uid and the content of the configmap
+// and tagged with its source so that it is not adopted again by another configmap
+var AdoptDashboards = false
+
+// adoptsDashboards checks whether the existing dashboards are adopted for the configmap
+func adoptsDashboards(cm *corev1.ConfigMap) bool {
+	switch cm.GetAnnotations()[dashboardAdoptKey] {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return AdoptDashboards
+}
+
+// isNameExists checks whether grafana refused the dashboard since another dashboard has its title in the folder
+func isNameExists(err error) bool {
+	apiErr, ok := err.(*GrafanaAPIError)
+	return ok && apiErr.StatusCode == http.StatusPreconditionFailed && strings.Contains(string(apiErr.Body), "name-exists")
+}
+
+// isManagedHit checks whether the dashboard is applied by the loader by its tags
+func isManagedHit(hit SearchHit) bool {
+	for _, tag := range hit.Tags {
+		if strings.HasPrefix(tag, provisionedByTagPrefix) || isOwnershipTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// adoptDashboard saves the dashboard over the one which has the same title in the folder,
+// saveErr is returned when there is no dashboard to adopt, e.g. the one with the title is managed by the loader
+func adoptDashboard(ctx context.Context, orgID string, cm *corev1.ConfigMap, key string, dashboard map[string]interface{},
+	folderID float64, saveErr error) (SavedDashboard, error) {
+	if !isNameExists(saveErr) || !adoptsDashboards(cm) {
+		return SavedDashboard{}, saveErr
+	}
+	hits, err := clientFor(ctx).SearchDashboards(ctx, orgID)
+	if err != nil {
+		klog.ErrorS(err, "failed to search the dashboard to adopt", "configmap", klog.KObj(cm), "key", key)
+		return SavedDashboard{}, saveErr
+	}
+	title, _ := dashboard["title"].(string)
+	for _, hit := range hits {
+		if hit.Type != "dash-db" || hit.FolderID != folderID || !strings.EqualFold(hit.Title, title) {
+			continue
+		}
+		if isManagedHit(hit) {
+			klog.InfoS("the dashboard with the same title is managed by the loader, it is not adopted",
+				"configmap", klog.KObj(cm), "key", key, "uid", hit.UID)
+			return SavedDashboard{}, saveErr
+		}
+		if err := checkDashboardUIDOwner(ctx, orgID, hit.UID, cm, key); err != nil {
+			klog.InfoS("the dashboard with the same title is not adopted", "configmap", klog.KObj(cm), "key", key,
+				"reason", err)
+			return SavedDashboard{}, saveErr
+		}
+
+		// the id of the existing dashboard makes grafana update it with the uid of the configmap
+		adopted := make(map[string]interface{}, len(dashboard))
+		for field, value := range dashboard {
+			adopted[field] = value
+		}
+		adopted["id"] = hit.ID
+		setOwnershipTags(adopted, cm, key)
+		saved, err := clientFor(ctx).SaveDashboard(ctx, orgID, adopted, folderID, true)
+		if err != nil {
+			return SavedDashboard{}, err
+		}
+		klog.InfoS("the existing dashboard is adopted", "configmap", klog.KObj(cm), "key", key, "uid", saved.UID,
+			"former uid", hit.UID, "org", orgID)
+		metrics.DashboardsAdopted.Inc()
+		recordEvent(cm, corev1.EventTypeNormal, reasonDashboardAdopted, "The existing dashboard %v (uid %v) is adopted for %v",
+			hit.Title, hit.UID, key)
+		forgetManagedDashboard(orgID, hit.UID)
+		return saved, nil
+	}
+	return SavedDashboard{}, saveErr
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// titleGrafanaClient refuses the new dashboard whose title is in the folder already as grafana does,
+// the dashboard saved with the id of an existing one replaces it with its own uid
+type titleGrafanaClient struct {
+	*fakeGrafanaClient
+}
+
+func (c *titleGrafanaClient) SaveDashboard(ctx context.Context, orgID string, dashboard map[string]interface{},
+	folderID float64, overwrite bool) (SavedDashboard, error) {
+	c.Lock()
+	for uid, d := range c.dashboards[orgID] {
+		title, _ := d.dashboard["title"].(string)
+		if uid == dashboard["uid"] || d.folderID != folderID || !strings.EqualFold(title, dashboard["title"].(string)) {
+			continue
+		}
+		if dashboard["id"] != d.id {
+			c.Unlock()
+			return SavedDashboard{}, &GrafanaAPIError{"POST", "/api/dashboards/db", http.StatusPreconditionFailed,
+				[]byte(`{"status": "name-exists"}`)}
+		}
+		delete(c.dashboards[orgID], uid)
+		c.dashboards[orgID][dashboard["uid"].(string)] = d
+	}
+	c.Unlock()
+	return c.fakeGrafanaClient.SaveDashboard(ctx, orgID, dashboard, folderID, overwrite)
+}
+
+func TestAdoptDashboard(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	grafanaClient = &titleGrafanaClient{fake}
+	defer func(adopt bool) { AdoptDashboards = adopt }(AdoptDashboards)
+
+	testCaseList := []struct {
+		name        string
+		adopt       bool
+		annotations map[string]string
+		existing    map[string]interface{}
+		hasErr      bool
+		expected    []string
+	}{
+		{"not adopted", false, nil, map[string]interface{}{"uid": "hand-made", "title": "SLO"}, true,
+			[]string{"hand-made"}},
+
+		{"adopted", true, nil, map[string]interface{}{"uid": "hand-made", "title": "SLO"}, false,
+			[]string{"slo"}},
+
+		{"adopted by annotation", false, map[string]string{dashboardAdoptKey: "true"},
+			map[string]interface{}{"uid": "hand-made", "title": "slo"}, false, []string{"slo"}},
+
+		{"disabled by annotation", true, map[string]string{dashboardAdoptKey: "false"},
+			map[string]interface{}{"uid": "hand-made", "title": "SLO"}, true, []string{"hand-made"}},
+
+		{"managed by the loader", true, nil,
+			map[string]interface{}{"uid": "managed", "title": "SLO", "tags": []interface{}{"source-name:other"}}, true,
+			[]string{"managed"}},
+	}
+
+	for _, c := range testCaseList {
+		AdoptDashboards = c.adopt
+		fake.dashboards = map[string]map[string]fakeDashboard{"": {c.existing["uid"].(string): {id: 100, dashboard: c.existing}}}
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "slo", Namespace: "test", Annotations: c.annotations,
+				Labels: map[string]string{generalFolderKey: "true"}},
+			Data: map[string]string{"slo.json": `{"uid": "slo", "title": "SLO"}`},
+		}
+		if err := updateDashboard(context.TODO(), nil, cm, false); (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+		output := []string{}
+		for uid := range fake.dashboards[""] {
+			output = append(output, uid)
+		}
+		if len(output) != len(c.expected) || output[0] != c.expected[0] {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+		if adopted, ok := fake.dashboards[""]["slo"]; ok && adopted.id != 100 {
+			t.Errorf("case (%v) the adopted dashboard id: (%v) is not the expected: (100)", c.name, adopted.id)
+		}
+		deleteDashboard(context.TODO(), cm)
+	}
+}
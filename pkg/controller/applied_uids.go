@@ -0,0 +1,115 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+type previousUIDsKey struct{}
+
+// withPreviousUIDs returns the context with the uids applied by the last sync of the configmap keyed by the data key
+func withPreviousUIDs(ctx context.Context, uids map[string]string) context.Context {
+	return context.WithValue(ctx, previousUIDsKey{}, uids)
+}
+
+// previousUIDs returns the uids applied by the last sync of the configmap keyed by the data key,
+// they are read from the status annotation when the configmap is not synced since the loader started
+func previousUIDs(state *syncState, cm *corev1.ConfigMap) map[string]string {
+	if uids := state.appliedUIDs(configmapKey(cm)); len(uids) > 0 {
+		return uids
+	}
+	uids := map[string]string{}
+	if value, ok := cm.GetAnnotations()[appliedUIDsKey]; ok {
+		if err := json.Unmarshal([]byte(value), &uids); err != nil {
+			klog.ErrorS(err, "invalid annotation, the renamed data keys are not cleaned up", "configmap", klog.KObj(cm),
+				"annotation", appliedUIDsKey)
+			return map[string]string{}
+		}
+	}
+	return uids
+}
+
+// withoutDataKeys returns the copy of the configmap without the data keys, e.g. the keys whose uids are known already
+func withoutDataKeys(cm *corev1.ConfigMap, keys map[string]string) *corev1.ConfigMap {
+	if len(keys) == 0 {
+		return cm
+	}
+	copied := &corev1.ConfigMap{ObjectMeta: cm.ObjectMeta, Data: map[string]string{}, BinaryData: map[string][]byte{}}
+	for key, value := range cm.Data {
+		if _, ok := keys[key]; !ok {
+			copied.Data[key] = value
+		}
+	}
+	for key, value := range cm.BinaryData {
+		if _, ok := keys[key]; !ok {
+			copied.BinaryData[key] = value
+		}
+	}
+	return copied
+}
+
+// keepPreviousUIDs keeps the previous uids which are no longer applied in the failed sync,
+// their dashboards are still in grafana so they are deleted by the next sync which succeeds
+func keepPreviousUIDs(applied *appliedDashboards, previous map[string]string) {
+	applied.Lock()
+	defer applied.Unlock()
+	current := map[string]bool{}
+	for _, uid := range applied.uids {
+		current[uid] = true
+	}
+	for key, uid := range previous {
+		if _, ok := applied.uids[key]; !ok && !current[uid] {
+			applied.uids[key] = uid
+		}
+	}
+}
+
+// pruneRenamedDashboards deletes the dashboards of the last sync whose uids are not applied any more,
+// e.g. the data key is renamed or removed, so that the dashboard does not stay in grafana under the old uid,
+// the applied uids are the uids of the current sync keyed by the data key
+func pruneRenamedDashboards(ctx context.Context, orgID string, cm *corev1.ConfigMap, applied map[string]string) error {
+	// the uids of the per cluster configmaps are not tracked by cluster, their dashboards are deleted with the cluster
+	if managedClusterOf(cm) != "" {
+		return nil
+	}
+	var pruneErr error
+	previous, _ := ctx.Value(previousUIDsKey{}).(map[string]string)
+	current := map[string]bool{}
+	for _, uid := range applied {
+		current[uid] = true
+	}
+	for key, uid := range previous {
+		if current[uid] {
+			continue
+		}
+		if isProtectedDashboard(ctx, orgID, uid, "") {
+			klog.InfoS("the protected dashboard of the renamed data key is not deleted", "configmap", klog.KObj(cm),
+				"key", key, "uid", uid)
+			continue
+		}
+		if err := checkDashboardUIDOwner(ctx, orgID, uid, cm, key); err != nil {
+			klog.InfoS("the dashboard of the renamed data key is not deleted", "configmap", klog.KObj(cm), "key", key,
+				"reason", err)
+			continue
+		}
+		err := clientFor(ctx).DeleteDashboard(ctx, orgID, uid)
+		// the old uid is kept by the failed sync until its dashboard is deleted
+		if err != nil && !isGrafanaNotFound(err) {
+			klog.ErrorS(err, "failed to delete the dashboard of the renamed data key", "configmap", klog.KObj(cm),
+				"key", key, "uid", uid)
+			pruneErr = fmt.Errorf("failed to delete the dashboard %v of the renamed data key %v: %v", uid, key, err)
+			continue
+		}
+		klog.InfoS("the dashboard of the renamed data key is deleted", "configmap", klog.KObj(cm), "key", key, "uid", uid,
+			"org", orgID)
+		forgetManagedDashboard(orgID, uid)
+	}
+	return pruneErr
+}
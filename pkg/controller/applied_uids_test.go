@@ -0,0 +1,155 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPruneRenamedDashboards(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+
+	renamedConfigmap := func(key string, annotations map[string]string) *corev1.ConfigMap {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[dashboardUIDStrategyKey] = uidStrategyHash
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "renamed", Namespace: "test", Annotations: annotations},
+			Data:       map[string]string{key: `{"title": "renamed"}`},
+		}
+	}
+	uidOf := func(key string) string {
+		uid, _ := getDashboardUID(renamedConfigmap(key, nil), key, map[string]interface{}{})
+		return uid
+	}
+	state := newSyncState("configmap")
+	defer state.forget(renamedConfigmap("a.json", nil))
+
+	testCaseList := []struct {
+		name     string
+		state    *syncState
+		cm       *corev1.ConfigMap
+		saveErr  bool
+		expected map[string]bool
+	}{
+		{"applied", state, renamedConfigmap("a.json", nil), false, map[string]bool{uidOf("a.json"): true}},
+
+		{"renamed", state, renamedConfigmap("b.json", nil), false,
+			map[string]bool{uidOf("a.json"): false, uidOf("b.json"): true}},
+
+		{"renamed again but failed", state, renamedConfigmap("c.json", nil), true,
+			map[string]bool{uidOf("b.json"): true, uidOf("c.json"): false}},
+
+		{"renamed after the failure", state, renamedConfigmap("c.json", nil), false,
+			map[string]bool{uidOf("b.json"): false, uidOf("c.json"): true}},
+
+		// the loader restarted, the uids of the last sync are read from the status annotation
+		{"renamed after restart", newSyncState("configmap"),
+			renamedConfigmap("d.json", map[string]string{appliedUIDsKey: `{"c.json": "` + uidOf("c.json") + `"}`}), false,
+			map[string]bool{uidOf("c.json"): false, uidOf("d.json"): true}},
+	}
+
+	for _, c := range testCaseList {
+		fake.saveErrs = nil
+		if c.saveErr {
+			fake.saveErrs = map[string]error{}
+			for key := range c.cm.Data {
+				fake.saveErrs[uidOf(key)] = &GrafanaAPIError{"POST", "/api/dashboards/db", 500, []byte("down")}
+			}
+		}
+		if err := syncDashboard(context.TODO(), c.state, nil, c.cm); (err != nil) != c.saveErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.saveErr)
+		}
+		for uid, expected := range c.expected {
+			if _, ok := fake.dashboards[""][uid]; ok != expected {
+				t.Errorf("case (%v) dashboard %v exists: (%v) is not the expected: (%v)", c.name, uid, ok, expected)
+			}
+		}
+	}
+}
+
+func TestPruneRenamedDashboardsUnreachable(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+
+	renamedConfigmap := func(key string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "unreachable", Namespace: "test",
+				Annotations: map[string]string{dashboardUIDStrategyKey: uidStrategyHash}},
+			Data: map[string]string{key: `{"title": "unreachable"}`},
+		}
+	}
+	uidOf := func(key string) string {
+		uid, _ := getDashboardUID(renamedConfigmap(key), key, map[string]interface{}{})
+		return uid
+	}
+	state := newSyncState("configmap")
+	defer state.forget(renamedConfigmap("a.json"))
+	if err := syncDashboard(context.TODO(), state, nil, renamedConfigmap("a.json")); err != nil {
+		t.Fatalf("failed to sync the dashboard: %v", err)
+	}
+
+	// the request without a response is reported as 404 without a body
+	fake.deleteErrs = map[string]error{uidOf("a.json"): &GrafanaAPIError{"DELETE", "/api/dashboards/uid", 404, nil}}
+	if err := syncDashboard(context.TODO(), state, nil, renamedConfigmap("b.json")); err == nil {
+		t.Errorf("the prune should fail while grafana is unreachable")
+	}
+	fake.deleteErrs = nil
+	if err := syncDashboard(context.TODO(), state, nil, renamedConfigmap("b.json")); err != nil {
+		t.Errorf("failed to sync the dashboard again: %v", err)
+	}
+	if _, ok := fake.dashboards[""][uidOf("a.json")]; ok {
+		t.Errorf("the dashboard of the renamed data key should be deleted once grafana is reachable")
+	}
+}
+
+func TestDeleteByAppliedUIDs(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+
+	testCaseList := []struct {
+		name     string
+		recorded map[string]string
+		hasErr   bool
+		expected []string
+	}{
+		{"recorded uids", map[string]string{"slo.json": "slo", "slo.remote": "remote"}, false, []string{"kept"}},
+
+		{"unresolvable reference without recorded uid", map[string]string{"slo.json": "slo"}, true, []string{"kept", "remote"}},
+	}
+
+	for _, c := range testCaseList {
+		fake.dashboards[""] = map[string]fakeDashboard{}
+		for _, uid := range []string{"slo", "remote", "kept"} {
+			fake.dashboards[""][uid] = fakeDashboard{id: 1, dashboard: map[string]interface{}{"uid": uid}}
+		}
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "slo", Namespace: "test"},
+			// the content resolves to another uid than the one applied by the last sync
+			Data: map[string]string{"slo.json": `{"uid": "kept", "title": "SLO"}`, "slo.remote": `{"url": "http://unreachable"}`},
+		}
+		err := deleteDashboard(withPreviousUIDs(context.TODO(), c.recorded), cm)
+		if (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+		output := map[string]bool{}
+		for uid := range fake.dashboards[""] {
+			output[uid] = true
+		}
+		if len(output) != len(c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+		for _, uid := range c.expected {
+			if !output[uid] {
+				t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// AuditLogFile is the file to append the audit records of the grafana changes to, one json per line
+	AuditLogFile = ""
+	// AuditWebhookURL is the endpoint to post every audit record of the grafana changes to
+	AuditWebhookURL = ""
+)
+
+// auditRecord is a change made to grafana by the loader
+type auditRecord struct {
+	Time time.Time `json:"time"`
+	// Source is the namespace/name of the configmap which caused the change, empty for the changes of the loader itself
+	Source   string  `json:"source,omitempty"`
+	Action   string  `json:"action"`
+	OrgID    string  `json:"orgId,omitempty"`
+	UID      string  `json:"uid,omitempty"`
+	Title    string  `json:"title,omitempty"`
+	FolderID float64 `json:"folderId,omitempty"`
+	// Version is the restored version of the dashboard
+	Version float64 `json:"version,omitempty"`
+	DryRun  bool    `json:"dryRun,omitempty"`
+	Result  string  `json:"result"`
+	Error   string  `json:"error,omitempty"`
+}
+
+type auditSourceKey struct{}
+
+// withAuditSource returns the context whose grafana changes are recorded as made for the configmap
+func withAuditSource(ctx context.Context, cm interface{}) context.Context {
+	if cm, ok := cm.(*corev1.ConfigMap); ok && cm != nil {
+		return context.WithValue(ctx, auditSourceKey{}, configmapKey(cm))
+	}
+	return ctx
+}
+
+func auditSource(ctx context.Context) string {
+	source, _ := ctx.Value(auditSourceKey{}).(string)
+	return source
+}
+
+// auditSink writes the audit records to the file and the webhook in the order of the changes
+type auditSink struct {
+	lock       sync.Mutex
+	file       io.Writer
+	webhookURL string
+}
+
+func (s *auditSink) write(record auditRecord) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file != nil {
+		b, err := json.Marshal(record)
+		if err == nil {
+			_, err = s.file.Write(append(b, '\n'))
+		}
+		if err != nil {
+			klog.ErrorS(err, "failed to write the audit record", "action", record.Action, "uid", record.UID)
+		}
+	}
+	if s.webhookURL != "" {
+		if err := postWebhook(s.webhookURL, record); err != nil {
+			klog.ErrorS(err, "failed to send the audit record", "action", record.Action, "uid", record.UID)
+		}
+	}
+}
+
+// newAuditSink opens the configured audit log file, nil means the audit is disabled
+func newAuditSink(file string, webhookURL string) (*auditSink, error) {
+	if file == "" && webhookURL == "" {
+		return nil, nil
+	}
+	sink := &auditSink{webhookURL: webhookURL}
+	if file != "" {
+		f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the audit log: %v", err)
+		}
+		sink.file = f
+	}
+	return sink, nil
+}
+
+// setUpAudit wraps the grafana client to record all the changes once an audit sink is configured
+func setUpAudit() error {
+	sink, err := newAuditSink(AuditLogFile, AuditWebhookURL)
+	if err != nil || sink == nil {
+		return err
+	}
+	grafanaClient = &auditGrafanaClient{GrafanaClient: grafanaClient, sink: sink}
+	return nil
+}
+
+// auditGrafanaClient records the changing calls to grafana, the reads go to the client directly
+type auditGrafanaClient struct {
+	GrafanaClient
+	sink *auditSink
+}
+
+func (c *auditGrafanaClient) record(ctx context.Context, record auditRecord, err error) {
+	record.Time = time.Now().UTC()
+	record.Source = auditSource(ctx)
+	record.DryRun = DryRun
+	record.Result = "success"
+	if err != nil {
+		record.Result = "failure"
+		record.Error = err.Error()
+	}
+	c.sink.write(record)
+}
+
+func (c *auditGrafanaClient) CreateFolder(ctx context.Context, orgID string, title string) (Folder, error) {
+	folder, err := c.GrafanaClient.CreateFolder(ctx, orgID, title)
+	c.record(ctx, auditRecord{Action: "create-folder", OrgID: orgID, UID: folder.UID, Title: title}, err)
+	return folder, err
+}
+
+func (c *auditGrafanaClient) DeleteFolder(ctx context.Context, orgID string, uid string) error {
+	err := c.GrafanaClient.DeleteFolder(ctx, orgID, uid)
+	c.record(ctx, auditRecord{Action: "delete-folder", OrgID: orgID, UID: uid}, err)
+	return err
+}
+
+func (c *auditGrafanaClient) SaveDashboard(ctx context.Context, orgID string, dashboard map[string]interface{},
+	folderID float64, overwrite bool) (SavedDashboard, error) {
+	saved, err := c.GrafanaClient.SaveDashboard(ctx, orgID, dashboard, folderID, overwrite)
+	uid := saved.UID
+	if uid == "" {
+		uid, _ = dashboard["uid"].(string)
+	}
+	title, _ := dashboard["title"].(string)
+	c.record(ctx, auditRecord{Action: "save-dashboard", OrgID: orgID, UID: uid, Title: title, FolderID: folderID}, err)
+	return saved, err
+}
+
+func (c *auditGrafanaClient) DeleteDashboard(ctx context.Context, orgID string, uid string) error {
+	err := c.GrafanaClient.DeleteDashboard(ctx, orgID, uid)
+	c.record(ctx, auditRecord{Action: "delete-dashboard", OrgID: orgID, UID: uid}, err)
+	return err
+}
+
+func (c *auditGrafanaClient) RestoreDashboardVersion(ctx context.Context, orgID string, uid string,
+	version float64) (SavedDashboard, error) {
+	saved, err := c.GrafanaClient.RestoreDashboardVersion(ctx, orgID, uid, version)
+	c.record(ctx, auditRecord{Action: "restore-dashboard", OrgID: orgID, UID: uid, Version: version}, err)
+	return saved, err
+}
+
+func (c *auditGrafanaClient) SavePublicDashboard(ctx context.Context, orgID string, dashboardUID string, publicUID string,
+	config map[string]interface{}) error {
+	err := c.GrafanaClient.SavePublicDashboard(ctx, orgID, dashboardUID, publicUID, config)
+	c.record(ctx, auditRecord{Action: "save-public-dashboard", OrgID: orgID, UID: dashboardUID}, err)
+	return err
+}
+
+func (c *auditGrafanaClient) UpdatePreferences(ctx context.Context, orgID string, preferences map[string]interface{}) error {
+	err := c.GrafanaClient.UpdatePreferences(ctx, orgID, preferences)
+	c.record(ctx, auditRecord{Action: "update-preferences", OrgID: orgID}, err)
+	return err
+}
+
+func (c *auditGrafanaClient) CreateOrg(ctx context.Context, name string) (string, error) {
+	orgID, err := c.GrafanaClient.CreateOrg(ctx, name)
+	c.record(ctx, auditRecord{Action: "create-org", OrgID: orgID, Title: name}, err)
+	return orgID, err
+}
+
+func (c *auditGrafanaClient) CreateTeam(ctx context.Context, orgID string, name string) (float64, error) {
+	teamID, err := c.GrafanaClient.CreateTeam(ctx, orgID, name)
+	c.record(ctx, auditRecord{Action: "create-team", OrgID: orgID, UID: fmt.Sprint(teamID), Title: name}, err)
+	return teamID, err
+}
+
+func (c *auditGrafanaClient) AddTeamMember(ctx context.Context, orgID string, teamID float64, userID float64) error {
+	err := c.GrafanaClient.AddTeamMember(ctx, orgID, teamID, userID)
+	c.record(ctx, auditRecord{Action: "add-team-member", OrgID: orgID, UID: fmt.Sprintf("%v/%v", teamID, userID)}, err)
+	return err
+}
+
+func (c *auditGrafanaClient) RemoveTeamMember(ctx context.Context, orgID string, teamID float64, userID float64) error {
+	err := c.GrafanaClient.RemoveTeamMember(ctx, orgID, teamID, userID)
+	c.record(ctx, auditRecord{Action: "remove-team-member", OrgID: orgID, UID: fmt.Sprintf("%v/%v", teamID, userID)}, err)
+	return err
+}
+
+func (c *auditGrafanaClient) UpdateFolderPermissions(ctx context.Context, orgID string, folderUID string,
+	permissions []FolderPermission) error {
+	err := c.GrafanaClient.UpdateFolderPermissions(ctx, orgID, folderUID, permissions)
+	c.record(ctx, auditRecord{Action: "update-folder-permissions", OrgID: orgID, UID: folderUID}, err)
+	return err
+}
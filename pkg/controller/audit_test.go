@@ -0,0 +1,101 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAuditGrafanaClient(t *testing.T) {
+	_, restore := useFakeGrafanaClient()
+	defer restore()
+	out := &bytes.Buffer{}
+	grafanaClient = &auditGrafanaClient{GrafanaClient: grafanaClient, sink: &auditSink{file: out}}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test",
+			Labels:      map[string]string{"grafana-custom-dashboard": "true"},
+			Annotations: map[string]string{customFolderKey: "Audit"}},
+		Data: map[string]string{"a.json": `{"uid": "a", "title": "a"}`},
+	}
+	if err := updateDashboard(context.TODO(), nil, cm, false); err != nil {
+		t.Fatalf("failed to apply the dashboard: %v", err)
+	}
+	grafanaClient.DeleteDashboard(context.TODO(), "", "missing")
+
+	records := []auditRecord{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		record := auditRecord{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("the audit record %v is not json: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 3 {
+		t.Fatalf("3 changes should be recorded: %v", out.String())
+	}
+
+	testCaseList := []struct {
+		name     string
+		record   auditRecord
+		action   string
+		source   string
+		result   string
+		expected string
+	}{
+		{"create folder", records[0], "create-folder", "test/test", "success", "Audit"},
+
+		{"save dashboard", records[1], "save-dashboard", "test/test", "success", "a"},
+
+		{"failed delete", records[2], "delete-dashboard", "", "failure", "missing"},
+	}
+	for _, c := range testCaseList {
+		output := c.record.UID
+		if c.action == "create-folder" {
+			output = c.record.Title
+		}
+		if c.record.Action != c.action || c.record.Source != c.source || c.record.Result != c.result || output != c.expected {
+			t.Errorf("case (%v) output: (%+v) is not the expected: (%v %v %v %v)", c.name, c.record,
+				c.action, c.source, c.result, c.expected)
+		}
+	}
+}
+
+func TestAuditSinkFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "audit.log")
+	if err := ioutil.WriteFile(file, []byte("{\"action\":\"existing\"}\n"), 0600); err != nil {
+		t.Fatalf("failed to write the audit log: %v", err)
+	}
+
+	sink, err := newAuditSink(file, "")
+	if err != nil {
+		t.Fatalf("failed to open the audit log: %v", err)
+	}
+	sink.write(auditRecord{Action: "delete-dashboard", UID: "a", Result: "success"})
+
+	content, _ := ioutil.ReadFile(file)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], "delete-dashboard") {
+		t.Errorf("the audit record should be appended to the existing ones: %s", content)
+	}
+
+	if sink, _ := newAuditSink("", ""); sink != nil {
+		t.Errorf("the audit should be disabled without a sink")
+	}
+}
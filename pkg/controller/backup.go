@@ -0,0 +1,312 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+const (
+	backupPrefix = "dashboards-"
+	backupSuffix = ".tar.gz"
+	// defaultOrgDir is the directory of the default org in the backup archive, the other orgs are in org-<id>
+	defaultOrgDir = "default"
+	orgDirPrefix  = "org-"
+)
+
+var (
+	// BackupDir is the directory, e.g. a mounted pvc, to write the backup archives to, empty means disabled
+	BackupDir = ""
+	// BackupInterval is how often the managed dashboards are backed up
+	BackupInterval = 24 * time.Hour
+	// BackupRetention is the number of the latest backup archives to keep
+	BackupRetention = 7
+)
+
+// managedDashboard is a dashboard which was saved to grafana by the loader
+type managedDashboard struct {
+	OrgID  string
+	UID    string
+	Folder string
+}
+
+var (
+	managedDashboardsLock sync.Mutex
+	// managedDashboards are the dashboards saved by the loader keyed by the org and uid
+	managedDashboards = map[string]managedDashboard{}
+)
+
+func recordManagedDashboard(orgID string, uid string, folder string) {
+	managedDashboardsLock.Lock()
+	defer managedDashboardsLock.Unlock()
+	managedDashboards[orgID+"/"+uid] = managedDashboard{orgID, uid, folder}
+	updateManagedGauges()
+}
+
+func forgetManagedDashboard(orgID string, uid string) {
+	managedDashboardsLock.Lock()
+	defer managedDashboardsLock.Unlock()
+	delete(managedDashboards, orgID+"/"+uid)
+	updateManagedGauges()
+}
+
+// updateManagedGauges sets the gauges of the managed dashboards, the caller holds the lock
+func updateManagedGauges() {
+	folders := map[string]bool{}
+	for _, d := range managedDashboards {
+		if d.Folder != "" {
+			folders[d.OrgID+"/"+d.Folder] = true
+		}
+	}
+	metrics.DashboardsManaged.Set(float64(len(managedDashboards)))
+	metrics.CustomFolders.Set(float64(len(folders)))
+}
+
+func listManagedDashboards() []managedDashboard {
+	managedDashboardsLock.Lock()
+	defer managedDashboardsLock.Unlock()
+	dashboards := []managedDashboard{}
+	for _, d := range managedDashboards {
+		dashboards = append(dashboards, d)
+	}
+	sort.Slice(dashboards, func(i, j int) bool {
+		if dashboards[i].OrgID != dashboards[j].OrgID {
+			return dashboards[i].OrgID < dashboards[j].OrgID
+		}
+		return dashboards[i].UID < dashboards[j].UID
+	})
+	return dashboards
+}
+
+// runBackups backs up the managed dashboards into dir every interval until ctx is done
+func runBackups(ctx context.Context, dir string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			archive, err := backupDashboards(ctx, dir, BackupRetention)
+			if err != nil {
+				klog.Error("failed to back up the dashboards ", "error ", err)
+				metrics.BackupFailures.Inc()
+				continue
+			}
+			if archive != "" {
+				klog.Infof("the managed dashboards are backed up to %v", archive)
+				metrics.BackupLastSuccessTimestamp.SetToCurrentTime()
+			}
+		}
+	}
+}
+
+// backupDashboards writes the managed dashboards as they are in grafana into a new archive in dir,
+// the archive follows the layout of the dashboard directory under a directory per org,
+// only the latest retention archives are kept
+func backupDashboards(ctx context.Context, dir string, retention int) (string, error) {
+	dashboards := listManagedDashboards()
+	if len(dashboards) == 0 {
+		klog.V(4).Info("skip the backup since no dashboard is managed yet")
+		return "", nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	archive := filepath.Join(dir, backupPrefix+time.Now().UTC().Format("20060102T150405Z")+backupSuffix)
+	tmp, err := ioutil.TempFile(dir, ".backup-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	err = writeBackup(ctx, tmp, dashboards)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	// the archive shows up once it is complete
+	if err := os.Rename(tmp.Name(), archive); err != nil {
+		return "", err
+	}
+
+	pruneBackups(dir, retention)
+	return archive, nil
+}
+
+func writeBackup(ctx context.Context, w io.Writer, dashboards []managedDashboard) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, d := range dashboards {
+		dashboard, err := clientFor(ctx).GetDashboard(ctx, d.OrgID, d.UID)
+		if apiErr, ok := err.(*GrafanaAPIError); ok && apiErr.StatusCode == http.StatusNotFound {
+			klog.Infof("skip the backup of dashboard %v which is not found in grafana", d.UID)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get dashboard %v: %v", d.UID, err)
+		}
+		delete(dashboard, "id")
+		delete(dashboard, "version")
+		b, err := json.MarshalIndent(dashboard, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		orgDir := defaultOrgDir
+		if d.OrgID != "" {
+			orgDir = orgDirPrefix + d.OrgID
+		}
+		folder := d.Folder
+		if folder == "" {
+			folder = generalFolderDir
+		}
+		err = tw.WriteHeader(&tar.Header{
+			Name:    path.Join(orgDir, folder, exportFileName(d.UID)+".json"),
+			Mode:    0644,
+			Size:    int64(len(b)),
+			ModTime: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := tw.Write(b); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// pruneBackups removes the archives except the latest retention ones, the names are sorted by the time
+func pruneBackups(dir string, retention int) {
+	archives, err := filepath.Glob(filepath.Join(dir, backupPrefix+"*"+backupSuffix))
+	if err != nil || retention <= 0 || len(archives) <= retention {
+		return
+	}
+	sort.Strings(archives)
+	for _, archive := range archives[:len(archives)-retention] {
+		if err := os.Remove(archive); err != nil {
+			klog.Errorf("failed to remove the old backup %v: %v", archive, err)
+		}
+	}
+}
+
+// RestoreBackup saves all the dashboards in the backup archive into their orgs and folders in grafana,
+// the dashboards in grafana are overwritten
+func RestoreBackup(ctx context.Context, archive string) (int, error) {
+	if err := setUpAudit(); err != nil {
+		return 0, err
+	}
+	dir, err := ioutil.TempDir("", "grafana-dashboards-restore")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+	if err := extractBackup(archive, dir); err != nil {
+		return 0, fmt.Errorf("failed to extract %v: %v", archive, err)
+	}
+
+	orgDirs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	restored := 0
+	var restoreErr error
+	for _, orgDir := range orgDirs {
+		if !orgDir.IsDir() {
+			continue
+		}
+		orgID := ""
+		if orgDir.Name() != defaultOrgDir {
+			orgID = strings.TrimPrefix(orgDir.Name(), orgDirPrefix)
+		}
+		files := &filesystemSource{dir: filepath.Join(dir, orgDir.Name()), loaded: map[string]*corev1.ConfigMap{}}
+		configmaps, err := files.scan()
+		if err != nil {
+			return restored, err
+		}
+		for file, cm := range configmaps {
+			if orgID != "" {
+				cm.Annotations[dashboardOrgIDKey] = orgID
+			}
+			// the restored dashboards are the ones of the configmaps which own their uids
+			if err := updateDashboard(withoutUIDIndex(ctx), nil, cm, true); err != nil {
+				klog.Errorf("failed to restore %v: %v", file, err)
+				restoreErr = fmt.Errorf("failed to restore some of the dashboards: %v", err)
+				continue
+			}
+			restored++
+		}
+	}
+	return restored, restoreErr
+}
+
+func extractBackup(archive string, dir string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("invalid file %v in the backup", header.Name)
+		}
+		if header.Size > maxDecompressedSize {
+			return fmt.Errorf("file %v in the backup is larger than %v bytes", header.Name, maxDecompressedSize)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		content, err := ioutil.ReadAll(io.LimitReader(tr, maxDecompressedSize))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(target, content, 0644); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func(dashboards map[string]managedDashboard) {
+		managedDashboardsLock.Lock()
+		managedDashboards = dashboards
+		managedDashboardsLock.Unlock()
+	}(managedDashboards)
+	managedDashboards = map[string]managedDashboard{}
+
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive, err := backupDashboards(context.TODO(), dir, 2)
+	if err != nil || archive != "" {
+		t.Errorf("nothing should be backed up before any dashboard is managed: %v, %v", archive, err)
+	}
+
+	configmaps := []*corev1.ConfigMap{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "slo", Namespace: "test",
+				Annotations: map[string]string{customFolderKey: "SLOs"}},
+			Data: map[string]string{"slo.json": `{"uid": "slo", "title": "SLO"}`},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "home", Namespace: "test",
+				Labels: map[string]string{generalFolderKey: "true"}},
+			Data: map[string]string{"home.json": `{"uid": "home", "title": "Home"}`},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "test",
+				Annotations: map[string]string{dashboardOrgIDKey: "2"}},
+			Data: map[string]string{"team.json": `{"uid": "team", "title": "Team"}`},
+		},
+	}
+	for _, cm := range configmaps {
+		if err := updateDashboard(context.TODO(), nil, cm, false); err != nil {
+			t.Fatalf("failed to save dashboard %v: %v", cm.Name, err)
+		}
+	}
+	if len(listManagedDashboards()) != 3 {
+		t.Fatalf("the managed dashboards %v are not the expected 3", listManagedDashboards())
+	}
+
+	archive, err = backupDashboards(context.TODO(), dir, 2)
+	if err != nil || archive == "" {
+		t.Fatalf("failed to back up the dashboards: %v", err)
+	}
+
+	// the dashboards are lost in grafana
+	fake.dashboards = map[string]map[string]fakeDashboard{}
+	fake.folders = map[string][]Folder{}
+	restored, err := RestoreBackup(context.TODO(), archive)
+	if err != nil || restored != 3 {
+		t.Fatalf("failed to restore the dashboards: %v, %v", restored, err)
+	}
+
+	testCaseList := []struct {
+		name     string
+		orgID    string
+		uid      string
+		expected string
+	}{
+		{"custom folder", "", "slo", "SLOs"},
+
+		{"general folder", "", "home", ""},
+
+		{"other org", "2", "team", defaultCustomFolder},
+	}
+
+	for _, c := range testCaseList {
+		d, ok := fake.dashboards[c.orgID][c.uid]
+		if !ok {
+			t.Errorf("case (%v) dashboard %v is not restored", c.name, c.uid)
+			continue
+		}
+		folder := ""
+		for _, f := range fake.folders[c.orgID] {
+			if f.ID == d.folderID {
+				folder = f.Title
+			}
+		}
+		if folder != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, folder, c.expected)
+		}
+	}
+
+	// only the latest archives are kept
+	for i := 0; i < 3; i++ {
+		ioutil.WriteFile(filepath.Join(dir, backupPrefix+time.Unix(int64(i), 0).UTC().Format("20060102T150405Z")+backupSuffix),
+			nil, 0644)
+	}
+	pruneBackups(dir, 2)
+	archives, _ := filepath.Glob(filepath.Join(dir, backupPrefix+"*"+backupSuffix))
+	if len(archives) != 2 || archives[1] != archive {
+		t.Errorf("case (prune) output: (%v) is not the expected: (%v)", archives, 2)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// strippedAnnotations are not used by the loader while they may be as big as the object itself
+var strippedAnnotations = []string{"kubectl.kubernetes.io/last-applied-configuration"}
+
+// stripObject returns the object without the managed fields and the stripped annotations,
+// the object may be shared with the client so a copy is stripped instead of the object itself
+func stripObject(obj runtime.Object) runtime.Object {
+	accessor, err := meta.Accessor(obj)
+	if err != nil || !needsStrip(accessor) {
+		return obj
+	}
+	obj = obj.DeepCopyObject()
+	accessor, err = meta.Accessor(obj)
+	if err != nil {
+		return obj
+	}
+	accessor.SetManagedFields(nil)
+	annotations := accessor.GetAnnotations()
+	for _, key := range strippedAnnotations {
+		delete(annotations, key)
+	}
+	accessor.SetAnnotations(annotations)
+	return obj
+}
+
+func needsStrip(accessor metav1.Object) bool {
+	if len(accessor.GetManagedFields()) > 0 {
+		return true
+	}
+	annotations := accessor.GetAnnotations()
+	for _, key := range strippedAnnotations {
+		if _, ok := annotations[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// stripMetadata strips the objects of the list and the watch before they enter the cache of the informer,
+// the informers of this client-go version have no transform function
+func stripMetadata(lw *cache.ListWatch) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			list, err := lw.List(opts)
+			if err != nil {
+				return nil, err
+			}
+			items, err := meta.ExtractList(list)
+			if err != nil {
+				return nil, err
+			}
+			for i := range items {
+				items[i] = stripObject(items[i])
+			}
+			return list, meta.SetList(list, items)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			w, err := lw.Watch(opts)
+			if err != nil {
+				return nil, err
+			}
+			return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+				event.Object = stripObject(event.Object)
+				return event, true
+			}), nil
+		},
+	}
+}
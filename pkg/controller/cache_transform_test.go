@@ -0,0 +1,75 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestStripMetadata(t *testing.T) {
+	configmap := func(name string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test",
+			Annotations: map[string]string{
+				"kubectl.kubernetes.io/last-applied-configuration": `{"data": {"a.json": "..."}}`,
+				customFolderKey: "SLOs",
+			},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		}}
+	}
+	kubeClient := kubefake.NewSimpleClientset(configmap("listed"))
+	watchlist := stripMetadata(&cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			return kubeClient.CoreV1().ConfigMaps("test").List(context.TODO(), opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			return kubeClient.CoreV1().ConfigMaps("test").Watch(context.TODO(), opts)
+		},
+	})
+
+	list, err := watchlist.List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to list the configmaps: %v", err)
+	}
+	w, err := watchlist.Watch(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to watch the configmaps: %v", err)
+	}
+	defer w.Stop()
+	kubeClient.CoreV1().ConfigMaps("test").Create(context.TODO(), configmap("watched"), metav1.CreateOptions{})
+	event := <-w.ResultChan()
+
+	testCaseList := []struct {
+		name string
+		cm   *corev1.ConfigMap
+	}{
+		{"listed", &list.(*corev1.ConfigMapList).Items[0]},
+
+		{"watched", event.Object.(*corev1.ConfigMap)},
+	}
+
+	for _, c := range testCaseList {
+		if len(c.cm.ManagedFields) != 0 {
+			t.Errorf("case (%v) the managed fields are not stripped: %v", c.name, c.cm.ManagedFields)
+		}
+		if _, ok := c.cm.Annotations["kubectl.kubernetes.io/last-applied-configuration"]; ok {
+			t.Errorf("case (%v) the last applied configuration is not stripped", c.name)
+		}
+		if output := c.cm.Annotations[customFolderKey]; output != "SLOs" {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, "SLOs")
+		}
+	}
+	// the watched object is shared with the client so it is stripped as a copy
+	stored, err := kubeClient.CoreV1().ConfigMaps("test").Get(context.TODO(), "watched", metav1.GetOptions{})
+	if err != nil || len(stored.ManagedFields) == 0 {
+		t.Errorf("the object of the client should not be stripped: %v", err)
+	}
+}
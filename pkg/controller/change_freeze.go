@@ -0,0 +1,194 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+// FreezeWindows are the change freezes during which the dashboards are neither applied nor deleted,
+// every window is a cron expression of its start in the time zone of the loader followed by its duration,
+// e.g. "0 22 * * 5 60h" freezes the weekends, the deferred changes are applied once the window ends
+var FreezeWindows []string
+
+// freezeCheckInterval is how often the end of a freeze window is checked
+const freezeCheckInterval = time.Minute
+
+// freezeWindow is a parsed freeze window, the fields are the allowed minute, hour, day of month, month and day of week
+type freezeWindow struct {
+	spec     string
+	fields   [5]map[int]bool
+	duration time.Duration
+	// anyDay are whether the day of month and the day of week are a wildcard, the day matches either of them otherwise
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+// freezeError means the change is deferred until the freeze window ends
+type freezeError struct {
+	window string
+	until  time.Time
+}
+
+func (e *freezeError) Error() string {
+	return fmt.Sprintf("the change is deferred until the freeze window %q ends at %v", e.window, e.until.Format(time.RFC3339))
+}
+
+// isFrozen checks whether the change is deferred by a freeze window
+func isFrozen(err error) bool {
+	var freezeErr *freezeError
+	return errors.As(err, &freezeErr)
+}
+
+var cronFieldRanges = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+// parseFreezeWindow parses the five cron fields and the duration of the window
+func parseFreezeWindow(spec string) (freezeWindow, error) {
+	window := freezeWindow{spec: spec}
+	fields := strings.Fields(spec)
+	if len(fields) != 6 {
+		return window, fmt.Errorf("invalid freeze window %q, it is a cron expression of five fields and a duration", spec)
+	}
+	var err error
+	window.duration, err = time.ParseDuration(fields[5])
+	if err != nil || window.duration <= 0 {
+		return window, fmt.Errorf("invalid duration of the freeze window %q", spec)
+	}
+	for i := range window.fields {
+		window.fields[i], err = parseCronField(fields[i], cronFieldRanges[i][0], cronFieldRanges[i][1])
+		if err != nil {
+			return window, fmt.Errorf("invalid freeze window %q: %v", spec, err)
+		}
+	}
+	window.anyDayOfMonth, window.anyDayOfWeek = fields[2] == "*", fields[4] == "*"
+	return window, nil
+}
+
+// parseCronField parses the comma separated values, ranges and steps of a cron field
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q", part)
+			}
+			part = part[:i]
+		}
+		from, to := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid range %q", part)
+				}
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("%q is out of the range %v-%v", part, min, max)
+		}
+		for value := from; value <= to; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+// starts checks whether the window starts at the minute
+func (w freezeWindow) starts(t time.Time) bool {
+	if !w.fields[0][t.Minute()] || !w.fields[1][t.Hour()] || !w.fields[3][int(t.Month())] {
+		return false
+	}
+	dayOfMonth, dayOfWeek := w.fields[2][t.Day()], w.fields[4][int(t.Weekday())]
+	switch {
+	case w.anyDayOfMonth && w.anyDayOfWeek:
+		return true
+	case w.anyDayOfMonth:
+		return dayOfWeek
+	case w.anyDayOfWeek:
+		return dayOfMonth
+	default:
+		return dayOfMonth || dayOfWeek
+	}
+}
+
+// end returns the end of the window which is in effect at now
+func (w freezeWindow) end(now time.Time) (time.Time, bool) {
+	for start := now.Truncate(time.Minute); now.Sub(start) < w.duration; start = start.Add(-time.Minute) {
+		if w.starts(start) {
+			return start.Add(w.duration), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ValidateFreezeWindows checks the freeze windows can be parsed
+func ValidateFreezeWindows(windows []string) error {
+	for _, spec := range windows {
+		if _, err := parseFreezeWindow(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFreezeWindows returns a freezeError while any freeze window is in effect, the latest end is reported
+func checkFreezeWindows(now time.Time) error {
+	var frozen *freezeError
+	for _, spec := range activeSettings().freezeWindows {
+		window, err := parseFreezeWindow(spec)
+		if err != nil {
+			continue
+		}
+		if end, ok := window.end(now); ok && (frozen == nil || end.After(frozen.until)) {
+			frozen = &freezeError{window: spec, until: end}
+		}
+	}
+	if frozen == nil {
+		return nil
+	}
+	return frozen
+}
+
+// runFreezeWindows applies the deferred changes once a freeze window ends until ctx is done
+func runFreezeWindows(ctx context.Context, coreClient corev1client.CoreV1Interface) {
+	ticker := time.NewTicker(freezeCheckInterval)
+	defer ticker.Stop()
+	frozen := false
+	for {
+		err := checkFreezeWindows(time.Now())
+		switch {
+		case err != nil && !frozen:
+			klog.InfoS("a freeze window is in effect, the changes of the dashboards are deferred", "reason", err)
+			metrics.FreezeWindowActive.Set(1)
+		case err == nil && frozen:
+			klog.InfoS("the freeze window ended, applying the deferred changes")
+			metrics.FreezeWindowActive.Set(0)
+			retryPendingWork(ctx, coreClient)
+			redeliverAll()
+		}
+		frozen = err != nil
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
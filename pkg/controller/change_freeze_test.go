@@ -0,0 +1,113 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseFreezeWindow(t *testing.T) {
+	testCaseList := []struct {
+		name   string
+		spec   string
+		hasErr bool
+	}{
+		{"weekend", "0 22 * * 5 60h", false},
+
+		{"lists ranges and steps", "*/15 9-17 1,15 1-12/3 * 30m", false},
+
+		{"no duration", "0 22 * * 5", true},
+
+		{"out of range", "0 24 * * 5 1h", true},
+
+		{"invalid step", "*/0 * * * * 1h", true},
+
+		{"invalid duration", "0 22 * * 5 forever", true},
+	}
+
+	for _, c := range testCaseList {
+		if _, err := parseFreezeWindow(c.spec); (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+	}
+}
+
+func TestCheckFreezeWindows(t *testing.T) {
+	defer func(windows []string) { FreezeWindows = windows }(FreezeWindows)
+	// the weekend from friday 22:00 to monday 10:00 and the first day of the month
+	FreezeWindows = []string{"0 22 * * 5 60h", "0 0 1 * * 24h"}
+
+	testCaseList := []struct {
+		name     string
+		now      time.Time
+		expected time.Time
+	}{
+		{"before the weekend", time.Date(2021, 10, 15, 21, 59, 0, 0, time.UTC), time.Time{}},
+
+		{"weekend", time.Date(2021, 10, 16, 12, 0, 0, 0, time.UTC), time.Date(2021, 10, 18, 10, 0, 0, 0, time.UTC)},
+
+		{"end of the weekend", time.Date(2021, 10, 18, 10, 0, 0, 0, time.UTC), time.Time{}},
+
+		{"first day of the month", time.Date(2021, 11, 1, 8, 30, 0, 0, time.UTC), time.Date(2021, 11, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, c := range testCaseList {
+		output := time.Time{}
+		if err := checkFreezeWindows(c.now); err != nil {
+			output = err.(*freezeError).until
+		}
+		if !output.Equal(c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestSyncDuringFreezeWindow(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func(windows []string) { FreezeWindows = windows }(FreezeWindows)
+	FreezeWindows = []string{"* * * * * 1h"}
+
+	state := newSyncState("freeze-test")
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "frozen", Namespace: "freeze-test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+		Data: map[string]string{"a.json": `{"uid": "frozen", "title": "Frozen", "panels": []}`},
+	}
+	defer state.forget(cm)
+	defer pendingWork.done(state.source, cm)
+
+	if err := syncDashboard(context.TODO(), state, nil, cm); !isFrozen(err) {
+		t.Errorf("case (apply) error: (%v) is not the expected freeze", err)
+	}
+	if _, ok := fake.dashboards[""]["frozen"]; ok {
+		t.Errorf("case (apply) the dashboard should not be applied during the freeze window")
+	}
+
+	fake.dashboards[""] = map[string]fakeDashboard{"frozen": {}}
+	if err := syncDeletion(context.TODO(), state, cm); !isFrozen(err) {
+		t.Errorf("case (delete) error: (%v) is not the expected freeze", err)
+	}
+	if _, ok := fake.dashboards[""]["frozen"]; !ok {
+		t.Errorf("case (delete) the dashboard should not be deleted during the freeze window")
+	}
+	queued := false
+	for _, item := range pendingWork.list() {
+		queued = queued || item.Op == workDelete && item.Configmap.GetName() == "frozen"
+	}
+	if !queued {
+		t.Errorf("case (delete) the deletion is not queued for after the freeze window")
+	}
+
+	FreezeWindows = nil
+	retryPendingWork(context.TODO(), nil)
+	if _, ok := fake.dashboards[""]["frozen"]; ok {
+		t.Errorf("case (window ended) the queued deletion is not applied after the freeze window")
+	}
+}
@@ -0,0 +1,405 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+var (
+	// BreakerFailureThreshold is the number of consecutive grafana failures to open the circuit breaker,
+	// 0 disables the circuit breaker
+	BreakerFailureThreshold = 5
+	// BreakerCooldown is how long the circuit breaker stays open before grafana is probed again
+	BreakerCooldown = 30 * time.Second
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker stops the calls to grafana after consecutive failures,
+// the pending calls wait until /api/health succeeds again instead of failing one by one
+type circuitBreaker struct {
+	sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     breakerState
+	failures  int
+	openedAt  time.Time
+	// closed is closed when the breaker is closed to wake up the waiting calls
+	closed chan struct{}
+	// probe checks whether grafana is available again
+	probe func(ctx context.Context) error
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration, probe func(ctx context.Context) error) *circuitBreaker {
+	closed := make(chan struct{})
+	close(closed)
+	metrics.CircuitBreakerState.Set(float64(breakerClosed))
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		closed:    closed,
+		probe:     probe,
+	}
+}
+
+// setState is called with the lock held
+func (b *circuitBreaker) setState(state breakerState) {
+	if b.state == state {
+		return
+	}
+	klog.Infof("grafana circuit breaker is %v", state)
+	switch state {
+	case breakerOpen:
+		b.openedAt = time.Now()
+		if b.state == breakerClosed {
+			b.closed = make(chan struct{})
+		}
+	case breakerClosed:
+		b.failures = 0
+		close(b.closed)
+	}
+	b.state = state
+	metrics.CircuitBreakerState.Set(float64(state))
+	metrics.CircuitBreakerTransitions.WithLabelValues(state.String()).Inc()
+}
+
+// wait blocks until the breaker is closed or ctx is done,
+// one of the waiting calls probes grafana once the cooldown is over
+func (b *circuitBreaker) wait(ctx context.Context) error {
+	for {
+		b.Lock()
+		if b.state == breakerClosed {
+			b.Unlock()
+			return nil
+		}
+		closed := b.closed
+		remaining := b.cooldown - time.Since(b.openedAt)
+		probing := b.state == breakerOpen && remaining <= 0
+		if probing {
+			b.setState(breakerHalfOpen)
+		}
+		b.Unlock()
+
+		if probing {
+			err := b.probe(ctx)
+			b.Lock()
+			if err == nil {
+				b.setState(breakerClosed)
+			} else {
+				klog.Infof("grafana is still unavailable: %v", err)
+				b.setState(breakerOpen)
+			}
+			b.Unlock()
+			continue
+		}
+
+		if remaining <= 0 {
+			// another call is probing
+			remaining = b.cooldown
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-closed:
+		case <-time.After(remaining):
+		}
+	}
+}
+
+// record counts the consecutive failures of the calls to grafana
+func (b *circuitBreaker) record(err error) {
+	b.Lock()
+	defer b.Unlock()
+	if !isGrafanaUnavailable(err) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == breakerClosed && b.failures >= b.threshold {
+		klog.Errorf("grafana failed %v times in a row: %v", b.failures, err)
+		b.setState(breakerOpen)
+	}
+}
+
+// isGrafanaUnavailable checks whether the error means grafana cannot serve the api,
+// the errors of the requests themselves do not count
+func isGrafanaUnavailable(err error) bool {
+	apiErr, ok := err.(*GrafanaAPIError)
+	if !ok {
+		return false
+	}
+	// the request without a response is reported as 404 without a body
+	return apiErr.StatusCode >= 500 || apiErr.Body == nil
+}
+
+// breakerGrafanaClient calls the client through the circuit breaker
+type breakerGrafanaClient struct {
+	client  GrafanaClient
+	breaker *circuitBreaker
+}
+
+func newBreakerGrafanaClient(client GrafanaClient, threshold int, cooldown time.Duration) *breakerGrafanaClient {
+	return &breakerGrafanaClient{
+		client:  client,
+		breaker: newCircuitBreaker(threshold, cooldown, client.Health),
+	}
+}
+
+func (c *breakerGrafanaClient) do(ctx context.Context, call func() error) error {
+	err := c.breaker.wait(ctx)
+	if err != nil {
+		return err
+	}
+	err = call()
+	c.breaker.record(err)
+	return err
+}
+
+func (c *breakerGrafanaClient) ListFolders(ctx context.Context, orgID string) ([]Folder, error) {
+	var folders []Folder
+	err := c.do(ctx, func() (err error) {
+		folders, err = c.client.ListFolders(ctx, orgID)
+		return err
+	})
+	return folders, err
+}
+
+func (c *breakerGrafanaClient) GetFolder(ctx context.Context, orgID string, id float64) (Folder, error) {
+	var folder Folder
+	err := c.do(ctx, func() (err error) {
+		folder, err = c.client.GetFolder(ctx, orgID, id)
+		return err
+	})
+	return folder, err
+}
+
+func (c *breakerGrafanaClient) CreateFolder(ctx context.Context, orgID string, title string) (Folder, error) {
+	var folder Folder
+	err := c.do(ctx, func() (err error) {
+		folder, err = c.client.CreateFolder(ctx, orgID, title)
+		return err
+	})
+	return folder, err
+}
+
+func (c *breakerGrafanaClient) DeleteFolder(ctx context.Context, orgID string, uid string) error {
+	return c.do(ctx, func() error {
+		return c.client.DeleteFolder(ctx, orgID, uid)
+	})
+}
+
+func (c *breakerGrafanaClient) SaveDashboard(ctx context.Context, orgID string, dashboard map[string]interface{},
+	folderID float64, overwrite bool) (SavedDashboard, error) {
+	var saved SavedDashboard
+	err := c.do(ctx, func() (err error) {
+		saved, err = c.client.SaveDashboard(ctx, orgID, dashboard, folderID, overwrite)
+		return err
+	})
+	return saved, err
+}
+
+func (c *breakerGrafanaClient) DeleteDashboard(ctx context.Context, orgID string, uid string) error {
+	return c.do(ctx, func() error {
+		return c.client.DeleteDashboard(ctx, orgID, uid)
+	})
+}
+
+func (c *breakerGrafanaClient) ListDashboardVersions(ctx context.Context, orgID string, uid string) ([]DashboardVersion, error) {
+	var versions []DashboardVersion
+	err := c.do(ctx, func() (err error) {
+		versions, err = c.client.ListDashboardVersions(ctx, orgID, uid)
+		return err
+	})
+	return versions, err
+}
+
+func (c *breakerGrafanaClient) RestoreDashboardVersion(ctx context.Context, orgID string, uid string,
+	version float64) (SavedDashboard, error) {
+	var saved SavedDashboard
+	err := c.do(ctx, func() (err error) {
+		saved, err = c.client.RestoreDashboardVersion(ctx, orgID, uid, version)
+		return err
+	})
+	return saved, err
+}
+
+func (c *breakerGrafanaClient) GetPublicDashboardUID(ctx context.Context, orgID string, dashboardUID string) (string, error) {
+	var uid string
+	err := c.do(ctx, func() (err error) {
+		uid, err = c.client.GetPublicDashboardUID(ctx, orgID, dashboardUID)
+		return err
+	})
+	return uid, err
+}
+
+func (c *breakerGrafanaClient) SavePublicDashboard(ctx context.Context, orgID string, dashboardUID string,
+	publicUID string, config map[string]interface{}) error {
+	return c.do(ctx, func() error {
+		return c.client.SavePublicDashboard(ctx, orgID, dashboardUID, publicUID, config)
+	})
+}
+
+func (c *breakerGrafanaClient) GetPreferences(ctx context.Context, orgID string) (map[string]interface{}, error) {
+	var preferences map[string]interface{}
+	err := c.do(ctx, func() (err error) {
+		preferences, err = c.client.GetPreferences(ctx, orgID)
+		return err
+	})
+	return preferences, err
+}
+
+func (c *breakerGrafanaClient) UpdatePreferences(ctx context.Context, orgID string, preferences map[string]interface{}) error {
+	return c.do(ctx, func() error {
+		return c.client.UpdatePreferences(ctx, orgID, preferences)
+	})
+}
+
+func (c *breakerGrafanaClient) SearchDashboards(ctx context.Context, orgID string) ([]SearchHit, error) {
+	var hits []SearchHit
+	err := c.do(ctx, func() (err error) {
+		hits, err = c.client.SearchDashboards(ctx, orgID)
+		return err
+	})
+	return hits, err
+}
+
+func (c *breakerGrafanaClient) GetDashboard(ctx context.Context, orgID string, uid string) (map[string]interface{}, error) {
+	var dashboard map[string]interface{}
+	err := c.do(ctx, func() (err error) {
+		dashboard, err = c.client.GetDashboard(ctx, orgID, uid)
+		return err
+	})
+	return dashboard, err
+}
+
+func (c *breakerGrafanaClient) GetDashboardWithMeta(ctx context.Context, orgID string, uid string) (map[string]interface{},
+	DashboardMeta, error) {
+	var dashboard map[string]interface{}
+	var meta DashboardMeta
+	err := c.do(ctx, func() (err error) {
+		dashboard, meta, err = c.client.GetDashboardWithMeta(ctx, orgID, uid)
+		return err
+	})
+	return dashboard, meta, err
+}
+
+func (c *breakerGrafanaClient) SearchFolderDashboards(ctx context.Context, orgID string, folderID float64) ([]SearchHit, error) {
+	var hits []SearchHit
+	err := c.do(ctx, func() (err error) {
+		hits, err = c.client.SearchFolderDashboards(ctx, orgID, folderID)
+		return err
+	})
+	return hits, err
+}
+
+func (c *breakerGrafanaClient) GetOrgID(ctx context.Context, name string) (string, error) {
+	var orgID string
+	err := c.do(ctx, func() (err error) {
+		orgID, err = c.client.GetOrgID(ctx, name)
+		return err
+	})
+	return orgID, err
+}
+
+func (c *breakerGrafanaClient) CreateOrg(ctx context.Context, name string) (string, error) {
+	var orgID string
+	err := c.do(ctx, func() (err error) {
+		orgID, err = c.client.CreateOrg(ctx, name)
+		return err
+	})
+	return orgID, err
+}
+
+func (c *breakerGrafanaClient) GetTeamID(ctx context.Context, orgID string, name string) (float64, error) {
+	var teamID float64
+	err := c.do(ctx, func() (err error) {
+		teamID, err = c.client.GetTeamID(ctx, orgID, name)
+		return err
+	})
+	return teamID, err
+}
+
+func (c *breakerGrafanaClient) CreateTeam(ctx context.Context, orgID string, name string) (float64, error) {
+	var teamID float64
+	err := c.do(ctx, func() (err error) {
+		teamID, err = c.client.CreateTeam(ctx, orgID, name)
+		return err
+	})
+	return teamID, err
+}
+
+func (c *breakerGrafanaClient) ListTeamMembers(ctx context.Context, orgID string, teamID float64) ([]TeamMember, error) {
+	var members []TeamMember
+	err := c.do(ctx, func() (err error) {
+		members, err = c.client.ListTeamMembers(ctx, orgID, teamID)
+		return err
+	})
+	return members, err
+}
+
+func (c *breakerGrafanaClient) AddTeamMember(ctx context.Context, orgID string, teamID float64, userID float64) error {
+	return c.do(ctx, func() error {
+		return c.client.AddTeamMember(ctx, orgID, teamID, userID)
+	})
+}
+
+func (c *breakerGrafanaClient) RemoveTeamMember(ctx context.Context, orgID string, teamID float64, userID float64) error {
+	return c.do(ctx, func() error {
+		return c.client.RemoveTeamMember(ctx, orgID, teamID, userID)
+	})
+}
+
+func (c *breakerGrafanaClient) GetUserID(ctx context.Context, login string) (float64, error) {
+	var userID float64
+	err := c.do(ctx, func() (err error) {
+		userID, err = c.client.GetUserID(ctx, login)
+		return err
+	})
+	return userID, err
+}
+
+func (c *breakerGrafanaClient) GetFolderPermissions(ctx context.Context, orgID string, folderUID string) ([]FolderPermission, error) {
+	var permissions []FolderPermission
+	err := c.do(ctx, func() (err error) {
+		permissions, err = c.client.GetFolderPermissions(ctx, orgID, folderUID)
+		return err
+	})
+	return permissions, err
+}
+
+func (c *breakerGrafanaClient) UpdateFolderPermissions(ctx context.Context, orgID string, folderUID string,
+	permissions []FolderPermission) error {
+	return c.do(ctx, func() error {
+		return c.client.UpdateFolderPermissions(ctx, orgID, folderUID, permissions)
+	})
+}
+
+// Health is not blocked by the breaker so that it always reports the current state of grafana
+func (c *breakerGrafanaClient) Health(ctx context.Context) error {
+	return c.client.Health(ctx)
+}
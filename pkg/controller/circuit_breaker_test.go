@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// unavailableGrafanaClient fails to list the folders as if grafana was down
+type unavailableGrafanaClient struct {
+	*fakeGrafanaClient
+	down bool
+}
+
+func (c *unavailableGrafanaClient) ListFolders(ctx context.Context, orgID string) ([]Folder, error) {
+	if c.down {
+		return nil, &GrafanaAPIError{StatusCode: http.StatusBadGateway, Body: []byte("bad gateway")}
+	}
+	return c.fakeGrafanaClient.ListFolders(ctx, orgID)
+}
+
+func TestIsGrafanaUnavailable(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+
+		{
+			"no error",
+			nil,
+			false,
+		},
+
+		{
+			"server error",
+			&GrafanaAPIError{StatusCode: http.StatusInternalServerError, Body: []byte("{}")},
+			true,
+		},
+
+		{
+			"no response",
+			&GrafanaAPIError{StatusCode: http.StatusNotFound},
+			true,
+		},
+
+		{
+			"not found",
+			&GrafanaAPIError{StatusCode: http.StatusNotFound, Body: []byte("{\"message\": \"not found\"}")},
+			false,
+		},
+
+		{
+			"other error",
+			fmt.Errorf("invalid json"),
+			false,
+		},
+	}
+
+	for _, c := range testCaseList {
+		output := isGrafanaUnavailable(c.err)
+		if output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	fake := &unavailableGrafanaClient{fakeGrafanaClient: newFakeGrafanaClient(), down: true}
+	fake.healthErr = fmt.Errorf("grafana is down")
+	client := newBreakerGrafanaClient(fake, 2, 50*time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListFolders(context.TODO(), ""); err == nil {
+			t.Fatalf("listing folders should fail when grafana is down")
+		}
+	}
+	if client.breaker.state != breakerOpen {
+		t.Fatalf("breaker should be open after the failures, got %v", client.breaker.state)
+	}
+
+	// the calls wait while grafana is still down
+	ctx, cancel := context.WithTimeout(context.TODO(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := client.ListFolders(ctx, ""); err != context.DeadlineExceeded {
+		t.Fatalf("the call should wait until the context is done, got %v", err)
+	}
+
+	// the waiting call goes through once the health probe succeeds
+	fake.down = false
+	fake.healthErr = nil
+	if _, err := client.ListFolders(context.TODO(), ""); err != nil {
+		t.Fatalf("listing folders should succeed after grafana is back: %v", err)
+	}
+	if client.breaker.state != breakerClosed {
+		t.Fatalf("breaker should be closed after grafana is back, got %v", client.breaker.state)
+	}
+}
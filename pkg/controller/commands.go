@@ -0,0 +1,207 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// SyncClusterObjects makes the one-shot sync apply the dashboard objects in the cluster
+var SyncClusterObjects = true
+
+// SyncOnce applies all the dashboards once and waits for them, so that it can run as a job or an init container:
+// the dashboard objects in the cluster are listed instead of watched,
+// and the configured directory, git repository and oci artifact are synced once
+func SyncOnce(ctx context.Context) error {
+	if err := setUpAudit(); err != nil {
+		return err
+	}
+	sources, err := newPolledSources()
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 && !SyncClusterObjects {
+		return fmt.Errorf("no dashboard source is configured, set --dashboard-dir, --git-repository or --oci-artifact")
+	}
+
+	failedObjects := 0
+	if SyncClusterObjects {
+		config, err := kubeConfig()
+		if err != nil {
+			return fmt.Errorf("failed to get cluster config: %v", err)
+		}
+		kubeClient, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return fmt.Errorf("failed to build kubeclient: %v", err)
+		}
+		var dynamicClient dynamic.Interface
+		if WatchGrafanaDashboards {
+			dynamicClient, err = dynamic.NewForConfig(config)
+			if err != nil {
+				return fmt.Errorf("failed to build dynamic client: %v", err)
+			}
+		}
+		total := 0
+		total, failedObjects, err = syncClusterObjects(ctx, kubeClient, dynamicClient)
+		if err != nil {
+			return err
+		}
+		klog.Infof("%v of %v dashboard objects in the cluster are synced", total-failedObjects, total)
+	}
+
+	failedSources := 0
+	for _, source := range sources {
+		if err := source.sync(ctx); err != nil {
+			klog.Error(err)
+			failedSources++
+		}
+	}
+	if failedObjects > 0 || failedSources > 0 {
+		return fmt.Errorf("failed to sync %v dashboard objects and %v of %v dashboard sources",
+			failedObjects, failedSources, len(sources))
+	}
+	return nil
+}
+
+// syncClusterObjects lists the dashboard configmaps, and the secrets and GrafanaDashboards once they are enabled,
+// in the watched namespace and applies them, the number of the dashboard objects and the failed ones are returned
+func syncClusterObjects(ctx context.Context, kubeClient kubernetes.Interface, dynamicClient dynamic.Interface) (int, int, error) {
+	watchedNS := os.Getenv("POD_NAMESPACE")
+	total, failed := 0, 0
+	apply := func(state *syncState, obj interface{}) {
+		if !isDesiredDashboardConfigmap(obj) {
+			return
+		}
+		total++
+		if err := syncDashboard(ctx, state, nil, obj); err != nil {
+			failed++
+		}
+	}
+
+	configmaps := kubeClient.CoreV1().ConfigMaps(watchedNS)
+	if err := eachListItem(ctx, configmapListOptions(metav1.ListOptions{}), func(opts metav1.ListOptions) (runtime.Object, error) {
+		return configmaps.List(ctx, opts)
+	}, func(obj runtime.Object) {
+		apply(appliedState, obj)
+	}); err != nil {
+		return total, failed, fmt.Errorf("failed to list configmaps: %v", err)
+	}
+
+	if WatchSecrets {
+		secrets := kubeClient.CoreV1().Secrets(watchedNS)
+		if err := eachListItem(ctx, metav1.ListOptions{}, func(opts metav1.ListOptions) (runtime.Object, error) {
+			return secrets.List(ctx, opts)
+		}, func(obj runtime.Object) {
+			apply(appliedSecretState, secretToConfigmap(obj))
+		}); err != nil {
+			return total, failed, fmt.Errorf("failed to list secrets: %v", err)
+		}
+	}
+
+	if WatchGrafanaDashboards && dynamicClient != nil {
+		for _, gvr := range servedGrafanaDashboardResources(kubeClient.Discovery()) {
+			resource := dynamicClient.Resource(gvr).Namespace(watchedNS)
+			if err := eachListItem(ctx, metav1.ListOptions{}, func(opts metav1.ListOptions) (runtime.Object, error) {
+				return resource.List(ctx, opts)
+			}, func(obj runtime.Object) {
+				apply(appliedGrafanaDashboardState, grafanaDashboardToConfigmap(obj))
+			}); err != nil {
+				return total, failed, fmt.Errorf("failed to list GrafanaDashboard %v: %v", gvr.GroupVersion(), err)
+			}
+		}
+	}
+	return total, failed, nil
+}
+
+// ValidateDashboards checks the *.json dashboards in the files and directories without calling grafana,
+// every problem is returned as an error with the file path
+func ValidateDashboards(paths []string) []error {
+	problems := []error{}
+	for _, path := range paths {
+		configmaps, err := loadDashboardFiles(path)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%v: %v", path, err))
+			continue
+		}
+		files := []string{}
+		for file := range configmaps {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			dashboards, err := getDashboardData(context.TODO(), configmaps[file])
+			if err != nil {
+				problems = append(problems, fmt.Errorf("%v: %v", file, err))
+			}
+			for _, value := range dashboards {
+				if err := validateDashboard(value); err != nil {
+					problems = append(problems, fmt.Errorf("%v: %v", file, err))
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// loadDashboardFiles reads the dashboard file, or all the dashboard files under the directory, as configmaps
+func loadDashboardFiles(path string) (map[string]*corev1.ConfigMap, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		files := &filesystemSource{dir: path, loaded: map[string]*corev1.ConfigMap{}}
+		return files.scan()
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]*corev1.ConfigMap{path: fileToConfigmap(filepath.Dir(path), path, string(content))}, nil
+}
+
+// validateDashboard checks the dashboard can be posted to grafana
+func validateDashboard(value string) error {
+	dashboard := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(value), &dashboard); err != nil {
+		return fmt.Errorf("invalid dashboard json: %v", err)
+	}
+	if title, ok := dashboard["title"].(string); !ok || strings.TrimSpace(title) == "" {
+		return fmt.Errorf("the dashboard has no title")
+	}
+	if panels, ok := dashboard["panels"]; ok && panels != nil {
+		if _, ok := panels.([]interface{}); !ok {
+			return fmt.Errorf("the panels of the dashboard is not a list")
+		}
+	}
+	if MigrateDashboards {
+		migrateDashboard(dashboard)
+	}
+	if hasPolicy() && activeSettings().policyMode == policyModeReject {
+		if violations := enforcePolicy(dashboard, false); len(violations) > 0 {
+			return fmt.Errorf("the dashboard violates the policy: %v", strings.Join(violations, "; "))
+		}
+	}
+	if err := validateDashboardSchema(dashboard); err != nil {
+		return err
+	}
+	if ValidatePromQL {
+		return validatePromQL(dashboard)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSyncOnceAndExport(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func(dir string, cluster bool) {
+		DashboardDir = dir
+		SyncClusterObjects = cluster
+	}(DashboardDir, SyncClusterObjects)
+
+	SyncClusterObjects = false
+	DashboardDir = ""
+	if err := SyncOnce(context.TODO()); err == nil {
+		t.Errorf("sync without any source should fail")
+	}
+
+	dir, err := ioutil.TempDir("", "dashboards")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "SLOs"), 0755)
+	os.MkdirAll(filepath.Join(dir, "General"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"title": "a", "uid": "uid-a", "schemaVersion": 27, "panels": []}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "SLOs", "b.json"), []byte(`{"title": "b", "uid": "uid-b", "schemaVersion": 27, "panels": []}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "General", "c.json"), []byte(`{"title": "c", "uid": "uid-c", "schemaVersion": 27, "panels": []}`), 0644)
+
+	DashboardDir = dir
+	if err := SyncOnce(context.TODO()); err != nil {
+		t.Fatalf("failed to sync the dashboard directory: %v", err)
+	}
+	if len(fake.dashboards[""]) != 3 {
+		t.Fatalf("the synced dashboards %v are not the expected 3", len(fake.dashboards[""]))
+	}
+
+	output, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(output)
+	exported, err := ExportDashboards(context.TODO(), output, ExportOptions{})
+	if err != nil || exported != 3 {
+		t.Fatalf("failed to export the dashboards: %v, %v", exported, err)
+	}
+
+	testCaseList := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"default folder", filepath.Join(output, defaultCustomFolder, "uid-a.json"), "a"},
+
+		{"custom folder", filepath.Join(output, "SLOs", "uid-b.json"), "b"},
+
+		{"general folder", filepath.Join(output, "General", "uid-c.json"), "c"},
+	}
+
+	for _, c := range testCaseList {
+		content, err := ioutil.ReadFile(c.path)
+		if err != nil {
+			t.Errorf("case (%v) failed to read the exported dashboard: %v", c.name, err)
+			continue
+		}
+		dashboard := map[string]interface{}{}
+		json.Unmarshal(content, &dashboard)
+		if dashboard["title"] != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, dashboard["title"], c.expected)
+		}
+		if _, ok := dashboard["id"]; ok {
+			t.Errorf("case (%v) the exported dashboard should not have the id", c.name)
+		}
+	}
+
+	// the exported directory can be loaded again
+	if problems := ValidateDashboards([]string{output}); len(problems) != 0 {
+		t.Errorf("the exported dashboards are not valid: %v", problems)
+	}
+}
+
+func TestSyncClusterObjects(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func(watch bool) { WatchSecrets = watch }(WatchSecrets)
+	WatchSecrets = true
+
+	labels := map[string]string{"grafana-custom-dashboard": "true"}
+	kubeClient := kubefake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "valid", Labels: labels},
+			Data:       map[string]string{"valid.json": `{"title": "valid", "uid": "valid"}`},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid", Labels: labels},
+			Data:       map[string]string{"invalid.json": `{"title": `},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "other"},
+			Data:       map[string]string{"other.json": `{"title": "other", "uid": "other"}`},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret", Labels: labels},
+			Data:       map[string][]byte{"secret.json": []byte(`{"title": "secret", "uid": "secret"}`)},
+		},
+	)
+
+	total, failed, err := syncClusterObjects(context.TODO(), kubeClient, nil)
+	if err != nil {
+		t.Fatalf("failed to sync the cluster objects: %v", err)
+	}
+	if total != 3 || failed != 1 {
+		t.Errorf("case (sync) output: (%v, %v) is not the expected: (3, 1)", total, failed)
+	}
+	for _, uid := range []string{"valid", "secret"} {
+		if _, ok := fake.dashboards[""][uid]; !ok {
+			t.Errorf("dashboard %v is not synced", uid)
+		}
+	}
+	if _, ok := fake.dashboards[""]["other"]; ok {
+		t.Errorf("the configmap which is not a dashboard should be skipped")
+	}
+}
+
+func TestValidateDashboards(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dashboards")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	testCaseList := []struct {
+		name     string
+		content  string
+		expected int
+	}{
+		{"valid dashboard", `{"title": "test", "schemaVersion": 27, "panels": []}`, 0},
+
+		{"schema mismatch", `{"title": "test", "schemaVersion": 27, "panels": [{"id": 1}]}`, 1},
+
+		{"invalid json", `{"title": `, 1},
+
+		{"no title", `{"panels": []}`, 1},
+
+		{"panels is not a list", `{"title": "test", "panels": {}}`, 1},
+	}
+
+	for i, c := range testCaseList {
+		path := filepath.Join(dir, string(rune('a'+i))+".json")
+		ioutil.WriteFile(path, []byte(c.content), 0644)
+		problems := ValidateDashboards([]string{path})
+		if len(problems) != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, problems, c.expected)
+		}
+	}
+
+	// all the files in the directory are checked
+	problems := ValidateDashboards([]string{dir, filepath.Join(dir, "missing.json")})
+	if len(problems) != 5 {
+		t.Errorf("case (directory) output: (%v) is not the expected: (%v)", problems, 5)
+	}
+}
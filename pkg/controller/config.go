@@ -0,0 +1,399 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
+)
+
+var (
+	// ConfigFile is the yaml or json config file, e.g. a mounted configmap, empty means only the flags are used
+	ConfigFile = ""
+	// ConfigPollInterval is how often the config file is checked for changes
+	ConfigPollInterval = 10 * time.Second
+)
+
+// Config is the content of the config file, the settings which are not in the file keep the values of the flags
+type Config struct {
+	Grafana   GrafanaConfig   `json:"grafana,omitempty"`
+	Selectors SelectorsConfig `json:"selectors,omitempty"`
+	Folders   FoldersConfig   `json:"folders,omitempty"`
+	Retry     RetryConfig     `json:"retry,omitempty"`
+	Policy    PolicyConfig    `json:"policy,omitempty"`
+}
+
+// GrafanaConfig is how grafana is connected
+type GrafanaConfig struct {
+	URL  string     `json:"url,omitempty"`
+	Auth AuthConfig `json:"auth,omitempty"`
+	// Replicas are the other urls of the same grafana, the requests fail over to them once the url cannot be connected
+	Replicas []string `json:"replicas,omitempty"`
+	// Targets are the grafana instances every dashboard is applied to instead of the url above
+	Targets []TargetConfig `json:"targets,omitempty"`
+}
+
+// TargetConfig is a grafana instance the dashboards are applied to
+type TargetConfig struct {
+	// Name identifies the target in the logs and the sync status
+	Name string     `json:"name"`
+	URL  string     `json:"url"`
+	Auth AuthConfig `json:"auth,omitempty"`
+	// Replicas are the other urls of the target to fail over to
+	Replicas []string `json:"replicas,omitempty"`
+	// Cloud means the target is a grafana cloud stack which needs a service account token
+	Cloud bool `json:"cloud,omitempty"`
+}
+
+// AuthConfig is how the requests to grafana are authenticated, the secrets are read from the mounted files
+type AuthConfig struct {
+	// ProxyUser is the user passed to the grafana auth proxy
+	ProxyUser string `json:"proxyUser,omitempty"`
+	// TokenFile contains the grafana api token or service account token
+	TokenFile    string `json:"tokenFile,omitempty"`
+	Username     string `json:"username,omitempty"`
+	PasswordFile string `json:"passwordFile,omitempty"`
+}
+
+// SelectorsConfig decides which configmaps are dashboards besides the grafana-custom-dashboard label
+type SelectorsConfig struct {
+	SidecarLabel            string  `json:"sidecarLabel,omitempty"`
+	SidecarLabelValue       *string `json:"sidecarLabelValue,omitempty"`
+	SidecarFolderAnnotation string  `json:"sidecarFolderAnnotation,omitempty"`
+}
+
+// FoldersConfig is the defaults of the dashboards
+type FoldersConfig struct {
+	Default               string `json:"default,omitempty"`
+	ClusterName           string `json:"clusterName,omitempty"`
+	NonEditableDashboards *bool  `json:"nonEditableDashboards,omitempty"`
+}
+
+// RetryConfig is the retry policy of the grafana requests
+type RetryConfig struct {
+	MaxRetries     int              `json:"maxRetries,omitempty"`
+	RequestTimeout *metav1.Duration `json:"requestTimeout,omitempty"`
+	SyncTimeout    *metav1.Duration `json:"syncTimeout,omitempty"`
+	QPS            *float32         `json:"qps,omitempty"`
+	Burst          int              `json:"burst,omitempty"`
+	MaxRetryAfter  *metav1.Duration `json:"maxRetryAfter,omitempty"`
+}
+
+// PolicyConfig are the panel plugins and the datasource types the dashboards must not use and the guardrails
+type PolicyConfig struct {
+	// Mode is either reject or sanitize
+	Mode                  string   `json:"mode,omitempty"`
+	BannedPanelTypes      []string `json:"bannedPanelTypes,omitempty"`
+	BannedDatasourceTypes []string `json:"bannedDatasourceTypes,omitempty"`
+	// MinRefreshInterval and MaxTimeRange are the guardrails of the auto refresh and the default time range
+	MinRefreshInterval *metav1.Duration `json:"minRefreshInterval,omitempty"`
+	MaxTimeRange       *metav1.Duration `json:"maxTimeRange,omitempty"`
+	// FreezeWindows are the cron expressions and the durations of the change freezes
+	FreezeWindows []string `json:"freezeWindows,omitempty"`
+}
+
+// settings are the values which can be set by the config file
+type settings struct {
+	grafanaURI              string
+	grafanaReplicas         []string
+	authProxyUser           string
+	bearerToken             string
+	basicAuthUsername       string
+	basicAuthPassword       string
+	sidecarLabel            string
+	sidecarLabelValue       string
+	sidecarFolderAnnotation string
+	defaultFolder           string
+	clusterName             string
+	nonEditableDashboards   bool
+	retry                   int
+	requestTimeout          time.Duration
+	syncTimeout             time.Duration
+	requestQPS              float32
+	requestBurst            int
+	maxRetryAfter           time.Duration
+	policyMode              string
+	bannedPanelTypes        []string
+	bannedDatasourceTypes   []string
+	minRefreshInterval      time.Duration
+	freezeWindows           []string
+	maxTimeRange            time.Duration
+	targets                 []targetSettings
+}
+
+var (
+	configLock sync.Mutex
+	// flagSettings are the settings before the config file is applied, the config file is applied on top of them
+	flagSettings *settings
+	// publishedSettings holds the *settings of the config file, they are replaced as a whole and never changed,
+	// so a sync reads the same settings from its start to its end while the config file is reloaded
+	publishedSettings atomic.Value
+)
+
+// activeSettings returns the settings of the config file, or the flags before any config file is applied,
+// the returned settings must not be changed
+func activeSettings() *settings {
+	if s, ok := publishedSettings.Load().(*settings); ok && s != nil {
+		return s
+	}
+	s := currentSettings()
+	return &s
+}
+
+// currentSettings returns the settings set by the flags
+func currentSettings() settings {
+	return settings{
+		grafanaURI:              grafanaURI,
+		grafanaReplicas:         grafanaReplicaURIs,
+		authProxyUser:           util.AuthProxyUser,
+		bearerToken:             util.BearerToken,
+		basicAuthUsername:       util.BasicAuthUsername,
+		basicAuthPassword:       util.BasicAuthPassword,
+		sidecarLabel:            SidecarLabel,
+		sidecarLabelValue:       SidecarLabelValue,
+		sidecarFolderAnnotation: SidecarFolderAnnotation,
+		defaultFolder:           DefaultFolder,
+		clusterName:             ClusterName,
+		nonEditableDashboards:   NonEditableDashboards,
+		retry:                   retry,
+		requestTimeout:          util.RequestTimeout,
+		syncTimeout:             SyncTimeout,
+		requestQPS:              util.RequestQPS,
+		requestBurst:            util.RequestBurst,
+		maxRetryAfter:           util.MaxRetryAfter,
+		policyMode:              PolicyMode,
+		bannedPanelTypes:        BannedPanelTypes,
+		bannedDatasourceTypes:   BannedDatasourceTypes,
+		minRefreshInterval:      MinRefreshInterval,
+		freezeWindows:           FreezeWindows,
+		maxTimeRange:            MaxTimeRange,
+		targets:                 currentTargets(),
+	}
+}
+
+// apply publishes the settings to the syncs and the grafana requests, the flag variables are kept
+func (s settings) apply() {
+	publishedSettings.Store(&s)
+	util.PublishSettings(&util.Settings{
+		Auth: util.GrafanaAuth{
+			ProxyUser:         s.authProxyUser,
+			BearerToken:       s.bearerToken,
+			BasicAuthUsername: s.basicAuthUsername,
+			BasicAuthPassword: s.basicAuthPassword,
+		},
+		RequestTimeout: s.requestTimeout,
+		RequestQPS:     s.requestQPS,
+		RequestBurst:   s.requestBurst,
+		MaxRetryAfter:  s.maxRetryAfter,
+	})
+	targetsLock.Lock()
+	configuredTargets = s.targets
+	targetsLock.Unlock()
+}
+
+// merge returns the settings overridden by the config, the secret files are read here
+func (c *Config) merge(s settings) (settings, error) {
+	if c.Grafana.URL != "" {
+		s.grafanaURI = strings.TrimSuffix(c.Grafana.URL, "/")
+	}
+	if c.Grafana.Replicas != nil {
+		s.grafanaReplicas = normalizeGrafanaURLs(c.Grafana.Replicas)
+	}
+	auth := c.Grafana.Auth
+	if auth.ProxyUser != "" {
+		s.authProxyUser = auth.ProxyUser
+	}
+	if auth.TokenFile != "" {
+		token, err := readSecretFile(auth.TokenFile)
+		if err != nil {
+			return s, err
+		}
+		s.bearerToken = token
+	}
+	if auth.Username != "" {
+		s.basicAuthUsername = auth.Username
+		password, err := readSecretFile(auth.PasswordFile)
+		if err != nil {
+			return s, err
+		}
+		s.basicAuthPassword = password
+	}
+	if c.Grafana.Targets != nil {
+		targets, err := c.Grafana.targetSettings()
+		if err != nil {
+			return s, err
+		}
+		s.targets = targets
+	}
+
+	if c.Selectors.SidecarLabel != "" {
+		s.sidecarLabel = c.Selectors.SidecarLabel
+	}
+	if c.Selectors.SidecarLabelValue != nil {
+		s.sidecarLabelValue = *c.Selectors.SidecarLabelValue
+	}
+	if c.Selectors.SidecarFolderAnnotation != "" {
+		s.sidecarFolderAnnotation = c.Selectors.SidecarFolderAnnotation
+	}
+
+	if c.Folders.Default != "" {
+		s.defaultFolder = c.Folders.Default
+	}
+	if c.Folders.ClusterName != "" {
+		s.clusterName = c.Folders.ClusterName
+	}
+	if c.Folders.NonEditableDashboards != nil {
+		s.nonEditableDashboards = *c.Folders.NonEditableDashboards
+	}
+
+	if c.Retry.MaxRetries > 0 {
+		s.retry = c.Retry.MaxRetries
+	}
+	if c.Retry.RequestTimeout != nil {
+		s.requestTimeout = c.Retry.RequestTimeout.Duration
+	}
+	if c.Retry.SyncTimeout != nil {
+		s.syncTimeout = c.Retry.SyncTimeout.Duration
+	}
+	if c.Retry.QPS != nil {
+		s.requestQPS = *c.Retry.QPS
+	}
+	if c.Retry.Burst > 0 {
+		s.requestBurst = c.Retry.Burst
+	}
+	if c.Retry.MaxRetryAfter != nil {
+		s.maxRetryAfter = c.Retry.MaxRetryAfter.Duration
+	}
+
+	if c.Policy.Mode != "" {
+		if err := ValidatePolicyMode(c.Policy.Mode); err != nil {
+			return s, err
+		}
+		s.policyMode = c.Policy.Mode
+	}
+	if c.Policy.BannedPanelTypes != nil {
+		s.bannedPanelTypes = c.Policy.BannedPanelTypes
+	}
+	if c.Policy.BannedDatasourceTypes != nil {
+		s.bannedDatasourceTypes = c.Policy.BannedDatasourceTypes
+	}
+	if c.Policy.FreezeWindows != nil {
+		if err := ValidateFreezeWindows(c.Policy.FreezeWindows); err != nil {
+			return s, err
+		}
+		s.freezeWindows = c.Policy.FreezeWindows
+	}
+	if c.Policy.MinRefreshInterval != nil {
+		s.minRefreshInterval = c.Policy.MinRefreshInterval.Duration
+	}
+	if c.Policy.MaxTimeRange != nil {
+		s.maxTimeRange = c.Policy.MaxTimeRange.Duration
+	}
+	return s, nil
+}
+
+// targetSettings checks the targets and reads their secret files
+func (c GrafanaConfig) targetSettings() ([]targetSettings, error) {
+	targets := []targetSettings{}
+	names := map[string]bool{}
+	for _, target := range c.Targets {
+		if target.Name == "" || target.URL == "" {
+			return nil, fmt.Errorf("the name and the url of the grafana targets are required")
+		}
+		if names[target.Name] {
+			return nil, fmt.Errorf("the grafana target %v is duplicated", target.Name)
+		}
+		names[target.Name] = true
+		settings := targetSettings{name: target.Name, url: normalizeGrafanaURL(target.URL), cloud: target.Cloud,
+			replicas: normalizeGrafanaURLs(target.Replicas)}
+		settings.auth.ProxyUser = target.Auth.ProxyUser
+		token, err := readSecretFile(target.Auth.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		settings.auth.BearerToken = token
+		if target.Cloud && token == "" {
+			return nil, fmt.Errorf("the grafana cloud target %v needs the token file of a service account token", target.Name)
+		}
+		if target.Auth.Username != "" {
+			settings.auth.BasicAuthUsername = target.Auth.Username
+			password, err := readSecretFile(target.Auth.PasswordFile)
+			if err != nil {
+				return nil, err
+			}
+			settings.auth.BasicAuthPassword = password
+		}
+		targets = append(targets, settings)
+	}
+	return targets, nil
+}
+
+func readSecretFile(file string) (string, error) {
+	if file == "" {
+		return "", nil
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %v: %v", file, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// LoadConfig applies the config file on top of the flags, it returns whether the settings are changed
+func LoadConfig(file string) (bool, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return false, fmt.Errorf("failed to parse config file %v: %v", file, err)
+	}
+
+	configLock.Lock()
+	defer configLock.Unlock()
+	if flagSettings == nil {
+		s := currentSettings()
+		flagSettings = &s
+	}
+	merged, err := config.merge(*flagSettings)
+	if err != nil {
+		return false, err
+	}
+	// the secret files may be rotated without changing the config file
+	changed := !reflect.DeepEqual(merged, *activeSettings())
+	if changed {
+		merged.apply()
+	}
+	return changed, nil
+}
+
+// watchConfig applies the config file once it is changed until ctx is done,
+// the dashboards are applied again so that the new selectors and folders take effect
+func watchConfig(ctx context.Context, file string, interval time.Duration) {
+	wait.Until(func() {
+		changed, err := LoadConfig(file)
+		if err != nil {
+			klog.Errorf("failed to reload config file %v, the last settings are kept: %v", file, err)
+			return
+		}
+		if changed {
+			klog.Infof("config file %v is reloaded", file)
+			resyncAll()
+		}
+	}, interval, ctx.Done())
+}
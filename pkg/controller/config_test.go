@@ -0,0 +1,126 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
+)
+
+func TestLoadConfig(t *testing.T) {
+	original := currentSettings()
+	defer func() {
+		// the flags are read again once no settings are published
+		publishedSettings.Store((*settings)(nil))
+		util.PublishSettings(nil)
+		targetsLock.Lock()
+		configuredTargets = original.targets
+		targetsLock.Unlock()
+		configLock.Lock()
+		flagSettings = nil
+		configLock.Unlock()
+	}()
+
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "config.yaml")
+	tokenFile := filepath.Join(dir, "token")
+	ioutil.WriteFile(tokenFile, []byte("secret\n"), 0600)
+
+	ioutil.WriteFile(file, []byte(`
+grafana:
+  url: http://grafana:3000/
+  replicas: [http://grafana-1:3000/]
+  auth:
+    tokenFile: `+tokenFile+`
+folders:
+  default: "{{ .Namespace }}"
+retry:
+  maxRetries: 3
+  requestTimeout: 10s
+policy:
+  mode: sanitize
+  bannedPanelTypes: [graph]
+`), 0644)
+	changed, err := LoadConfig(file)
+	if err != nil || !changed {
+		t.Fatalf("failed to load the config file: %v", err)
+	}
+	active := activeSettings()
+	published := active
+
+	testCaseList := []struct {
+		name     string
+		output   interface{}
+		expected interface{}
+	}{
+		{"grafana url", active.grafanaURI, "http://grafana:3000"},
+
+		{"grafana replicas", strings.Join(active.grafanaReplicas, ","), "http://grafana-1:3000"},
+
+		{"token", util.CurrentSettings().Auth.BearerToken, "secret"},
+
+		{"default folder", active.defaultFolder, "{{ .Namespace }}"},
+
+		{"retry", active.retry, 3},
+
+		{"request timeout", util.CurrentSettings().RequestTimeout, 10 * time.Second},
+
+		{"sync timeout from the flag", active.syncTimeout, original.syncTimeout},
+
+		{"policy mode", active.policyMode, policyModeSanitize},
+
+		{"banned panel types", strings.Join(active.bannedPanelTypes, ","), "graph"},
+	}
+
+	for _, c := range testCaseList {
+		if c.output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, c.output, c.expected)
+		}
+	}
+
+	changed, err = LoadConfig(file)
+	if err != nil || changed {
+		t.Errorf("the unchanged config file should not change the settings: %v", err)
+	}
+
+	// the removed settings go back to the flags
+	ioutil.WriteFile(file, []byte(`
+folders:
+  default: Team
+`), 0644)
+	changed, err = LoadConfig(file)
+	if err != nil || !changed {
+		t.Fatalf("failed to reload the config file: %v", err)
+	}
+	active = activeSettings()
+	token := util.CurrentSettings().Auth.BearerToken
+	if active.grafanaURI != original.grafanaURI || token != original.bearerToken || active.defaultFolder != "Team" {
+		t.Errorf("the settings (%v, %v, %v) are not reverted to the flags", active.grafanaURI, token, active.defaultFolder)
+	}
+	if active == published {
+		t.Errorf("the published settings should be replaced instead of changed")
+	}
+	if published.defaultFolder != "{{ .Namespace }}" {
+		t.Errorf("the settings read by a running sync should not be changed by the reload")
+	}
+
+	// an invalid config file keeps the last settings
+	ioutil.WriteFile(file, []byte("folders:\n  unknown: true\n"), 0644)
+	if _, err := LoadConfig(file); err == nil {
+		t.Errorf("the config file with an unknown field should be rejected")
+	}
+	if activeSettings().defaultFolder != "Team" {
+		t.Errorf("the settings should be kept after an invalid config file")
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ConfigmapLabelSelector is sent with the list and the watch of the configmaps so that the unrelated configmaps
+// of the namespace are filtered by the api server, e.g. "grafana-custom-dashboard", the sidecar dashboards
+// and the ones owned by MultiClusterObservability are only seen once they match it as well,
+// and the dashboards of a configmap which stops matching it are deleted, empty watches all the configmaps
+var ConfigmapLabelSelector = ""
+
+// ValidateConfigmapLabelSelector checks the label selector of the configmaps
+func ValidateConfigmapLabelSelector(selector string) error {
+	if _, err := labels.Parse(selector); err != nil {
+		return fmt.Errorf("invalid configmap label selector %q: %v", selector, err)
+	}
+	return nil
+}
+
+// configmapListOptions adds the label selector to the options of the informer, the resource version
+// and the timeout of the watch are kept
+func configmapListOptions(opts metav1.ListOptions) metav1.ListOptions {
+	opts.LabelSelector = ConfigmapLabelSelector
+	return opts
+}
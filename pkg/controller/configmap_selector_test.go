@@ -0,0 +1,53 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConfigmapListOptions(t *testing.T) {
+	defer func(selector string) { ConfigmapLabelSelector = selector }(ConfigmapLabelSelector)
+	kubeClient := kubefake.NewSimpleClientset(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "dashboard", Namespace: "test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "kube-root-ca.crt", Namespace: "test"}},
+	)
+
+	testCaseList := []struct {
+		name     string
+		selector string
+		hasErr   bool
+		expected int
+	}{
+		{"all", "", false, 2},
+
+		{"dashboards", "grafana-custom-dashboard", false, 1},
+
+		{"invalid", "grafana-custom-dashboard in (true", true, 0},
+	}
+
+	for _, c := range testCaseList {
+		if err := ValidateConfigmapLabelSelector(c.selector); (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+		if c.hasErr {
+			continue
+		}
+		ConfigmapLabelSelector = c.selector
+		opts := configmapListOptions(metav1.ListOptions{ResourceVersion: "10"})
+		if opts.ResourceVersion != "10" {
+			t.Errorf("case (%v) the resource version of the informer is not kept", c.name)
+		}
+		configmaps, err := kubeClient.CoreV1().ConfigMaps("test").List(context.TODO(), opts)
+		if err != nil || len(configmaps.Items) != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, len(configmaps.Items), c.expected)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+// BlockAngularPanels does not save the dashboards with angular panels which are not rendered by grafana 11 and later
+var BlockAngularPanels = false
+
+// angularPanelTypes are the core and the community panel plugins which are still built with angular
+var angularPanelTypes = map[string]bool{
+	"graph":                           true,
+	"singlestat":                      true,
+	"grafana-singlestat-panel":        true,
+	"table-old":                       true,
+	"grafana-piechart-panel":          true,
+	"grafana-worldmap-panel":          true,
+	"natel-discrete-panel":            true,
+	"natel-plotly-panel":              true,
+	"briangann-gauge-panel":           true,
+	"briangann-datatable-panel":       true,
+	"vonage-status-panel":             true,
+	"michaeldmoore-multistat-panel":   true,
+	"btplc-status-dot-panel":          true,
+	"jdbranham-diagram-panel":         true,
+	"flant-statusmap-panel":           true,
+	"yesoreyeram-boomtable-panel":     true,
+	"savantly-heatmap-panel":          true,
+	"mxswat-separator-panel":          true,
+	"digrich-bubblechart-panel":       true,
+	"neocat-cal-heatmap-panel":        true,
+	"petrslavotinek-carpetplot-panel": true,
+}
+
+// angularPanels returns the names of the angular panels of the dashboard keyed by the panel type
+func angularPanels(dashboard map[string]interface{}) map[string][]string {
+	panels := map[string][]string{}
+	for _, panel := range lintPanels(dashboard) {
+		panelType, _ := panel["type"].(string)
+		if angularPanelTypes[panelType] {
+			panels[panelType] = append(panels[panelType], panelName(panel))
+		}
+	}
+	return panels
+}
+
+// checkAngularPanels reports the angular panels of the dashboard, the returned error blocks the dashboard
+// when BlockAngularPanels is set
+func checkAngularPanels(cm *corev1.ConfigMap, key string, dashboard map[string]interface{}) error {
+	panels := angularPanels(dashboard)
+	if len(panels) == 0 {
+		return nil
+	}
+	types := []string{}
+	for panelType, names := range panels {
+		metrics.AngularPanels.WithLabelValues(panelType).Add(float64(len(names)))
+		types = append(types, fmt.Sprintf("%v (%v)", panelType, strings.Join(names, ", ")))
+	}
+	sort.Strings(types)
+	message := fmt.Sprintf("the dashboard has the angular panels which are not rendered by grafana 11 and later: %v", strings.Join(types, "; "))
+	recordEvent(cm, corev1.EventTypeWarning, reasonDashboardAngularPanels, "The dashboard %v has angular panels: %v", key, strings.Join(types, "; "))
+	if BlockAngularPanels {
+		return errors.New(message)
+	}
+	klog.InfoS(message, "configmap", klog.KObj(cm), "key", key)
+	return nil
+}
+
+// angularPanelWarning is the admission warning of the angular panels of the dashboard, empty means there is none
+func angularPanelWarning(dashboard map[string]interface{}) string {
+	panels := angularPanels(dashboard)
+	types := []string{}
+	for panelType := range panels {
+		types = append(types, panelType)
+	}
+	sort.Strings(types)
+	if len(types) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("the angular panels %v are not rendered by grafana 11 and later", strings.Join(types, ", "))
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+func TestAngularPanels(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		content  string
+		expected map[string][]string
+	}{
+		{"react panels", `{"panels": [{"id": 1, "type": "timeseries"}, {"id": 2, "type": "stat"}]}`, map[string][]string{}},
+
+		{"angular panels", `{"panels": [{"id": 1, "type": "graph", "title": "CPU"}, {"id": 2, "type": "row", "panels": [
+			{"id": 3, "type": "grafana-piechart-panel", "title": "Share"}, {"id": 4, "type": "graph", "title": "Memory"}]}]}`,
+			map[string][]string{"graph": {`"CPU"`, `"Memory"`}, "grafana-piechart-panel": {`"Share"`}}},
+	}
+
+	for _, c := range testCaseList {
+		dashboard := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(c.content), &dashboard); err != nil {
+			t.Fatalf("case (%v) invalid dashboard: %v", c.name, err)
+		}
+		if output := angularPanels(dashboard); !reflect.DeepEqual(output, c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestBlockAngularPanels(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func() { BlockAngularPanels = false }()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+		Data: map[string]string{
+			"react.json":   `{"uid": "react", "title": "react", "panels": [{"id": 1, "type": "timeseries"}]}`,
+			"angular.json": `{"uid": "angular", "title": "angular", "panels": [{"id": 1, "type": "singlestat"}]}`,
+		},
+	}
+
+	testCaseList := []struct {
+		name     string
+		block    bool
+		expected int
+		err      bool
+	}{
+		{"reported only", false, 2, false},
+
+		{"blocked", true, 1, true},
+	}
+
+	for _, c := range testCaseList {
+		fake.dashboards = map[string]map[string]fakeDashboard{}
+		BlockAngularPanels = c.block
+		found := testutil.ToFloat64(metrics.AngularPanels.WithLabelValues("singlestat"))
+		err := updateDashboard(context.TODO(), nil, cm, false)
+		if (err != nil) != c.err || err != nil && !strings.HasPrefix(err.Error(), "angular.json: ") {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.err)
+		}
+		if len(fake.dashboards[""]) != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, len(fake.dashboards[""]), c.expected)
+		}
+		if output := testutil.ToFloat64(metrics.AngularPanels.WithLabelValues("singlestat")) - found; output != 1 {
+			t.Errorf("case (%v) metrics output: (%v) is not the expected: (%v)", c.name, output, 1)
+		}
+	}
+}
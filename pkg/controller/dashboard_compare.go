@@ -0,0 +1,71 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+// SkipUnchangedDashboards gets the stored dashboard before it is saved and skips the save when the stored one
+// is the same as the one to apply, so that the full resyncs do not add a new version to the dashboard history
+var SkipUnchangedDashboards = false
+
+// unchangedDashboard returns the stored dashboard when it is the same as the one to apply in the same folder,
+// the dashboard is saved whenever the stored one cannot be got
+func unchangedDashboard(ctx context.Context, cm *corev1.ConfigMap, key string, orgID string,
+	dashboard map[string]interface{}, folderID float64) (SavedDashboard, bool) {
+	if !SkipUnchangedDashboards {
+		return SavedDashboard{}, false
+	}
+	uid, _ := dashboard["uid"].(string)
+	stored, meta, err := clientFor(ctx).GetDashboardWithMeta(ctx, orgID, uid)
+	if grafanaStatus(err) == http.StatusNotFound {
+		return SavedDashboard{}, false
+	}
+	if err != nil {
+		klog.ErrorS(err, "failed to get the stored dashboard to compare, it is saved", "configmap", klog.KObj(cm),
+			"key", key, "uid", uid)
+		return SavedDashboard{}, false
+	}
+	if meta.FolderID != folderID || !sameDashboards(stored, dashboard) {
+		return SavedDashboard{}, false
+	}
+	metrics.DashboardsUnchanged.Inc()
+	saved := SavedDashboard{UID: uid}
+	saved.ID, _ = stored["id"].(float64)
+	saved.Version, _ = stored["version"].(float64)
+	return saved, true
+}
+
+// sameDashboards compares the dashboards by their json without the fields which grafana changes on every save,
+// the keys of the json objects are sorted so the same dashboards have the same json whatever the go types are
+func sameDashboards(stored, desired map[string]interface{}) bool {
+	encoded := []*bytes.Buffer{}
+	defer func() {
+		for _, buffer := range encoded {
+			releaseBuffer(buffer)
+		}
+	}()
+	for _, dashboard := range []map[string]interface{}{stored, desired} {
+		kept := make(map[string]interface{}, len(dashboard))
+		for field, value := range dashboard {
+			if !diffIgnoredFields[field] {
+				kept[field] = value
+			}
+		}
+		buffer, err := encodeJSON(kept)
+		if err != nil {
+			return false
+		}
+		encoded = append(encoded, buffer)
+	}
+	return bytes.Equal(encoded[0].Bytes(), encoded[1].Bytes())
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+func TestSameDashboards(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		stored   map[string]interface{}
+		desired  map[string]interface{}
+		expected bool
+	}{
+		{"same", map[string]interface{}{"uid": "a", "title": "A", "id": float64(3), "version": float64(7)},
+			map[string]interface{}{"uid": "a", "title": "A", "id": nil}, true},
+
+		{"same tags", map[string]interface{}{"uid": "a", "tags": []interface{}{"slo"}},
+			map[string]interface{}{"uid": "a", "tags": []string{"slo"}}, true},
+
+		{"changed title", map[string]interface{}{"uid": "a", "title": "A"},
+			map[string]interface{}{"uid": "a", "title": "B"}, false},
+
+		{"added field", map[string]interface{}{"uid": "a"},
+			map[string]interface{}{"uid": "a", "editable": false}, false},
+	}
+
+	for _, c := range testCaseList {
+		if output := sameDashboards(c.stored, c.desired); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestSkipUnchangedDashboards(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func(skip bool) { SkipUnchangedDashboards = skip }(SkipUnchangedDashboards)
+	SkipUnchangedDashboards = true
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "unchanged", Namespace: "compare-test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+		Data: map[string]string{"a.json": `{"uid": "unchanged", "title": "Unchanged", "panels": []}`},
+	}
+
+	testCaseList := []struct {
+		name    string
+		prepare func()
+		skipped float64
+	}{
+		{"created", func() {}, 0},
+
+		{"unchanged", func() {}, 1},
+
+		{"changed in grafana", func() {
+			fake.dashboards[""]["unchanged"].dashboard["title"] = "Edited"
+		}, 0},
+
+		{"moved in grafana", func() {
+			d := fake.dashboards[""]["unchanged"]
+			d.folderID = 42
+			fake.dashboards[""]["unchanged"] = d
+		}, 0},
+	}
+
+	for _, c := range testCaseList {
+		c.prepare()
+		before := testutil.ToFloat64(metrics.DashboardsUnchanged)
+		if err := updateDashboard(context.TODO(), nil, cm, false); err != nil {
+			t.Errorf("case (%v) failed to update dashboard: %v", c.name, err)
+		}
+		if output := testutil.ToFloat64(metrics.DashboardsUnchanged) - before; output != c.skipped {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.skipped)
+		}
+		if title := fake.dashboards[""]["unchanged"].dashboard["title"]; title != "Unchanged" {
+			t.Errorf("case (%v) the stored dashboard title (%v) is not the expected: (Unchanged)", c.name, title)
+		}
+	}
+}
@@ -4,25 +4,35 @@
 package controller
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/klog"
+	"k8s.io/klog/v2"
 
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/features"
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/oci"
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/tracing"
 	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
 )
 
@@ -31,6 +41,17 @@ const (
 	customFolderKey     = "observability.open-cluster-management.io/dashboard-folder"
 	generalFolderKey    = "general-folder"
 	defaultCustomFolder = "Custom"
+	generalFolderTitle  = "general"
+	// dashboardOrgIDKey is the annotation of the grafana organization to load the dashboards into
+	dashboardOrgIDKey = "observability.open-cluster-management.io/dashboard-org-id"
+	// dashboardRetainKey is the annotation to keep the dashboards in grafana after the configmap is deleted
+	dashboardRetainKey = "observability.open-cluster-management.io/dashboard-retain"
+	// dashboardEditableKey is the annotation to decide whether the dashboards can be edited in grafana
+	dashboardEditableKey = "observability.open-cluster-management.io/dashboard-editable"
+	// dashboardUIDKeyPrefix followed by a data key is the annotation to pin the uid of the dashboard
+	dashboardUIDKeyPrefix = "dashboard-uid.observability.open-cluster-management.io/"
+	// dashboardFolderKeyPrefix followed by a data key is the annotation to put the dashboard into another folder
+	dashboardFolderKeyPrefix = "dashboard-folder.observability.open-cluster-management.io/"
 )
 
 // DashboardLoader ...
@@ -41,13 +62,23 @@ type DashboardLoader struct {
 
 var (
 	grafanaURI = "http://127.0.0.1:3001"
+	// grafanaReplicaURIs are the other urls of the default grafana to fail over to
+	grafanaReplicaURIs []string
 	//retry on errors
 	retry = 10
+	// ResyncPeriod is how often the informer re-delivers every watched configmap,
+	// unchanged configmaps are skipped by comparing them with the last applied hash
+	ResyncPeriod = 10 * time.Minute
+	// NonEditableDashboards makes all the dashboards non-editable in grafana unless the configmap says otherwise
+	NonEditableDashboards = false
+	// SyncTimeout limits the time to apply or delete the dashboards of a configmap including the retries
+	SyncTimeout = 5 * time.Minute
 )
 
-// RunGrafanaDashboardController ...
-func RunGrafanaDashboardController(stop <-chan struct{}) {
-	config, err := clientcmd.BuildConfigFromFlags("", "")
+// RunGrafanaDashboardController applies the dashboards until ctx is done, then the in-flight syncs are drained
+// before it returns
+func RunGrafanaDashboardController(ctx context.Context) {
+	config, err := kubeConfig()
 	if err != nil {
 		klog.Error("Failed to get cluster config", "error", err)
 	}
@@ -57,8 +88,170 @@ func RunGrafanaDashboardController(stop <-chan struct{}) {
 		klog.Fatal("Failed to build kubeclient", "error", err)
 	}
 
-	go newKubeInformer(kubeClient.CoreV1()).Run(stop)
+	eventRecorder = newEventRecorder(kubeClient)
+	appliedState.statusWriter = newStatusAnnotationWriter(kubeClient.CoreV1())
+
+	// no new event is handled once ctx is done, while the grafana requests of the in-flight syncs are only cancelled
+	// after the shutdown grace period
+	stop := ctx.Done()
+	workCtx, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+	sink, err := newAuditSink(AuditLogFile, AuditWebhookURL)
+	if err != nil {
+		klog.Fatal("Failed to set up the audit", "error", err)
+	}
+	var folderCache *folderCacheGrafanaClient
+	grafanaClient, folderCache = decorateGrafanaClient(grafanaClient, sink)
+	newTargetClient = func(settings targetSettings) (GrafanaClient, *folderCacheGrafanaClient) {
+		return decorateGrafanaClient(&httpGrafanaClient{url: settings.url, auth: settings.auth, cloud: settings.cloud,
+			replicas: settings.replicas}, sink)
+	}
+	// the cached folders and organizations are refreshed together with the resync of the dashboards
+	go wait.Until(func() {
+		folderCache.reset()
+		resetTargetFolders()
+		resetTenantOrgs()
+	}, ResyncPeriod, stop)
+
+	if ConfigFile != "" {
+		go watchConfig(ctx, ConfigFile, ConfigPollInterval)
+	}
+	if AdminAddr != "" {
+		go serveAdmin(AdminAddr)
+	}
+	if AdmissionAddr != "" {
+		go serveAdmission(AdmissionAddr)
+	}
+	if BackupDir != "" {
+		go runBackups(workCtx, BackupDir, BackupInterval)
+	}
+	var dynamicClient dynamic.Interface
+	if WatchGrafanaDashboards || ReportMCOStatus || ReportSyncStatus || WatchManagedClusters || TeamSync {
+		dynamicClient, err = dynamic.NewForConfig(config)
+		if err != nil {
+			klog.Fatal("Failed to build dynamic client", "error", err)
+		}
+	}
+	// the sidecar usually starts before grafana, the events are not processed until grafana is ready
+	// so that the retries are not used up by the startup
+	waitForGrafana(ctx, targetClients(), GrafanaReadyTimeout)
+	go runResetDetection(workCtx, GrafanaResetCheckInterval)
+	go runWatchHealth(ctx)
+	go runReplicaFailback(workCtx, GrafanaFailbackInterval)
+	go runFreezeWindows(workCtx, kubeClient.CoreV1())
+	// the work which was pending before the restart is retried together with the new failures
+	if RetryQueueConfigmap != "" {
+		if err := restoreRetryQueue(ctx, kubeClient.CoreV1(), os.Getenv("POD_NAMESPACE"), RetryQueueConfigmap); err != nil {
+			klog.ErrorS(err, "failed to restore the retry queue", "configmap", klog.KRef(os.Getenv("POD_NAMESPACE"), RetryQueueConfigmap))
+		}
+	}
+	go runRetryQueue(workCtx, kubeClient.CoreV1(), os.Getenv("POD_NAMESPACE"), RetryQueueInterval)
+	if Sharding {
+		// the namespaces of the replica are known before the first dashboards are applied
+		startSharding(ctx, kubeClient.CoordinationV1())
+	}
+	if NamespaceFolders || NamespaceSelector != "" {
+		// the folders and the labels of the namespaces are known before the first dashboards are applied
+		namespaceInformer := newNamespaceInformer(kubeClient.CoreV1())
+		go namespaceInformer.Run(stop)
+		cache.WaitForCacheSync(stop, namespaceInformer.HasSynced)
+	}
+	if WatchManagedClusters {
+		// the per cluster dashboards are applied for all the managed clusters from the start
+		managedClusterInformer := newManagedClusterInformer(dynamicClient)
+		go managedClusterInformer.Run(stop)
+		cache.WaitForCacheSync(stop, managedClusterInformer.HasSynced)
+	}
+	if GrafanaServiceSelector != "" {
+		go newGrafanaDiscoveryInformer(kubeClient.CoreV1()).Run(stop)
+	}
+	if MetadataInformer {
+		metadataClient, err := metadata.NewForConfig(config)
+		if err != nil {
+			klog.Fatal("Failed to build metadata client", "error", err)
+		}
+		go newMetadataInformer(workCtx, metadataClient, kubeClient.CoreV1()).Run(stop)
+	} else {
+		go newKubeInformer(workCtx, kubeClient.CoreV1()).Run(stop)
+	}
+	if WatchSecrets {
+		go newSecretInformer(workCtx, kubeClient.CoreV1()).Run(stop)
+	}
+	if WatchGrafanaDashboards {
+		gvrs := servedGrafanaDashboardResources(kubeClient.Discovery())
+		if len(gvrs) == 0 {
+			klog.Info("no GrafanaDashboard resource is installed in the cluster")
+		}
+		for _, gvr := range gvrs {
+			klog.Infof("watch GrafanaDashboard %v", gvr.GroupVersion())
+			go newGrafanaDashboardInformer(workCtx, dynamicClient, gvr).Run(stop)
+		}
+	}
+	if ReportMCOStatus {
+		go runMCOStatusReporter(workCtx, dynamicClient, MCOStatusInterval)
+	}
+	if ReportSyncStatus {
+		go newSyncReporter(dynamicClient).Run(workCtx, SyncReportInterval)
+	}
+	if TeamSync {
+		go runTeamSync(workCtx, kubeClient.RbacV1(), dynamicClient, TeamSyncInterval)
+	}
+	if StatusConfigmap != "" {
+		go runStatusConfigmapWriter(workCtx, kubeClient.CoreV1(), os.Getenv("POD_NAMESPACE"))
+	}
+	sources, err := newPolledSources()
+	if err != nil {
+		klog.Fatal("Failed to create dashboard source", "error", err)
+	}
+	for _, source := range sources {
+		go source.Run(workCtx)
+	}
 	<-stop
+	shutDown(cancelWork, kubeClient.CoreV1())
+}
+
+// decorateGrafanaClient wraps the client of a grafana instance with the circuit breaker,
+// the audit when the sink is not nil and the folder cache
+func decorateGrafanaClient(client GrafanaClient, sink *auditSink) (GrafanaClient, *folderCacheGrafanaClient) {
+	if BreakerFailureThreshold > 0 {
+		client = newBreakerGrafanaClient(client, BreakerFailureThreshold, BreakerCooldown)
+	}
+	if sink != nil {
+		client = &auditGrafanaClient{GrafanaClient: client, sink: sink}
+	}
+	folderCache := newFolderCacheGrafanaClient(client)
+	return folderCache, folderCache
+}
+
+// polledSource is a source which is checked for changes periodically instead of watched
+type polledSource interface {
+	// Run polls the source until ctx is done
+	Run(ctx context.Context)
+	// sync applies the changes of the source once
+	sync(ctx context.Context) error
+}
+
+// newPolledSources creates the configured directory, git and oci sources
+func newPolledSources() ([]polledSource, error) {
+	sources := []polledSource{}
+	if DashboardDir != "" {
+		sources = append(sources, newFilesystemSource(DashboardDir, DashboardDirPollInterval))
+	}
+	if GitRepository != "" {
+		gitSource, err := newGitSource(GitRepository, GitRef, GitPath, GitCheckoutDir, GitPollInterval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create git source: %v", err)
+		}
+		sources = append(sources, gitSource)
+	}
+	if OCIArtifact != "" {
+		ociSource, err := newOCISource(OCIArtifact, oci.NewClient(OCIInsecure, OCIUsername, OCIPassword), OCIPollInterval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create oci source: %v", err)
+		}
+		sources = append(sources, ociSource)
+	}
+	return sources, nil
 }
 
 func isDesiredDashboardConfigmap(obj interface{}) bool {
@@ -66,12 +259,20 @@ func isDesiredDashboardConfigmap(obj interface{}) bool {
 	if !ok || cm == nil {
 		return false
 	}
+	if !isAllowedNamespace(cm.GetNamespace()) {
+		klog.V(4).Infof("configmap %v is skipped since its namespace is not allowed", configmapKey(cm))
+		return false
+	}
 
 	labels := cm.ObjectMeta.Labels
 	if strings.ToLower(labels["grafana-custom-dashboard"]) == "true" {
 		return true
 	}
 
+	if isSidecarDashboard(cm) {
+		return true
+	}
+
 	owners := cm.GetOwnerReferences()
 	for _, owner := range owners {
 		if strings.Contains(cm.Name, "grafana-dashboard") && owner.Kind == "MultiClusterObservability" {
@@ -82,114 +283,172 @@ func isDesiredDashboardConfigmap(obj interface{}) bool {
 	return false
 }
 
-func newKubeInformer(coreClient corev1client.CoreV1Interface) cache.SharedIndexInformer {
+func newKubeInformer(ctx context.Context, coreClient corev1client.CoreV1Interface) cache.SharedIndexInformer {
 	// get watched namespace
 	watchedNS := os.Getenv("POD_NAMESPACE")
 	watchlist := &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
-			return coreClient.ConfigMaps(watchedNS).List(context.TODO(), metav1.ListOptions{})
+			return coreClient.ConfigMaps(watchedNS).List(context.TODO(), configmapListOptions(opts))
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
-			return coreClient.ConfigMaps(watchedNS).Watch(context.TODO(), metav1.ListOptions{})
+			return coreClient.ConfigMaps(watchedNS).Watch(context.TODO(), configmapListOptions(opts))
 		},
 	}
-	kubeInformer := cache.NewSharedIndexInformer(
-		watchlist,
+	kubeInformer := handleWatchErrors("configmaps", cache.NewSharedIndexInformer(
+		stripMetadata(tuneListWatch(watchlist)),
 		&corev1.ConfigMap{},
-		time.Second*0,
+		ResyncPeriod,
 		cache.Indexers{},
-	)
+	))
+
+	handler := newDashboardEventHandler(ctx, appliedState, configmapFromObject)
+	kubeInformer.AddEventHandler(handler)
+	registerInformerResync(kubeInformer, handler)
 
-	kubeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	return kubeInformer
+}
+
+// configmapFromObject unwraps the configmap of a tombstone, the configmap deleted while the watch
+// was disconnected is only known from the relist
+func configmapFromObject(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
+	}
+	return obj
+}
+
+// newDashboardEventHandler handles the events of a dashboard source,
+// toConfigmap converts the source object into a configmap so that all the sources share the same logic
+func newDashboardEventHandler(ctx context.Context, state *syncState, toConfigmap func(obj interface{}) interface{}) cache.ResourceEventHandler {
+	return debounceUpdates(UpdateDebounceWindow, parallelSyncs(ctx, SyncWorkers, cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
-			if !isDesiredDashboardConfigmap(obj) {
+			if !inFlightSyncs.start() {
+				return
+			}
+			defer inFlightSyncs.done()
+			defer recoverEventPanic(obj)
+			source := obj
+			obj = toConfigmap(obj)
+			if !isDesiredDashboardConfigmap(obj) || !ownsNamespace(obj.(*corev1.ConfigMap).GetNamespace()) {
 				return
 			}
 			klog.Infof("detect there is a new dashboard %v created", obj.(*corev1.ConfigMap).Name)
-			updateDashboard(nil, obj, false)
+			ctx, span := startEventSpan(ctx, "add", obj.(*corev1.ConfigMap))
+			defer span.End()
+			err := syncDashboard(ctx, state, nil, obj)
+			recordSpanError(span, err)
+			recordSyncEvent(source, err)
 		},
 		UpdateFunc: func(old, new interface{}) {
-			if !isDesiredDashboardConfigmap(new) {
+			if !inFlightSyncs.start() {
+				return
+			}
+			defer inFlightSyncs.done()
+			defer recoverEventPanic(new)
+			source := new
+			old, new = toConfigmap(old), toConfigmap(new)
+			if !isDesiredDashboardConfigmap(new) || !ownsNamespace(new.(*corev1.ConfigMap).GetNamespace()) {
+				return
+			}
+			if state.isSynced(new.(*corev1.ConfigMap)) {
+				klog.V(4).Infof("dashboard %v is unchanged since last sync, skip it", new.(*corev1.ConfigMap).Name)
 				return
 			}
 			klog.Infof("detect there is a dashboard %v updated", new.(*corev1.ConfigMap).Name)
-			updateDashboard(old, new, false)
+			ctx, span := startEventSpan(ctx, "update", new.(*corev1.ConfigMap))
+			defer span.End()
+			err := syncDashboard(ctx, state, old, new)
+			recordSpanError(span, err)
+			recordSyncEvent(source, err)
 		},
 		DeleteFunc: func(obj interface{}) {
-			if !isDesiredDashboardConfigmap(obj) {
+			if !inFlightSyncs.start() {
+				return
+			}
+			defer inFlightSyncs.done()
+			defer recoverEventPanic(obj)
+			source := obj
+			obj = toConfigmap(obj)
+			if !isDesiredDashboardConfigmap(obj) || !ownsNamespace(obj.(*corev1.ConfigMap).GetNamespace()) {
 				return
 			}
 			klog.Infof("detect there is a dashboard %v deleted", obj.(*corev1.ConfigMap).Name)
-			deleteDashboard(obj)
+			ctx, span := startEventSpan(ctx, "delete", obj.(*corev1.ConfigMap))
+			defer span.End()
+			if err := syncDeletion(ctx, state, obj.(*corev1.ConfigMap)); isFrozen(err) {
+				recordEvent(source, corev1.EventTypeNormal, reasonDashboardFrozen, "The dashboards are not deleted yet: %v", err)
+				return
+			}
+			recordDeleteEvent(source, obj)
 		},
-	})
+	}))
+}
 
-	return kubeInformer
+// startEventSpan starts the span which covers the handling of a configmap event
+func startEventSpan(ctx context.Context, event string, cm *corev1.ConfigMap) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "configmap "+event, trace.WithAttributes(
+		attribute.String("configmap.namespace", cm.GetNamespace()),
+		attribute.String("configmap.name", cm.GetName()),
+	))
 }
 
-func hasCustomFolder(folderTitle string) float64 {
-	grafanaURL := grafanaURI + "/api/folders"
-	body, _ := util.SetRequest("GET", grafanaURL, nil, retry)
+func recordSpanError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
 
-	folders := []map[string]interface{}{}
-	err := json.Unmarshal(body, &folders)
+func hasCustomFolder(ctx context.Context, orgID string, folderTitle string) float64 {
+	folders, err := clientFor(ctx).ListFolders(ctx, orgID)
 	if err != nil {
-		klog.Error(unmarshallErrMsg, "error", err)
+		klog.Error("failed to list folders", "error", err)
 		return 0
 	}
 
 	for _, folder := range folders {
-		if folder["title"] == folderTitle {
-			return folder["id"].(float64)
+		if folder.Title == folderTitle {
+			return folder.ID
 		}
 	}
 	return 0
 }
 
-func createCustomFolder(folderTitle string) float64 {
-	folderID := hasCustomFolder(folderTitle)
+// folderCreationLock keeps the parallel syncs from creating the same folder twice
+var folderCreationLock sync.Mutex
+
+func createCustomFolder(ctx context.Context, orgID string, folderTitle string) float64 {
+	folderCreationLock.Lock()
+	defer folderCreationLock.Unlock()
+	folderID := hasCustomFolder(ctx, orgID, folderTitle)
 	if folderID == 0 {
-		grafanaURL := grafanaURI + "/api/folders"
-		body, _ := util.SetRequest("POST", grafanaURL, strings.NewReader("{\"title\":\""+folderTitle+"\"}"), retry)
-		folder := map[string]interface{}{}
-		err := json.Unmarshal(body, &folder)
+		folder, err := clientFor(ctx).CreateFolder(ctx, orgID, folderTitle)
 		if err != nil {
-			klog.Error(unmarshallErrMsg, "error", err)
+			klog.Error("failed to create folder", "error", err)
 			return 0
 		}
-		return folder["id"].(float64)
+		return folder.ID
 	}
 	return folderID
 }
 
-func getCustomFolderUID(folderID float64) string {
-	grafanaURL := grafanaURI + "/api/folders/id/" + fmt.Sprint(folderID)
-	body, _ := util.SetRequest("GET", grafanaURL, nil, retry)
-	folder := map[string]interface{}{}
-	err := json.Unmarshal(body, &folder)
+func getCustomFolderUID(ctx context.Context, orgID string, folderID float64) string {
+	folder, err := clientFor(ctx).GetFolder(ctx, orgID, folderID)
 	if err != nil {
-		klog.Error(unmarshallErrMsg, "error", err)
+		klog.Error("failed to get folder", "error", err)
 		return ""
 	}
-	uid, ok := folder["uid"]
-	if ok {
-		return uid.(string)
-	}
-
-	return ""
+	return folder.UID
 }
 
-func isEmptyFolder(folderID float64) bool {
+func isEmptyFolder(ctx context.Context, orgID string, folderID float64) bool {
 	if folderID == 0 {
 		return false
 	}
 
-	grafanaURL := grafanaURI + "/api/search?folderIds=" + fmt.Sprint(folderID)
-	body, _ := util.SetRequest("GET", grafanaURL, nil, retry)
-	dashboards := []map[string]interface{}{}
-	err := json.Unmarshal(body, &dashboards)
+	dashboards, err := clientFor(ctx).SearchFolderDashboards(ctx, orgID, folderID)
 	if err != nil {
-		klog.Error(unmarshallErrMsg, "error", err)
+		klog.Error("failed to search folder", "error", err)
 		return false
 	}
 
@@ -201,21 +460,24 @@ func isEmptyFolder(folderID float64) bool {
 	return false
 }
 
-func deleteCustomFolder(folderID float64) bool {
+func deleteCustomFolder(ctx context.Context, orgID string, folderID float64) bool {
 	if folderID == 0 {
 		return false
 	}
 
-	uid := getCustomFolderUID(folderID)
+	uid := getCustomFolderUID(ctx, orgID, folderID)
 	if uid == "" {
 		klog.Error("Failed to get custom folder UID")
 		return false
 	}
+	if isProtectedFolder(ctx, orgID, folderID) {
+		klog.Infof("the protected folder %v is not deleted", folderID)
+		return false
+	}
 
-	grafanaURL := grafanaURI + "/api/folders/" + uid
-	_, respStatusCode := util.SetRequest("DELETE", grafanaURL, nil, retry)
-	if respStatusCode != http.StatusOK {
-		klog.Errorf("failed to delete custom folder %v with %v", folderID, respStatusCode)
+	err := clientFor(ctx).DeleteFolder(ctx, orgID, uid)
+	if err != nil {
+		klog.Errorf("failed to delete custom folder %v: %v", folderID, err)
 		return false
 	}
 
@@ -234,107 +496,508 @@ func getDashboardCustomFolderTitle(obj interface{}) string {
 		annotations := cm.ObjectMeta.Annotations
 		customFolder, ok := annotations[customFolderKey]
 		if !ok || customFolder == "" {
-			customFolder = defaultCustomFolder
+			customFolder = getSidecarFolderTitle(cm)
+		}
+		if customFolder == "" && isTenantConfigmap(cm) {
+			customFolder = TenantFolder
+		}
+		if customFolder == "" && NamespaceFolders && cm.GetNamespace() != "" {
+			customFolder = namespaceFolderTitle(cm.GetNamespace())
 		}
-		return customFolder
+		if customFolder == "" {
+			customFolder = activeSettings().defaultFolder
+		}
+		return renderFolderTitle(cm, customFolder)
 	}
 	return ""
 }
 
-// updateDashboard is used to update the customized dashboards via calling grafana api
-func updateDashboard(old, new interface{}, overwrite bool) {
-	folderID := 0.0
-	folderTitle := getDashboardCustomFolderTitle(new)
-	if folderTitle != "" {
-		folderID = createCustomFolder(folderTitle)
-		if folderID == 0 {
-			klog.Error("Failed to get custom folder id")
-			return
+// getDashboardFolderTitle returns the folder of the dashboard in the data key,
+// the folder annotation of the key overrides the folder of the configmap
+func getDashboardFolderTitle(obj interface{}, key string) string {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok || cm == nil {
+		return ""
+	}
+
+	folder := cm.GetAnnotations()[dashboardFolderKeyPrefix+key]
+	if folder == "" {
+		return getDashboardCustomFolderTitle(cm)
+	}
+	folder = renderFolderTitle(cm, folder)
+	if strings.ToLower(folder) == generalFolderTitle {
+		return ""
+	}
+	return folder
+}
+
+// getConfigmapFolderTitles returns all the custom folders which the dashboards of the configmap may land in
+func getConfigmapFolderTitles(obj interface{}) []string {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok || cm == nil {
+		return nil
+	}
+
+	titles := []string{}
+	seen := map[string]bool{"": true}
+	for key := range cm.GetAnnotations() {
+		if strings.HasPrefix(key, dashboardFolderKeyPrefix) {
+			title := getDashboardFolderTitle(cm, strings.TrimPrefix(key, dashboardFolderKeyPrefix))
+			if !seen[title] {
+				titles = append(titles, title)
+				seen[title] = true
+			}
+		}
+	}
+	if title := getDashboardCustomFolderTitle(cm); !seen[title] {
+		titles = append(titles, title)
+	}
+	return titles
+}
+
+// isRetainedDashboard checks whether the dashboards should be left in grafana when the configmap is deleted
+func isRetainedDashboard(obj interface{}) bool {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok || cm == nil {
+		return false
+	}
+	return strings.ToLower(cm.GetAnnotations()[dashboardRetainKey]) == "true"
+}
+
+// isEditableDashboard checks whether the dashboards can be edited in grafana,
+// the editable annotation of the configmap overrides the global option
+func isEditableDashboard(obj interface{}) bool {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok || cm == nil {
+		return !activeSettings().nonEditableDashboards
+	}
+	switch strings.ToLower(cm.GetAnnotations()[dashboardEditableKey]) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return !activeSettings().nonEditableDashboards
+}
+
+// getDashboardOrgID returns the grafana organization of the configmap, empty means the default one
+func getDashboardOrgID(obj interface{}) (string, error) {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok || cm == nil {
+		return "", nil
+	}
+
+	orgID := strings.TrimSpace(cm.GetAnnotations()[dashboardOrgIDKey])
+	if orgID == "" {
+		return "", nil
+	}
+	id, err := strconv.Atoi(orgID)
+	if err != nil || id <= 0 {
+		return "", fmt.Errorf("invalid grafana organization id %q", orgID)
+	}
+	return orgID, nil
+}
+
+// getDashboardUID returns the uid of the dashboard in the data key,
+// the uid annotation of the key overrides the uid chosen by the uid strategy of the configmap,
+// and the uid of a per cluster dashboard is made unique for its managed cluster
+func getDashboardUID(cm *corev1.ConfigMap, key string, dashboard map[string]interface{}) (string, error) {
+	uid := cm.GetAnnotations()[dashboardUIDKeyPrefix+key]
+	if uid != "" {
+		if err := util.ValidateUID(uid); err != nil {
+			return "", fmt.Errorf("invalid annotation %v: %v", dashboardUIDKeyPrefix+key, err)
+		}
+	} else {
+		strategy, err := configmapUIDStrategy(cm)
+		if err != nil {
+			return "", err
+		}
+		if uid, err = strategyUID(strategy, cm, key, dashboard); err != nil {
+			return "", err
 		}
 	}
+	if cluster := managedClusterOf(cm); cluster != "" {
+		return clusterDashboardUID(uid, cluster), nil
+	}
+	return uid, nil
+}
+
+// syncDashboard applies the dashboards and records the configmap hash once all of them succeeded,
+// a failed configmap is applied again on the next resync
+func syncDashboard(ctx context.Context, state *syncState, old, new interface{}) error {
+	metrics.SyncsPending.Inc()
+	defer metrics.SyncsPending.Dec()
+	ctx, cancel := context.WithTimeout(ctx, activeSettings().syncTimeout)
+	defer cancel()
+	if status, changed := state.markProgressing(new.(*corev1.ConfigMap)); changed && state.statusWriter != nil {
+		state.statusWriter(ctx, new.(*corev1.ConfigMap), status)
+	}
+	ctx, applied := withAppliedDashboards(ctx)
+	previous := previousUIDs(state, new.(*corev1.ConfigMap))
+	ctx = withPreviousUIDs(ctx, previous)
+	err := checkFreezeWindows(time.Now())
+	if err == nil {
+		err = dependencies.check(state, new.(*corev1.ConfigMap))
+	}
+	if err == nil {
+		err = updateTargets(ctx, state, old, new)
+	}
+	if err != nil {
+		keepPreviousUIDs(applied, previous)
+	}
+	var status dashboardStatus
+	switch {
+	case isFrozen(err) || isWaitingForDependencies(err):
+		// the configmap is applied once the freeze window ends or its dependencies are synced
+		klog.InfoS("the sync is postponed", "configmap", klog.KObj(new.(*corev1.ConfigMap)), "reason", err)
+		status = state.markFailed(new.(*corev1.ConfigMap), applied, err)
+	case err != nil:
+		klog.ErrorS(err, "failed to sync dashboard", "configmap", klog.KObj(new.(*corev1.ConfigMap)))
+		status = state.markFailed(new.(*corev1.ConfigMap), applied, err)
+		notifyWebhooks(webhookEventApplyFailed, new.(*corev1.ConfigMap), "", "", err.Error())
+		pendingWork.add(workApply, state.source, new.(*corev1.ConfigMap), status.UIDs, err)
+	default:
+		status = state.markSynced(new.(*corev1.ConfigMap), applied)
+		pendingWork.done(state.source, new.(*corev1.ConfigMap))
+		dependencies.synced(state, new.(*corev1.ConfigMap))
+	}
+	if state.statusWriter != nil {
+		state.statusWriter(ctx, new.(*corev1.ConfigMap), status)
+	}
+	return err
+}
+
+// updateDashboard is used to update the customized dashboards via calling grafana api
+func updateDashboard(ctx context.Context, old, new interface{}, overwrite bool) (err error) {
+	defer recoverSyncPanic(panicOpApply, new, &err)
+	if isPerClusterConfigmap(new) {
+		return updateManagedClusters(ctx, old, new.(*corev1.ConfigMap), overwrite)
+	}
+	ctx = withAuditSource(ctx, new)
+	orgID, err := dashboardOrgID(ctx, new)
+	if err != nil {
+		return err
+	}
 
-	for _, value := range new.(*corev1.ConfigMap).Data {
+	folderIDs := map[string]float64{}
+	claimed := map[string]string{}
+	dashboards, syncErr := dashboardData(ctx, new.(*corev1.ConfigMap))
+	for key, value := range dashboards {
+
+		folderTitle := getDashboardFolderTitle(new, key)
+		if err := checkFolderWritable(ctx, orgID, folderTitle, 0); err != nil {
+			klog.ErrorS(err, "the dashboard is not saved", "configmap", klog.KObj(new.(*corev1.ConfigMap)), "key", key)
+			syncErr = fmt.Errorf("%v: %v", key, err)
+			continue
+		}
+		folderID, ok := folderIDs[folderTitle]
+		if !ok && folderTitle != "" {
+			folderCtx, span := tracing.Start(ctx, "resolve folder", trace.WithAttributes(
+				attribute.String("grafana.org", orgID), attribute.String("grafana.folder", folderTitle)))
+			folderID = createCustomFolder(folderCtx, orgID, folderTitle)
+			span.End()
+			if folderID == 0 {
+				klog.Error("Failed to get custom folder id")
+				syncErr = fmt.Errorf("failed to get custom folder %v", folderTitle)
+				continue
+			}
+			// the folder may be protected by its uid
+			if err := checkFolderWritable(ctx, orgID, folderTitle, folderID); err != nil {
+				klog.ErrorS(err, "the dashboard is not saved", "configmap", klog.KObj(new.(*corev1.ConfigMap)), "key", key)
+				syncErr = fmt.Errorf("%v: %v", key, err)
+				continue
+			}
+		}
+		folderIDs[folderTitle] = folderID
 
 		dashboard := map[string]interface{}{}
-		err := json.Unmarshal([]byte(value), &dashboard)
+		err := json.Unmarshal([]byte(substituteManagedCluster(new.(*corev1.ConfigMap), value)), &dashboard)
 		if err != nil {
-			klog.Error("Failed to unmarshall data", "error", err)
-			return
+			klog.ErrorS(err, "the dashboard is not saved", "configmap", klog.KObj(new.(*corev1.ConfigMap)), "key", key)
+			syncErr = fmt.Errorf("%v: %v", key, err)
+			continue
 		}
-		if dashboard["uid"] == nil {
-			dashboard["uid"], _ = util.GenerateUID(new.(*corev1.ConfigMap).GetName(),
-				new.(*corev1.ConfigMap).GetNamespace())
+		removeSharedDashboardKeys(dashboard)
+		setClusterVariable(new.(*corev1.ConfigMap), dashboard)
+		if MigrateDashboards {
+			if report := migrateDashboard(dashboard); len(report) > 0 {
+				klog.InfoS("the legacy dashboard is migrated", "configmap", klog.KObj(new.(*corev1.ConfigMap)), "key", key, "changes", report)
+				recordEvent(new, corev1.EventTypeNormal, reasonDashboardMigrated, "The dashboard %v is migrated: %v", key, strings.Join(report, "; "))
+			}
 		}
-		dashboard["id"] = nil
-		data := map[string]interface{}{
-			"folderId":  folderID,
-			"overwrite": overwrite,
-			"dashboard": dashboard,
+		if err := checkPolicy(new.(*corev1.ConfigMap), key, dashboard); err != nil {
+			klog.ErrorS(err, "the dashboard is not saved", "configmap", klog.KObj(new.(*corev1.ConfigMap)), "key", key)
+			syncErr = fmt.Errorf("%v: %v", key, err)
+			continue
 		}
-
-		b, err := json.Marshal(data)
+		if features.Enabled(features.DashboardSchemaValidation) {
+			if err := validateDashboardSchema(dashboard); err != nil {
+				klog.ErrorS(err, "the dashboard is not saved", "configmap", klog.KObj(new.(*corev1.ConfigMap)), "key", key)
+				syncErr = fmt.Errorf("%v: %v", key, err)
+				continue
+			}
+		}
+		if ValidatePromQL {
+			if err := validatePromQL(dashboard); err != nil {
+				klog.ErrorS(err, "the dashboard is not saved", "configmap", klog.KObj(new.(*corev1.ConfigMap)), "key", key)
+				syncErr = fmt.Errorf("%v: %v", key, err)
+				continue
+			}
+		}
+		if err := checkAngularPanels(new.(*corev1.ConfigMap), key, dashboard); err != nil {
+			klog.ErrorS(err, "the dashboard is not saved", "configmap", klog.KObj(new.(*corev1.ConfigMap)), "key", key)
+			syncErr = fmt.Errorf("%v: %v", key, err)
+			continue
+		}
+		if findings := lintDashboard(dashboard); len(findings) > 0 {
+			recordLintFindings(ctx, key, findings)
+			recordLintEvent(new, key, findings)
+			if LintBlockOnError && hasLintErrors(findings) {
+				klog.InfoS("the dashboard is not saved because of the lint errors", "configmap", klog.KObj(new.(*corev1.ConfigMap)), "key", key)
+				syncErr = fmt.Errorf("%v: the dashboard has lint errors", key)
+				continue
+			}
+		}
+		if changes := applyGuardrails(dashboard); len(changes) > 0 {
+			klog.InfoS("the dashboard is changed by the guardrails", "configmap", klog.KObj(new.(*corev1.ConfigMap)), "key", key, "changes", changes)
+			recordEvent(new, corev1.EventTypeNormal, reasonDashboardGuardrails, "The dashboard %v is changed by the guardrails: %v", key, strings.Join(changes, "; "))
+		}
+		uid, err := getDashboardUID(new.(*corev1.ConfigMap), key, dashboard)
 		if err != nil {
-			klog.Error("failed to marshal body", "error", err)
-			return
+			klog.ErrorS(err, "the dashboard is not saved", "configmap", klog.KObj(new.(*corev1.ConfigMap)), "key", key)
+			syncErr = fmt.Errorf("%v: %v", key, err)
+			continue
 		}
-
-		grafanaURL := grafanaURI + "/api/dashboards/db"
-		body, respStatusCode := util.SetRequest("POST", grafanaURL, bytes.NewBuffer(b), retry)
-
-		if respStatusCode != http.StatusOK {
-			if respStatusCode == http.StatusPreconditionFailed {
-				if strings.Contains(string(body), "version-mismatch") {
-					updateDashboard(nil, new, true)
-				} else if strings.Contains(string(body), "name-exists") {
-					klog.Info("the dashboard name already existed")
+		if err := claimDashboardUID(ctx, orgID, uid, new.(*corev1.ConfigMap), key); err != nil {
+			klog.ErrorS(err, "the dashboard is not saved", "configmap", klog.KObj(new.(*corev1.ConfigMap)), "key", key)
+			syncErr = fmt.Errorf("%v: %v", key, err)
+			continue
+		}
+		claimed[key] = uid
+		if spec, ok := new.(*corev1.ConfigMap).GetAnnotations()[dashboardRollbackKeyPrefix+key]; ok {
+			if err := holdRolledBackDashboard(ctx, orgID, new.(*corev1.ConfigMap), key, uid, folderTitle, spec); err != nil {
+				klog.ErrorS(err, "failed to roll back the dashboard", "configmap", klog.KObj(new.(*corev1.ConfigMap)), "key", key)
+				syncErr = fmt.Errorf("%v: %v", key, err)
+			}
+			continue
+		}
+		formers := formerDashboardUIDs(new.(*corev1.ConfigMap), key, dashboard, uid)
+		dashboard["uid"] = uid
+		dashboard["id"] = nil
+		mergeDashboardTags(dashboard, getConfigmapTags(new.(*corev1.ConfigMap)))
+		if ProvisionedByTag {
+			setProvisionedByTag(dashboard)
+		}
+		if OwnershipTags {
+			setOwnershipTags(dashboard, new.(*corev1.ConfigMap), key)
+		}
+		if !isEditableDashboard(new) {
+			dashboard["editable"] = false
+		}
+		if err := checkDashboardSize(dashboard); err != nil {
+			klog.ErrorS(err, "the dashboard is not saved", "configmap", klog.KObj(new.(*corev1.ConfigMap)), "key", key)
+			syncErr = fmt.Errorf("%v: %v", key, err)
+			continue
+		}
+		if LogDashboardDiff {
+			logDashboardDiff(ctx, new.(*corev1.ConfigMap), key, orgID, dashboard)
+		}
+		saved, unchanged := unchangedDashboard(ctx, new.(*corev1.ConfigMap), key, orgID, dashboard, folderID)
+		migrated := ""
+		if !unchanged {
+			migrated = migrateFormerUID(ctx, orgID, new.(*corev1.ConfigMap), key, dashboard, formers)
+			saveCtx, span := tracing.Start(ctx, "save dashboard", trace.WithAttributes(
+				attribute.String("grafana.org", orgID), attribute.String("configmap.key", key),
+				attribute.String("grafana.dashboard.uid", dashboard["uid"].(string))))
+			// the migrated dashboard is updated in place whatever its version is
+			saved, err = clientFor(ctx).SaveDashboard(saveCtx, orgID, dashboard, folderID, overwrite || migrated != "")
+			if err != nil {
+				saved, err = adoptDashboard(saveCtx, orgID, new.(*corev1.ConfigMap), key, dashboard, folderID, err)
+			}
+			recordSpanError(span, err)
+			span.End()
+		}
+		if err != nil {
+			apiErr, ok := err.(*GrafanaAPIError)
+			if ok && apiErr.StatusCode == http.StatusPreconditionFailed {
+				if strings.Contains(string(apiErr.Body), "version-mismatch") {
+					notifyWebhooks(webhookEventDrift, new.(*corev1.ConfigMap), key, dashboard["uid"].(string),
+						"the dashboard was changed in grafana, it is overwritten by the configmap")
+					if err := updateDashboard(ctx, nil, new, true); err != nil {
+						syncErr = err
+					}
+				} else if strings.Contains(string(apiErr.Body), "name-exists") {
+					klog.InfoS("the dashboard name already existed", "configmap", klog.KObj(new.(*corev1.ConfigMap)),
+						"key", key, "uid", dashboard["uid"], "status", apiErr.StatusCode)
+					syncErr = fmt.Errorf("the dashboard name already existed")
 				} else {
-					klog.Infof("failed to create/update: %v", respStatusCode)
+					klog.InfoS("failed to create/update dashboard", "configmap", klog.KObj(new.(*corev1.ConfigMap)),
+						"key", key, "uid", dashboard["uid"], "status", apiErr.StatusCode)
+					syncErr = fmt.Errorf("failed to create/update: %v", apiErr.StatusCode)
 				}
+			} else if ok && apiErr.StatusCode == http.StatusRequestEntityTooLarge {
+				err := oversizedDashboardError(0)
+				klog.ErrorS(err, "failed to create/update dashboard", "configmap", klog.KObj(new.(*corev1.ConfigMap)),
+					"key", key, "uid", dashboard["uid"])
+				syncErr = fmt.Errorf("%v: %v", key, err)
 			} else {
-				klog.Infof("failed to create/update: %v", respStatusCode)
+				klog.InfoS("failed to create/update dashboard", "configmap", klog.KObj(new.(*corev1.ConfigMap)),
+					"key", key, "uid", dashboard["uid"], "err", err)
+				syncErr = fmt.Errorf("failed to create/update: %v", err)
 			}
 		} else {
-			klog.Info("Dashboard created/updated")
+			if unchanged {
+				klog.V(2).InfoS("dashboard is unchanged in grafana, it is not saved", "configmap", klog.KObj(new.(*corev1.ConfigMap)),
+					"key", key, "uid", saved.UID, "version", saved.Version, "folder", folderTitle, "org", orgID)
+			} else {
+				klog.InfoS("dashboard created/updated", "configmap", klog.KObj(new.(*corev1.ConfigMap)),
+					"key", key, "uid", saved.UID, "version", saved.Version, "folder", folderTitle, "org", orgID)
+			}
+			if migrated != "" {
+				recordMigratedUID(orgID, new.(*corev1.ConfigMap), key, migrated, saved.UID)
+			}
+			recordManagedDashboard(orgID, saved.UID, folderTitle)
+			recordStockDashboard(orgID, saved.UID, new.(*corev1.ConfigMap))
+			recordAppliedDashboard(ctx, key, saved.UID, folderTitle)
+			recordAppliedVersion(ctx, orgID, key, saved.UID, saved.Version)
+			if err := syncPublicDashboard(ctx, orgID, saved.UID, new.(*corev1.ConfigMap)); err != nil {
+				klog.Error("failed to sync public dashboard ", "error ", err)
+				syncErr = err
+			}
+			if isHomeDashboard(new.(*corev1.ConfigMap), key, len(dashboards)) {
+				if err := setHomeDashboard(ctx, orgID, saved); err != nil {
+					klog.Error("failed to set home dashboard ", "error ", err)
+					syncErr = err
+				}
+			} else if wasHomeDashboard(old, key) {
+				// the annotation is removed or moved to another dashboard
+				if err := unsetHomeDashboard(ctx, orgID, saved.UID); err != nil {
+					klog.Error("failed to unset home dashboard ", "error ", err)
+					syncErr = err
+				}
+			}
 		}
 	}
+	if syncErr == nil {
+		syncErr = pruneRenamedDashboards(ctx, orgID, new.(*corev1.ConfigMap), claimed)
+	}
+	releaseDashboardUIDs(ctx, orgID, new.(*corev1.ConfigMap), claimed)
 
-	folderTitle = getDashboardCustomFolderTitle(old)
-	folderID = hasCustomFolder(folderTitle)
-	if isEmptyFolder(folderID) {
-		deleteCustomFolder(folderID)
+	// the folders of the old configmap are in its own org
+	oldOrgID, err := dashboardOrgID(ctx, old)
+	if err != nil {
+		return syncErr
+	}
+	if oldCM, ok := old.(*corev1.ConfigMap); ok && oldCM != nil && oldOrgID != orgID {
+		klog.Infof("dashboard %v is moved from org %q to org %q", oldCM.Name, oldOrgID, orgID)
+		deleteDashboard(ctx, old)
+		return syncErr
 	}
+	for _, folderTitle := range getConfigmapFolderTitles(old) {
+		folderID := hasCustomFolder(ctx, oldOrgID, folderTitle)
+		if features.Enabled(features.EmptyFolderCleanup) && isEmptyFolder(ctx, oldOrgID, folderID) {
+			deleteCustomFolder(ctx, oldOrgID, folderID)
+		}
+	}
+	return syncErr
 }
 
 // DeleteDashboard ...
-func deleteDashboard(obj interface{}) {
-	for _, value := range obj.(*corev1.ConfigMap).Data {
+func deleteDashboard(ctx context.Context, obj interface{}) (err error) {
+	defer recoverSyncPanic(panicOpDelete, obj, &err)
+	ctx = withAuditSource(ctx, obj)
+	ctx, cancel := context.WithTimeout(ctx, activeSettings().syncTimeout)
+	defer cancel()
+	if isRetainedDashboard(obj) {
+		klog.Infof("dashboard %v is retained in grafana since it has annotation %v",
+			obj.(*corev1.ConfigMap).Name, dashboardRetainKey)
+		metrics.DashboardsRetained.Inc()
+		return nil
+	}
+	if isPerClusterConfigmap(obj) {
+		return deleteManagedClusters(ctx, obj.(*corev1.ConfigMap))
+	}
+
+	orgID, err := dashboardOrgID(ctx, obj)
+	if err != nil {
+		klog.Errorf("failed to delete dashboard %v: %v", obj.(*corev1.ConfigMap).Name, err)
+		return err
+	}
+
+	// the dashboards are deleted by the uids applied by the last sync, only the content of the other keys is resolved,
+	// so a reference which cannot be fetched any more does not orphan its dashboard
+	var deleteErr error
+	uids := map[string]string{}
+	recorded, _ := ctx.Value(previousUIDsKey{}).(map[string]string)
+	if managedClusterOf(obj.(*corev1.ConfigMap)) != "" {
+		// the uids of the per cluster configmaps are not tracked by cluster
+		recorded = nil
+	}
+	for key, uid := range recorded {
+		uids[key] = uid
+	}
+	dashboards, err := getDashboardData(ctx, withoutDataKeys(obj.(*corev1.ConfigMap), recorded))
+	if err != nil {
+		deleteErr = err
+	}
+	for key, value := range dashboards {
 
 		dashboard := map[string]interface{}{}
-		err := json.Unmarshal([]byte(value), &dashboard)
+		err := json.Unmarshal([]byte(substituteManagedCluster(obj.(*corev1.ConfigMap), value)), &dashboard)
 		if err != nil {
 			klog.Error("Failed to unmarshall data", "error", err)
-			return
+			if deleteErr == nil {
+				deleteErr = fmt.Errorf("%v: %v", key, err)
+			}
+			continue
 		}
 
-		uid, _ := util.GenerateUID(obj.(*corev1.ConfigMap).Name, obj.(*corev1.ConfigMap).Namespace)
-		if dashboard["uid"] != nil {
-			uid = dashboard["uid"].(string)
+		// the dashboard without uid was never saved
+		uid, err := getDashboardUID(obj.(*corev1.ConfigMap), key, dashboard)
+		if err != nil {
+			klog.ErrorS(err, "the dashboard is not deleted", "configmap", klog.KObj(obj.(*corev1.ConfigMap)), "key", key)
+			continue
+		}
+		uids[key] = uid
+	}
+	for key, uid := range uids {
+		if err := checkDashboardUIDOwner(ctx, orgID, uid, obj.(*corev1.ConfigMap), key); err != nil {
+			klog.InfoS("the dashboard is not deleted", "configmap", klog.KObj(obj.(*corev1.ConfigMap)), "key", key, "reason", err)
+			continue
+		}
+		if isProtectedDashboard(ctx, orgID, uid, getDashboardFolderTitle(obj, key)) {
+			klog.InfoS("the protected dashboard is not deleted", "configmap", klog.KObj(obj.(*corev1.ConfigMap)), "key", key, "uid", uid)
+			continue
 		}
 
-		grafanaURL := grafanaURI + "/api/dashboards/uid/" + uid
-
-		_, respStatusCode := util.SetRequest("DELETE", grafanaURL, nil, retry)
-		if respStatusCode != http.StatusOK {
-			klog.Errorf("failed to delete dashboard %v with %v", obj.(*corev1.ConfigMap).Name, respStatusCode)
+		err = clientFor(ctx).DeleteDashboard(ctx, orgID, uid)
+		if err != nil {
+			klog.ErrorS(err, "failed to delete dashboard", "configmap", klog.KObj(obj.(*corev1.ConfigMap)),
+				"key", key, "uid", uid, "status", grafanaStatus(err))
+			// the dashboards which are gone already do not need to be deleted again
+			if !isGrafanaNotFound(err) && deleteErr == nil {
+				deleteErr = fmt.Errorf("%v: %v", key, err)
+			}
 		} else {
-			klog.Info("Dashboard deleted")
+			klog.InfoS("dashboard deleted", "configmap", klog.KObj(obj.(*corev1.ConfigMap)), "key", key, "uid", uid, "org", orgID)
+			forgetManagedDashboard(orgID, uid)
+			if hasHomeDashboardAnnotation(obj) {
+				if err := unsetHomeDashboard(ctx, orgID, uid); err != nil {
+					klog.Error("failed to unset home dashboard ", "error ", err)
+					if deleteErr == nil {
+						deleteErr = fmt.Errorf("%v: %v", key, err)
+					}
+				}
+			}
+			notifyWebhooks(webhookEventDeleted, obj.(*corev1.ConfigMap), key, uid, "the dashboard is deleted from grafana")
 		}
 
-		folderTitle := getDashboardCustomFolderTitle(obj)
-		folderID := hasCustomFolder(folderTitle)
-		if isEmptyFolder(folderID) {
-			deleteCustomFolder(folderID)
+		folderTitle := getDashboardFolderTitle(obj, key)
+		folderID := hasCustomFolder(ctx, orgID, folderTitle)
+		if features.Enabled(features.EmptyFolderCleanup) && isEmptyFolder(ctx, orgID, folderID) {
+			deleteCustomFolder(ctx, orgID, folderID)
 		}
 	}
-	return
+	releaseDashboardUIDs(ctx, orgID, obj.(*corev1.ConfigMap), nil)
+	return deleteErr
 }
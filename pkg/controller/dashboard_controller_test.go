@@ -6,7 +6,9 @@ package controller
 import (
 	"context"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
@@ -15,6 +17,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
 )
 
 var (
@@ -84,10 +87,12 @@ func createFakeServer(t *testing.T) {
 		},
 	)
 
-	err := http.ListenAndServe(":3001", server3001)
+	// the server listens before it returns so the failure is reported by the test goroutine
+	listener, err := net.Listen("tcp", ":3001")
 	if err != nil {
 		t.Fatal("fail to create internal server at 3001")
 	}
+	go http.Serve(listener, server3001)
 }
 
 func TestGrafanaDashboardController(t *testing.T) {
@@ -95,13 +100,18 @@ func TestGrafanaDashboardController(t *testing.T) {
 	coreClient := fake.NewSimpleClientset().CoreV1()
 	stop := make(chan struct{})
 
-	go createFakeServer(t)
+	createFakeServer(t)
 	retry = 1
 
 	os.Setenv("POD_NAMESPACE", "ns2")
 
-	informer := newKubeInformer(coreClient)
-	go informer.Run(stop)
+	informer := newKubeInformer(context.TODO(), coreClient)
+	// the handlers of the informer are done once it returns
+	stopped := make(chan struct{})
+	go func() {
+		informer.Run(stop)
+		close(stopped)
+	}()
 
 	cm, err := createDashboard()
 	if err == nil {
@@ -111,7 +121,7 @@ func TestGrafanaDashboardController(t *testing.T) {
 		}
 		// wait for 2 second to trigger AddFunc of informer
 		time.Sleep(time.Second * 2)
-		updateDashboard(nil, cm, false)
+		updateDashboard(context.TODO(), nil, cm, false)
 
 		cm.Data = map[string]string{}
 		_, err = coreClient.ConfigMaps("ns2").Update(context.TODO(), cm, metav1.UpdateOptions{})
@@ -120,7 +130,7 @@ func TestGrafanaDashboardController(t *testing.T) {
 		}
 		// wait for 2 second to trigger UpdateFunc of informer
 		time.Sleep(time.Second * 2)
-		updateDashboard(nil, cm, false)
+		updateDashboard(context.TODO(), nil, cm, false)
 
 		cm, _ := createDashboard()
 		_, err = coreClient.ConfigMaps("ns2").Update(context.TODO(), cm, metav1.UpdateOptions{})
@@ -130,16 +140,16 @@ func TestGrafanaDashboardController(t *testing.T) {
 
 		// wait for 2 second to trigger UpdateFunc of informer
 		time.Sleep(time.Second * 2)
-		updateDashboard(nil, cm, false)
+		updateDashboard(context.TODO(), nil, cm, false)
 
 		coreClient.ConfigMaps("ns2").Delete(context.TODO(), cm.GetName(), metav1.DeleteOptions{})
 		time.Sleep(time.Second * 2)
-		deleteDashboard(cm)
+		deleteDashboard(context.TODO(), cm)
 
 	}
 
 	close(stop)
-	<-stop
+	<-stopped
 }
 
 func TestIsDesiredDashboardConfigmap(t *testing.T) {
@@ -233,7 +243,7 @@ func TestIsDesiredDashboardConfigmap(t *testing.T) {
 
 func TestGetCustomFolderUID(t *testing.T) {
 	if !hasFakeServer {
-		go createFakeServer(t)
+		createFakeServer(t)
 		retry = 1
 	}
 
@@ -260,7 +270,7 @@ func TestGetCustomFolderUID(t *testing.T) {
 		},
 	}
 	for _, c := range testCaseList {
-		output := getCustomFolderUID(c.id)
+		output := getCustomFolderUID(context.TODO(), "", c.id)
 		if output != c.expected {
 			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
 		}
@@ -269,7 +279,7 @@ func TestGetCustomFolderUID(t *testing.T) {
 
 func TestIsEmptyFolder(t *testing.T) {
 	if !hasFakeServer {
-		go createFakeServer(t)
+		createFakeServer(t)
 		retry = 1
 	}
 
@@ -293,7 +303,7 @@ func TestIsEmptyFolder(t *testing.T) {
 	}
 
 	for _, c := range testCaseList {
-		output := isEmptyFolder(c.folderID)
+		output := isEmptyFolder(context.TODO(), "", c.folderID)
 		if output != c.expected {
 			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
 		}
@@ -347,7 +357,7 @@ func TestGetDashboardCustomFolderTitle(t *testing.T) {
 
 func TestDeleteCustomFolder(t *testing.T) {
 	if !hasFakeServer {
-		go createFakeServer(t)
+		createFakeServer(t)
 		retry = 1
 	}
 
@@ -383,9 +393,359 @@ func TestDeleteCustomFolder(t *testing.T) {
 	}
 
 	for _, c := range testCaseList {
-		output := deleteCustomFolder(c.folderID)
+		output := deleteCustomFolder(context.TODO(), "", c.folderID)
 		if output != c.expected {
 			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
 		}
 	}
 }
+
+func TestGetDashboardUID(t *testing.T) {
+	testCaseList := []struct {
+		name        string
+		annotations map[string]string
+		dashboard   map[string]interface{}
+		expected    string
+	}{
+
+		{
+			"generated uid",
+			nil,
+			map[string]interface{}{},
+			"test-test",
+		},
+
+		{
+			"embedded uid",
+			nil,
+			map[string]interface{}{"uid": "embedded"},
+			"embedded",
+		},
+
+		{
+			"pinned uid",
+			map[string]string{dashboardUIDKeyPrefix + "test.json": "pinned"},
+			map[string]interface{}{"uid": "embedded"},
+			"pinned",
+		},
+
+		{
+			"uid pinned for another key",
+			map[string]string{dashboardUIDKeyPrefix + "other.json": "pinned"},
+			map[string]interface{}{},
+			"test-test",
+		},
+	}
+
+	for _, c := range testCaseList {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Namespace:   "test",
+				Annotations: c.annotations,
+			},
+		}
+		output, _ := getDashboardUID(cm, "test.json", c.dashboard)
+		if output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestGetDashboardFolderTitle(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+			Annotations: map[string]string{
+				customFolderKey:                        "Network",
+				dashboardFolderKeyPrefix + "slo.json":  "SLOs",
+				dashboardFolderKeyPrefix + "home.json": "General",
+			},
+		},
+	}
+
+	testCaseList := []struct {
+		name     string
+		key      string
+		expected string
+	}{
+
+		{
+			"configmap folder",
+			"network.json",
+			"Network",
+		},
+
+		{
+			"key folder",
+			"slo.json",
+			"SLOs",
+		},
+
+		{
+			"key general folder",
+			"home.json",
+			"",
+		},
+	}
+
+	for _, c := range testCaseList {
+		output := getDashboardFolderTitle(cm, c.key)
+		if output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+
+	titles := getConfigmapFolderTitles(cm)
+	if len(titles) != 2 || titles[len(titles)-1] != "Network" {
+		t.Errorf("the folders %v are not the expected [SLOs Network]", titles)
+	}
+	if getConfigmapFolderTitles(nil) != nil {
+		t.Errorf("invalid configmap should have no folders")
+	}
+}
+
+func TestGetDashboardOrgID(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		orgID    string
+		expected string
+		hasErr   bool
+	}{
+
+		{
+			"default org",
+			"",
+			"",
+			false,
+		},
+
+		{
+			"valid org",
+			" 3 ",
+			"3",
+			false,
+		},
+
+		{
+			"invalid org",
+			"main",
+			"",
+			true,
+		},
+	}
+
+	for _, c := range testCaseList {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Namespace:   "test",
+				Annotations: map[string]string{dashboardOrgIDKey: c.orgID},
+			},
+		}
+		output, err := getDashboardOrgID(cm)
+		if output != c.expected || (err != nil) != c.hasErr {
+			t.Errorf("case (%v) output: (%v, %v) is not the expected: (%v, %v)", c.name, output, err, c.expected, c.hasErr)
+		}
+	}
+}
+
+func TestUpdateDashboardInOrg(t *testing.T) {
+	orgs := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		orgs[req.Header.Get("X-Grafana-Org-Id")] = true
+		switch req.URL.Path {
+		case "/api/search":
+			if req.URL.Query().Get("type") == "dash-folder" {
+				w.Write([]byte("[{\"id\": 1, \"uid\": \"test\", \"title\": \"Custom\", \"type\": \"dash-folder\"}]"))
+				return
+			}
+			w.Write([]byte("[{\"uid\": \"test\"}]"))
+		default:
+			w.Write([]byte("{}"))
+		}
+	}))
+	defer server.Close()
+
+	originalURI := grafanaURI
+	grafanaURI = server.URL
+	retry = 1
+	defer func() {
+		grafanaURI = originalURI
+	}()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Namespace:   "test",
+			Annotations: map[string]string{dashboardOrgIDKey: "3"},
+		},
+		Data: map[string]string{"test.json": "{\"title\": \"test\"}"},
+	}
+	if err := updateDashboard(context.TODO(), nil, cm, false); err != nil {
+		t.Fatalf("failed to update dashboard: %v", err)
+	}
+	if len(orgs) != 1 || !orgs["3"] {
+		t.Fatalf("the requests are sent to orgs %v instead of org 3", orgs)
+	}
+
+	cm.Annotations[dashboardOrgIDKey] = "invalid"
+	if err := updateDashboard(context.TODO(), nil, cm, false); err == nil {
+		t.Fatalf("dashboard with invalid org should not be updated")
+	}
+}
+
+func TestRetainedDashboard(t *testing.T) {
+	deleted := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodDelete {
+			deleted = true
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	originalURI := grafanaURI
+	grafanaURI = server.URL
+	retry = 1
+	defer func() {
+		grafanaURI = originalURI
+	}()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Namespace:   "test",
+			Annotations: map[string]string{dashboardRetainKey: "True"},
+		},
+		Data: map[string]string{"test.json": "{\"title\": \"test\"}"},
+	}
+	deleteDashboard(context.TODO(), cm)
+	if deleted {
+		t.Fatalf("retained dashboard should not be deleted")
+	}
+
+	cm.Annotations[dashboardRetainKey] = "false"
+	deleteDashboard(context.TODO(), cm)
+	if !deleted {
+		t.Fatalf("dashboard should be deleted")
+	}
+}
+
+func TestDeleteDashboardWithInvalidKey(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	fake.dashboards[""] = map[string]fakeDashboard{"valid": {id: 1, dashboard: map[string]interface{}{"uid": "valid"}}}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Data:       map[string]string{"invalid.json": "{", "valid.json": `{"uid": "valid", "title": "valid"}`},
+	}
+	if err := deleteDashboard(context.TODO(), cm); err == nil {
+		t.Errorf("the deletion with an invalid key should fail to be retried")
+	}
+	if _, ok := fake.dashboards[""]["valid"]; ok {
+		t.Errorf("the dashboard of the valid key should be deleted")
+	}
+}
+
+func TestUpdateDashboardWithInvalidKey(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Data:       map[string]string{"invalid.json": "{", "valid.json": `{"uid": "valid", "title": "valid"}`},
+	}
+	if err := updateDashboard(context.TODO(), nil, cm, false); err == nil {
+		t.Errorf("the sync with an invalid key should fail to be retried")
+	}
+	if _, ok := fake.dashboards[""]["valid"]; !ok {
+		t.Errorf("the dashboard of the valid key should be saved")
+	}
+}
+
+func TestIsEditableDashboard(t *testing.T) {
+	defer func() {
+		NonEditableDashboards = false
+	}()
+
+	testCaseList := []struct {
+		name        string
+		nonEditable bool
+		annotation  string
+		expected    bool
+	}{
+
+		{
+			"default",
+			false,
+			"",
+			true,
+		},
+
+		{
+			"non-editable annotation",
+			false,
+			"false",
+			false,
+		},
+
+		{
+			"non-editable option",
+			true,
+			"",
+			false,
+		},
+
+		{
+			"editable annotation overrides option",
+			true,
+			"True",
+			true,
+		},
+	}
+
+	for _, c := range testCaseList {
+		NonEditableDashboards = c.nonEditable
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Namespace:   "test",
+				Annotations: map[string]string{dashboardEditableKey: c.annotation},
+			},
+		}
+		output := isEditableDashboard(cm)
+		if output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestDeleteTombstoneConfigmap(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	retry = 1
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+		Data: map[string]string{"test.json": `{"uid": "tombstone", "title": "tombstone"}`},
+	}
+	state := newSyncState("tombstone-test")
+	handler := newDashboardEventHandler(context.TODO(), state, configmapFromObject)
+	handler.OnAdd(cm)
+	if _, ok := fake.dashboards[""]["tombstone"]; !ok {
+		t.Fatalf("the dashboard should be saved")
+	}
+
+	// the configmap deleted while the watch was disconnected comes as a tombstone
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "test/test", Obj: cm})
+	if _, ok := fake.dashboards[""]["tombstone"]; ok {
+		t.Errorf("the dashboard of the tombstone should be deleted")
+	}
+	if state.isSynced(cm) {
+		t.Errorf("the sync state of the tombstone should be forgotten")
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	gzipSuffix = ".json.gz"
+	// maxDecompressedSize protects the loader from gzip bombs
+	maxDecompressedSize = 32 << 20
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func isGzipDashboard(key string, value []byte) bool {
+	return strings.HasSuffix(key, gzipSuffix) || bytes.HasPrefix(value, gzipMagic)
+}
+
+func gunzip(value []byte) (string, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(value))
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	// read one more byte to tell an oversized dashboard from one of exactly the max size,
+	// the builder gives the string of the read bytes without copying them again
+	data := strings.Builder{}
+	if _, err := io.Copy(&data, io.LimitReader(reader, maxDecompressedSize+1)); err != nil {
+		return "", err
+	}
+	if data.Len() > maxDecompressedSize {
+		return "", fmt.Errorf("decompressed dashboard is larger than %v bytes", maxDecompressedSize)
+	}
+	return data.String(), nil
+}
+
+type dashboardDataKey struct{}
+
+// withDashboardData returns the context which keeps the dashboards of the configmap,
+// so that the same dashboards are not downloaded, decompressed and substituted again for every managed cluster
+func withDashboardData(ctx context.Context, cm *corev1.ConfigMap) context.Context {
+	dashboards, err := getDashboardData(ctx, cm)
+	return context.WithValue(ctx, dashboardDataKey{}, resolvedDashboardData{dashboards, err})
+}
+
+// resolvedDashboardData is the result of getDashboardData kept in the context
+type resolvedDashboardData struct {
+	dashboards map[string]string
+	err        error
+}
+
+// dashboardData returns the dashboards kept in the context, or gets them from the configmap
+func dashboardData(ctx context.Context, cm *corev1.ConfigMap) (map[string]string, error) {
+	if resolved, ok := ctx.Value(dashboardDataKey{}).(resolvedDashboardData); ok {
+		return resolved.dashboards, resolved.err
+	}
+	return getDashboardData(ctx, cm)
+}
+
+// getDashboardData returns all the dashboards of the configmap keyed by the data key,
+// the referred dashboards are downloaded, the gzip-compressed dashboards in binaryData are decompressed
+// and the __inputs of the dashboards exported for sharing are substituted
+func getDashboardData(ctx context.Context, cm *corev1.ConfigMap) (map[string]string, error) {
+	dashboards := map[string]string{}
+	var decodeErr error
+	for key, value := range cm.Data {
+		var resolve func(context.Context, string) (string, error)
+		if isGrafanaComReference(key) {
+			resolve = resolveGrafanaComDashboard
+		} else if isRemoteReference(key) {
+			resolve = resolveRemoteDashboard
+		}
+		if resolve != nil {
+			dashboard, err := resolve(ctx, value)
+			if err != nil {
+				klog.Errorf("failed to resolve %v in %v: %v", key, cm.Name, err)
+				decodeErr = fmt.Errorf("failed to resolve %v: %v", key, err)
+				continue
+			}
+			value = dashboard
+		}
+		// the grafana.com dashboards are substituted with the inputs of the reference
+		if !isGrafanaComReference(key) {
+			dashboard, err := resolveDashboardInputs(cm, value)
+			if err != nil {
+				klog.Errorf("failed to substitute the inputs of %v in %v: %v", key, cm.Name, err)
+				decodeErr = fmt.Errorf("failed to substitute the inputs of %v: %v", key, err)
+				continue
+			}
+			value = dashboard
+		}
+		dashboards[key] = value
+	}
+
+	for key, value := range cm.BinaryData {
+		if !isGzipDashboard(key, value) {
+			klog.V(4).Infof("skip the non-gzip binary data %v in %v", key, cm.Name)
+			continue
+		}
+		dashboard, err := gunzip(value)
+		if err != nil {
+			klog.Errorf("failed to decompress %v in %v: %v", key, cm.Name, err)
+			decodeErr = fmt.Errorf("failed to decompress %v: %v", key, err)
+			continue
+		}
+		dashboard, err = resolveDashboardInputs(cm, dashboard)
+		if err != nil {
+			klog.Errorf("failed to substitute the inputs of %v in %v: %v", key, cm.Name, err)
+			decodeErr = fmt.Errorf("failed to substitute the inputs of %v: %v", key, err)
+			continue
+		}
+		dashboards[key] = dashboard
+	}
+	return dashboards, decodeErr
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func compress(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(data)); err != nil {
+		t.Fatalf("failed to compress data: %v", err)
+	}
+	writer.Close()
+	return buf.Bytes()
+}
+
+func TestGetDashboardData(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		binaryData map[string][]byte
+		expected   map[string]string
+		hasErr     bool
+	}{
+
+		{
+			"no binary data",
+			nil,
+			map[string]string{"plain.json": "{}"},
+			false,
+		},
+
+		{
+			"gzip by magic bytes",
+			map[string][]byte{"compressed": compress(t, "{\"title\": \"a\"}")},
+			map[string]string{"plain.json": "{}", "compressed": "{\"title\": \"a\"}"},
+			false,
+		},
+
+		{
+			"gzip by suffix",
+			map[string][]byte{"b.json.gz": compress(t, "{\"title\": \"b\"}")},
+			map[string]string{"plain.json": "{}", "b.json.gz": "{\"title\": \"b\"}"},
+			false,
+		},
+
+		{
+			"non-gzip binary data",
+			map[string][]byte{"logo.png": []byte("png")},
+			map[string]string{"plain.json": "{}"},
+			false,
+		},
+
+		{
+			"corrupted gzip",
+			map[string][]byte{"c.json.gz": []byte("not gzip")},
+			map[string]string{"plain.json": "{}"},
+			true,
+		},
+	}
+
+	for _, c := range testCaseList {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+			Data:       map[string]string{"plain.json": "{}"},
+			BinaryData: c.binaryData,
+		}
+		output, err := getDashboardData(context.TODO(), cm)
+		if (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+		if len(output) != len(c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+			continue
+		}
+		for key, value := range c.expected {
+			if output[key] != value {
+				t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output[key], value)
+			}
+		}
+	}
+}
+
+func TestDashboardDataOfContext(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		BinaryData: map[string][]byte{"a.json.gz": compress(t, `{"title": "A"}`)},
+	}
+	ctx := withDashboardData(context.TODO(), cm)
+	// the kept dashboards are returned without decompressing the configmap again
+	cm.BinaryData = map[string][]byte{"a.json.gz": []byte("corrupted")}
+
+	testCaseList := []struct {
+		name     string
+		ctx      context.Context
+		expected string
+		hasErr   bool
+	}{
+		{"kept", ctx, `{"title": "A"}`, false},
+
+		{"not kept", context.TODO(), "", true},
+	}
+
+	for _, c := range testCaseList {
+		dashboards, err := dashboardData(c.ctx, cm)
+		if output := dashboards["a.json.gz"]; output != c.expected || (err != nil) != c.hasErr {
+			t.Errorf("case (%v) output: (%v, %v) is not the expected: (%v, %v)", c.name, output, err, c.expected, c.hasErr)
+		}
+	}
+}
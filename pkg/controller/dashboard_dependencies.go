@@ -0,0 +1,147 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// dashboardDependsOnKey is the annotation to apply the dashboards of the configmap only after the named configmaps
+// of the same source are synced, e.g. the folder config or the library panels the dashboards refer to,
+// the value is the comma separated names, a name without namespace is in the namespace of the configmap
+const dashboardDependsOnKey = "observability.open-cluster-management.io/dashboard-depends-on"
+
+// dependencyWaitError means the configmap is not applied yet since its dependencies are not synced
+type dependencyWaitError struct {
+	waiting []string
+}
+
+func (e *dependencyWaitError) Error() string {
+	return fmt.Sprintf("waiting for the dependencies to be synced: %v", strings.Join(e.waiting, ", "))
+}
+
+// isWaitingForDependencies checks whether the sync is postponed until the dependencies are synced
+func isWaitingForDependencies(err error) bool {
+	var waitErr *dependencyWaitError
+	return errors.As(err, &waitErr)
+}
+
+// dependencyGraph is the dependencies declared by the configmaps keyed by the source and the configmap
+type dependencyGraph struct {
+	sync.Mutex
+	edges map[string][]string
+	// waiting are the configmaps which wait for their dependencies
+	waiting map[string]bool
+}
+
+var dependencies = &dependencyGraph{edges: map[string][]string{}, waiting: map[string]bool{}}
+
+func dependencyNode(source string, key string) string {
+	return source + ":" + key
+}
+
+// configmapDependencies returns the keys of the configmaps named by the depends-on annotation ordered by name
+func configmapDependencies(cm *corev1.ConfigMap) []string {
+	keys := []string{}
+	for name := range stringSet(strings.Split(cm.GetAnnotations()[dashboardDependsOnKey], ",")) {
+		if !strings.Contains(name, "/") {
+			name = cm.GetNamespace() + "/" + name
+		}
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// check records the dependencies of the configmap, it fails on a dependency cycle
+// and returns a dependencyWaitError while any dependency is not synced
+func (g *dependencyGraph) check(state *syncState, cm *corev1.ConfigMap) error {
+	node := dependencyNode(state.source, configmapKey(cm))
+	keys := configmapDependencies(cm)
+	g.Lock()
+	defer g.Unlock()
+	if len(keys) == 0 {
+		delete(g.edges, node)
+		delete(g.waiting, node)
+		return nil
+	}
+	g.edges[node] = nil
+	for _, key := range keys {
+		g.edges[node] = append(g.edges[node], dependencyNode(state.source, key))
+	}
+	if cycle := g.cycleFrom(node, []string{node}); cycle != nil {
+		delete(g.waiting, node)
+		for i := range cycle {
+			cycle[i] = strings.TrimPrefix(cycle[i], state.source+":")
+		}
+		return fmt.Errorf("the dependencies have a cycle: %v", strings.Join(cycle, " -> "))
+	}
+
+	waiting := []string{}
+	for _, key := range keys {
+		if !state.isKeySynced(key) {
+			waiting = append(waiting, key)
+		}
+	}
+	if len(waiting) == 0 {
+		delete(g.waiting, node)
+		return nil
+	}
+	g.waiting[node] = true
+	return &dependencyWaitError{waiting}
+}
+
+// cycleFrom returns the path back to the first node of the path, nil means there is no cycle, the caller holds the lock
+func (g *dependencyGraph) cycleFrom(node string, path []string) []string {
+	for _, next := range g.edges[node] {
+		if next == path[0] {
+			return append(append([]string{}, path...), next)
+		}
+		visited := false
+		for _, seen := range path {
+			visited = visited || seen == next
+		}
+		if visited {
+			continue
+		}
+		if cycle := g.cycleFrom(next, append(path, next)); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// synced delivers the objects again once the configmap is synced and other configmaps wait for it,
+// the waiting ones are applied then while the synced ones are skipped
+func (g *dependencyGraph) synced(state *syncState, cm *corev1.ConfigMap) {
+	node := dependencyNode(state.source, configmapKey(cm))
+	g.Lock()
+	dependents := false
+	for waiting := range g.waiting {
+		for _, dependency := range g.edges[waiting] {
+			dependents = dependents || dependency == node
+		}
+	}
+	g.Unlock()
+	if dependents {
+		klog.InfoS("the dependency is synced, applying the configmaps which wait for it", "configmap", klog.KObj(cm))
+		go redeliverAll()
+	}
+}
+
+// forget drops the dependencies of the deleted configmap, the configmaps depending on it keep waiting
+func (g *dependencyGraph) forget(state *syncState, cm *corev1.ConfigMap) {
+	node := dependencyNode(state.source, configmapKey(cm))
+	g.Lock()
+	defer g.Unlock()
+	delete(g.edges, node)
+	delete(g.waiting, node)
+}
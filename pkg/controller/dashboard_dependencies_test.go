@@ -0,0 +1,67 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDashboardDependencies(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+
+	state := newSyncState("dependency-test")
+	configmap := func(name string, dependsOn string) *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "dependency-test",
+				Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+			Data: map[string]string{"a.json": `{"uid": "` + name + `", "title": "` + name + `", "panels": []}`},
+		}
+		if dependsOn != "" {
+			cm.Annotations = map[string]string{dashboardDependsOnKey: dependsOn}
+		}
+		return cm
+	}
+
+	testCaseList := []struct {
+		name    string
+		cm      *corev1.ConfigMap
+		err     string
+		waiting bool
+		saved   bool
+	}{
+		{"waiting for the panels", configmap("dashboards", "panels"), "dependency-test/panels", true, false},
+
+		{"panels", configmap("panels", ""), "", false, true},
+
+		{"panels synced", configmap("dashboards", "panels"), "", false, true},
+
+		{"other namespace", configmap("other", "monitoring/panels, panels"), "monitoring/panels", true, false},
+
+		{"first of a cycle", configmap("a", "b"), "dependency-test/b", true, false},
+
+		{"cycle", configmap("b", "dependency-test/a"), "dependency-test/b -> dependency-test/a -> dependency-test/b", false, false},
+	}
+
+	for _, c := range testCaseList {
+		defer state.forget(c.cm)
+		defer dependencies.forget(state, c.cm)
+		defer pendingWork.done(state.source, c.cm)
+		err := syncDashboard(context.TODO(), state, nil, c.cm)
+		if (err != nil) != (c.err != "") || err != nil && !strings.Contains(err.Error(), c.err) {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.err)
+		}
+		if output := isWaitingForDependencies(err); output != c.waiting {
+			t.Errorf("case (%v) waiting: (%v) is not the expected: (%v)", c.name, output, c.waiting)
+		}
+		if _, output := fake.dashboards[""][c.cm.Name]; output != c.saved {
+			t.Errorf("case (%v) saved: (%v) is not the expected: (%v)", c.name, output, c.saved)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// LogDashboardDiff fetches the current version of every dashboard before it is overwritten to log what is changed
+var LogDashboardDiff = false
+
+// diffIgnoredFields are changed by grafana on every save
+var diffIgnoredFields = map[string]bool{"id": true, "version": true, "iteration": true}
+
+// logDashboardDiff logs the changes between the dashboard in grafana and the one to apply
+func logDashboardDiff(ctx context.Context, cm *corev1.ConfigMap, key string, orgID string, dashboard map[string]interface{}) {
+	uid, _ := dashboard["uid"].(string)
+	current, err := clientFor(ctx).GetDashboard(ctx, orgID, uid)
+	if grafanaStatus(err) == http.StatusNotFound {
+		klog.V(2).InfoS("dashboard is new", "configmap", klog.KObj(cm), "key", key, "uid", uid)
+		return
+	}
+	if err != nil {
+		klog.ErrorS(err, "failed to get the current dashboard to diff", "configmap", klog.KObj(cm), "key", key, "uid", uid)
+		return
+	}
+	changes := diffDashboards(current, dashboard)
+	if len(changes) == 0 {
+		klog.InfoS("dashboard is unchanged", "configmap", klog.KObj(cm), "key", key, "uid", uid)
+		return
+	}
+	klog.InfoS("dashboard is changed", "configmap", klog.KObj(cm), "key", key, "uid", uid, "changes", changes)
+}
+
+// diffDashboards returns the field level changes from the old dashboard to the new one,
+// the panels are matched by id so that the panels added, removed and the queries changed are told apart
+func diffDashboards(old, new map[string]interface{}) []string {
+	changes := []string{}
+	for _, field := range unionKeys(old, new) {
+		if diffIgnoredFields[field] || field == "panels" || field == "rows" {
+			continue
+		}
+		if !reflect.DeepEqual(old[field], new[field]) {
+			changes = append(changes, changeOf(field, old[field], new[field]))
+		}
+	}
+
+	oldPanels, newPanels := dashboardPanels(old), dashboardPanels(new)
+	for _, id := range unionPanelKeys(oldPanels, newPanels) {
+		oldPanel, inOld := oldPanels[id]
+		newPanel, inNew := newPanels[id]
+		switch {
+		case !inOld:
+			changes = append(changes, fmt.Sprintf("panel %v added", panelName(newPanel)))
+		case !inNew:
+			changes = append(changes, fmt.Sprintf("panel %v removed", panelName(oldPanel)))
+		default:
+			changes = append(changes, diffPanels(oldPanel, newPanel)...)
+		}
+	}
+	return changes
+}
+
+func diffPanels(old, new map[string]interface{}) []string {
+	changes := []string{}
+	name := panelName(new)
+	for _, field := range unionKeys(old, new) {
+		if field == "targets" || field == "panels" || reflect.DeepEqual(old[field], new[field]) {
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("panel %v %v", name, changeOf(field, old[field], new[field])))
+	}
+
+	oldTargets, newTargets := panelTargets(old), panelTargets(new)
+	for _, refID := range unionPanelKeys(oldTargets, newTargets) {
+		oldTarget, inOld := oldTargets[refID]
+		newTarget, inNew := newTargets[refID]
+		switch {
+		case !inOld:
+			changes = append(changes, fmt.Sprintf("panel %v query %v added: %v", name, refID, targetQuery(newTarget)))
+		case !inNew:
+			changes = append(changes, fmt.Sprintf("panel %v query %v removed", name, refID))
+		case targetQuery(oldTarget) != targetQuery(newTarget):
+			changes = append(changes, fmt.Sprintf("panel %v query %v changed: %q -> %q", name, refID,
+				targetQuery(oldTarget), targetQuery(newTarget)))
+		case !reflect.DeepEqual(oldTarget, newTarget):
+			changes = append(changes, fmt.Sprintf("panel %v query %v options changed", name, refID))
+		}
+	}
+	return changes
+}
+
+// changeOf describes the change of a field, only the scalar values are printed
+func changeOf(field string, old, new interface{}) string {
+	switch {
+	case old == nil:
+		return field + " added"
+	case new == nil:
+		return field + " removed"
+	}
+	if isComposite(old) || isComposite(new) {
+		return field + " changed"
+	}
+	return fmt.Sprintf("%v changed: %v -> %v", field, old, new)
+}
+
+func isComposite(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+// dashboardPanels returns all the panels keyed by id including the ones in the collapsed rows and the legacy rows
+func dashboardPanels(dashboard map[string]interface{}) map[string]map[string]interface{} {
+	panels := map[string]map[string]interface{}{}
+	var add func(items interface{})
+	add = func(items interface{}) {
+		list, _ := items.([]interface{})
+		for i, item := range list {
+			panel, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id := fmt.Sprint(panel["id"])
+			if panel["id"] == nil {
+				id = fmt.Sprintf("%v#%v", panel["title"], i)
+			}
+			panels[id] = panel
+			add(panel["panels"])
+		}
+	}
+	add(dashboard["panels"])
+	rows, _ := dashboard["rows"].([]interface{})
+	for _, row := range rows {
+		if row, ok := row.(map[string]interface{}); ok {
+			add(row["panels"])
+		}
+	}
+	return panels
+}
+
+func panelTargets(panel map[string]interface{}) map[string]map[string]interface{} {
+	targets := map[string]map[string]interface{}{}
+	list, _ := panel["targets"].([]interface{})
+	for i, item := range list {
+		target, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		refID, _ := target["refId"].(string)
+		if refID == "" {
+			refID = fmt.Sprint(i)
+		}
+		targets[refID] = target
+	}
+	return targets
+}
+
+func targetQuery(target map[string]interface{}) string {
+	for _, field := range []string{"expr", "query", "rawSql"} {
+		if query, ok := target[field].(string); ok {
+			return query
+		}
+	}
+	return ""
+}
+
+func panelName(panel map[string]interface{}) string {
+	if title, ok := panel["title"].(string); ok && title != "" {
+		return fmt.Sprintf("%q", title)
+	}
+	return fmt.Sprint(panel["id"])
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := []string{}
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func unionPanelKeys(a, b map[string]map[string]interface{}) []string {
+	keys := []string{}
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
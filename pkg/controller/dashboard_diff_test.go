@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDiffDashboards(t *testing.T) {
+	old := `{"id": 1, "version": 3, "uid": "a", "title": "CPU", "tags": ["a"], "panels": [
+		{"id": 1, "title": "Usage", "type": "graph", "targets": [{"refId": "A", "expr": "cpu"}]},
+		{"id": 2, "title": "Load", "type": "graph"},
+		{"id": 3, "title": "Row", "type": "row", "collapsed": true, "panels": [{"id": 4, "title": "Nested", "type": "stat"}]}]}`
+
+	testCaseList := []struct {
+		name     string
+		new      string
+		expected []string
+	}{
+		{
+			"unchanged",
+			`{"id": null, "uid": "a", "title": "CPU", "tags": ["a"], "panels": [
+				{"id": 1, "title": "Usage", "type": "graph", "targets": [{"refId": "A", "expr": "cpu"}]},
+				{"id": 2, "title": "Load", "type": "graph"},
+				{"id": 3, "title": "Row", "type": "row", "collapsed": true, "panels": [{"id": 4, "title": "Nested", "type": "stat"}]}]}`,
+			[]string{},
+		},
+
+		{
+			"fields",
+			`{"uid": "a", "title": "CPU v2", "tags": ["a", "b"], "refresh": "1m", "panels": [
+				{"id": 1, "title": "Usage", "type": "graph", "targets": [{"refId": "A", "expr": "cpu"}]},
+				{"id": 2, "title": "Load", "type": "graph"},
+				{"id": 3, "title": "Row", "type": "row", "collapsed": true, "panels": [{"id": 4, "title": "Nested", "type": "stat"}]}]}`,
+			[]string{"refresh added", "tags changed", "title changed: CPU -> CPU v2"},
+		},
+
+		{
+			"panels",
+			`{"uid": "a", "title": "CPU", "tags": ["a"], "panels": [
+				{"id": 1, "title": "Usage", "type": "timeseries", "targets": [{"refId": "A", "expr": "rate(cpu[5m])"}, {"refId": "B", "expr": "mem"}]},
+				{"id": 3, "title": "Row", "type": "row", "collapsed": true, "panels": [{"id": 4, "title": "Nested", "type": "stat"}]},
+				{"id": 5, "title": "Disk", "type": "graph"}]}`,
+			[]string{
+				`panel "Usage" type changed: graph -> timeseries`,
+				`panel "Usage" query A changed: "cpu" -> "rate(cpu[5m])"`,
+				`panel "Usage" query B added: mem`,
+				`panel "Load" removed`,
+				`panel "Disk" added`,
+			},
+		},
+
+		{
+			"nested panels",
+			`{"uid": "a", "title": "CPU", "tags": ["a"], "panels": [
+				{"id": 1, "title": "Usage", "type": "graph", "targets": [{"refId": "A", "expr": "cpu"}]},
+				{"id": 2, "title": "Load", "type": "graph"},
+				{"id": 3, "title": "Row", "type": "row", "collapsed": true, "panels": []}]}`,
+			[]string{`panel "Nested" removed`},
+		},
+	}
+
+	oldDashboard := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(old), &oldDashboard); err != nil {
+		t.Fatalf("invalid old dashboard: %v", err)
+	}
+	for _, c := range testCaseList {
+		newDashboard := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(c.new), &newDashboard); err != nil {
+			t.Fatalf("case (%v) invalid dashboard: %v", c.name, err)
+		}
+		output := diffDashboards(oldDashboard, newDashboard)
+		if !reflect.DeepEqual(output, c.expected) {
+			t.Errorf("case (%v) output: (%q) is not the expected: (%q)", c.name, output, c.expected)
+		}
+	}
+}
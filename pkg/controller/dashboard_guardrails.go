@@ -0,0 +1,128 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// MinRefreshInterval is the lowest auto refresh interval of the dashboards, 0 means no limit
+	MinRefreshInterval = time.Duration(0)
+	// MaxTimeRange is the longest default time range of the dashboards, 0 means no limit
+	MaxTimeRange = time.Duration(0)
+)
+
+// grafanaDurationUnits are the units of the grafana intervals from the largest, a month or a year is not a fixed duration
+var grafanaDurationUnits = []struct {
+	unit     string
+	duration time.Duration
+}{
+	{"w", 7 * 24 * time.Hour},
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+}
+
+var grafanaDurationPattern = regexp.MustCompile(`^(\d+)(w|d|h|m|s)$`)
+
+// relativeTimePattern matches the relative times of the time picker, e.g. now, now-6h or now-1d/d
+var relativeTimePattern = regexp.MustCompile(`^now(?:-(\d+[wdhms]))?(/[wdhms])?$`)
+
+// parseGrafanaDuration parses the intervals like 30s or 7d
+func parseGrafanaDuration(value string) (time.Duration, bool) {
+	match := grafanaDurationPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false
+	}
+	for _, u := range grafanaDurationUnits {
+		if u.unit == match[2] {
+			return time.Duration(n) * u.duration, true
+		}
+	}
+	return 0, false
+}
+
+// formatGrafanaDuration formats the duration with the largest unit which divides it
+func formatGrafanaDuration(d time.Duration) string {
+	for _, u := range grafanaDurationUnits {
+		if d >= u.duration && d%u.duration == 0 {
+			return fmt.Sprintf("%v%v", int64(d/u.duration), u.unit)
+		}
+	}
+	return fmt.Sprintf("%vs", int64(d.Seconds()))
+}
+
+// applyGuardrails clamps the refresh interval and the default time range of the dashboard,
+// it returns the changes which are made
+func applyGuardrails(dashboard map[string]interface{}) []string {
+	changes := []string{}
+	s := activeSettings()
+	if s.minRefreshInterval > 0 {
+		changes = append(changes, clampRefresh(dashboard, s.minRefreshInterval)...)
+	}
+	if s.maxTimeRange > 0 {
+		changes = append(changes, clampTimeRange(dashboard, s.maxTimeRange)...)
+	}
+	return changes
+}
+
+func clampRefresh(dashboard map[string]interface{}, minRefreshInterval time.Duration) []string {
+	changes := []string{}
+	minimum := formatGrafanaDuration(minRefreshInterval)
+	if refresh, ok := dashboard["refresh"].(string); ok {
+		if interval, ok := parseGrafanaDuration(refresh); ok && interval < minRefreshInterval {
+			dashboard["refresh"] = minimum
+			changes = append(changes, fmt.Sprintf("the refresh interval %v is raised to %v", refresh, minimum))
+		}
+	}
+
+	// the intervals below the minimum are removed from the time picker so that they cannot be chosen
+	timepicker, _ := dashboard["timepicker"].(map[string]interface{})
+	intervals, ok := timepicker["refresh_intervals"].([]interface{})
+	if !ok {
+		return changes
+	}
+	allowed, removed := []interface{}{}, []string{}
+	for _, item := range intervals {
+		value, _ := item.(string)
+		if interval, ok := parseGrafanaDuration(value); ok && interval < minRefreshInterval {
+			removed = append(removed, value)
+			continue
+		}
+		allowed = append(allowed, item)
+	}
+	if len(removed) > 0 {
+		timepicker["refresh_intervals"] = allowed
+		changes = append(changes, fmt.Sprintf("the refresh intervals %v are removed from the time picker", strings.Join(removed, ", ")))
+	}
+	return changes
+}
+
+func clampTimeRange(dashboard map[string]interface{}, maxTimeRange time.Duration) []string {
+	timeRange, _ := dashboard["time"].(map[string]interface{})
+	from, _ := timeRange["from"].(string)
+	to, _ := timeRange["to"].(string)
+	// the absolute time ranges are kept since they point at a past incident on purpose
+	fromMatch, toMatch := relativeTimePattern.FindStringSubmatch(from), relativeTimePattern.FindStringSubmatch(to)
+	if fromMatch == nil || toMatch == nil {
+		return nil
+	}
+	fromOffset, _ := parseGrafanaDuration(fromMatch[1])
+	toOffset, _ := parseGrafanaDuration(toMatch[1])
+	if fromOffset-toOffset <= maxTimeRange {
+		return nil
+	}
+	timeRange["from"] = "now-" + formatGrafanaDuration(toOffset+maxTimeRange) + fromMatch[2]
+	return []string{fmt.Sprintf("the default time range from %v to %v is shortened to %v", from, to, formatGrafanaDuration(maxTimeRange))}
+}
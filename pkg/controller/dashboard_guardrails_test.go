@@ -0,0 +1,98 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApplyGuardrails(t *testing.T) {
+	defer func() {
+		MinRefreshInterval = 0
+		MaxTimeRange = 0
+	}()
+
+	testCaseList := []struct {
+		name       string
+		minRefresh time.Duration
+		maxRange   time.Duration
+		content    string
+		expected   string
+		changes    []string
+	}{
+		{
+			"no guardrails",
+			0,
+			0,
+			`{"refresh": "1s", "time": {"from": "now-1y", "to": "now"}}`,
+			`{"refresh": "1s", "time": {"from": "now-1y", "to": "now"}}`,
+			[]string{},
+		},
+
+		{
+			"refresh raised",
+			30 * time.Second,
+			0,
+			`{"refresh": "5s", "timepicker": {"refresh_intervals": ["5s", "10s", "30s", "1m"]}}`,
+			`{"refresh": "30s", "timepicker": {"refresh_intervals": ["30s", "1m"]}}`,
+			[]string{"the refresh interval 5s is raised to 30s", "the refresh intervals 5s, 10s are removed from the time picker"},
+		},
+
+		{
+			"refresh kept",
+			30 * time.Second,
+			0,
+			`{"refresh": "1m"}`,
+			`{"refresh": "1m"}`,
+			[]string{},
+		},
+
+		{
+			"refresh disabled",
+			30 * time.Second,
+			0,
+			`{"refresh": false}`,
+			`{"refresh": false}`,
+			[]string{},
+		},
+
+		{
+			"time range shortened",
+			0,
+			7 * 24 * time.Hour,
+			`{"time": {"from": "now-90d/d", "to": "now-1d/d"}}`,
+			`{"time": {"from": "now-8d/d", "to": "now-1d/d"}}`,
+			[]string{"the default time range from now-90d/d to now-1d/d is shortened to 1w"},
+		},
+
+		{
+			"absolute time range",
+			0,
+			time.Hour,
+			`{"time": {"from": "2021-01-01T00:00:00.000Z", "to": "2021-06-01T00:00:00.000Z"}}`,
+			`{"time": {"from": "2021-01-01T00:00:00.000Z", "to": "2021-06-01T00:00:00.000Z"}}`,
+			[]string{},
+		},
+	}
+
+	for _, c := range testCaseList {
+		MinRefreshInterval, MaxTimeRange = c.minRefresh, c.maxRange
+		dashboard, expected := map[string]interface{}{}, map[string]interface{}{}
+		if err := json.Unmarshal([]byte(c.content), &dashboard); err != nil {
+			t.Fatalf("case (%v) invalid dashboard: %v", c.name, err)
+		}
+		if err := json.Unmarshal([]byte(c.expected), &expected); err != nil {
+			t.Fatalf("case (%v) invalid expected dashboard: %v", c.name, err)
+		}
+		if changes := applyGuardrails(dashboard); !reflect.DeepEqual(changes, c.changes) {
+			t.Errorf("case (%v) output: (%q) is not the expected: (%q)", c.name, changes, c.changes)
+		}
+		if !reflect.DeepEqual(dashboard, expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, dashboard, c.expected)
+		}
+	}
+}
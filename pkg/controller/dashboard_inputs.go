@@ -0,0 +1,71 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// dashboardInputsKey is the annotation of the values of the __inputs of the dashboards exported for sharing, e.g.
+//
+//	observability.open-cluster-management.io/dashboard-inputs: '{"DS_PROMETHEUS": "Observatorium"}'
+const dashboardInputsKey = "observability.open-cluster-management.io/dashboard-inputs"
+
+var (
+	// DashboardInputs are the values of the __inputs keyed by the input name, the annotation of the configmap takes precedence
+	DashboardInputs = map[string]string{}
+	// DatasourceInputs are the datasources of the datasource __inputs keyed by the plugin id, e.g. prometheus=Observatorium
+	DatasourceInputs = map[string]string{}
+)
+
+// sharedDashboardKeys are added by grafana when a dashboard is exported for sharing, the import api drops them too
+var sharedDashboardKeys = []string{"__inputs", "__requires"}
+
+func isSharedDashboard(value string) bool {
+	return strings.Contains(value, `"__inputs"`)
+}
+
+// resolveDashboardInputs substitutes the __inputs of the dashboard exported for sharing with the values of the
+// dashboard-inputs annotation, the --dashboard-inputs and the --datasource-inputs flags
+func resolveDashboardInputs(cm *corev1.ConfigMap, value string) (string, error) {
+	if !isSharedDashboard(value) {
+		return value, nil
+	}
+	values := map[string]string{}
+	if annotation, ok := cm.GetAnnotations()[dashboardInputsKey]; ok {
+		if err := json.Unmarshal([]byte(annotation), &values); err != nil {
+			return "", fmt.Errorf("invalid %v annotation: %v", dashboardInputsKey, err)
+		}
+	}
+	dashboard, err := substituteInputs(value, values)
+	if err != nil {
+		return "", fmt.Errorf("%v, set it in the %v annotation", err, dashboardInputsKey)
+	}
+	return dashboard, nil
+}
+
+// inputValue looks up the value of the input in the given values, the configured inputs and then the dashboard
+func inputValue(input dashboardInput, values map[string]string) (string, bool) {
+	if value, ok := values[input.Name]; ok {
+		return value, true
+	}
+	if value, ok := DashboardInputs[input.Name]; ok {
+		return value, true
+	}
+	if value, ok := DatasourceInputs[input.PluginID]; ok && input.Type == "datasource" {
+		return value, true
+	}
+	return input.Value, input.Value != ""
+}
+
+// removeSharedDashboardKeys drops the keys of the sharing export which are not part of the dashboard model
+func removeSharedDashboardKeys(dashboard map[string]interface{}) {
+	for _, key := range sharedDashboardKeys {
+		delete(dashboard, key)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolveDashboardInputs(t *testing.T) {
+	defer func() {
+		DashboardInputs = map[string]string{}
+		DatasourceInputs = map[string]string{}
+	}()
+
+	testCaseList := []struct {
+		name        string
+		annotations map[string]string
+		inputs      map[string]string
+		datasources map[string]string
+		value       string
+		expected    string
+		hasErr      bool
+	}{
+		{"plain dashboard", nil, nil, nil, `{"title": "${DS_PROMETHEUS}"}`, `{"title": "${DS_PROMETHEUS}"}`, false},
+
+		{"missing datasource", nil, nil, nil, sharedDashboard, "", true},
+
+		{"annotation", map[string]string{dashboardInputsKey: `{"DS_PROMETHEUS": "Observatorium", "VAR_JOB": "kubelet"}`},
+			map[string]string{"DS_PROMETHEUS": "Thanos"}, nil, sharedDashboard,
+			`"datasource": "Observatorium", "targets": [{"expr": "up{job=\"kubelet\"}"}]`, false},
+
+		{"input flag", nil, map[string]string{"DS_PROMETHEUS": "Thanos"}, map[string]string{"prometheus": "Observatorium"},
+			sharedDashboard, `"datasource": "Thanos", "targets": [{"expr": "up{job=\"node\"}"}]`, false},
+
+		{"datasource plugin", nil, nil, map[string]string{"prometheus": "Observatorium"},
+			sharedDashboard, `"datasource": "Observatorium", "targets": [{"expr": "up{job=\"node\"}"}]`, false},
+
+		{"invalid annotation", map[string]string{dashboardInputsKey: `DS_PROMETHEUS=Observatorium`}, nil, nil, sharedDashboard, "", true},
+	}
+
+	for _, c := range testCaseList {
+		DashboardInputs, DatasourceInputs = c.inputs, c.datasources
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: c.annotations}}
+		output, err := resolveDashboardInputs(cm, c.value)
+		if (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+		if !strings.Contains(output, c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestSyncSharedDashboard(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	fake.dashboards = map[string]map[string]fakeDashboard{}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test",
+			Labels:      map[string]string{"grafana-custom-dashboard": "true"},
+			Annotations: map[string]string{dashboardInputsKey: `{"DS_PROMETHEUS": "Observatorium"}`}},
+		Data: map[string]string{"node.json": sharedDashboard},
+	}
+	if err := updateDashboard(context.TODO(), nil, cm, false); err != nil {
+		t.Fatalf("case (shared dashboard) failed to sync: %v", err)
+	}
+	saved, ok := fake.dashboards[""]["rYdddlPWk"]
+	if !ok {
+		t.Fatalf("case (shared dashboard) the dashboard is not saved: %v", fake.dashboards)
+	}
+	for _, key := range sharedDashboardKeys {
+		if _, ok := saved.dashboard[key]; ok {
+			t.Errorf("case (shared dashboard) output: (%v) still has %v", saved.dashboard, key)
+		}
+	}
+}
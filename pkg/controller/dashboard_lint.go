@@ -0,0 +1,196 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	lintSeverityError   = "error"
+	lintSeverityWarning = "warning"
+
+	// rateInterval is the interval variable of grafana which is at least four times the scrape interval
+	rateInterval = "$__rate_interval"
+)
+
+var (
+	// LintRules are the names of the lint rules which run on every dashboard before it is saved, empty disables the linting
+	LintRules = []string{}
+	// LintBlockOnError does not save the dashboards with error findings
+	LintBlockOnError = false
+)
+
+// lintFinding is a problem found by a lint rule
+type lintFinding struct {
+	Rule     string
+	Severity string
+	Message  string
+}
+
+func (f lintFinding) String() string {
+	return fmt.Sprintf("[%v] %v: %v", f.Severity, f.Rule, f.Message)
+}
+
+// lintRule checks the dashboard, the rules are named after the ones of the grafana dashboard-linter
+type lintRule struct {
+	name     string
+	severity string
+	check    func(dashboard map[string]interface{}) []string
+}
+
+// dashboardLintRules are all the known rules in the order they run
+var dashboardLintRules = []lintRule{
+	{"template-datasource-rule", lintSeverityError, lintTemplateDatasource},
+	{"panel-datasource-rule", lintSeverityError, lintPanelDatasource},
+	{"panel-description-rule", lintSeverityWarning, lintPanelDescription},
+	{"target-rate-interval-rule", lintSeverityWarning, lintRateInterval},
+}
+
+// lintDashboard runs the enabled rules on the dashboard
+func lintDashboard(dashboard map[string]interface{}) []lintFinding {
+	enabled := map[string]bool{}
+	for _, name := range LintRules {
+		enabled[strings.TrimSpace(name)] = true
+	}
+	findings := []lintFinding{}
+	for _, rule := range dashboardLintRules {
+		if !enabled[rule.name] {
+			continue
+		}
+		for _, message := range rule.check(dashboard) {
+			findings = append(findings, lintFinding{Rule: rule.name, Severity: rule.severity, Message: message})
+		}
+	}
+	return findings
+}
+
+// hasLintErrors checks whether any finding blocks the dashboard
+func hasLintErrors(findings []lintFinding) bool {
+	for _, finding := range findings {
+		if finding.Severity == lintSeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// lintTemplateDatasource requires a datasource variable so that the dashboard works on any grafana
+func lintTemplateDatasource(dashboard map[string]interface{}) []string {
+	for _, variable := range templateVariables(dashboard) {
+		if variable["type"] == "datasource" {
+			return nil
+		}
+	}
+	return []string{"the dashboard has no datasource template variable"}
+}
+
+// lintPanelDatasource requires the panels and the targets to use the datasource variable instead of a named datasource
+func lintPanelDatasource(dashboard map[string]interface{}) []string {
+	problems := []string{}
+	for _, panel := range lintPanels(dashboard) {
+		if !isDatasourceVariable(panel["datasource"]) {
+			problems = append(problems, fmt.Sprintf("panel %v uses a hardcoded datasource", panelName(panel)))
+			continue
+		}
+		for _, target := range lintTargets(panel) {
+			if !isDatasourceVariable(target["datasource"]) {
+				problems = append(problems, fmt.Sprintf("panel %v query %v uses a hardcoded datasource", panelName(panel), target["refId"]))
+			}
+		}
+	}
+	return problems
+}
+
+// lintPanelDescription requires the panels to explain what they show
+func lintPanelDescription(dashboard map[string]interface{}) []string {
+	problems := []string{}
+	for _, panel := range lintPanels(dashboard) {
+		if description, _ := panel["description"].(string); strings.TrimSpace(description) == "" {
+			problems = append(problems, fmt.Sprintf("panel %v has no description", panelName(panel)))
+		}
+	}
+	return problems
+}
+
+var rangeFunctionPattern = regexp.MustCompile(`\b(rate|irate|increase)\s*\([^\[]*\[([^\]]+)\]`)
+
+// lintRateInterval requires the rate functions to use the $__rate_interval so that they never cover a single sample
+func lintRateInterval(dashboard map[string]interface{}) []string {
+	problems := []string{}
+	for _, panel := range lintPanels(dashboard) {
+		for _, target := range lintTargets(panel) {
+			expr, _ := target["expr"].(string)
+			for _, match := range rangeFunctionPattern.FindAllStringSubmatch(expr, -1) {
+				if strings.TrimSpace(match[2]) != rateInterval {
+					problems = append(problems, fmt.Sprintf("panel %v query %v should use %v instead of [%v] in %v",
+						panelName(panel), target["refId"], rateInterval, match[2], match[1]))
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// isDatasourceVariable checks whether the datasource is the default one or a template variable,
+// it is a name in the old dashboards and a {"type", "uid"} object in the new ones
+func isDatasourceVariable(datasource interface{}) bool {
+	switch v := datasource.(type) {
+	case nil:
+		return true
+	case string:
+		return v == "" || strings.HasPrefix(v, "$") || v == "-- Grafana --" || v == "-- Mixed --"
+	case map[string]interface{}:
+		uid, _ := v["uid"].(string)
+		return uid == "" || strings.HasPrefix(uid, "$") || uid == "grafana" || uid == "-- Mixed --"
+	}
+	return false
+}
+
+func templateVariables(dashboard map[string]interface{}) []map[string]interface{} {
+	templating, _ := dashboard["templating"].(map[string]interface{})
+	list, _ := templating["list"].([]interface{})
+	variables := []map[string]interface{}{}
+	for _, item := range list {
+		if variable, ok := item.(map[string]interface{}); ok {
+			variables = append(variables, variable)
+		}
+	}
+	return variables
+}
+
+// lintPanels returns the panels which show data ordered by id, the rows are skipped
+func lintPanels(dashboard map[string]interface{}) []map[string]interface{} {
+	all := dashboardPanels(dashboard)
+	ids := []string{}
+	for id, panel := range all {
+		if panel["type"] != "row" {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	panels := []map[string]interface{}{}
+	for _, id := range ids {
+		panels = append(panels, all[id])
+	}
+	return panels
+}
+
+// lintTargets returns the targets of the panel ordered by refId
+func lintTargets(panel map[string]interface{}) []map[string]interface{} {
+	all := panelTargets(panel)
+	refIDs := []string{}
+	for refID := range all {
+		refIDs = append(refIDs, refID)
+	}
+	sort.Strings(refIDs)
+	targets := []map[string]interface{}{}
+	for _, refID := range refIDs {
+		targets = append(targets, all[refID])
+	}
+	return targets
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLintDashboard(t *testing.T) {
+	defer func(rules []string) { LintRules = rules }(LintRules)
+	LintRules = []string{"template-datasource-rule", "panel-datasource-rule", "panel-description-rule", "target-rate-interval-rule"}
+
+	testCaseList := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			"clean",
+			`{"title": "a", "templating": {"list": [{"name": "datasource", "type": "datasource"}]}, "panels": [
+				{"id": 1, "type": "graph", "description": "CPU", "datasource": "$datasource",
+					"targets": [{"refId": "A", "expr": "sum(rate(cpu[$__rate_interval]))"}]},
+				{"id": 2, "type": "row", "panels": [{"id": 3, "type": "stat", "description": "Up", "datasource": {"type": "prometheus", "uid": "${datasource}"}}]}]}`,
+			[]string{},
+		},
+
+		{
+			"no datasource variable",
+			`{"title": "a", "templating": {"list": [{"name": "cluster", "type": "query"}]}, "panels": []}`,
+			[]string{"[error] template-datasource-rule: the dashboard has no datasource template variable"},
+		},
+
+		{
+			"hardcoded datasources",
+			`{"title": "a", "templating": {"list": [{"name": "datasource", "type": "datasource"}]}, "panels": [
+				{"id": 1, "type": "graph", "title": "CPU", "description": "CPU", "datasource": "Observatorium"},
+				{"id": 2, "type": "graph", "title": "Memory", "description": "Memory", "datasource": {"uid": "$datasource"},
+					"targets": [{"refId": "A", "datasource": {"type": "prometheus", "uid": "P1809F7CD0C75ACF3"}}]}]}`,
+			[]string{
+				`[error] panel-datasource-rule: panel "CPU" uses a hardcoded datasource`,
+				`[error] panel-datasource-rule: panel "Memory" query A uses a hardcoded datasource`,
+			},
+		},
+
+		{
+			"missing descriptions and rate intervals",
+			`{"title": "a", "templating": {"list": [{"name": "datasource", "type": "datasource"}]}, "panels": [
+				{"id": 1, "type": "graph", "title": "CPU", "targets": [
+					{"refId": "A", "expr": "sum(rate(cpu[5m])) / sum(increase(requests[$__rate_interval]))"},
+					{"refId": "B", "expr": "irate(cpu[1m])"}]}]}`,
+			[]string{
+				`[warning] panel-description-rule: panel "CPU" has no description`,
+				`[warning] target-rate-interval-rule: panel "CPU" query A should use $__rate_interval instead of [5m] in rate`,
+				`[warning] target-rate-interval-rule: panel "CPU" query B should use $__rate_interval instead of [1m] in irate`,
+			},
+		},
+	}
+
+	for _, c := range testCaseList {
+		dashboard := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(c.content), &dashboard); err != nil {
+			t.Fatalf("case (%v) invalid dashboard: %v", c.name, err)
+		}
+		output := []string{}
+		for _, finding := range lintDashboard(dashboard) {
+			output = append(output, finding.String())
+		}
+		if !reflect.DeepEqual(output, c.expected) {
+			t.Errorf("case (%v) output: (%q) is not the expected: (%q)", c.name, output, c.expected)
+		}
+	}
+
+	LintRules = []string{"panel-description-rule"}
+	dashboard := map[string]interface{}{"panels": []interface{}{map[string]interface{}{"id": 1, "type": "graph"}}}
+	if findings := lintDashboard(dashboard); len(findings) != 1 || hasLintErrors(findings) {
+		t.Errorf("case (selected rules) output: (%v) is not the expected: (%v)", findings, "one warning")
+	}
+}
+
+func TestLintBlockOnError(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func(rules []string, block bool) { LintRules, LintBlockOnError = rules, block }(LintRules, LintBlockOnError)
+	LintRules = []string{"template-datasource-rule", "panel-description-rule"}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+		Data: map[string]string{
+			"warning.json": `{"uid": "warning", "title": "warning", "templating": {"list": [{"name": "datasource", "type": "datasource"}]},
+				"panels": [{"id": 1, "type": "graph"}]}`,
+			"error.json": `{"uid": "error", "title": "error", "panels": []}`,
+		},
+	}
+
+	testCaseList := []struct {
+		name     string
+		block    bool
+		expected int
+		err      bool
+	}{
+		{"reported only", false, 2, false},
+
+		{"blocked", true, 1, true},
+	}
+
+	for _, c := range testCaseList {
+		fake.dashboards = map[string]map[string]fakeDashboard{}
+		LintBlockOnError = c.block
+		ctx, applied := withAppliedDashboards(context.TODO())
+		err := updateDashboard(ctx, nil, cm, false)
+		if (err != nil) != c.err {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.err)
+		}
+		if len(fake.dashboards[""]) != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, len(fake.dashboards[""]), c.expected)
+		}
+		if len(applied.lint["warning.json"]) != 1 || len(applied.lint["error.json"]) != 1 {
+			t.Errorf("case (%v) output: (%v) does not have the findings of both dashboards", c.name, applied.lint)
+		}
+	}
+
+	status := dashboardStatus{Synced: true, Health: healthHealthy, Lint: map[string][]string{"a.json": {"finding"}}}
+	annotations := statusAnnotations(&corev1.ConfigMap{}, status)
+	if annotations[lintKey] != `{"a.json":["finding"]}` {
+		t.Errorf("case (lint annotation) output: (%v) is not the expected: (%v)", annotations[lintKey], `{"a.json":["finding"]}`)
+	}
+	status.Lint = nil
+	annotations = statusAnnotations(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{lintKey: "{}"}}}, status)
+	if v, ok := annotations[lintKey]; !ok || v != nil {
+		t.Errorf("case (lint annotation removed) output: (%v) is not the expected: (%v)", v, nil)
+	}
+}
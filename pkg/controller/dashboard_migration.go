@@ -0,0 +1,367 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// gridColumns is the width of the grid layout, the legacy rows were 12 spans wide
+	gridColumns = 24
+	// gridCellHeight is the height in pixels of a grid unit
+	gridCellHeight = 30
+	// defaultRowHeight is the height of the legacy rows without a height
+	defaultRowHeight = 250
+)
+
+// MigrateDashboards upgrades the legacy constructs of the old dashboards before they are validated and posted,
+// the schemaVersion is kept so that grafana still runs its own migrations of the remaining parts
+var MigrateDashboards = false
+
+// migrateDashboard upgrades the legacy rows, panels and template variables in place and reports what was transformed
+func migrateDashboard(dashboard map[string]interface{}) []string {
+	report := []string{}
+	report = append(report, migrateRows(dashboard)...)
+	for _, panel := range lintPanels(dashboard) {
+		switch panel["type"] {
+		case "graph":
+			report = append(report, migrateGraphPanel(panel)...)
+		case "singlestat", "grafana-singlestat-panel":
+			report = append(report, migrateSinglestatPanel(panel)...)
+		}
+	}
+	report = append(report, migrateTemplating(dashboard)...)
+	return report
+}
+
+// migrateRows turns the rows of the dashboards older than schemaVersion 16 into the grid layout,
+// the panels of a collapsed row are moved into its row panel
+func migrateRows(dashboard map[string]interface{}) []string {
+	rows, ok := dashboard["rows"].([]interface{})
+	if !ok {
+		return nil
+	}
+	delete(dashboard, "rows")
+	if len(rows) == 0 {
+		return nil
+	}
+
+	nextID := 1
+	for _, panel := range dashboardPanels(map[string]interface{}{"rows": rows, "panels": dashboard["panels"]}) {
+		if id, ok := panel["id"].(float64); ok && int(id) >= nextID {
+			nextID = int(id) + 1
+		}
+	}
+
+	panels, _ := dashboard["panels"].([]interface{})
+	y := 0
+	for _, item := range rows {
+		row, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rowHeight := gridHeight(row["height"], defaultRowHeight)
+		collapsed, _ := row["collapse"].(bool)
+		showTitle, _ := row["showTitle"].(bool)
+		var rowPanel map[string]interface{}
+		if len(rows) > 1 || showTitle {
+			rowPanel = map[string]interface{}{
+				"type":      "row",
+				"id":        float64(nextID),
+				"title":     row["title"],
+				"collapsed": collapsed,
+				"panels":    []interface{}{},
+				"gridPos":   map[string]interface{}{"x": float64(0), "y": float64(y), "w": float64(gridColumns), "h": float64(1)},
+			}
+			if repeat, ok := row["repeat"]; ok {
+				rowPanel["repeat"] = repeat
+			}
+			nextID++
+			panels = append(panels, rowPanel)
+			y++
+		}
+
+		x, lineHeight := 0, 0
+		rowPanels, _ := row["panels"].([]interface{})
+		for _, item := range rowPanels {
+			panel, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			span := 4.0
+			if s, ok := panel["span"].(float64); ok && s > 0 {
+				span = s
+			}
+			w := int(math.Min(span*gridColumns/12, gridColumns))
+			h := rowHeight
+			if panel["height"] != nil {
+				h = gridHeight(panel["height"], rowHeight*gridCellHeight)
+			}
+			if x+w > gridColumns {
+				x, y, lineHeight = 0, y+lineHeight, 0
+			}
+			panel["gridPos"] = map[string]interface{}{"x": float64(x), "y": float64(y), "w": float64(w), "h": float64(h)}
+			delete(panel, "span")
+			delete(panel, "height")
+			if panel["id"] == nil {
+				panel["id"] = float64(nextID)
+				nextID++
+			}
+			x += w
+			if h > lineHeight {
+				lineHeight = h
+			}
+			if rowPanel != nil && collapsed {
+				rowPanel["panels"] = append(rowPanel["panels"].([]interface{}), panel)
+			} else {
+				panels = append(panels, panel)
+			}
+		}
+		if !collapsed {
+			y += lineHeight
+		}
+	}
+	dashboard["panels"] = panels
+	return []string{fmt.Sprintf("the legacy rows (%v) are converted into the grid layout", len(rows))}
+}
+
+// gridHeight converts the legacy height in pixels, e.g. "250px" or 250, into grid units
+func gridHeight(height interface{}, defaultHeight int) int {
+	pixels := float64(defaultHeight)
+	switch v := height.(type) {
+	case float64:
+		pixels = v
+	case string:
+		if p, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(v), "px"), 64); err == nil {
+			pixels = p
+		}
+	}
+	h := int(math.Ceil(pixels / gridCellHeight))
+	if h < 3 {
+		h = 3
+	}
+	return h
+}
+
+// migrateGraphPanel turns the angular graph panel into the timeseries panel, the panels with a legacy alert
+// or series overrides are kept since the timeseries panel cannot express them the same way
+func migrateGraphPanel(panel map[string]interface{}) []string {
+	name := panelName(panel)
+	if panel["alert"] != nil {
+		return []string{fmt.Sprintf("panel %v is kept as graph since it has a legacy alert", name)}
+	}
+	if overrides, _ := panel["seriesOverrides"].([]interface{}); len(overrides) > 0 {
+		return []string{fmt.Sprintf("panel %v is kept as graph since it has series overrides", name)}
+	}
+
+	custom := map[string]interface{}{"drawStyle": "line", "showPoints": "never", "spanNulls": false,
+		"lineWidth": float64(1), "fillOpacity": float64(10)}
+	if bars, _ := panel["bars"].(bool); bars {
+		custom["drawStyle"] = "bars"
+	} else if points, _ := panel["points"].(bool); points {
+		if lines, ok := panel["lines"].(bool); ok && !lines {
+			custom["drawStyle"] = "points"
+		}
+		custom["showPoints"] = "always"
+	}
+	if width, ok := panel["linewidth"].(float64); ok {
+		custom["lineWidth"] = width
+	}
+	if fill, ok := panel["fill"].(float64); ok {
+		custom["fillOpacity"] = fill * 10
+	}
+	if stack, _ := panel["stack"].(bool); stack {
+		custom["stacking"] = map[string]interface{}{"mode": "normal", "group": "A"}
+	}
+	if nullPoint, _ := panel["nullPointMode"].(string); nullPoint == "connected" {
+		custom["spanNulls"] = true
+	}
+
+	defaults := map[string]interface{}{"custom": custom}
+	if yaxes, _ := panel["yaxes"].([]interface{}); len(yaxes) > 0 {
+		if yaxis, ok := yaxes[0].(map[string]interface{}); ok {
+			if format, _ := yaxis["format"].(string); format != "" && format != "short" {
+				defaults["unit"] = format
+			}
+			for _, field := range []string{"min", "max"} {
+				if value, ok := numberOf(yaxis[field]); ok {
+					defaults[field] = value
+				}
+			}
+			if label, _ := yaxis["label"].(string); label != "" {
+				custom["axisLabel"] = label
+			}
+		}
+	}
+	if decimals, ok := panel["decimals"].(float64); ok {
+		defaults["decimals"] = decimals
+	}
+
+	legend := map[string]interface{}{"displayMode": "list", "placement": "bottom", "calcs": []interface{}{}}
+	if l, ok := panel["legend"].(map[string]interface{}); ok {
+		if show, ok := l["show"].(bool); ok && !show {
+			legend["displayMode"] = "hidden"
+		} else if alignAsTable, _ := l["alignAsTable"].(bool); alignAsTable {
+			legend["displayMode"] = "table"
+		}
+		if rightSide, _ := l["rightSide"].(bool); rightSide {
+			legend["placement"] = "right"
+		}
+		calcs := []interface{}{}
+		for _, calc := range [][2]string{{"min", "min"}, {"max", "max"}, {"avg", "mean"}, {"current", "lastNotNull"}, {"total", "sum"}} {
+			if enabled, _ := l[calc[0]].(bool); enabled {
+				calcs = append(calcs, calc[1])
+			}
+		}
+		legend["calcs"] = calcs
+	}
+	tooltip := map[string]interface{}{"mode": "multi"}
+	if t, ok := panel["tooltip"].(map[string]interface{}); ok && t["shared"] == false {
+		tooltip["mode"] = "single"
+	}
+
+	fieldConfig, _ := panel["fieldConfig"].(map[string]interface{})
+	if fieldConfig == nil {
+		fieldConfig = map[string]interface{}{}
+	}
+	existing, _ := fieldConfig["defaults"].(map[string]interface{})
+	for key, value := range existing {
+		defaults[key] = value
+	}
+	fieldConfig["defaults"] = defaults
+	if fieldConfig["overrides"] == nil {
+		fieldConfig["overrides"] = []interface{}{}
+	}
+	panel["fieldConfig"] = fieldConfig
+	panel["options"] = map[string]interface{}{"legend": legend, "tooltip": tooltip}
+	panel["type"] = "timeseries"
+	for _, field := range []string{"bars", "lines", "points", "linewidth", "fill", "fillGradient", "stack", "percentage",
+		"nullPointMode", "steppedLine", "yaxes", "yaxis", "xaxis", "legend", "tooltip", "aliasColors", "dashes",
+		"dashLength", "spaceLength", "pointradius", "renderer", "seriesOverrides", "thresholds", "timeRegions", "decimals"} {
+		delete(panel, field)
+	}
+	return []string{fmt.Sprintf("panel %v is migrated from graph to timeseries", name)}
+}
+
+// migrateSinglestatPanel turns the removed singlestat panel into the stat panel
+func migrateSinglestatPanel(panel map[string]interface{}) []string {
+	defaults := map[string]interface{}{}
+	if format, _ := panel["format"].(string); format != "" && format != "none" {
+		defaults["unit"] = format
+	}
+	if decimals, ok := panel["decimals"].(float64); ok {
+		defaults["decimals"] = decimals
+	}
+
+	calc := "mean"
+	switch panel["valueName"] {
+	case "current":
+		calc = "lastNotNull"
+	case "max", "min", "first", "range", "diff":
+		calc = panel["valueName"].(string)
+	case "total":
+		calc = "sum"
+	case "name":
+		calc = "last"
+	}
+
+	// the colors of the singlestat are the ones of the base, the warning and the critical ranges
+	colors, _ := panel["colors"].([]interface{})
+	base := "green"
+	if len(colors) > 0 {
+		if c, ok := colors[0].(string); ok {
+			base = c
+		}
+	}
+	steps := []interface{}{map[string]interface{}{"color": base, "value": nil}}
+	thresholds, _ := panel["thresholds"].(string)
+	for i, threshold := range strings.Split(thresholds, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(threshold), 64)
+		if err != nil {
+			continue
+		}
+		color := []string{"orange", "red"}[int(math.Min(float64(i), 1))]
+		if len(colors) > i+1 {
+			if c, ok := colors[i+1].(string); ok {
+				color = c
+			}
+		}
+		steps = append(steps, map[string]interface{}{"color": color, "value": value})
+	}
+	defaults["thresholds"] = map[string]interface{}{"mode": "absolute", "steps": steps}
+
+	colorMode := "none"
+	if colorValue, _ := panel["colorValue"].(bool); colorValue {
+		colorMode = "value"
+	}
+	if colorBackground, _ := panel["colorBackground"].(bool); colorBackground {
+		colorMode = "background"
+	}
+	graphMode := "none"
+	if sparkline, ok := panel["sparkline"].(map[string]interface{}); ok && sparkline["show"] == true {
+		graphMode = "area"
+	}
+
+	panel["fieldConfig"] = map[string]interface{}{"defaults": defaults, "overrides": []interface{}{}}
+	panel["options"] = map[string]interface{}{
+		"reduceOptions": map[string]interface{}{"calcs": []interface{}{calc}, "fields": "", "values": false},
+		"colorMode":     colorMode,
+		"graphMode":     graphMode,
+		"justifyMode":   "auto",
+		"textMode":      "auto",
+		"orientation":   "auto",
+	}
+	name := panelName(panel)
+	from := panel["type"]
+	panel["type"] = "stat"
+	for _, field := range []string{"format", "decimals", "valueName", "thresholds", "colors", "colorValue",
+		"colorBackground", "sparkline", "gauge", "prefix", "postfix", "prefixFontSize", "postfixFontSize",
+		"valueFontSize", "valueMaps", "mappingType", "mappingTypes", "rangeMaps", "nullPointMode", "nullText",
+		"tableColumn", "cacheTimeout"} {
+		delete(panel, field)
+	}
+	return []string{fmt.Sprintf("panel %v is migrated from %v to stat", name, from)}
+}
+
+// migrateTemplating upgrades the variables of the old templating format,
+// the boolean refresh and the removed allFormat and multiFormat
+func migrateTemplating(dashboard map[string]interface{}) []string {
+	report := []string{}
+	for _, variable := range templateVariables(dashboard) {
+		changed := false
+		if refresh, ok := variable["refresh"].(bool); ok {
+			// false never refreshes the options, true refreshes them on the dashboard load
+			variable["refresh"] = float64(0)
+			if refresh {
+				variable["refresh"] = float64(1)
+			}
+			changed = true
+		}
+		for _, field := range []string{"allFormat", "multiFormat"} {
+			if _, ok := variable[field]; ok {
+				delete(variable, field)
+				changed = true
+			}
+		}
+		if changed {
+			report = append(report, fmt.Sprintf("variable %v is migrated from the old templating format", variable["name"]))
+		}
+	}
+	return report
+}
+
+func numberOf(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
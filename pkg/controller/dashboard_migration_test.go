@@ -0,0 +1,112 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMigrateDashboard(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		content  string
+		expected string
+		report   []string
+	}{
+		{
+			"current dashboard",
+			`{"title": "a", "panels": [{"id": 1, "type": "timeseries"}], "templating": {"list": [{"name": "a", "refresh": 1}]}}`,
+			`{"title": "a", "panels": [{"id": 1, "type": "timeseries"}], "templating": {"list": [{"name": "a", "refresh": 1}]}}`,
+			[]string{},
+		},
+
+		{
+			"legacy rows",
+			`{"title": "a", "rows": [
+				{"title": "CPU", "height": "300px", "panels": [
+					{"id": 1, "type": "text", "span": 6}, {"id": 2, "type": "text", "span": 8}, {"id": 3, "type": "text", "span": 4, "height": 120}]},
+				{"title": "Memory", "collapse": true, "panels": [{"type": "text", "span": 12}]}]}`,
+			`{"title": "a", "panels": [
+				{"id": 4, "type": "row", "title": "CPU", "collapsed": false, "panels": [], "gridPos": {"x": 0, "y": 0, "w": 24, "h": 1}},
+				{"id": 1, "type": "text", "gridPos": {"x": 0, "y": 1, "w": 12, "h": 10}},
+				{"id": 2, "type": "text", "gridPos": {"x": 0, "y": 11, "w": 16, "h": 10}},
+				{"id": 3, "type": "text", "gridPos": {"x": 16, "y": 11, "w": 8, "h": 4}},
+				{"id": 5, "type": "row", "title": "Memory", "collapsed": true, "gridPos": {"x": 0, "y": 21, "w": 24, "h": 1}, "panels": [
+					{"id": 6, "type": "text", "gridPos": {"x": 0, "y": 22, "w": 24, "h": 9}}]}]}`,
+			[]string{"the legacy rows (2) are converted into the grid layout"},
+		},
+
+		{
+			"single row without title",
+			`{"title": "a", "rows": [{"title": "Row", "panels": [{"id": 1, "type": "text", "span": 12}]}]}`,
+			`{"title": "a", "panels": [{"id": 1, "type": "text", "gridPos": {"x": 0, "y": 0, "w": 24, "h": 9}}]}`,
+			[]string{"the legacy rows (1) are converted into the grid layout"},
+		},
+
+		{
+			"graph panel",
+			`{"title": "a", "panels": [{"id": 1, "title": "CPU", "type": "graph", "lines": true, "linewidth": 2, "fill": 0, "stack": true,
+				"nullPointMode": "connected", "yaxes": [{"format": "percentunit", "min": "0", "max": null, "label": "usage"}, {"format": "short"}],
+				"legend": {"show": true, "alignAsTable": true, "rightSide": true, "avg": true, "current": true}, "tooltip": {"shared": false},
+				"targets": [{"refId": "A", "expr": "cpu"}]}]}`,
+			`{"title": "a", "panels": [{"id": 1, "title": "CPU", "type": "timeseries", "targets": [{"refId": "A", "expr": "cpu"}],
+				"fieldConfig": {"defaults": {"unit": "percentunit", "min": 0, "custom": {"drawStyle": "line", "showPoints": "never", "spanNulls": true,
+					"lineWidth": 2, "fillOpacity": 0, "stacking": {"mode": "normal", "group": "A"}, "axisLabel": "usage"}}, "overrides": []},
+				"options": {"legend": {"displayMode": "table", "placement": "right", "calcs": ["mean", "lastNotNull"]}, "tooltip": {"mode": "single"}}}]}`,
+			[]string{`panel "CPU" is migrated from graph to timeseries`},
+		},
+
+		{
+			"graph panel with alert",
+			`{"title": "a", "panels": [{"id": 1, "title": "CPU", "type": "graph", "alert": {"name": "high"}}]}`,
+			`{"title": "a", "panels": [{"id": 1, "title": "CPU", "type": "graph", "alert": {"name": "high"}}]}`,
+			[]string{`panel "CPU" is kept as graph since it has a legacy alert`},
+		},
+
+		{
+			"singlestat panel",
+			`{"title": "a", "panels": [{"id": 1, "title": "Up", "type": "singlestat", "format": "percent", "valueName": "current",
+				"thresholds": "50,80", "colors": ["#d44a3a", "rgba(237, 129, 40, 0.89)", "#299c46"], "colorBackground": true,
+				"sparkline": {"show": true}}]}`,
+			`{"title": "a", "panels": [{"id": 1, "title": "Up", "type": "stat",
+				"fieldConfig": {"defaults": {"unit": "percent", "thresholds": {"mode": "absolute", "steps": [
+					{"color": "#d44a3a", "value": null}, {"color": "rgba(237, 129, 40, 0.89)", "value": 50}, {"color": "#299c46", "value": 80}]}},
+					"overrides": []},
+				"options": {"reduceOptions": {"calcs": ["lastNotNull"], "fields": "", "values": false}, "colorMode": "background",
+					"graphMode": "area", "justifyMode": "auto", "textMode": "auto", "orientation": "auto"}}]}`,
+			[]string{`panel "Up" is migrated from singlestat to stat`},
+		},
+
+		{
+			"old templating",
+			`{"title": "a", "panels": [], "templating": {"list": [{"name": "a", "refresh": true, "allFormat": "glob", "multiFormat": "regex values"},
+				{"name": "b", "refresh": false}]}}`,
+			`{"title": "a", "panels": [], "templating": {"list": [{"name": "a", "refresh": 1}, {"name": "b", "refresh": 0}]}}`,
+			[]string{"variable a is migrated from the old templating format", "variable b is migrated from the old templating format"},
+		},
+	}
+
+	for _, c := range testCaseList {
+		dashboard, expected := map[string]interface{}{}, map[string]interface{}{}
+		if err := json.Unmarshal([]byte(c.content), &dashboard); err != nil {
+			t.Fatalf("case (%v) invalid dashboard: %v", c.name, err)
+		}
+		if err := json.Unmarshal([]byte(c.expected), &expected); err != nil {
+			t.Fatalf("case (%v) invalid expected dashboard: %v", c.name, err)
+		}
+		report := migrateDashboard(dashboard)
+		if !reflect.DeepEqual(report, c.report) {
+			t.Errorf("case (%v) output: (%q) is not the expected: (%q)", c.name, report, c.report)
+		}
+		// the numbers are compared after a round trip so that the ints and float64s are the same
+		b, _ := json.Marshal(dashboard)
+		output := map[string]interface{}{}
+		json.Unmarshal(b, &output)
+		if !reflect.DeepEqual(output, expected) {
+			t.Errorf("case (%v) output: (%s) is not the expected: (%v)", c.name, b, c.expected)
+		}
+	}
+}
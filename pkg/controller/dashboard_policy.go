@@ -0,0 +1,173 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+const (
+	// policyModeReject does not save the dashboards which violate the policy
+	policyModeReject = "reject"
+	// policyModeSanitize replaces the banned panels with text panels and removes the banned queries and variables
+	policyModeSanitize = "sanitize"
+)
+
+var (
+	// PolicyMode is what is done with the dashboards which violate the policy, reject or sanitize
+	PolicyMode = policyModeReject
+	// BannedPanelTypes are the panel plugins the dashboards must not use
+	BannedPanelTypes = []string{}
+	// BannedDatasourceTypes are the datasource plugins the panels, the queries and the variables must not use,
+	// the datasources referred by name in the old dashboards are not checked since their type is not known
+	BannedDatasourceTypes = []string{}
+)
+
+// ValidatePolicyMode checks the mode is a known one
+func ValidatePolicyMode(mode string) error {
+	if mode != policyModeReject && mode != policyModeSanitize {
+		return fmt.Errorf("invalid policy mode %q, it is either %v or %v", mode, policyModeReject, policyModeSanitize)
+	}
+	return nil
+}
+
+// hasPolicy checks whether anything is banned
+func hasPolicy() bool {
+	s := activeSettings()
+	return len(s.bannedPanelTypes) > 0 || len(s.bannedDatasourceTypes) > 0
+}
+
+// enforcePolicy returns the policy violations of the dashboard, they are removed from the dashboard when sanitize is set
+func enforcePolicy(dashboard map[string]interface{}, sanitize bool) []string {
+	s := activeSettings()
+	panelTypes, datasourceTypes := stringSet(s.bannedPanelTypes), stringSet(s.bannedDatasourceTypes)
+	violations := []string{}
+
+	var walk func(items interface{})
+	walk = func(items interface{}) {
+		list, _ := items.([]interface{})
+		for i, item := range list {
+			panel, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if panel["type"] == "row" {
+				walk(panel["panels"])
+				continue
+			}
+			reason := ""
+			if panelType, _ := panel["type"].(string); panelTypes[panelType] {
+				reason = fmt.Sprintf("the panel plugin %v is banned", panelType)
+			} else if dsType := datasourceType(panel["datasource"]); datasourceTypes[dsType] {
+				reason = fmt.Sprintf("the datasource type %v is banned", dsType)
+			}
+			if reason != "" {
+				violations = append(violations, fmt.Sprintf("panel %v: %v", panelName(panel), reason))
+				if sanitize {
+					list[i] = policyPlaceholder(panel, reason)
+				}
+				continue
+			}
+
+			targets, _ := panel["targets"].([]interface{})
+			allowed := []interface{}{}
+			for _, item := range targets {
+				target, _ := item.(map[string]interface{})
+				if dsType := datasourceType(target["datasource"]); datasourceTypes[dsType] {
+					violations = append(violations, fmt.Sprintf("panel %v query %v: the datasource type %v is banned",
+						panelName(panel), target["refId"], dsType))
+					continue
+				}
+				allowed = append(allowed, item)
+			}
+			if sanitize && len(allowed) != len(targets) {
+				panel["targets"] = allowed
+			}
+		}
+	}
+	walk(dashboard["panels"])
+	rows, _ := dashboard["rows"].([]interface{})
+	for _, row := range rows {
+		if row, ok := row.(map[string]interface{}); ok {
+			walk(row["panels"])
+		}
+	}
+
+	allowed := []interface{}{}
+	for _, variable := range templateVariables(dashboard) {
+		dsType := datasourceType(variable["datasource"])
+		if variable["type"] == "datasource" {
+			dsType, _ = variable["query"].(string)
+		}
+		if datasourceTypes[dsType] {
+			violations = append(violations, fmt.Sprintf("variable %v: the datasource type %v is banned", variable["name"], dsType))
+			continue
+		}
+		allowed = append(allowed, variable)
+	}
+	if sanitize && len(allowed) != len(templateVariables(dashboard)) {
+		dashboard["templating"].(map[string]interface{})["list"] = allowed
+	}
+	return violations
+}
+
+// checkPolicy enforces the policy on the dashboard, the returned error blocks the dashboard in the reject mode
+func checkPolicy(cm *corev1.ConfigMap, key string, dashboard map[string]interface{}) error {
+	if !hasPolicy() {
+		return nil
+	}
+	mode := activeSettings().policyMode
+	violations := enforcePolicy(dashboard, mode == policyModeSanitize)
+	if len(violations) == 0 {
+		return nil
+	}
+	metrics.PolicyViolations.WithLabelValues(mode).Add(float64(len(violations)))
+	recordEvent(cm, corev1.EventTypeWarning, reasonDashboardPolicyViolated, "The dashboard %v violates the policy (%v): %v",
+		key, mode, strings.Join(violations, "; "))
+	if mode == policyModeSanitize {
+		klog.InfoS("the dashboard is sanitized by the policy", "configmap", klog.KObj(cm), "key", key, "violations", violations)
+		return nil
+	}
+	return fmt.Errorf("the dashboard violates the policy: %v", strings.Join(violations, "; "))
+}
+
+// policyPlaceholder is the text panel which takes the place of a banned panel so that the layout is kept
+func policyPlaceholder(panel map[string]interface{}, reason string) map[string]interface{} {
+	placeholder := map[string]interface{}{
+		"type": "text",
+		"options": map[string]interface{}{
+			"mode":    "markdown",
+			"content": fmt.Sprintf("This panel is removed by the dashboard policy: %v.", reason),
+		},
+	}
+	for _, field := range []string{"id", "title", "gridPos"} {
+		if value, ok := panel[field]; ok {
+			placeholder[field] = value
+		}
+	}
+	return placeholder
+}
+
+// datasourceType returns the plugin type of the {"type", "uid"} datasource, it is empty for a named datasource
+func datasourceType(datasource interface{}) string {
+	ds, _ := datasource.(map[string]interface{})
+	dsType, _ := ds["type"].(string)
+	return dsType
+}
+
+func stringSet(values []string) map[string]bool {
+	set := map[string]bool{}
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			set[value] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const policyDashboard = `{"title": "a", "panels": [
+	{"id": 1, "type": "graph", "title": "CPU", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8}},
+	{"id": 2, "type": "row", "panels": [
+		{"id": 3, "type": "timeseries", "title": "Logs", "datasource": {"type": "loki", "uid": "logs"}},
+		{"id": 4, "type": "timeseries", "title": "Memory", "targets": [
+			{"refId": "A", "expr": "memory"}, {"refId": "B", "datasource": {"type": "loki", "uid": "logs"}, "expr": "{job=\"a\"}"}]}]}],
+	"templating": {"list": [{"name": "datasource", "type": "datasource", "query": "prometheus"},
+		{"name": "logs", "type": "datasource", "query": "loki"}]}}`
+
+func TestEnforcePolicy(t *testing.T) {
+	defer func() {
+		BannedPanelTypes = []string{}
+		BannedDatasourceTypes = []string{}
+	}()
+
+	testCaseList := []struct {
+		name        string
+		panelTypes  []string
+		datasources []string
+		sanitize    bool
+		expected    []string
+		sanitized   string
+	}{
+		{"allowed", []string{"singlestat"}, []string{"elasticsearch"}, true, []string{}, policyDashboard},
+
+		{
+			"banned panel",
+			[]string{"graph"},
+			nil,
+			true,
+			[]string{`panel "CPU": the panel plugin graph is banned`},
+			`{"title": "a", "panels": [
+				{"id": 1, "type": "text", "title": "CPU", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8},
+					"options": {"mode": "markdown", "content": "This panel is removed by the dashboard policy: the panel plugin graph is banned."}},
+				{"id": 2, "type": "row", "panels": [
+					{"id": 3, "type": "timeseries", "title": "Logs", "datasource": {"type": "loki", "uid": "logs"}},
+					{"id": 4, "type": "timeseries", "title": "Memory", "targets": [
+						{"refId": "A", "expr": "memory"}, {"refId": "B", "datasource": {"type": "loki", "uid": "logs"}, "expr": "{job=\"a\"}"}]}]}],
+				"templating": {"list": [{"name": "datasource", "type": "datasource", "query": "prometheus"},
+					{"name": "logs", "type": "datasource", "query": "loki"}]}}`,
+		},
+
+		{
+			"banned datasource",
+			nil,
+			[]string{"loki"},
+			true,
+			[]string{`panel "Logs": the datasource type loki is banned`, `panel "Memory" query B: the datasource type loki is banned`,
+				"variable logs: the datasource type loki is banned"},
+			`{"title": "a", "panels": [
+				{"id": 1, "type": "graph", "title": "CPU", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8}},
+				{"id": 2, "type": "row", "panels": [
+					{"id": 3, "type": "text", "title": "Logs",
+						"options": {"mode": "markdown", "content": "This panel is removed by the dashboard policy: the datasource type loki is banned."}},
+					{"id": 4, "type": "timeseries", "title": "Memory", "targets": [{"refId": "A", "expr": "memory"}]}]}],
+				"templating": {"list": [{"name": "datasource", "type": "datasource", "query": "prometheus"}]}}`,
+		},
+
+		{
+			"rejected",
+			[]string{"graph"},
+			[]string{"loki"},
+			false,
+			[]string{`panel "CPU": the panel plugin graph is banned`, `panel "Logs": the datasource type loki is banned`,
+				`panel "Memory" query B: the datasource type loki is banned`, "variable logs: the datasource type loki is banned"},
+			policyDashboard,
+		},
+	}
+
+	for _, c := range testCaseList {
+		BannedPanelTypes, BannedDatasourceTypes = c.panelTypes, c.datasources
+		dashboard, expected := map[string]interface{}{}, map[string]interface{}{}
+		if err := json.Unmarshal([]byte(policyDashboard), &dashboard); err != nil {
+			t.Fatalf("case (%v) invalid dashboard: %v", c.name, err)
+		}
+		if err := json.Unmarshal([]byte(c.sanitized), &expected); err != nil {
+			t.Fatalf("case (%v) invalid expected dashboard: %v", c.name, err)
+		}
+		if output := enforcePolicy(dashboard, c.sanitize); !reflect.DeepEqual(output, c.expected) {
+			t.Errorf("case (%v) output: (%q) is not the expected: (%q)", c.name, output, c.expected)
+		}
+		if !reflect.DeepEqual(dashboard, expected) {
+			b, _ := json.Marshal(dashboard)
+			t.Errorf("case (%v) output: (%s) is not the expected: (%v)", c.name, b, c.sanitized)
+		}
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func() {
+		PolicyMode = policyModeReject
+		BannedPanelTypes = []string{}
+	}()
+	BannedPanelTypes = []string{"graph"}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+		Data: map[string]string{
+			"allowed.json": `{"uid": "allowed", "title": "allowed", "panels": [{"id": 1, "type": "timeseries"}]}`,
+			"banned.json":  `{"uid": "banned", "title": "banned", "panels": [{"id": 1, "type": "graph"}]}`,
+		},
+	}
+
+	testCaseList := []struct {
+		name     string
+		mode     string
+		expected int
+		err      bool
+	}{
+		{"rejected", policyModeReject, 1, true},
+
+		{"sanitized", policyModeSanitize, 2, false},
+	}
+
+	for _, c := range testCaseList {
+		fake.dashboards = map[string]map[string]fakeDashboard{}
+		PolicyMode = c.mode
+		err := updateDashboard(context.TODO(), nil, cm, false)
+		if (err != nil) != c.err || err != nil && !strings.HasPrefix(err.Error(), "banned.json: ") {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.err)
+		}
+		if len(fake.dashboards[""]) != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, len(fake.dashboards[""]), c.expected)
+		}
+	}
+	if err := ValidatePolicyMode("drop"); err == nil {
+		t.Errorf("case (invalid mode) the mode drop should be rejected")
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+var (
+	// ValidatePromQL parses the queries of the prometheus targets and rejects the dashboards with invalid queries
+	ValidatePromQL = false
+	// InvalidPromQLSelectors are the metric selectors which must not be queried,
+	// e.g. the metrics which are dropped by the allowlist of the observability addon
+	InvalidPromQLSelectors = []string{}
+)
+
+// grafanaVariablePattern matches the template variables in the $var, ${var}, ${var:format} and [[var]] syntax
+var grafanaVariablePattern = regexp.MustCompile(`\$\{([a-zA-Z0-9_]+)(?::[^}]*)?\}|\[\[([a-zA-Z0-9_]+)(?::[^\]]*)?\]\]|\$([a-zA-Z0-9_]+)`)
+
+// expandGrafanaVariables replaces the template variables with the values which keep the query parseable,
+// the interval variables are durations, the time ones are numbers and the others are identifiers
+// which are valid as the metric names, label names and in the label values
+func expandGrafanaVariables(expr string) string {
+	return grafanaVariablePattern.ReplaceAllStringFunc(expr, func(variable string) string {
+		match := grafanaVariablePattern.FindStringSubmatch(variable)
+		name := match[1] + match[2] + match[3]
+		switch name {
+		case "__interval", "__rate_interval", "__range", "__auto_interval":
+			return "5m"
+		case "__interval_ms", "__range_ms", "__range_s", "__from", "__to":
+			return "300"
+		}
+		if strings.HasPrefix(name, "__auto_interval_") {
+			return "5m"
+		}
+		return "grafana_variable"
+	})
+}
+
+// invalidSelectors parses the configured selectors, the broken ones are returned as errors
+func invalidSelectors() ([][]*labels.Matcher, error) {
+	selectors := [][]*labels.Matcher{}
+	for _, selector := range InvalidPromQLSelectors {
+		matchers, err := parser.ParseMetricSelector(strings.TrimSpace(selector))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %v: %v", selector, err)
+		}
+		selectors = append(selectors, matchers)
+	}
+	return selectors, nil
+}
+
+// validatePromQL parses the queries of all the prometheus targets of the dashboard, all the problems are returned in one error
+func validatePromQL(dashboard map[string]interface{}) error {
+	selectors, err := invalidSelectors()
+	if err != nil {
+		return err
+	}
+	problems := []string{}
+	for _, panel := range lintPanels(dashboard) {
+		for _, target := range lintTargets(panel) {
+			expr, _ := target["expr"].(string)
+			if strings.TrimSpace(expr) == "" || !isPrometheusTarget(panel, target) {
+				continue
+			}
+			query := fmt.Sprintf("panel %v query %v", panelName(panel), target["refId"])
+			parsed, err := parser.ParseExpr(expandGrafanaVariables(expr))
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%v: invalid promql: %v", query, err))
+				continue
+			}
+			parser.Inspect(parsed, func(node parser.Node, _ []parser.Node) error {
+				vector, ok := node.(*parser.VectorSelector)
+				if !ok {
+					return nil
+				}
+				for i, selector := range selectors {
+					if selectorMatches(selector, vector.LabelMatchers) {
+						problems = append(problems, fmt.Sprintf("%v: %v matches the invalid selector %v", query, vector, InvalidPromQLSelectors[i]))
+					}
+				}
+				return nil
+			})
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the dashboard has invalid queries: %v", strings.Join(problems, "; "))
+}
+
+// selectorMatches checks whether the queried series are covered by the invalid selector,
+// that is every label of the invalid selector is queried with an equality which the selector matches
+func selectorMatches(selector []*labels.Matcher, queried []*labels.Matcher) bool {
+	for _, matcher := range selector {
+		found := false
+		for _, q := range queried {
+			if q.Name == matcher.Name && q.Type == labels.MatchEqual && matcher.Matches(q.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return len(selector) > 0
+}
+
+// isPrometheusTarget checks the datasource type of the target or the panel, the targets of the other
+// datasources with an expr, e.g. loki, are skipped while the ones with an unknown type are parsed
+func isPrometheusTarget(panel map[string]interface{}, target map[string]interface{}) bool {
+	for _, datasource := range []interface{}{target["datasource"], panel["datasource"]} {
+		if ds, ok := datasource.(map[string]interface{}); ok {
+			if dsType, _ := ds["type"].(string); dsType != "" {
+				return dsType == "prometheus"
+			}
+		}
+	}
+	return true
+}
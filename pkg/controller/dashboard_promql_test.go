@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidatePromQL(t *testing.T) {
+	defer func(selectors []string) { InvalidPromQLSelectors = selectors }(InvalidPromQLSelectors)
+
+	testCaseList := []struct {
+		name       string
+		expr       string
+		datasource string
+		selectors  []string
+		expected   string
+	}{
+		{"valid", `sum(rate(cpu_seconds_total{cluster="$cluster"}[5m])) by (pod)`, "", nil, ""},
+
+		{"grafana variables", `sum by ($groupby) (rate(${metric}{namespace=~"[[namespace]]"}[$__rate_interval])) / $__range_s`, "", nil, ""},
+
+		{"syntax error", `sum(rate(cpu_seconds_total[5m])`, "", nil, "invalid promql"},
+
+		{"bad duration", `rate(cpu_seconds_total[5])`, "", nil, "invalid promql"},
+
+		{"loki target", `{app="a"} |= "error"`, `{"type": "loki", "uid": "loki"}`, nil, ""},
+
+		{"prometheus target", `{app="a"} |= "error"`, `{"type": "prometheus", "uid": "$datasource"}`, nil, "invalid promql"},
+
+		{"invalid metric", `sum(dropped_metric{cluster="a"}) + up`, "", []string{"dropped_metric"},
+			`dropped_metric{cluster="a"} matches the invalid selector dropped_metric`},
+
+		{"invalid label", `up{job="old"} or up{job="new"}`, "", []string{`up{job=~"old|legacy"}`},
+			`up{job="old"} matches the invalid selector up{job=~"old|legacy"}`},
+
+		{"not covered by the selector", `up{job=~"old"}`, "", []string{`up{job="old"}`}, ""},
+
+		{"broken selector", `up`, "", []string{`up{`}, "invalid selector"},
+	}
+
+	for _, c := range testCaseList {
+		InvalidPromQLSelectors = c.selectors
+		target := map[string]interface{}{"refId": "A", "expr": c.expr}
+		if c.datasource != "" {
+			datasource := map[string]interface{}{}
+			json.Unmarshal([]byte(c.datasource), &datasource)
+			target["datasource"] = datasource
+		}
+		dashboard := map[string]interface{}{"panels": []interface{}{
+			map[string]interface{}{"id": 1, "type": "graph", "targets": []interface{}{target}},
+		}}
+		output := ""
+		if err := validatePromQL(dashboard); err != nil {
+			output = err.Error()
+		}
+		if (c.expected == "") != (output == "") || !strings.Contains(output, c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestValidatePromQLBeforeSave(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func() { ValidatePromQL = false }()
+	ValidatePromQL = true
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+		Data: map[string]string{
+			"valid.json":   `{"uid": "valid", "title": "valid", "panels": [{"id": 1, "type": "graph", "targets": [{"refId": "A", "expr": "up"}]}]}`,
+			"invalid.json": `{"uid": "invalid", "title": "invalid", "panels": [{"id": 1, "type": "graph", "targets": [{"refId": "A", "expr": "sum(up"}]}]}`,
+		},
+	}
+	err := updateDashboard(context.TODO(), nil, cm, false)
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid.json: ") {
+		t.Errorf("case (invalid query) error: (%v) does not name the key", err)
+	}
+	if _, ok := fake.dashboards[""]["valid"]; !ok || len(fake.dashboards[""]) != 1 {
+		t.Errorf("case (valid query) output: (%v) is not the expected: (%v)", len(fake.dashboards[""]), 1)
+	}
+}
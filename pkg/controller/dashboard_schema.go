@@ -0,0 +1,158 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	// the schema is bundled into the binary
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// dashboardSchemaJSON is the subset of the json schema of the grafana dashboard which is checked,
+// only type, required, properties, items, minLength and the local $ref are supported
+//
+//go:embed dashboard_schema.json
+var dashboardSchemaJSON []byte
+
+// jsonSchema is a node of the bundled schema
+type jsonSchema struct {
+	Type        schemaTypes            `json:"type"`
+	Required    []string               `json:"required"`
+	Properties  map[string]*jsonSchema `json:"properties"`
+	Items       *jsonSchema            `json:"items"`
+	MinLength   int                    `json:"minLength"`
+	Ref         string                 `json:"$ref"`
+	Definitions map[string]*jsonSchema `json:"definitions"`
+}
+
+// schemaTypes is the type of the schema node, it is a string or a list of strings in the schema
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(b, &multiple); err != nil {
+		return err
+	}
+	*t = schemaTypes(multiple)
+	return nil
+}
+
+var dashboardSchema = mustLoadSchema(dashboardSchemaJSON)
+
+func mustLoadSchema(b []byte) *jsonSchema {
+	schema := &jsonSchema{}
+	if err := json.Unmarshal(b, schema); err != nil {
+		panic(fmt.Sprintf("invalid bundled dashboard schema: %v", err))
+	}
+	return schema
+}
+
+// validateDashboardSchema checks the dashboard against the bundled schema, all the problems are returned in one error
+func validateDashboardSchema(dashboard map[string]interface{}) error {
+	problems := dashboardSchema.validate(dashboardSchema, "", dashboard)
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the dashboard does not match the grafana schema: %v", strings.Join(problems, "; "))
+}
+
+func (s *jsonSchema) validate(root *jsonSchema, path string, value interface{}) []string {
+	if s.Ref != "" {
+		ref := root.Definitions[strings.TrimPrefix(s.Ref, "#/definitions/")]
+		if ref == nil {
+			return []string{fmt.Sprintf("%v: unknown schema %v", pathOrRoot(path), s.Ref)}
+		}
+		return ref.validate(root, path, value)
+	}
+
+	if len(s.Type) > 0 && !s.Type.matches(value) {
+		return []string{fmt.Sprintf("%v: expected %v, got %v", pathOrRoot(path), strings.Join(s.Type, " or "), jsonType(value))}
+	}
+
+	problems := []string{}
+	switch v := value.(type) {
+	case string:
+		if len(v) < s.MinLength {
+			problems = append(problems, fmt.Sprintf("%v: must not be empty", pathOrRoot(path)))
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				problems = append(problems, s.Items.validate(root, fmt.Sprintf("%v[%v]", path, i), item)...)
+			}
+		}
+	case map[string]interface{}:
+		for _, field := range s.Required {
+			if _, ok := v[field]; !ok {
+				problems = append(problems, fmt.Sprintf("%v: missing required field", joinPath(path, field)))
+			}
+		}
+		fields := []string{}
+		for field := range s.Properties {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			if fieldValue, ok := v[field]; ok {
+				problems = append(problems, s.Properties[field].validate(root, joinPath(path, field), fieldValue)...)
+			}
+		}
+	}
+	return problems
+}
+
+func (t schemaTypes) matches(value interface{}) bool {
+	actual := jsonType(value)
+	for _, expected := range t {
+		if expected == actual || (expected == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the json schema type of the unmarshalled value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func joinPath(path string, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "dashboard"
+	}
+	return path
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/features"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateDashboardSchema(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"valid dashboard", `{"title": "a", "uid": null, "schemaVersion": 27, "refresh": false, "panels": [
+			{"id": 1, "type": "graph", "gridPos": {"h": 8, "w": 12, "x": 0, "y": 0}, "targets": [{"refId": "A", "expr": "up"}]},
+			{"id": 2, "type": "row", "panels": [{"id": 3, "type": "stat"}]}]}`, ""},
+
+		{"unknown fields", `{"title": "a", "schemaVersion": 27, "panels": [], "custom": {"a": 1}}`, ""},
+
+		{"missing fields", `{"title": "a"}`,
+			"panels: missing required field; schemaVersion: missing required field"},
+
+		{"empty title", `{"title": "", "schemaVersion": 27, "panels": []}`, "title: must not be empty"},
+
+		{"wrong types", `{"title": "a", "schemaVersion": "27", "tags": ["a", 1], "panels": []}`,
+			"schemaVersion: expected integer, got string; tags[1]: expected string, got integer"},
+
+		{"fractional integer", `{"title": "a", "schemaVersion": 27.5, "panels": []}`,
+			"schemaVersion: expected integer, got number"},
+
+		{"invalid panels", `{"title": "a", "schemaVersion": 27, "panels": [
+			{"id": 1, "type": "graph", "gridPos": {"h": "8", "w": 12, "x": 0}},
+			{"id": 2, "type": "row", "panels": [{"id": 3}]}]}`,
+			"panels[0].gridPos.y: missing required field; panels[0].gridPos.h: expected integer, got string; panels[1].panels[0].type: missing required field"},
+
+		{"invalid variables", `{"title": "a", "schemaVersion": 27, "panels": [], "templating": {"list": [{"type": "query"}]}}`,
+			"templating.list[0].name: missing required field"},
+
+		{"not an object", `{"title": "a", "schemaVersion": 27, "panels": [], "time": "now"}`,
+			"time: expected object, got string"},
+	}
+
+	for _, c := range testCaseList {
+		dashboard := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(c.content), &dashboard); err != nil {
+			t.Fatalf("case (%v) invalid dashboard: %v", c.name, err)
+		}
+		output := ""
+		if err := validateDashboardSchema(dashboard); err != nil {
+			output = strings.TrimPrefix(err.Error(), "the dashboard does not match the grafana schema: ")
+		}
+		if output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestSchemaValidationFeature(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+		Data: map[string]string{
+			"valid.json":   `{"uid": "valid", "title": "valid", "schemaVersion": 27, "panels": []}`,
+			"invalid.json": `{"uid": "invalid", "title": "invalid", "panels": [{"id": 1}]}`,
+		},
+	}
+
+	testCaseList := []struct {
+		name     string
+		enabled  bool
+		expected int
+		err      bool
+	}{
+		{"disabled", false, 2, false},
+
+		{"enabled", true, 1, true},
+	}
+
+	for _, c := range testCaseList {
+		fake.dashboards = map[string]map[string]fakeDashboard{}
+		if err := features.DefaultMutableFeatureGate.Set(fmt.Sprintf("DashboardSchemaValidation=%v", c.enabled)); err != nil {
+			t.Fatalf("failed to set the feature gate: %v", err)
+		}
+		err := updateDashboard(context.TODO(), nil, cm, false)
+		if (err != nil) != c.err {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.err)
+		}
+		if err != nil && !strings.HasPrefix(err.Error(), "invalid.json: ") {
+			t.Errorf("case (%v) error: (%v) does not name the key", c.name, err)
+		}
+		if len(fake.dashboards[""]) != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, len(fake.dashboards[""]), c.expected)
+		}
+	}
+	features.DefaultMutableFeatureGate.Set("DashboardSchemaValidation=false")
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"fmt"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+const (
+	// maxConfigmapSize is the limit of etcd on the size of an object
+	maxConfigmapSize = 1 << 20
+
+	sizeLimitGrafana   = "grafana-request"
+	sizeLimitConfigmap = "configmap"
+)
+
+// MaxDashboardSize is the limit of the request body of grafana, or of the proxy in front of it,
+// the larger dashboards are not posted, 0 means no limit
+var MaxDashboardSize = 10 << 20
+
+// checkDashboardSize rejects the dashboard which is larger than the request body limit of grafana
+func checkDashboardSize(dashboard map[string]interface{}) error {
+	if MaxDashboardSize <= 0 {
+		return nil
+	}
+	size, err := encodedSize(dashboard)
+	if err != nil {
+		return err
+	}
+	if size <= MaxDashboardSize {
+		return nil
+	}
+	return oversizedDashboardError(size)
+}
+
+// oversizedDashboardError tells how to get the dashboard into grafana, size 0 means the size is unknown
+func oversizedDashboardError(size int) error {
+	metrics.DashboardsOversized.WithLabelValues(sizeLimitGrafana).Inc()
+	if size == 0 {
+		return fmt.Errorf("the dashboard is rejected by grafana as too large, " +
+			"remove the unused panels and inline data or raise the request body limit of grafana and its proxy")
+	}
+	return fmt.Errorf("the dashboard is %v which is larger than the %v limit of the grafana requests, "+
+		"remove the unused panels and inline data or raise --max-dashboard-size together with the limit of grafana and its proxy",
+		formatBytes(size), formatBytes(MaxDashboardSize))
+}
+
+// checkConfigmapSize rejects the configmap data which is larger than the limit of etcd
+func checkConfigmapSize(size int) error {
+	if size <= maxConfigmapSize {
+		return nil
+	}
+	metrics.DashboardsOversized.WithLabelValues(sizeLimitConfigmap).Inc()
+	return fmt.Errorf("the data is %v which is larger than the %v limit of a configmap, "+
+		"compress the dashboards into binaryData as .json.gz or split them into more configmaps",
+		formatBytes(size), formatBytes(maxConfigmapSize))
+}
+
+func formatBytes(size int) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1fMiB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1fKiB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%vB", size)
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+func TestDashboardSize(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func(size int) { MaxDashboardSize = size }(MaxDashboardSize)
+	MaxDashboardSize = 2048
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+		Data: map[string]string{
+			"small.json": `{"uid": "small", "title": "small", "panels": []}`,
+			"large.json": `{"uid": "large", "title": "large", "description": "` + strings.Repeat("a", 4096) + `", "panels": []}`,
+			"proxy.json": `{"uid": "proxy", "title": "proxy", "panels": []}`,
+		},
+	}
+	fake.saveErrs = map[string]error{"proxy": &GrafanaAPIError{"POST", "/api/dashboards/db", 413, nil}}
+	defer func() { fake.saveErrs = nil }()
+	grafanaRejected := testutil.ToFloat64(metrics.DashboardsOversized.WithLabelValues(sizeLimitGrafana))
+
+	err := updateDashboard(context.TODO(), nil, cm, false)
+	if err == nil || !strings.Contains(err.Error(), "too large") && !strings.Contains(err.Error(), "larger than the 2.0KiB limit") {
+		t.Errorf("case (oversized) error: (%v) does not tell the limit", err)
+	}
+	if _, ok := fake.dashboards[""]["small"]; !ok || len(fake.dashboards[""]) != 1 {
+		t.Errorf("case (small) output: (%v) is not the expected: (%v)", len(fake.dashboards[""]), 1)
+	}
+	if output := testutil.ToFloat64(metrics.DashboardsOversized.WithLabelValues(sizeLimitGrafana)) - grafanaRejected; output != 2 {
+		t.Errorf("case (metrics) output: (%v) is not the expected: (%v)", output, 2)
+	}
+
+	testCaseList := []struct {
+		name     string
+		size     int
+		expected string
+	}{
+		{"fits", maxConfigmapSize, ""},
+
+		{"too large", 3 << 20, "the data is 3.0MiB which is larger than the 1.0MiB limit of a configmap"},
+	}
+
+	for _, c := range testCaseList {
+		output := ""
+		if err := checkConfigmapSize(c.size); err != nil {
+			output = err.Error()
+		}
+		if !strings.HasPrefix(output, c.expected) || (c.expected == "") != (output == "") {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestExportLargeConfigmap(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		dashboard  string
+		compressed bool
+	}{
+		{"small", `{"title": "a"}`, false},
+
+		{"compressed", `{"title": "a", "description": "` + strings.Repeat("a", maxConfigmapSize) + `"}`, true},
+	}
+
+	for _, c := range testCaseList {
+		cm, err := dashboardConfigmap("a", "SLOs", c.dashboard, ExportOptions{})
+		if err != nil {
+			t.Fatalf("case (%v) failed to build the configmap: %v", c.name, err)
+		}
+		if output := len(cm.BinaryData) == 1; output != c.compressed {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.compressed)
+		}
+		dashboards, err := getDashboardData(context.TODO(), cm)
+		if err != nil || dashboards["a.json"] != c.dashboard && dashboards["a.json.gz"] != c.dashboard {
+			t.Errorf("case (%v) the dashboard cannot be loaded from the configmap: %v", c.name, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/version"
+)
+
+const (
+	// dashboardTagsKey is the annotation listing the comma separated tags to add to the dashboards
+	dashboardTagsKey = "observability.open-cluster-management.io/dashboard-tags"
+	// provisionedByTagPrefix followed by the version of the loader is the tag of the dashboards applied by the loader
+	provisionedByTagPrefix = "provisioned-by:grafana-dashboard-loader/"
+)
+
+// ProvisionedByTag adds the provisioned-by tag with the version of the loader to the dashboards
+var ProvisionedByTag = true
+
+// getConfigmapTags returns the tags in the tags annotation of the configmap
+func getConfigmapTags(cm *corev1.ConfigMap) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(cm.GetAnnotations()[dashboardTagsKey], ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// mergeDashboardTags appends the tags which are not in the dashboard tags yet
+func mergeDashboardTags(dashboard map[string]interface{}, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+
+	merged := []interface{}{}
+	existing := map[string]bool{}
+	if current, ok := dashboard["tags"].([]interface{}); ok {
+		for _, tag := range current {
+			merged = append(merged, tag)
+			if s, ok := tag.(string); ok {
+				existing[s] = true
+			}
+		}
+	}
+	for _, tag := range tags {
+		if !existing[tag] {
+			merged = append(merged, tag)
+			existing[tag] = true
+		}
+	}
+	dashboard["tags"] = merged
+}
+
+// setProvisionedByTag tags the dashboard with the version of the loader, the tags of the former versions are replaced
+func setProvisionedByTag(dashboard map[string]interface{}) {
+	removeProvisionedByTag(dashboard)
+	tags, _ := dashboard["tags"].([]interface{})
+	dashboard["tags"] = append(tags, provisionedByTagPrefix+version.Version)
+}
+
+// removeProvisionedByTag drops the provisioned-by tags so that the dashboard can be applied by any version again
+func removeProvisionedByTag(dashboard map[string]interface{}) {
+	current, ok := dashboard["tags"].([]interface{})
+	if !ok {
+		return
+	}
+	tags := []interface{}{}
+	for _, tag := range current {
+		if s, ok := tag.(string); ok && strings.HasPrefix(s, provisionedByTagPrefix) {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	dashboard["tags"] = tags
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/version"
+)
+
+func TestMergeDashboardTags(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		annotation string
+		dashboard  map[string]interface{}
+		expected   interface{}
+	}{
+
+		{
+			"no annotation",
+			"",
+			map[string]interface{}{},
+			nil,
+		},
+
+		{
+			"no tags in dashboard",
+			"acm, networking,,",
+			map[string]interface{}{},
+			[]interface{}{"acm", "networking"},
+		},
+
+		{
+			"existing tags",
+			"acm,networking",
+			map[string]interface{}{"tags": []interface{}{"networking", "k8s"}},
+			[]interface{}{"networking", "k8s", "acm"},
+		},
+	}
+
+	for _, c := range testCaseList {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Namespace:   "test",
+				Annotations: map[string]string{dashboardTagsKey: c.annotation},
+			},
+		}
+		mergeDashboardTags(c.dashboard, getConfigmapTags(cm))
+		output := c.dashboard["tags"]
+		if !reflect.DeepEqual(output, c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestSetProvisionedByTag(t *testing.T) {
+	tag := provisionedByTagPrefix + version.Version
+	testCaseList := []struct {
+		name      string
+		dashboard map[string]interface{}
+		expected  interface{}
+	}{
+		{
+			"no tags",
+			map[string]interface{}{},
+			[]interface{}{tag},
+		},
+
+		{
+			"former version",
+			map[string]interface{}{"tags": []interface{}{"acm", provisionedByTagPrefix + "2.2.0"}},
+			[]interface{}{"acm", tag},
+		},
+	}
+
+	for _, c := range testCaseList {
+		setProvisionedByTag(c.dashboard)
+		output := c.dashboard["tags"]
+		if !reflect.DeepEqual(output, c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+
+	dashboard := map[string]interface{}{"tags": []interface{}{"acm", tag}}
+	removeProvisionedByTag(dashboard)
+	if !reflect.DeepEqual(dashboard["tags"], []interface{}{"acm"}) {
+		t.Errorf("case (remove) output: (%v) is not the expected: ([acm])", dashboard["tags"])
+	}
+}
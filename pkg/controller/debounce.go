@@ -0,0 +1,102 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+// UpdateDebounceWindow is how long the updates of a configmap are coalesced before the last one is applied,
+// e.g. to post a single dashboard version during a helm upgrade, 0 applies every update at once
+var UpdateDebounceWindow time.Duration
+
+// pendingUpdate is the coalesced update of an object, old is the object before the first update of the window
+type pendingUpdate struct {
+	old, new interface{}
+	timer    *time.Timer
+}
+
+// updateDebouncer delays the updates of the handler by the window, the events are still passed on one by one
+// like the informer delivers them
+type updateDebouncer struct {
+	handler cache.ResourceEventHandler
+	window  time.Duration
+	lock    sync.Mutex
+	pending map[string]*pendingUpdate
+	// handling serializes the delayed updates with the other events
+	handling sync.Mutex
+}
+
+// debounceUpdates returns the handler which coalesces the updates of the same object within the window,
+// the adds and the deletes are handled at once and a delete drops the pending update
+func debounceUpdates(window time.Duration, handler cache.ResourceEventHandler) cache.ResourceEventHandler {
+	if window <= 0 {
+		return handler
+	}
+	return &updateDebouncer{handler: handler, window: window, pending: map[string]*pendingUpdate{}}
+}
+
+func (d *updateDebouncer) OnAdd(obj interface{}) {
+	d.handling.Lock()
+	defer d.handling.Unlock()
+	d.handler.OnAdd(obj)
+}
+
+func (d *updateDebouncer) OnUpdate(old, new interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(new)
+	if err != nil {
+		klog.ErrorS(err, "failed to debounce the update, it is handled at once")
+		d.handling.Lock()
+		defer d.handling.Unlock()
+		d.handler.OnUpdate(old, new)
+		return
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if update, ok := d.pending[key]; ok {
+		metrics.CoalescedUpdates.Inc()
+		klog.V(4).InfoS("the update is coalesced with the pending one", "key", key)
+		update.new = new
+		update.timer.Reset(d.window)
+		return
+	}
+	update := &pendingUpdate{old: old, new: new}
+	update.timer = time.AfterFunc(d.window, func() { d.fire(key, update) })
+	d.pending[key] = update
+}
+
+// fire handles the last update of the window unless it is dropped by a delete
+func (d *updateDebouncer) fire(key string, update *pendingUpdate) {
+	d.handling.Lock()
+	defer d.handling.Unlock()
+	d.lock.Lock()
+	if d.pending[key] != update {
+		d.lock.Unlock()
+		return
+	}
+	delete(d.pending, key)
+	old, new := update.old, update.new
+	d.lock.Unlock()
+	d.handler.OnUpdate(old, new)
+}
+
+func (d *updateDebouncer) OnDelete(obj interface{}) {
+	if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+		d.lock.Lock()
+		if update, ok := d.pending[key]; ok {
+			update.timer.Stop()
+			delete(d.pending, key)
+		}
+		d.lock.Unlock()
+	}
+	d.handling.Lock()
+	defer d.handling.Unlock()
+	d.handler.OnDelete(obj)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestDebounceUpdates(t *testing.T) {
+	version := func(name string, v string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", ResourceVersion: v}}
+	}
+
+	testCaseList := []struct {
+		name     string
+		events   func(handler cache.ResourceEventHandler)
+		expected string
+	}{
+		{"coalesced", func(handler cache.ResourceEventHandler) {
+			handler.OnUpdate(version("a", "1"), version("a", "2"))
+			handler.OnUpdate(version("a", "2"), version("a", "3"))
+			handler.OnUpdate(version("a", "3"), version("a", "4"))
+		}, "update a 1->4"},
+
+		{"different configmaps", func(handler cache.ResourceEventHandler) {
+			handler.OnUpdate(version("a", "1"), version("a", "2"))
+			handler.OnUpdate(version("b", "1"), version("b", "2"))
+		}, "update a 1->2,update b 1->2"},
+
+		{"deleted", func(handler cache.ResourceEventHandler) {
+			handler.OnUpdate(version("a", "1"), version("a", "2"))
+			handler.OnDelete(version("a", "2"))
+		}, "delete a"},
+
+		{"added", func(handler cache.ResourceEventHandler) {
+			handler.OnAdd(version("a", "1"))
+		}, "add a"},
+	}
+
+	for _, c := range testCaseList {
+		lock := sync.Mutex{}
+		events := []string{}
+		record := func(event string) {
+			lock.Lock()
+			defer lock.Unlock()
+			events = append(events, event)
+		}
+		handler := debounceUpdates(50*time.Millisecond, cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) { record("add " + obj.(*corev1.ConfigMap).Name) },
+			UpdateFunc: func(old, new interface{}) {
+				record("update " + new.(*corev1.ConfigMap).Name + " " + old.(*corev1.ConfigMap).ResourceVersion +
+					"->" + new.(*corev1.ConfigMap).ResourceVersion)
+			},
+			DeleteFunc: func(obj interface{}) { record("delete " + obj.(*corev1.ConfigMap).Name) },
+		})
+		c.events(handler)
+		time.Sleep(200 * time.Millisecond)
+		lock.Lock()
+		output := events
+		lock.Unlock()
+		if len(output) == 2 && output[0] > output[1] {
+			output = []string{output[1], output[0]}
+		}
+		if strings.Join(output, ",") != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	rpprof "runtime/pprof"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+var (
+	// EnableDebug exposes the pprof handlers and the dump trigger on the admin api
+	EnableDebug = false
+	// DebugDumpDir is the directory to write the goroutine and heap dumps to
+	DebugDumpDir = os.TempDir()
+)
+
+// registerDebugHandlers adds the pprof handlers and the dump trigger to the admin api
+func registerDebugHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/api/v1/debug/dump", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		profile := req.URL.Query().Get("profile")
+		if profile == "" {
+			profile = "goroutine"
+		}
+		file, err := writeDump(DebugDumpDir, profile)
+		if err != nil {
+			klog.Errorf("failed to dump %v: %v", profile, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		klog.Infof("%v is dumped to %v", profile, file)
+		writeJSON(w, http.StatusOK, map[string]string{"file": file})
+	})
+}
+
+// writeDump writes the goroutine or heap profile into a new file in dir,
+// the goroutines are written as the stack traces so that they can be read without the pprof tool
+func writeDump(dir string, profile string) (string, error) {
+	if profile != "goroutine" && profile != "heap" {
+		return "", fmt.Errorf("unknown profile %v, only goroutine and heap are supported", profile)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, fmt.Sprintf("%v-%v.dump", profile, time.Now().UTC().Format("20060102T150405.000Z")))
+	f, err := os.Create(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	debug := 0
+	if profile == "goroutine" {
+		debug = 2
+	} else {
+		// the heap profile is as of the last gc
+		runtime.GC()
+	}
+	if err := rpprof.Lookup(profile).WriteTo(f, debug); err != nil {
+		return "", err
+	}
+	return file, nil
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDebugHandlers(t *testing.T) {
+	defer func(token string, enabled bool, dir string) {
+		AdminToken, EnableDebug, DebugDumpDir = token, enabled, dir
+	}(AdminToken, EnableDebug, DebugDumpDir)
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	AdminToken, DebugDumpDir = "secret", dir
+
+	EnableDebug = false
+	req := httptest.NewRequest("GET", "/debug/pprof/", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	newAdminHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("case (disabled) output: (%v) is not the expected: (%v)", w.Code, http.StatusNotFound)
+	}
+
+	EnableDebug = true
+	handler := newAdminHandler()
+	testCaseList := []struct {
+		name     string
+		method   string
+		path     string
+		expected int
+		content  string
+	}{
+		{"pprof index", "GET", "/debug/pprof/", http.StatusOK, ""},
+
+		{"goroutine dump", "POST", "/api/v1/debug/dump", http.StatusOK, "goroutine"},
+
+		{"heap dump", "POST", "/api/v1/debug/dump?profile=heap", http.StatusOK, ""},
+
+		{"unknown profile", "POST", "/api/v1/debug/dump?profile=cpu", http.StatusBadRequest, ""},
+
+		{"dump with GET", "GET", "/api/v1/debug/dump", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, c := range testCaseList {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, w.Code, c.expected)
+			continue
+		}
+		if c.content == "" {
+			continue
+		}
+		result := map[string]string{}
+		json.Unmarshal(w.Body.Bytes(), &result)
+		dump, err := ioutil.ReadFile(result["file"])
+		if err != nil || !strings.Contains(string(dump), c.content) {
+			t.Errorf("case (%v) the dump %v does not contain %v: %v", c.name, result["file"], c.content, err)
+		}
+	}
+}
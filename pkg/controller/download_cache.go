@@ -0,0 +1,60 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DownloadCacheSize is the number of the downloaded dashboards kept for each kind of reference,
+// the least recently used ones are downloaded again, 0 disables the cache
+var DownloadCacheSize = 100
+
+// downloadedDashboard is a cached dashboard with the key it is cached by
+type downloadedDashboard struct {
+	key       string
+	dashboard string
+}
+
+// downloadCache keeps the latest used downloaded dashboards
+type downloadCache struct {
+	lock    sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newDownloadCache() *downloadCache {
+	return &downloadCache{order: list.New(), entries: map[string]*list.Element{}}
+}
+
+func (c *downloadCache) get(key string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*downloadedDashboard).dashboard, true
+}
+
+func (c *downloadCache) add(key string, dashboard string) {
+	if DownloadCacheSize <= 0 {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value = &downloadedDashboard{key, dashboard}
+		c.order.MoveToFront(element)
+	} else {
+		c.entries[key] = c.order.PushFront(&downloadedDashboard{key, dashboard})
+	}
+	for c.order.Len() > DownloadCacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*downloadedDashboard).key)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"testing"
+)
+
+func TestDownloadCache(t *testing.T) {
+	defer func(size int) { DownloadCacheSize = size }(DownloadCacheSize)
+	DownloadCacheSize = 2
+	cache := newDownloadCache()
+	cache.add("1/1", "a")
+	cache.add("2/1", "b")
+	// 1/1 is used so 2/1 is the least recently used one
+	if dashboard, ok := cache.get("1/1"); !ok || dashboard != "a" {
+		t.Errorf("the cached dashboard (%v, %v) is not the expected (a, true)", dashboard, ok)
+	}
+	cache.add("3/1", "c")
+
+	testCaseList := []struct {
+		name     string
+		key      string
+		expected bool
+	}{
+		{"the recently used dashboard is kept", "1/1", true},
+
+		{"the least recently used dashboard is evicted", "2/1", false},
+
+		{"the new dashboard is cached", "3/1", true},
+	}
+
+	for _, c := range testCaseList {
+		_, ok := cache.get(c.key)
+		if ok != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, ok, c.expected)
+		}
+	}
+
+	DownloadCacheSize = 0
+	cache.add("4/1", "d")
+	if _, ok := cache.get("4/1"); ok {
+		t.Errorf("nothing should be cached when the cache is disabled")
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
+)
+
+// DryRun makes the loader only report the changes it would make in grafana
+var DryRun = false
+
+// dryRunFolderID stands for the folder which would be created under dry-run mode
+const dryRunFolderID = -1
+
+// dryRunTeamID stands for the team which would be created under dry-run mode
+const dryRunTeamID = -1
+
+// setMutatingRequest sends the request which changes grafana,
+// under dry-run mode the request is only logged together with the rendered payload
+func setMutatingRequest(ctx context.Context, orgID string, method string, url string, body []byte) ([]byte, int) {
+	if DryRun {
+		klog.Infof("[dry-run] %v %v in org %q %s", method, url, orgID, body)
+		return nil, http.StatusOK
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewBuffer(body)
+	}
+	return util.SetOrgRequestContext(ctx, method, url, reader, activeSettings().retry, orgID)
+}
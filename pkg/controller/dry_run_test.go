@@ -0,0 +1,53 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	mutated := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			mutated = append(mutated, req.Method+" "+req.URL.Path)
+		}
+		if strings.HasPrefix(req.URL.Path, "/api/folders/id/") {
+			w.Write([]byte("{\"uid\": \"test\"}"))
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	originalURI := grafanaURI
+	grafanaURI = server.URL
+	DryRun = true
+	retry = 1
+	defer func() {
+		grafanaURI = originalURI
+		DryRun = false
+	}()
+
+	cm, err := createDashboard()
+	if err != nil {
+		t.Fatalf("failed to create dashboard configmap: %v", err)
+	}
+
+	if err := updateDashboard(context.TODO(), nil, cm, false); err != nil {
+		t.Fatalf("dry-run update should not fail: %v", err)
+	}
+	deleteDashboard(context.TODO(), cm)
+	if !deleteCustomFolder(context.TODO(), "", 1) {
+		t.Fatalf("folder deletion should be reported under dry-run mode")
+	}
+
+	if len(mutated) != 0 {
+		t.Fatalf("grafana should not be changed under dry-run mode: %v", mutated)
+	}
+}
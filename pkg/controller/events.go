@@ -0,0 +1,88 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	eventComponent = "grafana-dashboard-loader"
+
+	reasonDashboardApplied        = "DashboardApplied"
+	reasonDashboardApplyFailed    = "DashboardApplyFailed"
+	reasonDashboardDeleted        = "DashboardDeleted"
+	reasonDashboardRetained       = "DashboardRetained"
+	reasonDashboardLintFailed     = "DashboardLintFailed"
+	reasonDashboardMigrated       = "DashboardMigrated"
+	reasonDashboardAngularPanels  = "DashboardAngularPanels"
+	reasonDashboardPolicyViolated = "DashboardPolicyViolated"
+	reasonDashboardGuardrails     = "DashboardGuardrailsApplied"
+	reasonWatchFailing            = "WatchFailing"
+	reasonDashboardWaiting        = "DashboardWaitingForDependencies"
+	reasonDashboardFrozen         = "DashboardChangeFrozen"
+	reasonDashboardUIDConflict    = "DashboardUIDConflict"
+	reasonDashboardAdopted        = "DashboardAdopted"
+	reasonDashboardRolledBack     = "DashboardRolledBack"
+)
+
+// eventRecorder posts the events on the source objects of the dashboards, nil means no event is posted
+var eventRecorder record.EventRecorder
+
+func newEventRecorder(kubeClient kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: eventComponent})
+}
+
+// recordEvent posts the event on the source object, the sources which are not kubernetes objects are skipped
+func recordEvent(source interface{}, eventType, reason, messageFmt string, args ...interface{}) {
+	obj, ok := source.(runtime.Object)
+	if eventRecorder == nil || !ok {
+		return
+	}
+	eventRecorder.Eventf(obj, eventType, reason, messageFmt, args...)
+}
+
+// recordSyncEvent tells the owner of the source object whether the dashboards are applied
+func recordSyncEvent(source interface{}, err error) {
+	if isFrozen(err) {
+		recordEvent(source, corev1.EventTypeNormal, reasonDashboardFrozen, "The dashboards are not applied yet: %v", err)
+		return
+	}
+	if isWaitingForDependencies(err) {
+		recordEvent(source, corev1.EventTypeNormal, reasonDashboardWaiting, "The dashboards are not applied yet: %v", err)
+		return
+	}
+	if err != nil {
+		recordEvent(source, corev1.EventTypeWarning, reasonDashboardApplyFailed, "Failed to apply the dashboards: %v", err)
+		return
+	}
+	recordEvent(source, corev1.EventTypeNormal, reasonDashboardApplied, "The dashboards are applied to grafana")
+}
+
+// recordDeleteEvent tells whether the dashboards of the deleted configmap are retained
+func recordDeleteEvent(source interface{}, cm interface{}) {
+	if isRetainedDashboard(cm) {
+		recordEvent(source, corev1.EventTypeNormal, reasonDashboardRetained, "The dashboards are retained in grafana")
+		return
+	}
+	recordEvent(source, corev1.EventTypeNormal, reasonDashboardDeleted, "The dashboards are deleted from grafana")
+}
+
+// recordLintEvent tells the owner of the source object what the lint rules found in the dashboard
+func recordLintEvent(source interface{}, key string, findings []lintFinding) {
+	messages := []string{}
+	for _, finding := range findings {
+		messages = append(messages, finding.String())
+	}
+	recordEvent(source, corev1.EventTypeWarning, reasonDashboardLintFailed, "The dashboard %v has lint findings: %v", key, strings.Join(messages, "; "))
+}
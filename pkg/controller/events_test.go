@@ -0,0 +1,75 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestDashboardEvents(t *testing.T) {
+	_, restore := useFakeGrafanaClient()
+	defer restore()
+	recorder := record.NewFakeRecorder(10)
+	defer func(r record.EventRecorder) { eventRecorder = r }(eventRecorder)
+	eventRecorder = recorder
+
+	newConfigmap := func(name string, data string, annotations map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Annotations: annotations,
+				Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+			Data: map[string]string{name + ".json": data},
+		}
+	}
+	handler := newDashboardEventHandler(context.TODO(), newSyncState("events-test"), func(obj interface{}) interface{} {
+		return obj
+	})
+
+	testCaseList := []struct {
+		name     string
+		event    func()
+		expected string
+	}{
+		{"applied", func() { handler.OnAdd(newConfigmap("valid", `{"title": "valid"}`, nil)) },
+			"Normal " + reasonDashboardApplied},
+
+		{"failed", func() { handler.OnAdd(newConfigmap("invalid", `{"title": `, nil)) },
+			"Warning " + reasonDashboardApplyFailed},
+
+		{"deleted", func() { handler.OnDelete(newConfigmap("valid", `{"title": "valid"}`, nil)) },
+			"Normal " + reasonDashboardDeleted},
+
+		{"retained", func() {
+			handler.OnDelete(newConfigmap("retained", `{"title": "retained"}`, map[string]string{dashboardRetainKey: "true"}))
+		}, "Normal " + reasonDashboardRetained},
+	}
+
+	for _, c := range testCaseList {
+		c.event()
+		select {
+		case event := <-recorder.Events:
+			if !strings.HasPrefix(event, c.expected) {
+				t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, event, c.expected)
+			}
+		default:
+			t.Errorf("case (%v) no event is posted", c.name)
+		}
+	}
+
+	// the unchanged configmap posts no event on resync
+	cm := newConfigmap("resync", `{"title": "resync"}`, nil)
+	handler.OnAdd(cm)
+	<-recorder.Events
+	handler.OnUpdate(cm, cm)
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("case (resync) unexpected event: %v", event)
+	default:
+	}
+}
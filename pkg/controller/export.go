@@ -0,0 +1,182 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// generalFolderDir is the sub directory of the filesystem source for the general folder
+const generalFolderDir = "General"
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// ExportOptions selects the dashboards to export and how they are written
+type ExportOptions struct {
+	// OrgID is the grafana organization to export, empty means the default org
+	OrgID string
+	// Folders only exports the dashboards in these folders, "General" is the general folder
+	Folders []string
+	// Tags only exports the dashboards which have all these tags
+	Tags []string
+	// Configmaps writes every dashboard as a configmap manifest instead of the dashboard directory layout
+	Configmaps bool
+	// Namespace is the namespace of the configmap manifests
+	Namespace string
+}
+
+// ExportDashboards writes the dashboards of the org into dir, either with the same layout as the dashboard directory
+// so that the directory can be loaded by --dashboard-dir, or as the configmaps which are loaded into the same folders
+func ExportDashboards(ctx context.Context, dir string, opts ExportOptions) (int, error) {
+	hits, err := clientFor(ctx).SearchDashboards(ctx, opts.OrgID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to search dashboards: %v", err)
+	}
+	exported := 0
+	for _, hit := range hits {
+		folder := hit.FolderTitle
+		if hit.FolderID == 0 || folder == "" {
+			folder = generalFolderDir
+		}
+		if !opts.matches(folder, hit.Tags) {
+			continue
+		}
+
+		dashboard, err := clientFor(ctx).GetDashboard(ctx, opts.OrgID, hit.UID)
+		if err != nil {
+			return exported, fmt.Errorf("failed to get dashboard %v: %v", hit.UID, err)
+		}
+		// the id, version, provisioned-by and source tags belong to the grafana instance
+		delete(dashboard, "id")
+		delete(dashboard, "version")
+		removeProvisionedByTag(dashboard)
+		removeOwnershipTags(dashboard)
+		b, err := json.MarshalIndent(dashboard, "", "  ")
+		if err != nil {
+			return exported, err
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(folder), exportFileName(hit.UID)+".json")
+		content := append(b, '\n')
+		if opts.Configmaps {
+			path = filepath.Join(dir, exportConfigmapName(hit.UID)+".yaml")
+			cm, err := dashboardConfigmap(hit.UID, folder, string(b), opts)
+			if err != nil {
+				return exported, fmt.Errorf("failed to export dashboard %v: %v", hit.UID, err)
+			}
+			content, err = yaml.Marshal(cm)
+			if err != nil {
+				return exported, err
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return exported, err
+		}
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return exported, err
+		}
+		klog.V(4).Infof("export dashboard %v to %v", hit.UID, path)
+		exported++
+	}
+	return exported, nil
+}
+
+func (opts ExportOptions) matches(folder string, tags []string) bool {
+	if len(opts.Folders) > 0 {
+		found := false
+		for _, f := range opts.Folders {
+			if f == folder {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, tag := range opts.Tags {
+		found := false
+		for _, t := range tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// dashboardConfigmap builds the configmap which loads the dashboard into the same org and folder again,
+// the dashboard which does not fit into a configmap is compressed into binaryData
+func dashboardConfigmap(uid string, folder string, dashboard string, opts ExportOptions) (*corev1.ConfigMap, error) {
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        exportConfigmapName(uid),
+			Namespace:   opts.Namespace,
+			Labels:      map[string]string{"grafana-custom-dashboard": "true"},
+			Annotations: map[string]string{},
+		},
+		Data: map[string]string{exportFileName(uid) + ".json": dashboard},
+	}
+	if folder == generalFolderDir {
+		cm.Labels[generalFolderKey] = "true"
+	} else {
+		cm.Annotations[customFolderKey] = folder
+	}
+	if opts.OrgID != "" {
+		cm.Annotations[dashboardOrgIDKey] = opts.OrgID
+	}
+	key := exportFileName(uid) + ".json"
+	if len(key)+len(dashboard) > maxConfigmapSize {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write([]byte(dashboard)); err != nil {
+			return nil, err
+		}
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+		key = exportFileName(uid) + gzipSuffix
+		if err := checkConfigmapSize(len(key) + buf.Len()); err != nil {
+			return nil, err
+		}
+		cm.Data = nil
+		cm.BinaryData = map[string][]byte{key: buf.Bytes()}
+	}
+	return cm, nil
+}
+
+// exportFileName keeps the file of the dashboard in its folder whatever the uid contains
+func exportFileName(uid string) string {
+	return strings.NewReplacer("/", "-", "\\", "-", "..", "-").Replace(uid)
+}
+
+// exportConfigmapName turns the uid into a valid configmap name
+func exportConfigmapName(uid string) string {
+	name := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(uid), "-"), "-.")
+	name = "grafana-dashboard-" + name
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		name = strings.TrimRight(name[:validation.DNS1123SubdomainMaxLength], "-.")
+	}
+	return name
+}
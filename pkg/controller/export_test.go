@@ -0,0 +1,108 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestExportDashboards(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	folder, _ := fake.CreateFolder(context.TODO(), "", "SLOs")
+	fake.SaveDashboard(context.TODO(), "", map[string]interface{}{
+		"uid": "slo", "title": "SLO", "tags": []interface{}{"slo", "team-a"}}, folder.ID, false)
+	fake.SaveDashboard(context.TODO(), "", map[string]interface{}{
+		"uid": "Home_Page", "title": "Home", "tags": []interface{}{"team-a"}}, 0, false)
+
+	testCaseList := []struct {
+		name     string
+		opts     ExportOptions
+		expected int
+	}{
+		{"all the dashboards", ExportOptions{}, 2},
+
+		{"folder", ExportOptions{Folders: []string{"SLOs"}}, 1},
+
+		{"general folder", ExportOptions{Folders: []string{"General"}}, 1},
+
+		{"tag", ExportOptions{Tags: []string{"team-a"}}, 2},
+
+		{"all the tags", ExportOptions{Tags: []string{"team-a", "slo"}}, 1},
+
+		{"no match", ExportOptions{Folders: []string{"SLOs"}, Tags: []string{"team-b"}}, 0},
+	}
+
+	for _, c := range testCaseList {
+		output, err := ioutil.TempDir("", "export")
+		if err != nil {
+			t.Fatalf("failed to create temp dir: %v", err)
+		}
+		exported, err := ExportDashboards(context.TODO(), output, c.opts)
+		os.RemoveAll(output)
+		if err != nil || exported != c.expected {
+			t.Errorf("case (%v) output: (%v, %v) is not the expected: (%v)", c.name, exported, err, c.expected)
+		}
+	}
+}
+
+func TestExportDashboardsAsConfigmaps(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	folder, _ := fake.CreateFolder(context.TODO(), "2", "SLOs")
+	fake.SaveDashboard(context.TODO(), "2", map[string]interface{}{"uid": "slo", "title": "SLO"}, folder.ID, false)
+	fake.SaveDashboard(context.TODO(), "2", map[string]interface{}{"uid": "Home_Page", "title": "Home"}, 0, false)
+
+	output, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(output)
+	exported, err := ExportDashboards(context.TODO(), output,
+		ExportOptions{OrgID: "2", Configmaps: true, Namespace: "open-cluster-management-observability"})
+	if err != nil || exported != 2 {
+		t.Fatalf("failed to export the dashboards: %v, %v", exported, err)
+	}
+
+	testCaseList := []struct {
+		name     string
+		file     string
+		expected string
+	}{
+		{"custom folder", "grafana-dashboard-slo.yaml", "SLOs"},
+
+		{"general folder", "grafana-dashboard-home-page.yaml", ""},
+	}
+
+	for _, c := range testCaseList {
+		content, err := ioutil.ReadFile(filepath.Join(output, c.file))
+		if err != nil {
+			t.Errorf("case (%v) failed to read the exported configmap: %v", c.name, err)
+			continue
+		}
+		cm := &corev1.ConfigMap{}
+		if err := yaml.Unmarshal(content, cm); err != nil {
+			t.Errorf("case (%v) failed to parse the exported configmap: %v", c.name, err)
+			continue
+		}
+		if !isDesiredDashboardConfigmap(cm) || cm.Namespace != "open-cluster-management-observability" {
+			t.Errorf("case (%v) the exported configmap %v is not a dashboard", c.name, cm.Name)
+		}
+		if orgID, _ := getDashboardOrgID(cm); orgID != "2" {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, orgID, "2")
+		}
+		for key := range cm.Data {
+			if folder := getDashboardFolderTitle(cm, key); folder != c.expected {
+				t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, folder, c.expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,368 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// fakeGrafanaClient keeps the folders and dashboards of all the orgs in memory
+type fakeGrafanaClient struct {
+	sync.Mutex
+	nextID      float64
+	folders     map[string][]Folder
+	dashboards  map[string]map[string]fakeDashboard
+	public      map[string]map[string]interface{}
+	preferences map[string]map[string]interface{}
+	// orgs are the ids of the organizations keyed by the name
+	orgs map[string]string
+	// teams are the ids of the teams keyed by the org and the name, the members are keyed by the team id
+	teams   map[string]map[string]float64
+	members map[float64][]TeamMember
+	// users are the ids of the users keyed by the login
+	users map[string]float64
+	// permissions are the permissions of the folders keyed by the org and the folder uid
+	permissions map[string]map[string][]FolderPermission
+	// healthErr is returned by Health
+	healthErr error
+	// saveErrs are returned by SaveDashboard for the dashboards keyed by uid
+	saveErrs map[string]error
+	// deleteErrs are returned by DeleteDashboard for the dashboards keyed by uid
+	deleteErrs map[string]error
+	// versions are the version history of the dashboards keyed by the org and the uid, the restores are appended
+	versions map[string][]DashboardVersion
+}
+
+type fakeDashboard struct {
+	id        float64
+	folderID  float64
+	dashboard map[string]interface{}
+}
+
+// fakeNotFound is the 404 of grafana which has a message unlike the request without a response
+func fakeNotFound() error {
+	return &GrafanaAPIError{StatusCode: http.StatusNotFound, Body: []byte("{\"message\": \"not found\"}")}
+}
+
+func newFakeGrafanaClient() *fakeGrafanaClient {
+	return &fakeGrafanaClient{
+		folders:     map[string][]Folder{},
+		dashboards:  map[string]map[string]fakeDashboard{},
+		public:      map[string]map[string]interface{}{},
+		preferences: map[string]map[string]interface{}{},
+		orgs:        map[string]string{},
+		teams:       map[string]map[string]float64{},
+		members:     map[float64][]TeamMember{},
+		users:       map[string]float64{},
+		permissions: map[string]map[string][]FolderPermission{},
+		versions:    map[string][]DashboardVersion{},
+	}
+}
+
+func (c *fakeGrafanaClient) ListFolders(ctx context.Context, orgID string) ([]Folder, error) {
+	c.Lock()
+	defer c.Unlock()
+	return append([]Folder{}, c.folders[orgID]...), nil
+}
+
+func (c *fakeGrafanaClient) GetFolder(ctx context.Context, orgID string, id float64) (Folder, error) {
+	c.Lock()
+	defer c.Unlock()
+	for _, folder := range c.folders[orgID] {
+		if folder.ID == id {
+			return folder, nil
+		}
+	}
+	return Folder{}, fakeNotFound()
+}
+
+func (c *fakeGrafanaClient) CreateFolder(ctx context.Context, orgID string, title string) (Folder, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.nextID++
+	folder := Folder{ID: c.nextID, UID: "folder-" + title, Title: title}
+	c.folders[orgID] = append(c.folders[orgID], folder)
+	return folder, nil
+}
+
+func (c *fakeGrafanaClient) DeleteFolder(ctx context.Context, orgID string, uid string) error {
+	c.Lock()
+	defer c.Unlock()
+	for i, folder := range c.folders[orgID] {
+		if folder.UID == uid {
+			c.folders[orgID] = append(c.folders[orgID][:i], c.folders[orgID][i+1:]...)
+			return nil
+		}
+	}
+	return fakeNotFound()
+}
+
+func (c *fakeGrafanaClient) SaveDashboard(ctx context.Context, orgID string, dashboard map[string]interface{},
+	folderID float64, overwrite bool) (SavedDashboard, error) {
+	c.Lock()
+	defer c.Unlock()
+	uid, _ := dashboard["uid"].(string)
+	if err, ok := c.saveErrs[uid]; ok {
+		return SavedDashboard{}, err
+	}
+	if c.dashboards[orgID] == nil {
+		c.dashboards[orgID] = map[string]fakeDashboard{}
+	}
+	existing, ok := c.dashboards[orgID][uid]
+	if !ok {
+		c.nextID++
+		existing.id = c.nextID
+	}
+	c.dashboards[orgID][uid] = fakeDashboard{existing.id, folderID, dashboard}
+	return SavedDashboard{ID: existing.id, UID: uid, Version: 1}, nil
+}
+
+func (c *fakeGrafanaClient) DeleteDashboard(ctx context.Context, orgID string, uid string) error {
+	c.Lock()
+	defer c.Unlock()
+	if err, ok := c.deleteErrs[uid]; ok {
+		return err
+	}
+	if _, ok := c.dashboards[orgID][uid]; !ok {
+		return fakeNotFound()
+	}
+	delete(c.dashboards[orgID], uid)
+	return nil
+}
+
+func (c *fakeGrafanaClient) GetDashboard(ctx context.Context, orgID string, uid string) (map[string]interface{}, error) {
+	c.Lock()
+	defer c.Unlock()
+	d, ok := c.dashboards[orgID][uid]
+	if !ok {
+		return nil, fakeNotFound()
+	}
+	dashboard := map[string]interface{}{}
+	for k, v := range d.dashboard {
+		dashboard[k] = v
+	}
+	dashboard["id"] = d.id
+	return dashboard, nil
+}
+
+func (c *fakeGrafanaClient) GetDashboardWithMeta(ctx context.Context, orgID string, uid string) (map[string]interface{},
+	DashboardMeta, error) {
+	dashboard, err := c.GetDashboard(ctx, orgID, uid)
+	if err != nil {
+		return nil, DashboardMeta{}, err
+	}
+	c.Lock()
+	defer c.Unlock()
+	return dashboard, DashboardMeta{FolderID: c.dashboards[orgID][uid].folderID}, nil
+}
+
+func (c *fakeGrafanaClient) ListDashboardVersions(ctx context.Context, orgID string, uid string) ([]DashboardVersion, error) {
+	c.Lock()
+	defer c.Unlock()
+	versions, ok := c.versions[orgID+"/"+uid]
+	if !ok {
+		return nil, fakeNotFound()
+	}
+	return append([]DashboardVersion{}, versions...), nil
+}
+
+func (c *fakeGrafanaClient) RestoreDashboardVersion(ctx context.Context, orgID string, uid string,
+	version float64) (SavedDashboard, error) {
+	c.Lock()
+	defer c.Unlock()
+	versions := c.versions[orgID+"/"+uid]
+	for _, v := range versions {
+		if v.Version != version {
+			continue
+		}
+		restored := DashboardVersion{Version: float64(len(versions) + 1), Message: fmt.Sprintf("Restored from version %v", version)}
+		c.versions[orgID+"/"+uid] = append(versions, restored)
+		return SavedDashboard{ID: c.dashboards[orgID][uid].id, UID: uid, Version: restored.Version}, nil
+	}
+	return SavedDashboard{}, fakeNotFound()
+}
+
+func (c *fakeGrafanaClient) GetPublicDashboardUID(ctx context.Context, orgID string, dashboardUID string) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.public[orgID+"/"+dashboardUID]; ok {
+		return "public-" + dashboardUID, nil
+	}
+	return "", nil
+}
+
+func (c *fakeGrafanaClient) SavePublicDashboard(ctx context.Context, orgID string, dashboardUID string, publicUID string,
+	config map[string]interface{}) error {
+	c.Lock()
+	defer c.Unlock()
+	c.public[orgID+"/"+dashboardUID] = config
+	return nil
+}
+
+func (c *fakeGrafanaClient) GetPreferences(ctx context.Context, orgID string) (map[string]interface{}, error) {
+	c.Lock()
+	defer c.Unlock()
+	preferences := map[string]interface{}{}
+	for k, v := range c.preferences[orgID] {
+		preferences[k] = v
+	}
+	return preferences, nil
+}
+
+func (c *fakeGrafanaClient) UpdatePreferences(ctx context.Context, orgID string, preferences map[string]interface{}) error {
+	c.Lock()
+	defer c.Unlock()
+	c.preferences[orgID] = preferences
+	return nil
+}
+
+func (c *fakeGrafanaClient) SearchFolderDashboards(ctx context.Context, orgID string, folderID float64) ([]SearchHit, error) {
+	c.Lock()
+	defer c.Unlock()
+	hits := []SearchHit{}
+	for uid, d := range c.dashboards[orgID] {
+		if d.folderID == folderID {
+			hits = append(hits, SearchHit{ID: d.id, UID: uid, Type: "dash-db"})
+		}
+	}
+	return hits, nil
+}
+
+func (c *fakeGrafanaClient) SearchDashboards(ctx context.Context, orgID string) ([]SearchHit, error) {
+	c.Lock()
+	defer c.Unlock()
+	hits := []SearchHit{}
+	for uid, d := range c.dashboards[orgID] {
+		hit := SearchHit{ID: d.id, UID: uid, Type: "dash-db", FolderID: d.folderID}
+		hit.Title, _ = d.dashboard["title"].(string)
+		for _, folder := range c.folders[orgID] {
+			if folder.ID == d.folderID {
+				hit.FolderTitle = folder.Title
+			}
+		}
+		if tags, ok := d.dashboard["tags"].([]interface{}); ok {
+			for _, tag := range tags {
+				hit.Tags = append(hit.Tags, fmt.Sprint(tag))
+			}
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+func (c *fakeGrafanaClient) GetOrgID(ctx context.Context, name string) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+	return c.orgs[name], nil
+}
+
+func (c *fakeGrafanaClient) CreateOrg(ctx context.Context, name string) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+	// the main org is 1 so the created ones start from 2
+	c.orgs[name] = fmt.Sprint(len(c.orgs) + 2)
+	return c.orgs[name], nil
+}
+
+func (c *fakeGrafanaClient) GetTeamID(ctx context.Context, orgID string, name string) (float64, error) {
+	c.Lock()
+	defer c.Unlock()
+	return c.teams[orgID][name], nil
+}
+
+func (c *fakeGrafanaClient) CreateTeam(ctx context.Context, orgID string, name string) (float64, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.nextID++
+	if c.teams[orgID] == nil {
+		c.teams[orgID] = map[string]float64{}
+	}
+	c.teams[orgID][name] = c.nextID
+	return c.nextID, nil
+}
+
+func (c *fakeGrafanaClient) ListTeamMembers(ctx context.Context, orgID string, teamID float64) ([]TeamMember, error) {
+	c.Lock()
+	defer c.Unlock()
+	return append([]TeamMember{}, c.members[teamID]...), nil
+}
+
+func (c *fakeGrafanaClient) AddTeamMember(ctx context.Context, orgID string, teamID float64, userID float64) error {
+	c.Lock()
+	defer c.Unlock()
+	for login, id := range c.users {
+		if id == userID {
+			c.members[teamID] = append(c.members[teamID], TeamMember{UserID: userID, Login: login})
+			return nil
+		}
+	}
+	return fakeNotFound()
+}
+
+func (c *fakeGrafanaClient) RemoveTeamMember(ctx context.Context, orgID string, teamID float64, userID float64) error {
+	c.Lock()
+	defer c.Unlock()
+	for i, member := range c.members[teamID] {
+		if member.UserID == userID {
+			c.members[teamID] = append(c.members[teamID][:i], c.members[teamID][i+1:]...)
+			return nil
+		}
+	}
+	return fakeNotFound()
+}
+
+func (c *fakeGrafanaClient) GetUserID(ctx context.Context, login string) (float64, error) {
+	c.Lock()
+	defer c.Unlock()
+	return c.users[login], nil
+}
+
+func (c *fakeGrafanaClient) GetFolderPermissions(ctx context.Context, orgID string, folderUID string) ([]FolderPermission, error) {
+	c.Lock()
+	defer c.Unlock()
+	return append([]FolderPermission{}, c.permissions[orgID][folderUID]...), nil
+}
+
+func (c *fakeGrafanaClient) UpdateFolderPermissions(ctx context.Context, orgID string, folderUID string,
+	permissions []FolderPermission) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.permissions[orgID] == nil {
+		c.permissions[orgID] = map[string][]FolderPermission{}
+	}
+	c.permissions[orgID][folderUID] = append([]FolderPermission{}, permissions...)
+	return nil
+}
+
+func (c *fakeGrafanaClient) Health(ctx context.Context) error {
+	return c.healthErr
+}
+
+// useFakeGrafanaClient replaces the grafana client until the returned func is called
+func useFakeGrafanaClient() (*fakeGrafanaClient, func()) {
+	fake := newFakeGrafanaClient()
+	original := grafanaClient
+	grafanaClient = fake
+	restoreUIDs := useEmptyUIDIndex()
+	return fake, func() {
+		grafanaClient = original
+		restoreUIDs()
+	}
+}
+
+// useEmptyUIDIndex makes the uids desired in the grafana of the test only
+func useEmptyUIDIndex() func() {
+	desiredUIDsLock.Lock()
+	defer desiredUIDsLock.Unlock()
+	original := desiredUIDs
+	desiredUIDs = map[desiredUID]uidOwner{}
+	return func() {
+		desiredUIDsLock.Lock()
+		defer desiredUIDsLock.Unlock()
+		desiredUIDs = original
+	}
+}
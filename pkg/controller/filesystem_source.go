@@ -0,0 +1,173 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// DashboardDir is the local directory to load the *.json dashboards from, empty means disabled
+	DashboardDir = ""
+	// DashboardDirPollInterval is how often the dashboard directory is scanned for changes
+	DashboardDirPollInterval = 30 * time.Second
+)
+
+// filesystemSource syncs the dashboards in a directory, every file is handled as a configmap:
+// the files in the top directory land in the default custom folder,
+// the files in a sub directory land in the folder named after the sub directory,
+// and the files in the "General" sub directory land in the general folder.
+type filesystemSource struct {
+	dir      string
+	interval time.Duration
+	state    *syncState
+	// loaded are the configmaps built in the last scan keyed by the file path
+	loaded map[string]*corev1.ConfigMap
+}
+
+func newFilesystemSource(dir string, interval time.Duration) *filesystemSource {
+	return &filesystemSource{
+		dir:      dir,
+		interval: interval,
+		state:    newSyncState("file:" + dir),
+		loaded:   map[string]*corev1.ConfigMap{},
+	}
+}
+
+// Run scans the directory periodically until ctx is done
+func (f *filesystemSource) Run(ctx context.Context) {
+	wait.Until(func() { f.poll(ctx) }, f.interval, ctx.Done())
+}
+
+func (f *filesystemSource) poll(ctx context.Context) {
+	if !inFlightSyncs.start() {
+		return
+	}
+	defer inFlightSyncs.done()
+	err := f.sync(ctx)
+	if err != nil {
+		klog.Errorf("failed to sync dashboard directory %v: %v", f.dir, err)
+	}
+}
+
+// sync applies the changed files and deletes the dashboards of the removed files,
+// the failed files are applied again in the next sync, and nothing is applied by the replicas not owning the files
+func (f *filesystemSource) sync(ctx context.Context) error {
+	if !ownsNamespace("") {
+		klog.V(4).Infof("dashboard directory %v is applied by another replica", f.dir)
+		return nil
+	}
+	current, err := f.scan()
+	if err != nil {
+		return err
+	}
+
+	var syncErr error
+	for path, cm := range current {
+		if f.state.isSynced(cm) {
+			continue
+		}
+		klog.Infof("detect dashboard file %v changed", path)
+		if err := syncDashboard(ctx, f.state, f.loaded[path], cm); err != nil {
+			syncErr = fmt.Errorf("failed to sync dashboard file %v: %v", path, err)
+		}
+	}
+
+	for path, cm := range f.loaded {
+		if _, ok := current[path]; !ok {
+			klog.Infof("detect dashboard file %v deleted", path)
+			if err := syncDeletion(ctx, f.state, cm); err != nil {
+				syncErr = fmt.Errorf("failed to delete dashboard file %v: %v", path, err)
+			}
+		}
+	}
+	f.loaded = current
+	return syncErr
+}
+
+// isSynced checks whether all the files loaded last time are still applied
+func (f *filesystemSource) isSynced() bool {
+	for _, cm := range f.loaded {
+		if !f.state.isSynced(cm) {
+			return false
+		}
+	}
+	return true
+}
+
+// scan reads all the *.json files under the directory,
+// a file which cannot be read keeps the content from the last scan
+func (f *filesystemSource) scan() (map[string]*corev1.ConfigMap, error) {
+	current := map[string]*corev1.ConfigMap{}
+	// filepath.Walk does not descend into the root if it is a symlink
+	root, err := filepath.EvalSymlinks(f.dir)
+	if err != nil {
+		return nil, err
+	}
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// skip the hidden entries such as the ..data directories of projected volumes
+		if path != root && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".json") {
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			klog.Errorf("failed to read dashboard file %v: %v", path, err)
+			if cm, ok := f.loaded[path]; ok {
+				current[path] = cm
+			}
+			return nil
+		}
+		current[path] = fileToConfigmap(root, path, string(content))
+		return nil
+	})
+	return current, err
+}
+
+func fileToConfigmap(root string, path string, content string) *corev1.ConfigMap {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	rel = filepath.ToSlash(rel)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			// the name is used to generate the dashboard uid
+			Name:        strings.ReplaceAll(strings.TrimSuffix(rel, ".json"), "/", "-"),
+			Namespace:   os.Getenv("POD_NAMESPACE"),
+			Labels:      map[string]string{"grafana-custom-dashboard": "true"},
+			Annotations: map[string]string{},
+		},
+		Data: map[string]string{filepath.Base(path): content},
+	}
+
+	folder := filepath.ToSlash(filepath.Dir(rel))
+	if strings.ToLower(folder) == generalFolderTitle {
+		cm.Labels[generalFolderKey] = "true"
+	} else if folder != "." {
+		cm.Annotations[customFolderKey] = folder
+	}
+	return cm
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileToConfigmap(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+
+		{
+			"top directory",
+			"/dashboards/a.json",
+			"Custom",
+		},
+
+		{
+			"sub directory",
+			"/dashboards/SLOs/b.json",
+			"SLOs",
+		},
+
+		{
+			"general directory",
+			"/dashboards/General/c.json",
+			"",
+		},
+	}
+
+	for _, c := range testCaseList {
+		cm := fileToConfigmap("/dashboards", c.path, "{}")
+		if !isDesiredDashboardConfigmap(cm) {
+			t.Errorf("case (%v) configmap should be desired", c.name)
+		}
+		output := getDashboardCustomFolderTitle(cm)
+		if output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+
+	cm := fileToConfigmap("/dashboards", "/dashboards/SLOs/b.json", "{}")
+	if cm.Name != "SLOs-b" {
+		t.Errorf("the name %v is not the expected %v", cm.Name, "SLOs-b")
+	}
+}
+
+func TestFilesystemSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	originalURI := grafanaURI
+	grafanaURI = server.URL
+	DryRun = true
+	retry = 1
+	defer func() {
+		grafanaURI = originalURI
+		DryRun = false
+	}()
+
+	dir, err := ioutil.TempDir("", "dashboards")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "SLOs"), 0755)
+	os.MkdirAll(filepath.Join(dir, "..data"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "a.json"), []byte("{\"title\": \"a\"}"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "SLOs", "b.json"), []byte("{\"title\": \"b\"}"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "..data", "c.json"), []byte("{\"title\": \"c\"}"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("readme"), 0644)
+
+	source := newFilesystemSource(dir, time.Second)
+	source.poll(context.TODO())
+	if len(source.loaded) != 2 {
+		t.Fatalf("the loaded dashboards %v are not the expected 2", len(source.loaded))
+	}
+	for path, cm := range source.loaded {
+		if !source.state.isSynced(cm) {
+			t.Errorf("dashboard file %v should be synced", path)
+		}
+	}
+
+	os.Remove(filepath.Join(dir, "a.json"))
+	source.poll(context.TODO())
+	if len(source.loaded) != 1 {
+		t.Fatalf("the loaded dashboards %v are not the expected 1", len(source.loaded))
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// folderCacheGrafanaClient keeps the folders of every org in memory so that
+// resolving the folder of a dashboard does not list all the folders every time,
+// and the snapshot of the dashboards got by a single search so that deleting a dashboard does not search its folder
+// every time, the other calls go to the client directly
+type folderCacheGrafanaClient struct {
+	GrafanaClient
+	lock    sync.Mutex
+	folders map[string][]Folder
+	// dashboards are the search hits of the dashboards keyed by the org and the uid
+	dashboards map[string]map[string]SearchHit
+}
+
+func newFolderCacheGrafanaClient(client GrafanaClient) *folderCacheGrafanaClient {
+	return &folderCacheGrafanaClient{
+		GrafanaClient: client,
+		folders:       map[string][]Folder{},
+		dashboards:    map[string]map[string]SearchHit{},
+	}
+}
+
+// reset drops all the cached folders, they are listed again on demand
+func (c *folderCacheGrafanaClient) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.folders = map[string][]Folder{}
+	c.dashboards = map[string]map[string]SearchHit{}
+}
+
+func (c *folderCacheGrafanaClient) invalidate(orgID string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.folders, orgID)
+	delete(c.dashboards, orgID)
+}
+
+func (c *folderCacheGrafanaClient) cached(orgID string) ([]Folder, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	folders, ok := c.folders[orgID]
+	return append([]Folder{}, folders...), ok
+}
+
+func (c *folderCacheGrafanaClient) ListFolders(ctx context.Context, orgID string) ([]Folder, error) {
+	if folders, ok := c.cached(orgID); ok {
+		return folders, nil
+	}
+	folders, err := c.GrafanaClient.ListFolders(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	c.folders[orgID] = append([]Folder{}, folders...)
+	c.lock.Unlock()
+	return folders, nil
+}
+
+func (c *folderCacheGrafanaClient) GetFolder(ctx context.Context, orgID string, id float64) (Folder, error) {
+	folders, _ := c.cached(orgID)
+	for _, folder := range folders {
+		if folder.ID == id && folder.UID != "" {
+			return folder, nil
+		}
+	}
+	return c.GrafanaClient.GetFolder(ctx, orgID, id)
+}
+
+func (c *folderCacheGrafanaClient) CreateFolder(ctx context.Context, orgID string, title string) (Folder, error) {
+	folder, err := c.GrafanaClient.CreateFolder(ctx, orgID, title)
+	if err != nil {
+		// the folder may be created by others in the meantime
+		c.invalidate(orgID)
+		return folder, err
+	}
+	c.lock.Lock()
+	if folders, ok := c.folders[orgID]; ok {
+		c.folders[orgID] = append(folders, folder)
+	}
+	c.lock.Unlock()
+	return folder, nil
+}
+
+func (c *folderCacheGrafanaClient) DeleteFolder(ctx context.Context, orgID string, uid string) error {
+	err := c.GrafanaClient.DeleteFolder(ctx, orgID, uid)
+	if err != nil {
+		c.invalidate(orgID)
+		return err
+	}
+	c.lock.Lock()
+	folders := []Folder{}
+	for _, folder := range c.folders[orgID] {
+		if folder.UID != uid {
+			folders = append(folders, folder)
+			continue
+		}
+		// grafana deletes the dashboards of the folder together with it
+		for dashboardUID, hit := range c.dashboards[orgID] {
+			if hit.FolderID == folder.ID {
+				delete(c.dashboards[orgID], dashboardUID)
+			}
+		}
+	}
+	if _, ok := c.folders[orgID]; ok {
+		c.folders[orgID] = folders
+	}
+	c.lock.Unlock()
+	return nil
+}
+
+func (c *folderCacheGrafanaClient) SaveDashboard(ctx context.Context, orgID string, dashboard map[string]interface{},
+	folderID float64, overwrite bool) (SavedDashboard, error) {
+	saved, err := c.GrafanaClient.SaveDashboard(ctx, orgID, dashboard, folderID, overwrite)
+	if apiErr, ok := err.(*GrafanaAPIError); ok && isFolderNotFound(apiErr) {
+		klog.Infof("folder %v is not found in org %q, drop the cached folders", folderID, orgID)
+		c.invalidate(orgID)
+	}
+	if err != nil {
+		return saved, err
+	}
+	c.lock.Lock()
+	if dashboards, ok := c.dashboards[orgID]; ok {
+		title, _ := dashboard["title"].(string)
+		dashboards[saved.UID] = SearchHit{ID: saved.ID, UID: saved.UID, Title: title, Type: "dash-db", FolderID: folderID}
+	}
+	c.lock.Unlock()
+	return saved, nil
+}
+
+func (c *folderCacheGrafanaClient) DeleteDashboard(ctx context.Context, orgID string, uid string) error {
+	if err := c.GrafanaClient.DeleteDashboard(ctx, orgID, uid); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	delete(c.dashboards[orgID], uid)
+	c.lock.Unlock()
+	return nil
+}
+
+// SearchFolderDashboards returns the dashboards of the folder by the snapshot of the org,
+// a folder which looks empty is searched again since grafana deletes the dashboards of a deleted folder
+// and a dashboard may be moved into the folder from the grafana ui after the snapshot
+func (c *folderCacheGrafanaClient) SearchFolderDashboards(ctx context.Context, orgID string, folderID float64) ([]SearchHit, error) {
+	c.lock.Lock()
+	dashboards, ok := c.dashboards[orgID]
+	c.lock.Unlock()
+	if !ok {
+		hits, err := c.GrafanaClient.SearchDashboards(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		dashboards = map[string]SearchHit{}
+		for _, hit := range hits {
+			dashboards[hit.UID] = hit
+		}
+		c.lock.Lock()
+		c.dashboards[orgID] = dashboards
+		c.lock.Unlock()
+	}
+
+	hits := []SearchHit{}
+	c.lock.Lock()
+	for _, hit := range dashboards {
+		if hit.FolderID == folderID {
+			hits = append(hits, hit)
+		}
+	}
+	c.lock.Unlock()
+	if len(hits) > 0 {
+		return hits, nil
+	}
+	return c.GrafanaClient.SearchFolderDashboards(ctx, orgID, folderID)
+}
+
+// isFolderNotFound checks whether grafana rejected the dashboard since its folder does not exist,
+// e.g. the folder was deleted from the grafana ui
+func isFolderNotFound(err *GrafanaAPIError) bool {
+	body := strings.ToLower(string(err.Body))
+	return strings.Contains(body, "folder-not-found") || strings.Contains(body, "folder not found")
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// countingGrafanaClient counts the folder listings and the searches and rejects the dashboards in missing folders
+type countingGrafanaClient struct {
+	*fakeGrafanaClient
+	lists    int
+	searches int
+}
+
+func (c *countingGrafanaClient) SearchFolderDashboards(ctx context.Context, orgID string, folderID float64) ([]SearchHit, error) {
+	c.searches++
+	return c.fakeGrafanaClient.SearchFolderDashboards(ctx, orgID, folderID)
+}
+
+func (c *countingGrafanaClient) SearchDashboards(ctx context.Context, orgID string) ([]SearchHit, error) {
+	c.searches++
+	return c.fakeGrafanaClient.SearchDashboards(ctx, orgID)
+}
+
+func (c *countingGrafanaClient) ListFolders(ctx context.Context, orgID string) ([]Folder, error) {
+	c.lists++
+	return c.fakeGrafanaClient.ListFolders(ctx, orgID)
+}
+
+func (c *countingGrafanaClient) SaveDashboard(ctx context.Context, orgID string, dashboard map[string]interface{},
+	folderID float64, overwrite bool) (SavedDashboard, error) {
+	if _, err := c.fakeGrafanaClient.GetFolder(ctx, orgID, folderID); folderID != 0 && err != nil {
+		return SavedDashboard{}, &GrafanaAPIError{StatusCode: http.StatusBadRequest,
+			Body: []byte("{\"message\": \"Folder not found\", \"status\": \"folder-not-found\"}")}
+	}
+	return c.fakeGrafanaClient.SaveDashboard(ctx, orgID, dashboard, folderID, overwrite)
+}
+
+func TestFolderCache(t *testing.T) {
+	counting := &countingGrafanaClient{fakeGrafanaClient: newFakeGrafanaClient()}
+	cache := newFolderCacheGrafanaClient(counting)
+	original := grafanaClient
+	grafanaClient = cache
+	defer func() { grafanaClient = original }()
+
+	for i := 0; i < 3; i++ {
+		if folderID := createCustomFolder(context.TODO(), "", "SLOs"); folderID == 0 {
+			t.Fatalf("failed to create folder SLOs")
+		}
+	}
+	if counting.lists != 1 || len(counting.folders[""]) != 1 {
+		t.Fatalf("folders are listed %v times and %v folders are created", counting.lists, len(counting.folders[""]))
+	}
+	if uid := getCustomFolderUID(context.TODO(), "", 1); uid != "folder-SLOs" {
+		t.Fatalf("the cached folder uid %v is not the expected", uid)
+	}
+
+	// the folder is deleted behind the back of the loader
+	counting.DeleteFolder(context.TODO(), "", "folder-SLOs")
+	_, err := cache.SaveDashboard(context.TODO(), "", map[string]interface{}{"uid": "test"}, 1, false)
+	if err == nil {
+		t.Fatalf("saving dashboard into the deleted folder should fail")
+	}
+	if folderID := createCustomFolder(context.TODO(), "", "SLOs"); folderID == 0 || folderID == 1 {
+		t.Fatalf("folder SLOs should be created again, got %v", folderID)
+	}
+	if counting.lists != 2 {
+		t.Fatalf("folders should be listed again after the cache is invalidated, listed %v times", counting.lists)
+	}
+
+	cache.reset()
+	hasCustomFolder(context.TODO(), "", "SLOs")
+	if counting.lists != 3 {
+		t.Fatalf("folders should be listed again after the cache is reset, listed %v times", counting.lists)
+	}
+}
+
+func TestDashboardSnapshot(t *testing.T) {
+	counting := &countingGrafanaClient{fakeGrafanaClient: newFakeGrafanaClient()}
+	cache := newFolderCacheGrafanaClient(counting)
+	folder, _ := cache.CreateFolder(context.TODO(), "", "SLOs")
+	for _, uid := range []string{"a", "b", "c"} {
+		if _, err := cache.SaveDashboard(context.TODO(), "", map[string]interface{}{"uid": uid}, folder.ID, false); err != nil {
+			t.Fatalf("failed to save dashboard %v: %v", uid, err)
+		}
+	}
+
+	testCaseList := []struct {
+		name     string
+		deleted  string
+		searches int
+		empty    bool
+	}{
+		{"snapshot", "a", 1, false},
+
+		{"from the snapshot", "b", 1, false},
+
+		{"empty folder is searched again", "c", 2, true},
+	}
+
+	for _, c := range testCaseList {
+		if err := cache.DeleteDashboard(context.TODO(), "", c.deleted); err != nil {
+			t.Fatalf("case (%v) failed to delete dashboard: %v", c.name, err)
+		}
+		hits, err := cache.SearchFolderDashboards(context.TODO(), "", folder.ID)
+		if err != nil || (len(hits) == 0) != c.empty {
+			t.Errorf("case (%v) output: (%v, %v) is not the expected: (%v)", c.name, hits, err, c.empty)
+		}
+		if counting.searches != c.searches {
+			t.Errorf("case (%v) searches: (%v) is not the expected: (%v)", c.name, counting.searches, c.searches)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// DefaultFolder is the folder of the dashboards without a folder annotation, it can be a template as well
+	DefaultFolder = defaultCustomFolder
+	// ClusterName is the name of the cluster the loader runs in, it is exposed to the folder templates
+	ClusterName = ""
+)
+
+// folderTemplateData is the data the folder templates are executed with
+type folderTemplateData struct {
+	Name        string
+	Namespace   string
+	ClusterName string
+	// ManagedCluster is the managed cluster of the per cluster dashboards
+	ManagedCluster string
+	Labels         map[string]string
+	Annotations    map[string]string
+}
+
+// renderFolderTitle executes the folder title as a go template, e.g. "{{ .Namespace }} dashboards",
+// the title is used as it is when it is not a valid template
+func renderFolderTitle(cm *corev1.ConfigMap, title string) string {
+	rendered, err := executeFolderTemplate(cm, title)
+	if err != nil {
+		klog.Error(err)
+		return title
+	}
+	return rendered
+}
+
+// validateFolderTitle checks that the folder title is a valid template which renders into a non empty title
+func validateFolderTitle(cm *corev1.ConfigMap, title string) error {
+	rendered, err := executeFolderTemplate(cm, title)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(rendered) == "" {
+		return fmt.Errorf("the folder %q is empty", title)
+	}
+	return nil
+}
+
+func executeFolderTemplate(cm *corev1.ConfigMap, title string) (string, error) {
+	if !strings.Contains(title, "{{") {
+		return title, nil
+	}
+
+	tmpl, err := template.New("folder").Option("missingkey=error").Parse(title)
+	if err != nil {
+		return "", fmt.Errorf("invalid folder template %q: %v", title, err)
+	}
+	data := folderTemplateData{
+		Name:           cm.GetName(),
+		Namespace:      cm.GetNamespace(),
+		ClusterName:    activeSettings().clusterName,
+		ManagedCluster: managedClusterOf(cm),
+		Labels:         cm.GetLabels(),
+		Annotations:    cm.GetAnnotations(),
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute folder template %q: %v", title, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRenderFolderTitle(t *testing.T) {
+	defer func(folder, cluster string) {
+		DefaultFolder = folder
+		ClusterName = cluster
+	}(DefaultFolder, ClusterName)
+	ClusterName = "hub"
+
+	testCaseList := []struct {
+		name          string
+		annotations   map[string]string
+		defaultFolder string
+		expected      string
+	}{
+
+		{
+			"plain folder",
+			map[string]string{customFolderKey: "SLOs"},
+			defaultCustomFolder,
+			"SLOs",
+		},
+
+		{
+			"namespace folder",
+			map[string]string{customFolderKey: "{{ .Namespace }} dashboards"},
+			defaultCustomFolder,
+			"team-a dashboards",
+		},
+
+		{
+			"templated default folder",
+			map[string]string{},
+			"{{ .ClusterName }} {{ .Namespace }}",
+			"hub team-a",
+		},
+
+		{
+			"per key folder",
+			map[string]string{dashboardFolderKeyPrefix + "test.json": "{{ .Name }}"},
+			defaultCustomFolder,
+			"test",
+		},
+
+		{
+			"label variable",
+			map[string]string{customFolderKey: "{{ index .Labels \"team\" }}"},
+			defaultCustomFolder,
+			"alpha",
+		},
+
+		{
+			"invalid template",
+			map[string]string{customFolderKey: "{{ .Namespace"},
+			defaultCustomFolder,
+			"{{ .Namespace",
+		},
+
+		{
+			"unknown variable",
+			map[string]string{customFolderKey: "{{ .Unknown }}"},
+			defaultCustomFolder,
+			"{{ .Unknown }}",
+		},
+	}
+
+	for _, c := range testCaseList {
+		DefaultFolder = c.defaultFolder
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Namespace:   "team-a",
+				Labels:      map[string]string{"team": "alpha"},
+				Annotations: c.annotations,
+			},
+		}
+		output := getDashboardFolderTitle(cm, "test.json")
+		if output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+var (
+	// GitRepository is the git repository to load the dashboards from, empty means disabled
+	GitRepository = ""
+	// GitRef is the branch, tag or commit of the git repository to sync
+	GitRef = "HEAD"
+	// GitPath is the directory in the git repository which contains the dashboards
+	GitPath = ""
+	// GitPollInterval is how often the git repository is fetched for new commits
+	GitPollInterval = time.Minute
+	// GitCheckoutDir is where the git repository is checked out, empty means a temp directory
+	GitCheckoutDir = ""
+)
+
+// gitSource syncs the dashboards from a git repository with the git binary shipped in the image,
+// the checked out directory follows the same layout as the filesystem source
+type gitSource struct {
+	repository  string
+	ref         string
+	checkoutDir string
+	interval    time.Duration
+	files       *filesystemSource
+	// syncedCommit is the commit which was synced successfully last time
+	syncedCommit string
+}
+
+func newGitSource(repository, ref, path, checkoutDir string, interval time.Duration) (*gitSource, error) {
+	if checkoutDir == "" {
+		dir, err := ioutil.TempDir("", "grafana-dashboards")
+		if err != nil {
+			return nil, err
+		}
+		checkoutDir = dir
+	}
+	if err := os.MkdirAll(checkoutDir, 0755); err != nil {
+		return nil, err
+	}
+
+	files := newFilesystemSource(filepath.Join(checkoutDir, path), interval)
+	files.state.source = "git:" + redactURL(repository)
+	return &gitSource{
+		repository:  repository,
+		ref:         ref,
+		checkoutDir: checkoutDir,
+		interval:    interval,
+		files:       files,
+	}, nil
+}
+
+// Run polls the git repository periodically until ctx is done
+func (g *gitSource) Run(ctx context.Context) {
+	wait.Until(func() { g.poll(ctx) }, g.interval, ctx.Done())
+}
+
+func (g *gitSource) poll(ctx context.Context) {
+	if !inFlightSyncs.start() {
+		return
+	}
+	defer inFlightSyncs.done()
+	err := g.sync(ctx)
+	if err != nil {
+		klog.Errorf("failed to sync git repository %v: %v", redactURL(g.repository), err)
+		metrics.GitSyncFailures.WithLabelValues(redactURL(g.repository), g.ref).Inc()
+	}
+}
+
+func (g *gitSource) sync(ctx context.Context) error {
+	// a hung fetch must not block the following polls and the shutdown
+	checkoutCtx, cancel := context.WithTimeout(ctx, activeSettings().syncTimeout)
+	commit, err := g.checkout(checkoutCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+	// the files of the same commit are applied again after a resync
+	if commit == g.syncedCommit && g.files.isSynced() {
+		return nil
+	}
+
+	klog.Infof("sync git repository %v at commit %v", redactURL(g.repository), commit)
+	err = g.files.sync(ctx)
+	if err != nil {
+		return err
+	}
+
+	repository := redactURL(g.repository)
+	if g.syncedCommit != "" {
+		metrics.GitLastSyncedCommit.Delete(prometheus.Labels{
+			"repository": repository, "ref": g.ref, "commit": g.syncedCommit})
+	}
+	metrics.GitLastSyncedCommit.WithLabelValues(repository, g.ref, commit).Set(1)
+	metrics.GitLastSyncTimestamp.WithLabelValues(repository, g.ref).SetToCurrentTime()
+	g.syncedCommit = commit
+	return nil
+}
+
+// checkout fetches the ref and checks it out, it returns the checked out commit
+func (g *gitSource) checkout(ctx context.Context) (string, error) {
+	if _, err := os.Stat(filepath.Join(g.checkoutDir, ".git")); os.IsNotExist(err) {
+		if _, err := g.git(ctx, "init", "--quiet"); err != nil {
+			return "", err
+		}
+		if _, err := g.git(ctx, "remote", "add", "origin", g.repository); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := g.git(ctx, "fetch", "--quiet", "--depth", "1", "origin", g.ref); err != nil {
+		return "", err
+	}
+	if _, err := g.git(ctx, "checkout", "--quiet", "--force", "FETCH_HEAD"); err != nil {
+		return "", err
+	}
+	commit, err := g.git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(commit), nil
+}
+
+func (g *gitSource) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = g.checkoutDir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		// the output may contain the credentials of the repository
+		return "", fmt.Errorf("git %v failed: %v: %s", args[0], err,
+			strings.ReplaceAll(string(out), g.repository, redactURL(g.repository)))
+	}
+	return string(out), nil
+}
+
+// redactURL removes the credentials from the repository url so that it can be logged
+func redactURL(repository string) string {
+	u, err := url.Parse(repository)
+	if err != nil || u.User == nil {
+		return repository
+	}
+	u.User = nil
+	return u.String()
+}
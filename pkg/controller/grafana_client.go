@@ -0,0 +1,555 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
+)
+
+// GrafanaClient is the grafana api used by the loader,
+// all the calls are scoped to the org, empty orgID means the default org
+type GrafanaClient interface {
+	// Folders
+	ListFolders(ctx context.Context, orgID string) ([]Folder, error)
+	GetFolder(ctx context.Context, orgID string, id float64) (Folder, error)
+	CreateFolder(ctx context.Context, orgID string, title string) (Folder, error)
+	DeleteFolder(ctx context.Context, orgID string, uid string) error
+
+	// Dashboards
+	SaveDashboard(ctx context.Context, orgID string, dashboard map[string]interface{}, folderID float64, overwrite bool) (SavedDashboard, error)
+	DeleteDashboard(ctx context.Context, orgID string, uid string) error
+	// GetDashboard returns the model of the dashboard without the meta
+	GetDashboard(ctx context.Context, orgID string, uid string) (map[string]interface{}, error)
+	// GetDashboardWithMeta returns the model of the dashboard and the meta which tells where it is stored
+	GetDashboardWithMeta(ctx context.Context, orgID string, uid string) (map[string]interface{}, DashboardMeta, error)
+	// ListDashboardVersions returns the version history of the dashboard
+	ListDashboardVersions(ctx context.Context, orgID string, uid string) ([]DashboardVersion, error)
+	// RestoreDashboardVersion saves the content of the version as the new version of the dashboard
+	RestoreDashboardVersion(ctx context.Context, orgID string, uid string, version float64) (SavedDashboard, error)
+	// GetPublicDashboardUID returns the uid of the public share of the dashboard, empty means it is not shared
+	GetPublicDashboardUID(ctx context.Context, orgID string, dashboardUID string) (string, error)
+	// SavePublicDashboard creates the public share when publicUID is empty, otherwise updates it
+	SavePublicDashboard(ctx context.Context, orgID string, dashboardUID string, publicUID string, config map[string]interface{}) error
+	GetPreferences(ctx context.Context, orgID string) (map[string]interface{}, error)
+	UpdatePreferences(ctx context.Context, orgID string, preferences map[string]interface{}) error
+
+	// Search
+	SearchFolderDashboards(ctx context.Context, orgID string, folderID float64) ([]SearchHit, error)
+	// SearchDashboards returns all the dashboards of the org
+	SearchDashboards(ctx context.Context, orgID string) ([]SearchHit, error)
+
+	// Organizations
+	// GetOrgID returns the id of the organization with the name, empty means there is no such organization
+	GetOrgID(ctx context.Context, name string) (string, error)
+	CreateOrg(ctx context.Context, name string) (string, error)
+
+	// Teams
+	// GetTeamID returns the id of the team with the name, 0 means there is no such team
+	GetTeamID(ctx context.Context, orgID string, name string) (float64, error)
+	CreateTeam(ctx context.Context, orgID string, name string) (float64, error)
+	ListTeamMembers(ctx context.Context, orgID string, teamID float64) ([]TeamMember, error)
+	AddTeamMember(ctx context.Context, orgID string, teamID float64, userID float64) error
+	RemoveTeamMember(ctx context.Context, orgID string, teamID float64, userID float64) error
+	// GetUserID returns the id of the user with the login, 0 means the user has never logged into grafana
+	GetUserID(ctx context.Context, login string) (float64, error)
+	GetFolderPermissions(ctx context.Context, orgID string, folderUID string) ([]FolderPermission, error)
+	// UpdateFolderPermissions replaces all the permissions of the folder
+	UpdateFolderPermissions(ctx context.Context, orgID string, folderUID string, permissions []FolderPermission) error
+
+	// Health
+	Health(ctx context.Context) error
+}
+
+// Folder is a grafana folder
+type Folder struct {
+	ID    float64 `json:"id"`
+	UID   string  `json:"uid"`
+	Title string  `json:"title"`
+}
+
+// SavedDashboard is the response of grafana to a created or updated dashboard
+type SavedDashboard struct {
+	ID      float64 `json:"id"`
+	UID     string  `json:"uid"`
+	Version float64 `json:"version"`
+}
+
+// DashboardVersion is a version in the history of a dashboard
+type DashboardVersion struct {
+	Version   float64 `json:"version"`
+	Created   string  `json:"created"`
+	CreatedBy string  `json:"createdBy"`
+	Message   string  `json:"message"`
+}
+
+// DashboardMeta is the meta of a stored dashboard
+type DashboardMeta struct {
+	FolderID float64 `json:"folderId"`
+}
+
+// TeamMember is a user of a grafana team
+type TeamMember struct {
+	UserID float64 `json:"userId"`
+	Login  string  `json:"login"`
+}
+
+// FolderPermission grants the permission of a folder to a team, a user or an org role,
+// the permission is 1 for view, 2 for edit and 4 for admin
+type FolderPermission struct {
+	TeamID     float64 `json:"teamId,omitempty"`
+	UserID     float64 `json:"userId,omitempty"`
+	Role       string  `json:"role,omitempty"`
+	Permission int     `json:"permission"`
+}
+
+// SearchHit is a dashboard or folder found by the search api
+type SearchHit struct {
+	ID          float64  `json:"id"`
+	UID         string   `json:"uid"`
+	Title       string   `json:"title"`
+	Type        string   `json:"type"`
+	Tags        []string `json:"tags"`
+	FolderID    float64  `json:"folderId"`
+	FolderTitle string   `json:"folderTitle"`
+}
+
+// GrafanaAPIError is returned when grafana does not respond with 200
+type GrafanaAPIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *GrafanaAPIError) Error() string {
+	return fmt.Sprintf("%v %v failed with %v: %s", e.Method, e.URL, e.StatusCode, e.Body)
+}
+
+// grafanaStatus returns the status code of the grafana response of the error, 0 means no response
+func grafanaStatus(err error) int {
+	if apiErr, ok := err.(*GrafanaAPIError); ok {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// isGrafanaNotFound checks whether grafana answered the request with 404, the request without a response
+// is reported as 404 without a body as well so it does not mean the object is gone
+func isGrafanaNotFound(err error) bool {
+	apiErr, ok := err.(*GrafanaAPIError)
+	return ok && apiErr.StatusCode == http.StatusNotFound && apiErr.Body != nil
+}
+
+// grafanaClient is the client used by the controller
+var grafanaClient GrafanaClient = &httpGrafanaClient{}
+
+// httpGrafanaClient calls the grafana http api on grafanaURI, or on the url of a grafana target,
+// the changes are only logged under dry-run mode
+type httpGrafanaClient struct {
+	// url and auth are the grafana target, empty url means grafanaURI with the global credentials
+	url  string
+	auth util.GrafanaAuth
+	// cloud means the target is a grafana cloud stack, the default grafana is one under GrafanaCloud
+	cloud bool
+	// replicas are the other urls of the target, the default grafana has grafanaReplicaURIs
+	replicas []string
+}
+
+// request returns the context and the url of the request to the target, the url is the one of the active replica
+func (c *httpGrafanaClient) request(ctx context.Context, path string) (context.Context, string) {
+	url, others := normalizeGrafanaURL(c.url), c.replicas
+	if c.url == "" {
+		s := activeSettings()
+		url, others = normalizeGrafanaURL(s.grafanaURI), s.grafanaReplicas
+	} else {
+		ctx = util.WithGrafanaAuth(ctx, c.auth)
+	}
+	if replicas := replicasOf(url, others); replicas != nil {
+		return util.WithReplicas(ctx, replicas), replicas.Active() + path
+	}
+	return ctx, url + path
+}
+
+func (c *httpGrafanaClient) isCloud() bool {
+	return c.cloud || c.url == "" && GrafanaCloud
+}
+
+func (c *httpGrafanaClient) get(ctx context.Context, orgID string, path string, out interface{}) error {
+	if c.isCloud() {
+		if err := checkCloudOrg(orgID); err != nil {
+			return err
+		}
+	}
+	ctx, grafanaURL := c.request(ctx, path)
+	body, respStatusCode := util.SetOrgRequestContext(ctx, "GET", grafanaURL, nil, activeSettings().retry, orgID)
+	if respStatusCode != http.StatusOK {
+		return &GrafanaAPIError{"GET", grafanaURL, respStatusCode, body}
+	}
+	err := json.Unmarshal(body, out)
+	if err != nil {
+		return fmt.Errorf("%v: %v", unmarshallErrMsg, err)
+	}
+	return nil
+}
+
+func (c *httpGrafanaClient) mutate(ctx context.Context, orgID string, method string, path string, in interface{}) ([]byte, error) {
+	var b []byte
+	if in != nil {
+		var err error
+		b, err = json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.isCloud() {
+		if err := checkCloudOrg(orgID); err != nil {
+			return nil, err
+		}
+	}
+	ctx, grafanaURL := c.request(ctx, path)
+	body, respStatusCode := setMutatingRequest(ctx, orgID, method, grafanaURL, b)
+	if respStatusCode != http.StatusOK {
+		return body, &GrafanaAPIError{method, grafanaURL, respStatusCode, body}
+	}
+	return body, nil
+}
+
+// ListFolders gets the folders by the search api which is paginated unlike /api/folders of the older grafana versions
+func (c *httpGrafanaClient) ListFolders(ctx context.Context, orgID string) ([]Folder, error) {
+	hits, err := c.search(ctx, orgID, "type=dash-folder")
+	folders := []Folder{}
+	for _, hit := range hits {
+		folders = append(folders, Folder{ID: hit.ID, UID: hit.UID, Title: hit.Title})
+	}
+	return folders, err
+}
+
+func (c *httpGrafanaClient) GetFolder(ctx context.Context, orgID string, id float64) (Folder, error) {
+	folder := Folder{}
+	err := c.get(ctx, orgID, "/api/folders/id/"+fmt.Sprint(id), &folder)
+	return folder, err
+}
+
+func (c *httpGrafanaClient) CreateFolder(ctx context.Context, orgID string, title string) (Folder, error) {
+	data := map[string]string{"title": title}
+	if description := folderDescription(ctx); description != "" {
+		data["description"] = description
+	}
+	body, err := c.mutate(ctx, orgID, "POST", "/api/folders", data)
+	if err != nil {
+		return Folder{}, err
+	}
+	if DryRun {
+		return Folder{ID: dryRunFolderID, Title: title}, nil
+	}
+	folder := Folder{}
+	err = json.Unmarshal(body, &folder)
+	if err != nil {
+		return Folder{}, fmt.Errorf("%v: %v", unmarshallErrMsg, err)
+	}
+	return folder, nil
+}
+
+func (c *httpGrafanaClient) DeleteFolder(ctx context.Context, orgID string, uid string) error {
+	_, err := c.mutate(ctx, orgID, "DELETE", "/api/folders/"+uid, nil)
+	return err
+}
+
+func (c *httpGrafanaClient) SaveDashboard(ctx context.Context, orgID string, dashboard map[string]interface{},
+	folderID float64, overwrite bool) (SavedDashboard, error) {
+	data := map[string]interface{}{
+		"folderId":  folderID,
+		"overwrite": overwrite,
+		"dashboard": dashboard,
+	}
+	body, err := c.mutate(ctx, orgID, "POST", "/api/dashboards/db", data)
+	if err != nil {
+		return SavedDashboard{}, err
+	}
+
+	saved := SavedDashboard{}
+	if !DryRun {
+		err = json.Unmarshal(body, &saved)
+		if err != nil {
+			klog.Infof("failed to parse the saved dashboard: %v", err)
+		}
+	}
+	if saved.UID == "" {
+		saved.UID, _ = dashboard["uid"].(string)
+	}
+	return saved, nil
+}
+
+func (c *httpGrafanaClient) DeleteDashboard(ctx context.Context, orgID string, uid string) error {
+	_, err := c.mutate(ctx, orgID, "DELETE", "/api/dashboards/uid/"+uid, nil)
+	return err
+}
+
+func (c *httpGrafanaClient) GetDashboard(ctx context.Context, orgID string, uid string) (map[string]interface{}, error) {
+	result := struct {
+		Dashboard map[string]interface{} `json:"dashboard"`
+	}{}
+	err := c.get(ctx, orgID, "/api/dashboards/uid/"+uid, &result)
+	return result.Dashboard, err
+}
+
+func (c *httpGrafanaClient) GetDashboardWithMeta(ctx context.Context, orgID string, uid string) (map[string]interface{},
+	DashboardMeta, error) {
+	result := struct {
+		Dashboard map[string]interface{} `json:"dashboard"`
+		Meta      DashboardMeta          `json:"meta"`
+	}{}
+	err := c.get(ctx, orgID, "/api/dashboards/uid/"+uid, &result)
+	return result.Dashboard, result.Meta, err
+}
+
+// ListDashboardVersions reads the list of the older grafana versions and the paginated result of the newer ones
+func (c *httpGrafanaClient) ListDashboardVersions(ctx context.Context, orgID string, uid string) ([]DashboardVersion, error) {
+	result := json.RawMessage{}
+	if err := c.get(ctx, orgID, "/api/dashboards/uid/"+uid+"/versions", &result); err != nil {
+		return nil, err
+	}
+	versions := []DashboardVersion{}
+	if trimmed := bytes.TrimSpace(result); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &versions); err != nil {
+			return nil, fmt.Errorf("%v: %v", unmarshallErrMsg, err)
+		}
+		return versions, nil
+	}
+	page := struct {
+		Versions []DashboardVersion `json:"versions"`
+	}{}
+	if err := json.Unmarshal(result, &page); err != nil {
+		return nil, fmt.Errorf("%v: %v", unmarshallErrMsg, err)
+	}
+	return append(versions, page.Versions...), nil
+}
+
+func (c *httpGrafanaClient) RestoreDashboardVersion(ctx context.Context, orgID string, uid string,
+	version float64) (SavedDashboard, error) {
+	body, err := c.mutate(ctx, orgID, "POST", "/api/dashboards/uid/"+uid+"/restore", map[string]float64{"version": version})
+	if err != nil {
+		return SavedDashboard{}, err
+	}
+
+	saved := SavedDashboard{}
+	if !DryRun {
+		err = json.Unmarshal(body, &saved)
+		if err != nil {
+			klog.Infof("failed to parse the restored dashboard: %v", err)
+		}
+	}
+	if saved.UID == "" {
+		saved.UID = uid
+	}
+	return saved, nil
+}
+
+func (c *httpGrafanaClient) GetPublicDashboardUID(ctx context.Context, orgID string, dashboardUID string) (string, error) {
+	existing := struct {
+		UID string `json:"uid"`
+	}{}
+	err := c.get(ctx, orgID, "/api/dashboards/uid/"+dashboardUID+"/public-dashboards", &existing)
+	if apiErr, ok := err.(*GrafanaAPIError); ok && apiErr.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	return existing.UID, err
+}
+
+func (c *httpGrafanaClient) SavePublicDashboard(ctx context.Context, orgID string, dashboardUID string, publicUID string,
+	config map[string]interface{}) error {
+	path := "/api/dashboards/uid/" + dashboardUID + "/public-dashboards"
+	method := "POST"
+	if publicUID != "" {
+		method = "PATCH"
+		path = path + "/" + publicUID
+	}
+	_, err := c.mutate(ctx, orgID, method, path, config)
+	return err
+}
+
+func (c *httpGrafanaClient) GetPreferences(ctx context.Context, orgID string) (map[string]interface{}, error) {
+	preferences := map[string]interface{}{}
+	err := c.get(ctx, orgID, "/api/org/preferences", &preferences)
+	return preferences, err
+}
+
+func (c *httpGrafanaClient) UpdatePreferences(ctx context.Context, orgID string, preferences map[string]interface{}) error {
+	_, err := c.mutate(ctx, orgID, "PUT", "/api/org/preferences", preferences)
+	return err
+}
+
+func (c *httpGrafanaClient) SearchFolderDashboards(ctx context.Context, orgID string, folderID float64) ([]SearchHit, error) {
+	return c.search(ctx, orgID, "folderIds="+fmt.Sprint(folderID))
+}
+
+func (c *httpGrafanaClient) SearchDashboards(ctx context.Context, orgID string) ([]SearchHit, error) {
+	return c.search(ctx, orgID, "type=dash-db")
+}
+
+// searchPageLimit is the number of the hits got by every search request, grafana caps it at 5000
+const searchPageLimit = 1000
+
+// search gets all the hits of the query page by page
+func (c *httpGrafanaClient) search(ctx context.Context, orgID string, query string) ([]SearchHit, error) {
+	hits := []SearchHit{}
+	for page := 1; ; page++ {
+		pageHits := []SearchHit{}
+		path := fmt.Sprintf("/api/search?%v&limit=%v&page=%v", query, searchPageLimit, page)
+		if err := c.get(ctx, orgID, path, &pageHits); err != nil {
+			return hits, err
+		}
+		hits = append(hits, pageHits...)
+		if len(pageHits) < searchPageLimit {
+			return hits, nil
+		}
+	}
+}
+
+func (c *httpGrafanaClient) GetOrgID(ctx context.Context, name string) (string, error) {
+	if c.isCloud() {
+		return "", errCloudOrganizations
+	}
+	org := struct {
+		ID float64 `json:"id"`
+	}{}
+	err := c.get(ctx, "", "/api/orgs/name/"+url.PathEscape(name), &org)
+	if apiErr, ok := err.(*GrafanaAPIError); ok && apiErr.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(org.ID), nil
+}
+
+func (c *httpGrafanaClient) CreateOrg(ctx context.Context, name string) (string, error) {
+	if c.isCloud() {
+		return "", errCloudOrganizations
+	}
+	body, err := c.mutate(ctx, "", "POST", "/api/orgs", map[string]string{"name": name})
+	if err != nil {
+		return "", err
+	}
+	created := struct {
+		OrgID float64 `json:"orgId"`
+	}{}
+	err = json.Unmarshal(body, &created)
+	if err != nil {
+		return "", fmt.Errorf("%v: %v", unmarshallErrMsg, err)
+	}
+	return fmt.Sprint(created.OrgID), nil
+}
+
+func (c *httpGrafanaClient) GetTeamID(ctx context.Context, orgID string, name string) (float64, error) {
+	result := struct {
+		Teams []struct {
+			ID   float64 `json:"id"`
+			Name string  `json:"name"`
+		} `json:"teams"`
+	}{}
+	err := c.get(ctx, orgID, "/api/teams/search?name="+url.QueryEscape(name), &result)
+	if err != nil {
+		return 0, err
+	}
+	for _, team := range result.Teams {
+		if team.Name == name {
+			return team.ID, nil
+		}
+	}
+	return 0, nil
+}
+
+func (c *httpGrafanaClient) CreateTeam(ctx context.Context, orgID string, name string) (float64, error) {
+	body, err := c.mutate(ctx, orgID, "POST", "/api/teams", map[string]string{"name": name})
+	if err != nil {
+		return 0, err
+	}
+	if DryRun {
+		return dryRunTeamID, nil
+	}
+	created := struct {
+		TeamID float64 `json:"teamId"`
+	}{}
+	err = json.Unmarshal(body, &created)
+	if err != nil {
+		return 0, fmt.Errorf("%v: %v", unmarshallErrMsg, err)
+	}
+	return created.TeamID, nil
+}
+
+func (c *httpGrafanaClient) ListTeamMembers(ctx context.Context, orgID string, teamID float64) ([]TeamMember, error) {
+	members := []TeamMember{}
+	err := c.get(ctx, orgID, "/api/teams/"+fmt.Sprint(teamID)+"/members", &members)
+	return members, err
+}
+
+func (c *httpGrafanaClient) AddTeamMember(ctx context.Context, orgID string, teamID float64, userID float64) error {
+	_, err := c.mutate(ctx, orgID, "POST", "/api/teams/"+fmt.Sprint(teamID)+"/members", map[string]float64{"userId": userID})
+	return err
+}
+
+func (c *httpGrafanaClient) RemoveTeamMember(ctx context.Context, orgID string, teamID float64, userID float64) error {
+	_, err := c.mutate(ctx, orgID, "DELETE", "/api/teams/"+fmt.Sprint(teamID)+"/members/"+fmt.Sprint(userID), nil)
+	return err
+}
+
+func (c *httpGrafanaClient) GetUserID(ctx context.Context, login string) (float64, error) {
+	if c.isCloud() {
+		// the users of grafana cloud are looked up in the organization since there is no server admin api
+		users := []TeamMember{}
+		err := c.get(ctx, "", "/api/org/users/lookup?limit=10&query="+url.QueryEscape(login), &users)
+		if err != nil {
+			return 0, err
+		}
+		for _, user := range users {
+			if user.Login == login {
+				return user.UserID, nil
+			}
+		}
+		return 0, nil
+	}
+	user := struct {
+		ID float64 `json:"id"`
+	}{}
+	err := c.get(ctx, "", "/api/users/lookup?loginOrEmail="+url.QueryEscape(login), &user)
+	if apiErr, ok := err.(*GrafanaAPIError); ok && apiErr.StatusCode == http.StatusNotFound {
+		return 0, nil
+	}
+	return user.ID, err
+}
+
+func (c *httpGrafanaClient) GetFolderPermissions(ctx context.Context, orgID string, folderUID string) ([]FolderPermission, error) {
+	permissions := []FolderPermission{}
+	err := c.get(ctx, orgID, "/api/folders/"+folderUID+"/permissions", &permissions)
+	return permissions, err
+}
+
+func (c *httpGrafanaClient) UpdateFolderPermissions(ctx context.Context, orgID string, folderUID string,
+	permissions []FolderPermission) error {
+	_, err := c.mutate(ctx, orgID, "POST", "/api/folders/"+folderUID+"/permissions",
+		map[string]interface{}{"items": permissions})
+	return err
+}
+
+func (c *httpGrafanaClient) Health(ctx context.Context) error {
+	health := map[string]interface{}{}
+	err := c.get(ctx, "", "/api/health", &health)
+	if err != nil {
+		return err
+	}
+	if health["database"] != "ok" {
+		return fmt.Errorf("grafana database is %v", health["database"])
+	}
+	return nil
+}
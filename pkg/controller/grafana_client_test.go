@@ -0,0 +1,175 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/features"
+)
+
+func TestHTTPGrafanaClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/api/health":
+			w.Write([]byte("{\"database\": \"ok\"}"))
+		case "/api/folders":
+			w.Write([]byte("{\"id\": 5, \"uid\": \"slo\", \"title\": \"SLOs\"}"))
+		case "/api/dashboards/uid/test":
+			w.Write([]byte("{\"dashboard\": {\"uid\": \"test\", \"title\": \"Test\"}, \"meta\": {\"folderId\": 5}}"))
+		case "/api/search":
+			w.Write([]byte("[{\"uid\": \"test\", \"type\": \"dash-db\", \"folderId\": 5, \"folderTitle\": \"SLOs\"}]"))
+		case "/api/dashboards/uid/test/versions":
+			w.Write([]byte("[{\"version\": 2}, {\"version\": 1}]"))
+		case "/api/dashboards/uid/paged/versions":
+			w.Write([]byte("{\"continueToken\": \"\", \"versions\": [{\"version\": 3, \"message\": \"Restored from version 1\"}]}"))
+		case "/api/dashboards/uid/test/restore":
+			w.Write([]byte("{\"id\": 7, \"uid\": \"test\", \"version\": 3}"))
+		case "/api/dashboards/db":
+			w.WriteHeader(http.StatusPreconditionFailed)
+			w.Write([]byte("{\"status\": \"version-mismatch\"}"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	defer func(uri string, r int) {
+		grafanaURI = uri
+		retry = r
+	}(grafanaURI, retry)
+	grafanaURI = server.URL
+	retry = 1
+	client := &httpGrafanaClient{}
+
+	if err := client.Health(context.TODO()); err != nil {
+		t.Errorf("grafana should be healthy: %v", err)
+	}
+
+	folder, err := client.CreateFolder(context.TODO(), "", "SLOs")
+	if err != nil || folder.ID != 5 || folder.UID != "slo" {
+		t.Errorf("folder (%v) is not the expected: %v", folder, err)
+	}
+
+	_, err = client.SaveDashboard(context.TODO(), "", map[string]interface{}{"uid": "test"}, 0, false)
+	apiErr, ok := err.(*GrafanaAPIError)
+	if !ok || apiErr.StatusCode != http.StatusPreconditionFailed || string(apiErr.Body) != "{\"status\": \"version-mismatch\"}" {
+		t.Errorf("error (%v) is not the expected version mismatch", err)
+	}
+
+	dashboard, err := client.GetDashboard(context.TODO(), "", "test")
+	if err != nil || dashboard["title"] != "Test" {
+		t.Errorf("dashboard (%v) is not the expected: %v", dashboard, err)
+	}
+
+	hits, err := client.SearchDashboards(context.TODO(), "")
+	if err != nil || len(hits) != 1 || hits[0].FolderTitle != "SLOs" {
+		t.Errorf("search result (%v) is not the expected: %v", hits, err)
+	}
+
+	versions, err := client.ListDashboardVersions(context.TODO(), "", "test")
+	if err != nil || len(versions) != 2 || versions[0].Version != 2 {
+		t.Errorf("versions (%v) are not the expected: %v", versions, err)
+	}
+
+	versions, err = client.ListDashboardVersions(context.TODO(), "", "paged")
+	if err != nil || len(versions) != 1 || versions[0].Message != "Restored from version 1" {
+		t.Errorf("paged versions (%v) are not the expected: %v", versions, err)
+	}
+
+	saved, err := client.RestoreDashboardVersion(context.TODO(), "", "test", 1)
+	if err != nil || saved.Version != 3 || saved.ID != 7 {
+		t.Errorf("restored dashboard (%v) is not the expected: %v", saved, err)
+	}
+
+	uid, err := client.GetPublicDashboardUID(context.TODO(), "", "test")
+	if err != nil || uid != "" {
+		t.Errorf("dashboard which is not shared should have no public uid: %v %v", uid, err)
+	}
+}
+
+func TestHTTPGrafanaClientSearchPages(t *testing.T) {
+	pages := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		page := req.URL.Query().Get("page")
+		pages = append(pages, page)
+		hits := []SearchHit{}
+		if page != "3" {
+			for i := 0; i < searchPageLimit; i++ {
+				hits = append(hits, SearchHit{UID: fmt.Sprintf("%v-%v", page, i), Type: "dash-db"})
+			}
+		} else {
+			hits = append(hits, SearchHit{UID: "last", Type: "dash-db"})
+		}
+		body, _ := json.Marshal(hits)
+		w.Write(body)
+	}))
+	defer server.Close()
+	defer func(r int) { retry = r }(retry)
+	retry = 1
+
+	client := &httpGrafanaClient{url: server.URL}
+	hits, err := client.SearchDashboards(context.TODO(), "")
+	if err != nil || len(hits) != 2*searchPageLimit+1 {
+		t.Errorf("case (hits) output: (%v, %v) is not the expected: (%v)", len(hits), err, 2*searchPageLimit+1)
+	}
+	if expected := []string{"1", "2", "3"}; !reflect.DeepEqual(pages, expected) {
+		t.Errorf("case (pages) output: (%v) is not the expected: (%v)", pages, expected)
+	}
+}
+
+func TestDashboardWithFakeGrafanaClient(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Namespace:   "test",
+			Annotations: map[string]string{customFolderKey: "SLOs", homeDashboardKey: "true"},
+		},
+		Data: map[string]string{"test.json": "{\"uid\": \"slo\", \"title\": \"SLO\"}"},
+	}
+
+	if err := updateDashboard(context.TODO(), nil, cm, false); err != nil {
+		t.Fatalf("failed to update dashboard: %v", err)
+	}
+	if len(fake.folders[""]) != 1 || fake.folders[""][0].Title != "SLOs" {
+		t.Errorf("folders (%v) are not the expected", fake.folders[""])
+	}
+	saved, ok := fake.dashboards[""]["slo"]
+	if !ok || saved.folderID != fake.folders[""][0].ID {
+		t.Errorf("dashboard (%v) is not saved into the folder", saved)
+	}
+	if fake.preferences[""]["homeDashboardUID"] != "slo" {
+		t.Errorf("preferences (%v) do not have the home dashboard", fake.preferences[""])
+	}
+
+	deleteDashboard(context.TODO(), cm)
+	if len(fake.dashboards[""]) != 0 || len(fake.folders[""]) != 0 {
+		t.Errorf("dashboards (%v) and folders (%v) should be deleted", fake.dashboards[""], fake.folders[""])
+	}
+
+	// the empty folder is kept once the cleanup is disabled
+	if err := features.DefaultMutableFeatureGate.Set("EmptyFolderCleanup=false"); err != nil {
+		t.Fatalf("failed to disable the folder cleanup: %v", err)
+	}
+	defer features.DefaultMutableFeatureGate.Set("EmptyFolderCleanup=true")
+	if err := updateDashboard(context.TODO(), nil, cm, false); err != nil {
+		t.Fatalf("failed to update dashboard: %v", err)
+	}
+	deleteDashboard(context.TODO(), cm)
+	if len(fake.dashboards[""]) != 0 || len(fake.folders[""]) != 1 {
+		t.Errorf("dashboards (%v) should be deleted and folders (%v) should be kept", fake.dashboards[""], fake.folders[""])
+	}
+}
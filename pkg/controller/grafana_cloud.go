@@ -0,0 +1,35 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// GrafanaCloud makes the default grafana a hosted grafana cloud stack, which is authenticated with a stack scoped
+// service account token, has a single organization and does not serve the server admin api
+var GrafanaCloud = false
+
+// errCloudOrganizations is returned by the organization api of grafana cloud
+var errCloudOrganizations = errors.New("a grafana cloud stack has a single organization, the organizations cannot be managed")
+
+// cloudOrgID is the only organization of a grafana cloud stack
+const cloudOrgID = "1"
+
+// checkCloudOrg checks that the request goes to the organization of the grafana cloud stack
+func checkCloudOrg(orgID string) error {
+	if orgID != "" && orgID != cloudOrgID {
+		return fmt.Errorf("the grafana organization %v is not available, %v", orgID, errCloudOrganizations)
+	}
+	return nil
+}
+
+// normalizeGrafanaURL trims the trailing slash and /api from the url of grafana since all the paths start with /api,
+// e.g. the api url of grafana cloud https://example.grafana.net/api is the same as https://example.grafana.net
+func normalizeGrafanaURL(url string) string {
+	url = strings.TrimSuffix(url, "/")
+	return strings.TrimSuffix(url, "/api")
+}
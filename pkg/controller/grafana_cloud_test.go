@@ -0,0 +1,75 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
+)
+
+func TestNormalizeGrafanaURL(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{"plain", "https://example.grafana.net", "https://example.grafana.net"},
+
+		{"trailing slash", "https://example.grafana.net/", "https://example.grafana.net"},
+
+		{"api prefix", "https://example.grafana.net/api/", "https://example.grafana.net"},
+
+		{"sub path", "https://example.com/grafana", "https://example.com/grafana"},
+	}
+
+	for _, c := range testCaseList {
+		if output := normalizeGrafanaURL(c.url); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestGrafanaCloudClient(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.URL.Path)
+		if req.Header.Get("Authorization") != "Bearer glsa_token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch req.URL.Path {
+		case "/api/search":
+			w.Write([]byte(`[{"id": 5, "uid": "slo", "title": "SLOs", "type": "dash-folder"}]`))
+		case "/api/org/users/lookup":
+			w.Write([]byte(`[{"userId": 7, "login": "alice-admin"}, {"userId": 8, "login": "alice"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	defer func(r int) { retry = r }(retry)
+	retry = 1
+
+	client := &httpGrafanaClient{url: server.URL + "/api", auth: util.GrafanaAuth{BearerToken: "glsa_token"}, cloud: true}
+	if folders, err := client.ListFolders(context.TODO(), "1"); err != nil || len(folders) != 1 {
+		t.Errorf("case (folders) output: (%v, %v) is not the expected: (%v)", folders, err, 1)
+	}
+	if _, err := client.ListFolders(context.TODO(), "2"); err == nil {
+		t.Errorf("case (other org) output: (%v) is not the expected: (%v)", err, "an error")
+	}
+	if userID, err := client.GetUserID(context.TODO(), "alice"); err != nil || userID != 8 {
+		t.Errorf("case (user) output: (%v, %v) is not the expected: (%v)", userID, err, 8)
+	}
+	if _, err := client.CreateOrg(context.TODO(), "team-a"); err != errCloudOrganizations {
+		t.Errorf("case (org) output: (%v) is not the expected: (%v)", err, errCloudOrganizations)
+	}
+	expected := []string{"/api/search", "/api/org/users/lookup"}
+	if len(requests) != len(expected) || requests[0] != expected[0] || requests[1] != expected[1] {
+		t.Errorf("case (requests) output: (%v) is not the expected: (%v)", requests, expected)
+	}
+}
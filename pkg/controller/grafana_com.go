@@ -0,0 +1,99 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
+)
+
+// grafanaComSuffix marks the data keys which refer to a dashboard published on grafana.com, e.g.
+//
+//	node-exporter.grafana-com: '{"id": 1860, "revision": 31, "inputs": {"DS_PROMETHEUS": "Observatorium"}}'
+const grafanaComSuffix = ".grafana-com"
+
+// GrafanaComURL is where the referred dashboards are downloaded from, it can point to a mirror
+var GrafanaComURL = "https://grafana.com"
+
+// grafanaComReference refers to a dashboard revision on grafana.com
+type grafanaComReference struct {
+	ID       int `json:"id"`
+	Revision int `json:"revision"`
+	// Inputs are the values of the dashboard __inputs keyed by the input name
+	Inputs map[string]string `json:"inputs,omitempty"`
+}
+
+// dashboardInput is an entry of __inputs in the dashboards exported for sharing
+type dashboardInput struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	PluginID string `json:"pluginId"`
+	Value    string `json:"value"`
+}
+
+// a published revision never changes so the downloaded dashboards are cached for good
+var grafanaComCache = newDownloadCache()
+
+func isGrafanaComReference(key string) bool {
+	return strings.HasSuffix(key, grafanaComSuffix)
+}
+
+// resolveGrafanaComDashboard downloads the referred dashboard and substitutes its inputs
+func resolveGrafanaComDashboard(ctx context.Context, value string) (string, error) {
+	ref := grafanaComReference{}
+	err := json.Unmarshal([]byte(value), &ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid grafana.com reference: %v", err)
+	}
+	if ref.ID <= 0 || ref.Revision <= 0 {
+		return "", fmt.Errorf("invalid grafana.com reference: id and revision are required")
+	}
+
+	cacheKey := fmt.Sprintf("%v/%v", ref.ID, ref.Revision)
+	dashboard, ok := grafanaComCache.get(cacheKey)
+	if !ok {
+		url := fmt.Sprintf("%v/api/dashboards/%v/revisions/%v/download", GrafanaComURL, ref.ID, ref.Revision)
+		klog.Infof("download dashboard %v revision %v from grafana.com", ref.ID, ref.Revision)
+		body, err := util.Download(ctx, url, activeSettings().retry, maxDecompressedSize)
+		if err != nil {
+			return "", err
+		}
+		dashboard = string(body)
+		grafanaComCache.add(cacheKey, dashboard)
+	}
+
+	return substituteInputs(dashboard, ref.Inputs)
+}
+
+// substituteInputs replaces the ${NAME} placeholders of the dashboard __inputs,
+// an input without a given or configured value falls back to the value in the dashboard
+func substituteInputs(dashboard string, values map[string]string) (string, error) {
+	content := struct {
+		Inputs []dashboardInput `json:"__inputs"`
+	}{}
+	err := json.Unmarshal([]byte(dashboard), &content)
+	if err != nil {
+		return "", err
+	}
+
+	for _, input := range content.Inputs {
+		value, ok := inputValue(input, values)
+		if !ok {
+			return "", fmt.Errorf("the value of %v input %v is not set", input.Type, input.Name)
+		}
+		// the placeholders are in json strings so the value needs to be escaped
+		escaped, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		dashboard = strings.ReplaceAll(dashboard, "${"+input.Name+"}", string(escaped[1:len(escaped)-1]))
+	}
+	return dashboard, nil
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const sharedDashboard = `{
+  "__inputs": [
+    {"name": "DS_PROMETHEUS", "type": "datasource", "pluginId": "prometheus"},
+    {"name": "VAR_JOB", "type": "constant", "value": "node"}
+  ],
+  "panels": [{"datasource": "${DS_PROMETHEUS}", "targets": [{"expr": "up{job=\"${VAR_JOB}\"}"}]}],
+  "title": "Node Exporter",
+  "uid": "rYdddlPWk"
+}`
+
+func TestResolveGrafanaComDashboard(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/dashboards/1860/revisions/31/download" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		downloads++
+		w.Write([]byte(sharedDashboard))
+	}))
+	defer server.Close()
+
+	originalURL := GrafanaComURL
+	GrafanaComURL = server.URL
+	retry = 1
+	defer func() {
+		GrafanaComURL = originalURL
+	}()
+
+	testCaseList := []struct {
+		name     string
+		value    string
+		expected string
+		hasErr   bool
+	}{
+
+		{
+			"invalid reference",
+			"{\"id\": 1860}",
+			"",
+			true,
+		},
+
+		{
+			"unknown dashboard",
+			"{\"id\": 1, \"revision\": 1}",
+			"",
+			true,
+		},
+
+		{
+			"missing datasource",
+			"{\"id\": 1860, \"revision\": 31}",
+			"",
+			true,
+		},
+
+		{
+			"datasource input",
+			"{\"id\": 1860, \"revision\": 31, \"inputs\": {\"DS_PROMETHEUS\": \"Observatorium\"}}",
+			`"datasource": "Observatorium", "targets": [{"expr": "up{job=\"node\"}"}]`,
+			false,
+		},
+	}
+
+	for _, c := range testCaseList {
+		output, err := resolveGrafanaComDashboard(context.TODO(), c.value)
+		if (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+		if c.expected != "" && !strings.Contains(output, c.expected) {
+			t.Errorf("case (%v) output: (%v) does not contain: (%v)", c.name, output, c.expected)
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("the dashboard is downloaded %v times instead of once", downloads)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Data: map[string]string{
+			"node-exporter.grafana-com": "{\"id\": 1860, \"revision\": 31, \"inputs\": {\"DS_PROMETHEUS\": \"Observatorium\"}}",
+		},
+	}
+	dashboards, err := getDashboardData(context.TODO(), cm)
+	if err != nil || !strings.Contains(dashboards["node-exporter.grafana-com"], "rYdddlPWk") {
+		t.Fatalf("the referred dashboard is not resolved: %v", err)
+	}
+}
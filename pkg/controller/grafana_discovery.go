@@ -0,0 +1,156 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// GrafanaServiceSelector is the label selector of the grafana services the dashboards are applied to,
+	// empty disables the discovery
+	GrafanaServiceSelector = ""
+	// GrafanaServiceNamespace is where the grafana services are, empty means the namespace of the loader
+	GrafanaServiceNamespace = ""
+	// GrafanaServicePort is the name of the service port grafana is served on
+	GrafanaServicePort = "http"
+	// GrafanaServiceScheme is the scheme of the discovered grafana urls
+	GrafanaServiceScheme = "http"
+	// GrafanaServiceEndpoints applies the dashboards to every ready endpoint of the services instead of the services,
+	// for the grafana replicas which do not share a database
+	GrafanaServiceEndpoints = false
+)
+
+// serviceTargets returns the target of the service, nil when the service has no port of the name
+func serviceTargets(svc *corev1.Service) []targetSettings {
+	for _, port := range svc.Spec.Ports {
+		if port.Name == GrafanaServicePort {
+			host := fmt.Sprintf("%v.%v.svc", svc.Name, svc.Namespace)
+			return []targetSettings{{
+				name: svc.Namespace + "/" + svc.Name,
+				url:  fmt.Sprintf("%v://%v", GrafanaServiceScheme, net.JoinHostPort(host, fmt.Sprint(port.Port))),
+			}}
+		}
+	}
+	return nil
+}
+
+// endpointsTargets returns a target for every ready address of the endpoints, the addresses which are not ready are skipped
+func endpointsTargets(endpoints *corev1.Endpoints) []targetSettings {
+	targets := []targetSettings{}
+	for _, subset := range endpoints.Subsets {
+		for _, port := range subset.Ports {
+			if port.Name != GrafanaServicePort {
+				continue
+			}
+			for _, address := range subset.Addresses {
+				name := address.IP
+				if address.TargetRef != nil && address.TargetRef.Name != "" {
+					name = address.TargetRef.Name
+				}
+				targets = append(targets, targetSettings{
+					name: fmt.Sprintf("%v/%v/%v", endpoints.Namespace, endpoints.Name, name),
+					url:  fmt.Sprintf("%v://%v", GrafanaServiceScheme, net.JoinHostPort(address.IP, fmt.Sprint(port.Port))),
+				})
+			}
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].name < targets[j].name })
+	return targets
+}
+
+// setDiscoveredTargets records the targets of the discovered object, the dashboards are applied to the new targets
+func setDiscoveredTargets(key string, targets []targetSettings) {
+	targetsLock.Lock()
+	changed := !reflect.DeepEqual(discoveredTargets[key], targets)
+	if len(targets) == 0 {
+		_, changed = discoveredTargets[key]
+		delete(discoveredTargets, key)
+	} else {
+		discoveredTargets[key] = targets
+	}
+	targetsLock.Unlock()
+	if !changed {
+		return
+	}
+	names := []string{}
+	for _, target := range targets {
+		names = append(names, target.name)
+	}
+	klog.InfoS("the grafana targets are discovered", "key", key, "targets", names)
+	// the configmaps which are synced to all the targets are skipped
+	go redeliverAll()
+}
+
+// newGrafanaDiscoveryInformer watches the grafana services, or their endpoints, to find the grafana targets
+func newGrafanaDiscoveryInformer(coreClient corev1client.CoreV1Interface) cache.SharedIndexInformer {
+	namespace := GrafanaServiceNamespace
+	if namespace == "" {
+		namespace = os.Getenv("POD_NAMESPACE")
+	}
+	options := func(opts metav1.ListOptions) metav1.ListOptions {
+		opts.LabelSelector = GrafanaServiceSelector
+		return opts
+	}
+
+	var watchlist *cache.ListWatch
+	var objType runtime.Object
+	resource := "services"
+	var toTargets func(obj interface{}) []targetSettings
+	if GrafanaServiceEndpoints {
+		// the endpoints have the labels of their service
+		watchlist = &cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				return coreClient.Endpoints(namespace).List(context.TODO(), options(opts))
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				return coreClient.Endpoints(namespace).Watch(context.TODO(), options(opts))
+			},
+		}
+		objType = &corev1.Endpoints{}
+		resource = "endpoints"
+		toTargets = func(obj interface{}) []targetSettings { return endpointsTargets(obj.(*corev1.Endpoints)) }
+	} else {
+		watchlist = &cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				return coreClient.Services(namespace).List(context.TODO(), options(opts))
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				return coreClient.Services(namespace).Watch(context.TODO(), options(opts))
+			},
+		}
+		objType = &corev1.Service{}
+		toTargets = func(obj interface{}) []targetSettings { return serviceTargets(obj.(*corev1.Service)) }
+	}
+
+	informer := handleWatchErrors(resource, cache.NewSharedIndexInformer(watchlist, objType, ResyncPeriod, cache.Indexers{}))
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			key, _ := cache.MetaNamespaceKeyFunc(obj)
+			setDiscoveredTargets(key, toTargets(obj))
+		},
+		UpdateFunc: func(old, new interface{}) {
+			key, _ := cache.MetaNamespaceKeyFunc(new)
+			setDiscoveredTargets(key, toTargets(new))
+		},
+		DeleteFunc: func(obj interface{}) {
+			key, _ := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+			setDiscoveredTargets(key, nil)
+		},
+	})
+	return informer
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDiscoveredTargets(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: "monitoring", Labels: map[string]string{"app": "grafana"}},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "metrics", Port: 9090}, {Name: "http", Port: 3000}}},
+	}
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: "monitoring"},
+		Subsets: []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{
+				{IP: "10.0.0.2", TargetRef: &corev1.ObjectReference{Name: "grafana-1"}},
+				{IP: "fd00::1"},
+			},
+			NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.3"}},
+			Ports:             []corev1.EndpointPort{{Name: "http", Port: 3000}},
+		}},
+	}
+
+	testCaseList := []struct {
+		name     string
+		output   []targetSettings
+		expected []targetSettings
+	}{
+		{"service", serviceTargets(svc), []targetSettings{{name: "monitoring/grafana", url: "http://grafana.monitoring.svc:3000"}}},
+
+		{"service without the port", serviceTargets(&corev1.Service{}), nil},
+
+		{"ready endpoints", endpointsTargets(endpoints), []targetSettings{
+			{name: "monitoring/grafana/fd00::1", url: "http://[fd00::1]:3000"},
+			{name: "monitoring/grafana/grafana-1", url: "http://10.0.0.2:3000"}}},
+	}
+
+	for _, c := range testCaseList {
+		if !reflect.DeepEqual(c.output, c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, c.output, c.expected)
+		}
+	}
+}
+
+func TestGrafanaDiscoveryInformer(t *testing.T) {
+	defer func() {
+		GrafanaServiceSelector, GrafanaServiceNamespace = "", ""
+		discoveredTargets = map[string][]targetSettings{}
+		builtTargets = map[string]*grafanaTarget{}
+	}()
+	GrafanaServiceSelector, GrafanaServiceNamespace = "app=grafana", "monitoring"
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: "monitoring", Labels: map[string]string{"app": "grafana"}},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 3000}}},
+	}
+	coreClient := fake.NewSimpleClientset(svc).CoreV1()
+	stop := make(chan struct{})
+	defer close(stop)
+	go newGrafanaDiscoveryInformer(coreClient).Run(stop)
+
+	waitForTargets := func(expected []string) []string {
+		var names []string
+		for i := 0; i < 50; i++ {
+			if names = targetNames(); reflect.DeepEqual(names, expected) {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		return names
+	}
+	if output := waitForTargets([]string{"monitoring/grafana"}); !reflect.DeepEqual(output, []string{"monitoring/grafana"}) {
+		t.Errorf("case (discovered) output: (%v) is not the expected: (%v)", output, []string{"monitoring/grafana"})
+	}
+
+	if err := coreClient.Services("monitoring").Delete(context.TODO(), "grafana", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("failed to delete the service: %v", err)
+	}
+	if output := waitForTargets([]string{}); len(output) != 0 {
+		t.Errorf("case (deleted) output: (%v) is not the expected: (%v)", output, []string{})
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
+)
+
+// GrafanaFailbackInterval is how often the preferred replicas of a grafana which failed over are probed,
+// the requests go back to the first healthy one in the order of the config file
+var GrafanaFailbackInterval = 30 * time.Second
+
+var (
+	replicasLock sync.Mutex
+	// replicaSets are the replicas keyed by their urls, the active replica is kept while the urls are the same
+	replicaSets = map[string]*util.Replicas{}
+)
+
+// normalizeGrafanaURLs normalizes the replica urls of the config file
+func normalizeGrafanaURLs(urls []string) []string {
+	if urls == nil {
+		return nil
+	}
+	normalized := []string{}
+	for _, url := range urls {
+		normalized = append(normalized, normalizeGrafanaURL(url))
+	}
+	return normalized
+}
+
+// replicasOf returns the replicas of the grafana at url with the other urls, nil means grafana has a single url
+func replicasOf(url string, others []string) *util.Replicas {
+	if len(others) == 0 {
+		return nil
+	}
+	urls := append([]string{url}, others...)
+	key := strings.Join(urls, ",")
+	replicasLock.Lock()
+	defer replicasLock.Unlock()
+	replicas, ok := replicaSets[key]
+	if !ok {
+		replicas = util.NewReplicas(urls)
+		replicaSets[key] = replicas
+	}
+	return replicas
+}
+
+// runReplicaFailback moves the requests back to the preferred replicas once they are healthy until ctx is done
+func runReplicaFailback(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		replicasLock.Lock()
+		sets := []*util.Replicas{}
+		for _, replicas := range replicaSets {
+			sets = append(sets, replicas)
+		}
+		replicasLock.Unlock()
+		for _, replicas := range sets {
+			replicas.Recover(ctx)
+		}
+	}, interval)
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
+)
+
+func TestHTTPGrafanaClientFailover(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("{\"database\": \"ok\"}"))
+	}))
+	defer healthy.Close()
+	restarting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	restartingURL := restarting.URL
+	restarting.Close()
+
+	defer func(uri string, replicas []string, r int) {
+		grafanaURI, grafanaReplicaURIs, retry = uri, replicas, r
+	}(grafanaURI, grafanaReplicaURIs, retry)
+	retry = 1
+
+	testCaseList := []struct {
+		name     string
+		client   *httpGrafanaClient
+		setup    func()
+		expected string
+	}{
+		{"default grafana", &httpGrafanaClient{}, func() {
+			grafanaURI, grafanaReplicaURIs = restartingURL, []string{healthy.URL}
+		}, healthy.URL},
+
+		{"target", &httpGrafanaClient{url: restartingURL + "/", replicas: []string{healthy.URL}}, func() {}, healthy.URL},
+
+		{"single url", &httpGrafanaClient{url: healthy.URL}, func() {}, healthy.URL},
+	}
+
+	for _, c := range testCaseList {
+		c.setup()
+		if err := c.client.Health(context.TODO()); err != nil {
+			t.Errorf("case (%v) grafana should be healthy after failing over: %v", c.name, err)
+		}
+		if _, output := c.client.request(context.TODO(), ""); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+	replicasLock.Lock()
+	replicaSets = map[string]*util.Replicas{}
+	replicasLock.Unlock()
+}
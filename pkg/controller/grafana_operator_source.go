@@ -0,0 +1,125 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// WatchGrafanaDashboards enables loading dashboards from the grafana-operator GrafanaDashboard resources
+	WatchGrafanaDashboards = false
+
+	// the resources of grafana-operator v4 and v5
+	grafanaDashboardResources = []schema.GroupVersionResource{
+		{Group: "integreatly.org", Version: "v1alpha1", Resource: "grafanadashboards"},
+		{Group: "grafana.integreatly.org", Version: "v1beta1", Resource: "grafanadashboards"},
+	}
+
+	// the resources are tracked separately since they may have the same name as a configmap
+	appliedGrafanaDashboardState = newSyncState("grafanadashboard")
+)
+
+// grafanaDashboardToConfigmap converts the GrafanaDashboard into a configmap with the same metadata,
+// spec.json is the dashboard and spec.folder (v5) or spec.customFolderName (v4) is the folder,
+// the GrafanaDashboard without spec.json has no dashboard so the one applied before is deleted
+func grafanaDashboardToConfigmap(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok || u == nil {
+		return nil
+	}
+
+	dashboard, found, err := unstructured.NestedString(u.Object, "spec", "json")
+	if err != nil || !found || dashboard == "" {
+		klog.V(4).Infof("GrafanaDashboard %v has no spec.json", u.GetName())
+	}
+
+	cm := &corev1.ConfigMap{}
+	cm.SetName(u.GetName())
+	cm.SetNamespace(u.GetNamespace())
+	cm.SetAnnotations(map[string]string{})
+	for key, value := range u.GetAnnotations() {
+		cm.Annotations[key] = value
+	}
+	// every GrafanaDashboard is a dashboard to load
+	cm.SetLabels(map[string]string{"grafana-custom-dashboard": "true"})
+	for key, value := range u.GetLabels() {
+		cm.Labels[key] = value
+	}
+	cm.Data = map[string]string{}
+	if dashboard != "" {
+		cm.Data[u.GetName()+".json"] = dashboard
+	}
+
+	if _, ok := cm.Annotations[customFolderKey]; !ok {
+		for _, field := range []string{"folder", "customFolderName"} {
+			folder, _, _ := unstructured.NestedString(u.Object, "spec", field)
+			if folder != "" {
+				cm.Annotations[customFolderKey] = folder
+				break
+			}
+		}
+	}
+	return cm
+}
+
+// servedGrafanaDashboardResources returns the GrafanaDashboard resources installed in the cluster
+func servedGrafanaDashboardResources(client discovery.DiscoveryInterface) []schema.GroupVersionResource {
+	served := []schema.GroupVersionResource{}
+	for _, gvr := range grafanaDashboardResources {
+		resources, err := client.ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+		if err != nil {
+			klog.V(4).Infof("GrafanaDashboard %v is not served: %v", gvr.GroupVersion(), err)
+			continue
+		}
+		for _, resource := range resources.APIResources {
+			if resource.Name == gvr.Resource {
+				served = append(served, gvr)
+				break
+			}
+		}
+	}
+	return served
+}
+
+func newGrafanaDashboardInformer(ctx context.Context, client dynamic.Interface, gvr schema.GroupVersionResource) cache.SharedIndexInformer {
+	// get watched namespace
+	watchedNS := os.Getenv("POD_NAMESPACE")
+	resource := client.Resource(gvr).Namespace(watchedNS)
+	watchlist := &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			return resource.List(context.TODO(), opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			return resource.Watch(context.TODO(), opts)
+		},
+	}
+	informer := handleWatchErrors(gvr.GroupResource().String(), cache.NewSharedIndexInformer(
+		stripMetadata(tuneListWatch(watchlist)),
+		&unstructured.Unstructured{},
+		ResyncPeriod,
+		cache.Indexers{},
+	))
+
+	handler := newDashboardEventHandler(ctx, appliedGrafanaDashboardState, grafanaDashboardToConfigmap)
+	informer.AddEventHandler(handler)
+	registerInformerResync(informer, handler)
+
+	return informer
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newGrafanaDashboard(apiVersion string, spec map[string]interface{}, annotations map[string]string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       "GrafanaDashboard",
+		"spec":       spec,
+	}}
+	u.SetName("test")
+	u.SetNamespace("test")
+	u.SetAnnotations(annotations)
+	return u
+}
+
+func TestGrafanaDashboardToConfigmap(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		obj       interface{}
+		expected  string
+		dashboard string
+		desired   bool
+	}{
+
+		{
+			"invalid object",
+			&corev1.ConfigMap{},
+			"",
+			"",
+			false,
+		},
+
+		{
+			"no spec.json",
+			newGrafanaDashboard("grafana.integreatly.org/v1beta1",
+				map[string]interface{}{"url": "https://example.com/a.json"}, nil),
+			"Custom",
+			"",
+			true,
+		},
+
+		{
+			"default folder",
+			newGrafanaDashboard("grafana.integreatly.org/v1beta1",
+				map[string]interface{}{"json": "{}"}, nil),
+			"Custom",
+			"{}",
+			true,
+		},
+
+		{
+			"v5 folder",
+			newGrafanaDashboard("grafana.integreatly.org/v1beta1",
+				map[string]interface{}{"json": "{}", "folder": "SLOs"}, nil),
+			"SLOs",
+			"{}",
+			true,
+		},
+
+		{
+			"v4 folder",
+			newGrafanaDashboard("integreatly.org/v1alpha1",
+				map[string]interface{}{"json": "{}", "customFolderName": "SLOs"}, nil),
+			"SLOs",
+			"{}",
+			true,
+		},
+
+		{
+			"folder annotation",
+			newGrafanaDashboard("grafana.integreatly.org/v1beta1",
+				map[string]interface{}{"json": "{}", "folder": "SLOs"}, map[string]string{customFolderKey: "Network"}),
+			"Network",
+			"{}",
+			true,
+		},
+
+		{
+			"tombstone",
+			cache.DeletedFinalStateUnknown{Key: "test/test", Obj: newGrafanaDashboard("grafana.integreatly.org/v1beta1",
+				map[string]interface{}{"json": "{}"}, nil)},
+			"Custom",
+			"{}",
+			true,
+		},
+	}
+
+	for _, c := range testCaseList {
+		output := grafanaDashboardToConfigmap(c.obj)
+		if isDesiredDashboardConfigmap(output) != c.desired {
+			t.Errorf("case (%v) desired: (%v) is not the expected: (%v)", c.name, !c.desired, c.desired)
+		}
+		if !c.desired {
+			continue
+		}
+		cm := output.(*corev1.ConfigMap)
+		if folder := getDashboardCustomFolderTitle(cm); folder != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, folder, c.expected)
+		}
+		if cm.Data["test.json"] != c.dashboard || c.dashboard == "" && len(cm.Data) != 0 {
+			t.Errorf("case (%v) the dashboard is not kept: %v", c.name, cm.Data)
+		}
+	}
+}
+
+func TestServedGrafanaDashboardResources(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "grafana.integreatly.org/v1beta1",
+			APIResources: []metav1.APIResource{{Name: "grafanadashboards"}, {Name: "grafanas"}},
+		},
+	}
+
+	served := servedGrafanaDashboardResources(client.Discovery())
+	if len(served) != 1 || served[0].Group != "grafana.integreatly.org" {
+		t.Fatalf("the served resources %v are not the expected grafana.integreatly.org/v1beta1", served)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// GrafanaReadyTimeout is how long the loader waits for /api/health of grafana before the first sync,
+// the dashboards are applied with the usual retries after the timeout, 0 does not wait
+var GrafanaReadyTimeout = 5 * time.Minute
+
+// grafanaReadyBackoff is the interval between the health probes, it grows up to the cap until grafana is ready
+var grafanaReadyBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    10,
+	Cap:      30 * time.Second,
+}
+
+// targetClients returns the clients of the grafana targets keyed by the target name,
+// the default grafana is the only one without any target
+func targetClients() map[string]GrafanaClient {
+	clients := map[string]GrafanaClient{}
+	for _, target := range grafanaTargets() {
+		clients[target.settings.name] = target.client
+	}
+	if len(clients) == 0 {
+		clients[""] = grafanaClient
+	}
+	return clients
+}
+
+// waitForGrafana probes /api/health of every grafana until they are all ready, the timeout passes or ctx is done,
+// it returns whether all of them are ready
+func waitForGrafana(ctx context.Context, clients map[string]GrafanaClient, timeout time.Duration) bool {
+	if timeout <= 0 {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	backoff := grafanaReadyBackoff
+	pending := map[string]GrafanaClient{}
+	for name, client := range clients {
+		pending[name] = client
+	}
+	errs := map[string]error{}
+	for {
+		// the ready targets are not probed again
+		for name, client := range pending {
+			if err := client.Health(ctx); err != nil {
+				errs[name] = err
+				continue
+			}
+			delete(pending, name)
+			delete(errs, name)
+			klog.InfoS("grafana is ready", "target", name)
+		}
+		if len(pending) == 0 {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			names := []string{}
+			for name := range errs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				klog.ErrorS(errs[name], "grafana is not ready, the dashboards are applied with the retries", "target", name, "timeout", timeout)
+			}
+			return false
+		case <-time.After(backoff.Step()):
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// startingGrafanaClient fails the health probes until it is probed the given times
+type startingGrafanaClient struct {
+	*fakeGrafanaClient
+	failures int
+	probes   int
+}
+
+func (c *startingGrafanaClient) Health(ctx context.Context) error {
+	c.probes++
+	if c.probes <= c.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestWaitForGrafana(t *testing.T) {
+	defer func(backoff time.Duration) { grafanaReadyBackoff.Duration = backoff }(grafanaReadyBackoff.Duration)
+	grafanaReadyBackoff.Duration = time.Millisecond
+
+	testCaseList := []struct {
+		name     string
+		failures map[string]int
+		timeout  time.Duration
+		expected bool
+		probes   map[string]int
+	}{
+		{"ready", map[string]int{"": 0}, time.Second, true, map[string]int{"": 1}},
+
+		{"starting", map[string]int{"prod": 0, "dev": 3}, time.Second, true, map[string]int{"prod": 1, "dev": 4}},
+
+		{"not ready", map[string]int{"": 1000}, 20 * time.Millisecond, false, nil},
+
+		{"not waiting", map[string]int{"": 1000}, 0, true, map[string]int{"": 0}},
+	}
+
+	for _, c := range testCaseList {
+		clients := map[string]GrafanaClient{}
+		starting := map[string]*startingGrafanaClient{}
+		for name, failures := range c.failures {
+			starting[name] = &startingGrafanaClient{fakeGrafanaClient: newFakeGrafanaClient(), failures: failures}
+			clients[name] = starting[name]
+		}
+		if output := waitForGrafana(context.TODO(), clients, c.timeout); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+		for name, probes := range c.probes {
+			if starting[name].probes != probes {
+				t.Errorf("case (%v) target %v probes: (%v) is not the expected: (%v)", c.name, name, starting[name].probes, probes)
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+// GrafanaResetCheckInterval is how often the sentinel dashboard is checked to find out that grafana is reinstalled
+// without its database, 0 disables the check
+var GrafanaResetCheckInterval = time.Minute
+
+// sentinelDashboardUID is the dashboard which is kept in the general folder of every grafana,
+// it is gone once grafana comes back with an empty database
+const sentinelDashboardUID = "grafana-dashboard-loader-sentinel"
+
+func sentinelDashboard() map[string]interface{} {
+	return map[string]interface{}{
+		"uid":   sentinelDashboardUID,
+		"title": "Grafana Dashboard Loader",
+		"description": "The dashboards of the loader are applied again once this dashboard is missing, " +
+			"e.g. grafana is redeployed without a persistent volume.",
+		"tags":   []interface{}{"grafana-dashboard-loader"},
+		"panels": []interface{}{},
+	}
+}
+
+// resetDetector checks the sentinel dashboard of every grafana target
+type resetDetector struct {
+	// marked are the targets which have the sentinel dashboard saved by the loader
+	marked map[string]bool
+}
+
+func newResetDetector() *resetDetector {
+	return &resetDetector{marked: map[string]bool{}}
+}
+
+// check saves the sentinel dashboard once it is missing, it returns true when the sentinel was saved before,
+// so grafana has lost all the dashboards since then
+func (d *resetDetector) check(ctx context.Context, name string, client GrafanaClient) (bool, error) {
+	_, err := client.GetDashboard(ctx, "", sentinelDashboardUID)
+	if err == nil {
+		d.marked[name] = true
+		return false, nil
+	}
+	if grafanaStatus(err) != http.StatusNotFound {
+		return false, err
+	}
+	reset := d.marked[name]
+	if _, err := client.SaveDashboard(ctx, "", sentinelDashboard(), 0, true); err != nil {
+		return reset, err
+	}
+	d.marked[name] = true
+	return reset, nil
+}
+
+// checkAll applies all the dashboards again to the grafana targets which are found reset
+func (d *resetDetector) checkAll(ctx context.Context) {
+	reset := false
+	clients := targetClients()
+	for name, client := range clients {
+		found, err := d.check(ctx, name, client)
+		if err != nil {
+			klog.ErrorS(err, "failed to check the sentinel dashboard", "target", name)
+		}
+		if !found {
+			continue
+		}
+		klog.InfoS("grafana is reset since the sentinel dashboard is missing, all the dashboards are applied again", "target", name)
+		metrics.GrafanaResets.WithLabelValues(name).Inc()
+		forgetGrafana(name)
+		reset = true
+	}
+	for name := range d.marked {
+		if _, ok := clients[name]; !ok {
+			delete(d.marked, name)
+		}
+	}
+	if reset {
+		redeliverAll()
+	}
+}
+
+// forgetGrafana drops what is known about the dashboards and the folders of the target,
+// empty is the default grafana
+func forgetGrafana(name string) {
+	resetTenantOrgs()
+	if name == "" {
+		if folders, ok := grafanaClient.(*folderCacheGrafanaClient); ok {
+			folders.reset()
+		}
+		for _, state := range allSyncStates() {
+			state.reset()
+		}
+		return
+	}
+	targetsLock.Lock()
+	if target, ok := builtTargets[name]; ok && target.folders != nil {
+		target.folders.reset()
+	}
+	targetsLock.Unlock()
+	for _, state := range allSyncStates() {
+		state.resetTarget(name)
+	}
+}
+
+// runResetDetection checks the sentinel dashboards every interval until ctx is done,
+// the check is skipped under dry-run mode since the sentinel is never saved
+func runResetDetection(ctx context.Context, interval time.Duration) {
+	if interval <= 0 || DryRun {
+		return
+	}
+	detector := newResetDetector()
+	wait.UntilWithContext(ctx, detector.checkAll, interval)
+}
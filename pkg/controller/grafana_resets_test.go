@@ -0,0 +1,74 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+func TestResetDetector(t *testing.T) {
+	fakes := map[string]*fakeGrafanaClient{"dev": newFakeGrafanaClient(), "prod": newFakeGrafanaClient()}
+	originalFactory := newTargetClient
+	newTargetClient = func(settings targetSettings) (GrafanaClient, *folderCacheGrafanaClient) {
+		return fakes[settings.name], nil
+	}
+	configuredTargets = []targetSettings{{name: "prod", url: "http://prod"}, {name: "dev", url: "http://dev"}}
+	defer func() {
+		newTargetClient = originalFactory
+		configuredTargets = nil
+		builtTargets = map[string]*grafanaTarget{}
+	}()
+	defer useEmptyUIDIndex()()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "reset", Namespace: "test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+		Data: map[string]string{"a.json": `{"uid": "a", "title": "a", "panels": []}`},
+	}
+	state := newSyncState("configmap")
+	defer state.forget(cm)
+	if err := syncDashboard(context.TODO(), state, nil, cm); err != nil {
+		t.Fatalf("failed to sync the configmap: %v", err)
+	}
+
+	detector := newResetDetector()
+	testCaseList := []struct {
+		name     string
+		wiped    string
+		expected map[string]bool
+		resets   float64
+	}{
+		{"first check", "", map[string]bool{"dev": true, "prod": true}, 0},
+
+		{"unchanged", "", map[string]bool{"dev": true, "prod": true}, 0},
+
+		{"dev reinstalled", "dev", map[string]bool{"dev": false, "prod": true}, 1},
+	}
+
+	for _, c := range testCaseList {
+		resets := testutil.ToFloat64(metrics.GrafanaResets.WithLabelValues("dev"))
+		if c.wiped != "" {
+			fakes[c.wiped].dashboards = map[string]map[string]fakeDashboard{}
+		}
+		detector.checkAll(context.TODO())
+		for name, synced := range c.expected {
+			if output := state.isTargetSynced(cm, name); output != synced {
+				t.Errorf("case (%v) target %v output: (%v) is not the expected: (%v)", c.name, name, output, synced)
+			}
+			if _, ok := fakes[name].dashboards[""][sentinelDashboardUID]; !ok {
+				t.Errorf("case (%v) target %v has no sentinel dashboard", c.name, name)
+			}
+		}
+		if output := testutil.ToFloat64(metrics.GrafanaResets.WithLabelValues("dev")) - resets; output != c.resets {
+			t.Errorf("case (%v) metrics output: (%v) is not the expected: (%v)", c.name, output, c.resets)
+		}
+	}
+}
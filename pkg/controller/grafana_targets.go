@@ -0,0 +1,277 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
+)
+
+// grafanaInstanceKey is the annotation to apply the dashboards of the configmap to the named grafana targets only,
+// the value is the comma separated target names, empty means all the targets
+const grafanaInstanceKey = "observability.open-cluster-management.io/grafana-instance"
+
+// targetSettings are a grafana target of the config file with the secrets read from the files
+type targetSettings struct {
+	name  string
+	url   string
+	auth  util.GrafanaAuth
+	cloud bool
+	// replicas are the other urls of the target to fail over to
+	replicas []string
+}
+
+// grafanaTarget is one of the grafana instances every dashboard is applied to
+type grafanaTarget struct {
+	settings targetSettings
+	client   GrafanaClient
+	// folders is the folder cache of the target, nil means the folders are not cached
+	folders *folderCacheGrafanaClient
+}
+
+// targetStatus is the result of the last sync of a configmap to a grafana target
+type targetStatus struct {
+	Synced       bool      `json:"synced"`
+	LastSyncTime time.Time `json:"lastSyncTime"`
+	Error        string    `json:"error,omitempty"`
+	// hash is the content of the configmap which was synced to the target
+	hash string
+}
+
+var (
+	targetsLock sync.Mutex
+	// configuredTargets are the targets of the config file, without any target the dashboards go to grafanaURI only
+	configuredTargets []targetSettings
+	// discoveredTargets are the targets found by the service discovery keyed by the service or the endpoints,
+	// they use the global credentials
+	discoveredTargets = map[string][]targetSettings{}
+	// builtTargets are the clients of the targets keyed by the target name, they are kept while the settings are the same
+	builtTargets = map[string]*grafanaTarget{}
+	// newTargetClient builds the client of a target, it is decorated like the default client once the controller starts
+	newTargetClient = func(settings targetSettings) (GrafanaClient, *folderCacheGrafanaClient) {
+		return &httpGrafanaClient{url: settings.url, auth: settings.auth, cloud: settings.cloud, replicas: settings.replicas}, nil
+	}
+)
+
+func currentTargets() []targetSettings {
+	targetsLock.Lock()
+	defer targetsLock.Unlock()
+	return configuredTargets
+}
+
+type grafanaTargetKey struct{}
+
+// withGrafanaTarget returns the context to call the grafana target instead of the default grafana
+func withGrafanaTarget(ctx context.Context, target *grafanaTarget) context.Context {
+	return context.WithValue(ctx, grafanaTargetKey{}, target)
+}
+
+// targetName returns the name of the grafana target of the context, empty means the default grafana
+func targetName(ctx context.Context) string {
+	if target, ok := ctx.Value(grafanaTargetKey{}).(*grafanaTarget); ok {
+		return target.settings.name
+	}
+	return ""
+}
+
+// clientFor returns the client of the grafana target of the context, it is the default client without a target
+func clientFor(ctx context.Context) GrafanaClient {
+	if target, ok := ctx.Value(grafanaTargetKey{}).(*grafanaTarget); ok {
+		return target.client
+	}
+	return grafanaClient
+}
+
+// allTargetSettings returns the configured and the discovered targets, the caller holds targetsLock
+func allTargetSettings() []targetSettings {
+	all := append([]targetSettings{}, configuredTargets...)
+	auth := util.CurrentSettings().Auth
+	for _, targets := range discoveredTargets {
+		for _, target := range targets {
+			target.auth = auth
+			all = append(all, target)
+		}
+	}
+	return all
+}
+
+// targetNames returns the names of the current targets
+func targetNames() []string {
+	targetsLock.Lock()
+	defer targetsLock.Unlock()
+	names := []string{}
+	for _, settings := range allTargetSettings() {
+		names = append(names, settings.name)
+	}
+	return names
+}
+
+// routedTargetNames returns the targets named by the grafana-instance annotation of the configmap, nil means all of them
+func routedTargetNames(cm *corev1.ConfigMap) map[string]bool {
+	if cm == nil {
+		return nil
+	}
+	names := stringSet(strings.Split(cm.GetAnnotations()[grafanaInstanceKey], ","))
+	if len(names) == 0 {
+		return nil
+	}
+	return names
+}
+
+// isRoutedTo checks whether the dashboards of the configmap are applied to the target
+func isRoutedTo(cm *corev1.ConfigMap, target string) bool {
+	names := routedTargetNames(cm)
+	return names == nil || names[target]
+}
+
+// unknownTargetNames returns the names of the grafana-instance annotation which are not current targets ordered by name
+func unknownTargetNames(cm *corev1.ConfigMap, targets []string) []string {
+	current := stringSet(targets)
+	unknown := []string{}
+	for name := range routedTargetNames(cm) {
+		if !current[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// grafanaTargets returns the configured and the discovered targets ordered by name,
+// the clients are built again once their settings change
+func grafanaTargets() []*grafanaTarget {
+	targetsLock.Lock()
+	defer targetsLock.Unlock()
+	targets := []*grafanaTarget{}
+	names := map[string]bool{}
+	for _, settings := range allTargetSettings() {
+		names[settings.name] = true
+		target, ok := builtTargets[settings.name]
+		if !ok || !reflect.DeepEqual(target.settings, settings) {
+			client, folders := newTargetClient(settings)
+			target = &grafanaTarget{settings: settings, client: client, folders: folders}
+			builtTargets[settings.name] = target
+		}
+		targets = append(targets, target)
+	}
+	for name := range builtTargets {
+		if !names[name] {
+			delete(builtTargets, name)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].settings.name < targets[j].settings.name })
+	return targets
+}
+
+// resetTargetFolders drops the cached folders of all the targets
+func resetTargetFolders() {
+	targetsLock.Lock()
+	defer targetsLock.Unlock()
+	for _, target := range builtTargets {
+		if target.folders != nil {
+			target.folders.reset()
+		}
+	}
+}
+
+// updateTargets applies the configmap to every grafana target at the same time, the targets which have the same
+// content already are skipped so that a failing target does not apply the dashboards to the other ones again,
+// and the dashboards are deleted from the targets which the configmap is no longer routed to
+func updateTargets(ctx context.Context, state *syncState, old, new interface{}) error {
+	targets := grafanaTargets()
+	if len(targets) == 0 {
+		return updateDashboard(ctx, old, new, false)
+	}
+
+	cm := new.(*corev1.ConfigMap)
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		if !isRoutedTo(cm, target.settings.name) {
+			if state.hasTarget(cm, target.settings.name) {
+				klog.InfoS("the dashboards are no longer routed to the target", "configmap", klog.KObj(cm), "target", target.settings.name)
+				previous := old
+				if oldCM, ok := old.(*corev1.ConfigMap); !ok || oldCM == nil {
+					previous = new
+				}
+				deleteDashboard(withGrafanaTarget(ctx, target), previous)
+				state.forgetTarget(cm, target.settings.name)
+			}
+			continue
+		}
+		if state.isTargetSynced(cm, target.settings.name) {
+			klog.V(4).InfoS("the dashboards are synced to the target already", "configmap", klog.KObj(cm), "target", target.settings.name)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, target *grafanaTarget) {
+			defer wg.Done()
+			errs[i] = updateDashboard(withGrafanaTarget(ctx, target), old, new, false)
+			state.markTargetSynced(cm, target.settings.name, errs[i])
+		}(i, target)
+	}
+	wg.Wait()
+
+	problems := []string{}
+	for i, err := range errs {
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%v: %v", targets[i].settings.name, err))
+		}
+	}
+	names := []string{}
+	for _, target := range targets {
+		names = append(names, target.settings.name)
+	}
+	for _, name := range unknownTargetNames(cm, names) {
+		problems = append(problems, fmt.Sprintf("%v: the target is not configured", name))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("failed to sync to the targets %v", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// deleteFromTargets deletes the dashboards of the configmap from every grafana target it is routed to,
+// the error names the targets which failed
+func deleteFromTargets(ctx context.Context, obj interface{}) error {
+	targets := grafanaTargets()
+	if len(targets) == 0 {
+		return deleteDashboard(ctx, obj)
+	}
+	cm, _ := obj.(*corev1.ConfigMap)
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		if !isRoutedTo(cm, target.settings.name) {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, target *grafanaTarget) {
+			defer wg.Done()
+			errs[i] = deleteDashboard(withGrafanaTarget(ctx, target), obj)
+		}(i, target)
+	}
+	wg.Wait()
+
+	problems := []string{}
+	for i, err := range errs {
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%v: %v", targets[i].settings.name, err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("failed to delete from the targets %v", strings.Join(problems, "; "))
+	}
+	return nil
+}
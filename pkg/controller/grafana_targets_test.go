@@ -0,0 +1,173 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
+)
+
+func TestSyncToTargets(t *testing.T) {
+	fakes := map[string]*fakeGrafanaClient{"dev": newFakeGrafanaClient(), "prod": newFakeGrafanaClient()}
+	originalFactory := newTargetClient
+	newTargetClient = func(settings targetSettings) (GrafanaClient, *folderCacheGrafanaClient) {
+		return fakes[settings.name], nil
+	}
+	configuredTargets = []targetSettings{{name: "prod", url: "http://prod"}, {name: "dev", url: "http://dev"}}
+	defer func() {
+		newTargetClient = originalFactory
+		configuredTargets = nil
+		builtTargets = map[string]*grafanaTarget{}
+	}()
+	defer useEmptyUIDIndex()()
+
+	state := newSyncState("configmap")
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+		Data: map[string]string{"a.json": `{"uid": "a", "title": "a", "panels": []}`},
+	}
+	fakes["dev"].saveErrs = map[string]error{"a": &GrafanaAPIError{"POST", "/api/dashboards/db", 500, []byte("down")}}
+
+	testCaseList := []struct {
+		name     string
+		fix      bool
+		err      string
+		expected map[string]bool
+		saved    map[string]int
+	}{
+		{"dev failed", false, "dev: ", map[string]bool{"dev": false, "prod": true}, map[string]int{"dev": 0, "prod": 1}},
+
+		// prod is not applied again, its dashboard is removed to tell
+		{"dev retried", true, "", map[string]bool{"dev": true, "prod": true}, map[string]int{"dev": 1, "prod": 0}},
+	}
+
+	for _, c := range testCaseList {
+		if c.fix {
+			fakes["dev"].saveErrs = nil
+			fakes["prod"].dashboards = map[string]map[string]fakeDashboard{}
+		}
+		err := syncDashboard(context.TODO(), state, nil, cm)
+		if (err != nil) != (c.err != "") || err != nil && !strings.Contains(err.Error(), c.err) {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.err)
+		}
+		status := state.listStatuses()[0]
+		for name, synced := range c.expected {
+			if status.Targets[name].Synced != synced {
+				t.Errorf("case (%v) target %v output: (%v) is not the expected: (%v)", c.name, name, status.Targets[name], synced)
+			}
+			if output := len(fakes[name].dashboards[""]); output != c.saved[name] {
+				t.Errorf("case (%v) target %v saved: (%v) is not the expected: (%v)", c.name, name, output, c.saved[name])
+			}
+		}
+	}
+
+	deleteFromTargets(context.TODO(), cm)
+	if len(fakes["dev"].dashboards[""]) != 0 {
+		t.Errorf("case (delete) the dashboards of dev are not deleted: %v", fakes["dev"].dashboards)
+	}
+}
+
+func TestRouteToTargets(t *testing.T) {
+	fakes := map[string]*fakeGrafanaClient{"dev": newFakeGrafanaClient(), "prod": newFakeGrafanaClient()}
+	originalFactory := newTargetClient
+	newTargetClient = func(settings targetSettings) (GrafanaClient, *folderCacheGrafanaClient) {
+		return fakes[settings.name], nil
+	}
+	configuredTargets = []targetSettings{{name: "prod", url: "http://prod"}, {name: "dev", url: "http://dev"}}
+	defer func() {
+		newTargetClient = originalFactory
+		configuredTargets = nil
+		builtTargets = map[string]*grafanaTarget{}
+	}()
+	defer useEmptyUIDIndex()()
+
+	state := newSyncState("configmap")
+	// the configmap is forgotten so that the failed status is not reported by the other tests
+	defer state.forget(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}})
+	testCaseList := []struct {
+		name     string
+		instance string
+		err      string
+		expected map[string]int
+	}{
+		{"all targets", "", "", map[string]int{"dev": 1, "prod": 1}},
+
+		{"prod only", "prod", "", map[string]int{"dev": 0, "prod": 1}},
+
+		{"dev only", " dev ", "", map[string]int{"dev": 1, "prod": 0}},
+
+		{"unknown target", "dev,staging", "staging: the target is not configured", map[string]int{"dev": 1, "prod": 0}},
+	}
+
+	var old *corev1.ConfigMap
+	for _, c := range testCaseList {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test",
+				Labels:      map[string]string{"grafana-custom-dashboard": "true"},
+				Annotations: map[string]string{grafanaInstanceKey: c.instance}},
+			Data: map[string]string{"a.json": `{"uid": "a", "title": "a", "panels": []}`},
+		}
+		err := syncDashboard(context.TODO(), state, old, cm)
+		if (err != nil) != (c.err != "") || err != nil && !strings.Contains(err.Error(), c.err) {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.err)
+		}
+		for name, expected := range c.expected {
+			if output := len(fakes[name].dashboards[""]); output != expected {
+				t.Errorf("case (%v) target %v output: (%v) is not the expected: (%v)", c.name, name, output, expected)
+			}
+		}
+		if c.err == "" && !state.isSynced(cm) {
+			t.Errorf("case (%v) the configmap is not synced to the routed targets", c.name)
+		}
+		old = cm
+	}
+}
+
+func TestTargetSettings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "targets")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	ioutil.WriteFile(tokenFile, []byte("secret\n"), 0600)
+
+	testCaseList := []struct {
+		name     string
+		targets  []TargetConfig
+		expected []targetSettings
+		hasErr   bool
+	}{
+		{"token", []TargetConfig{{Name: "prod", URL: "http://prod/", Auth: AuthConfig{TokenFile: tokenFile}}},
+			[]targetSettings{{name: "prod", url: "http://prod", auth: util.GrafanaAuth{BearerToken: "secret"}}}, false},
+
+		{"replicas", []TargetConfig{{Name: "prod", URL: "http://prod-0/", Replicas: []string{"http://prod-1/api"}}},
+			[]targetSettings{{name: "prod", url: "http://prod-0", replicas: []string{"http://prod-1"}}}, false},
+
+		{"no url", []TargetConfig{{Name: "prod"}}, nil, true},
+
+		{"duplicated", []TargetConfig{{Name: "prod", URL: "http://a"}, {Name: "prod", URL: "http://b"}}, nil, true},
+	}
+
+	for _, c := range testCaseList {
+		output, err := GrafanaConfig{Targets: c.targets}.targetSettings()
+		if (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+		if !c.hasErr && !reflect.DeepEqual(output, c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
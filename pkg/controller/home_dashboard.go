@@ -0,0 +1,81 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// homeDashboardKey is the annotation to make the dashboard the home dashboard of the org,
+// "true" works for a configmap with only one dashboard, otherwise the value is the data key of the dashboard
+const homeDashboardKey = "observability.open-cluster-management.io/home-dashboard"
+
+// isHomeDashboard checks whether the dashboard in the data key is the home dashboard
+func isHomeDashboard(cm *corev1.ConfigMap, key string, dashboards int) bool {
+	value := strings.TrimSpace(cm.GetAnnotations()[homeDashboardKey])
+	if strings.ToLower(value) == "true" {
+		return dashboards == 1
+	}
+	return value != "" && value == key
+}
+
+// wasHomeDashboard checks whether the dashboard in the data key was the home dashboard of the old configmap
+func wasHomeDashboard(old interface{}, key string) bool {
+	cm, ok := old.(*corev1.ConfigMap)
+	if !ok || cm == nil {
+		return false
+	}
+	return isHomeDashboard(cm, key, len(cm.Data)+len(cm.BinaryData))
+}
+
+// setHomeDashboard updates the preferences of the org with the saved dashboard
+func setHomeDashboard(ctx context.Context, orgID string, dashboard SavedDashboard) error {
+	// PUT replaces all the preferences so the current ones are kept
+	preferences, err := clientFor(ctx).GetPreferences(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to get the org preferences: %v", err)
+	}
+	// grafana 9 prefers homeDashboardUID while the older versions only know homeDashboardId
+	preferences["homeDashboardId"] = dashboard.ID
+	preferences["homeDashboardUID"] = dashboard.UID
+
+	err = clientFor(ctx).UpdatePreferences(ctx, orgID, preferences)
+	if err != nil {
+		return fmt.Errorf("failed to set home dashboard: %v", err)
+	}
+	klog.Infof("home dashboard of org %q is set to %v", orgID, dashboard.UID)
+	return nil
+}
+
+// hasHomeDashboardAnnotation checks whether any dashboard of the configmap may be the home dashboard
+func hasHomeDashboardAnnotation(obj interface{}) bool {
+	cm, ok := obj.(*corev1.ConfigMap)
+	return ok && cm != nil && strings.TrimSpace(cm.GetAnnotations()[homeDashboardKey]) != ""
+}
+
+// unsetHomeDashboard resets the home dashboard of the org to the grafana default,
+// only when it is still the dashboard of the uid so the home dashboard chosen by someone else is kept
+func unsetHomeDashboard(ctx context.Context, orgID string, uid string) error {
+	preferences, err := clientFor(ctx).GetPreferences(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to get the org preferences: %v", err)
+	}
+	if home, _ := preferences["homeDashboardUID"].(string); home != uid {
+		return nil
+	}
+	preferences["homeDashboardId"] = 0
+	preferences["homeDashboardUID"] = ""
+
+	err = clientFor(ctx).UpdatePreferences(ctx, orgID, preferences)
+	if err != nil {
+		return fmt.Errorf("failed to unset home dashboard: %v", err)
+	}
+	klog.Infof("home dashboard %v of org %q is unset", uid, orgID)
+	return nil
+}
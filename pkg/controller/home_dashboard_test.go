@@ -0,0 +1,179 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsHomeDashboard(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		annotation string
+		dashboards int
+		expected   bool
+	}{
+
+		{
+			"no annotation",
+			"",
+			1,
+			false,
+		},
+
+		{
+			"single dashboard",
+			"true",
+			1,
+			true,
+		},
+
+		{
+			"ambiguous dashboards",
+			"true",
+			2,
+			false,
+		},
+
+		{
+			"data key",
+			"home.json",
+			2,
+			true,
+		},
+	}
+
+	for _, c := range testCaseList {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Namespace:   "test",
+				Annotations: map[string]string{homeDashboardKey: c.annotation},
+			},
+		}
+		output := isHomeDashboard(cm, "home.json", c.dashboards)
+		if output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestSetHomeDashboard(t *testing.T) {
+	preferences := map[string]interface{}{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/org/preferences" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.Method == http.MethodPut {
+			body, _ := ioutil.ReadAll(req.Body)
+			json.Unmarshal(body, &preferences)
+		}
+		w.Write([]byte("{\"theme\": \"dark\", \"homeDashboardId\": 0}"))
+	}))
+	defer server.Close()
+
+	originalURI := grafanaURI
+	grafanaURI = server.URL
+	retry = 1
+	defer func() {
+		grafanaURI = originalURI
+	}()
+
+	err := setHomeDashboard(context.TODO(), "", SavedDashboard{ID: 7, UID: "home"})
+	if err != nil {
+		t.Fatalf("failed to set home dashboard: %v", err)
+	}
+	if preferences["theme"] != "dark" || preferences["homeDashboardId"] != 7.0 || preferences["homeDashboardUID"] != "home" {
+		t.Fatalf("the preferences %v are not the expected", preferences)
+	}
+}
+
+func TestUnsetHomeDashboard(t *testing.T) {
+	homeConfigmap := func(annotation string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Namespace:   "test",
+				Annotations: map[string]string{homeDashboardKey: annotation},
+			},
+			Data: map[string]string{
+				"home.json":  "{\"uid\": \"home\", \"title\": \"Home\"}",
+				"other.json": "{\"uid\": \"other\", \"title\": \"Other\"}",
+			},
+		}
+	}
+
+	testCaseList := []struct {
+		name     string
+		home     string
+		new      *corev1.ConfigMap
+		deleted  bool
+		expected string
+	}{
+
+		{
+			"annotation removed",
+			"home",
+			homeConfigmap(""),
+			false,
+			"",
+		},
+
+		{
+			"annotation moved",
+			"home",
+			homeConfigmap("other.json"),
+			false,
+			"other",
+		},
+
+		{
+			"configmap deleted",
+			"home",
+			nil,
+			true,
+			"",
+		},
+
+		{
+			"home dashboard chosen by someone else",
+			"custom",
+			homeConfigmap(""),
+			false,
+			"custom",
+		},
+	}
+
+	for _, c := range testCaseList {
+		fake, restore := useFakeGrafanaClient()
+		old := homeConfigmap("home.json")
+		if err := updateDashboard(context.TODO(), nil, old, false); err != nil {
+			t.Fatalf("case (%v) failed to update dashboard: %v", c.name, err)
+		}
+		fake.preferences[""]["homeDashboardUID"] = c.home
+
+		var err error
+		if c.deleted {
+			err = deleteDashboard(context.TODO(), old)
+		} else {
+			err = updateDashboard(context.TODO(), old, c.new, false)
+		}
+		if err != nil {
+			t.Errorf("case (%v) failed to sync: %v", c.name, err)
+		}
+		if output := fake.preferences[""]["homeDashboardUID"]; output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+		restore()
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// maxPooledBuffer is the largest encoding buffer kept for the next dashboards, the larger ones are left to the gc
+const maxPooledBuffer = 4 << 20
+
+// encodeBuffers are reused to encode the dashboards which are only checked and then dropped,
+// e.g. for their size, so that every sync does not allocate a buffer of the size of the dashboard
+var encodeBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// encodeJSON encodes the value into a pooled buffer the same as json.Marshal does,
+// the buffer is given back by releaseBuffer once its bytes are not used any more
+func encodeJSON(v interface{}) (*bytes.Buffer, error) {
+	buffer := encodeBuffers.Get().(*bytes.Buffer)
+	buffer.Reset()
+	if err := json.NewEncoder(buffer).Encode(v); err != nil {
+		releaseBuffer(buffer)
+		return nil, err
+	}
+	// the encoder ends the value with a newline unlike json.Marshal
+	buffer.Truncate(buffer.Len() - 1)
+	return buffer, nil
+}
+
+func releaseBuffer(buffer *bytes.Buffer) {
+	if buffer.Cap() <= maxPooledBuffer {
+		encodeBuffers.Put(buffer)
+	}
+}
+
+// encodedSize returns the size of the json of the value
+func encodedSize(v interface{}) (int, error) {
+	buffer, err := encodeJSON(v)
+	if err != nil {
+		return 0, err
+	}
+	defer releaseBuffer(buffer)
+	return buffer.Len(), nil
+}
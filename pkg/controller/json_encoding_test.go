@@ -0,0 +1,42 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEncodeJSON(t *testing.T) {
+	testCaseList := []struct {
+		name  string
+		value interface{}
+	}{
+		{"dashboard", map[string]interface{}{"uid": "a", "title": "A", "panels": []interface{}{map[string]interface{}{"id": 1}}}},
+
+		{"html", map[string]interface{}{"description": "<b>latency</b> & errors"}},
+
+		{"empty", map[string]interface{}{}},
+	}
+
+	for _, c := range testCaseList {
+		expected, _ := json.Marshal(c.value)
+		buffer, err := encodeJSON(c.value)
+		if err != nil {
+			t.Errorf("case (%v) error: (%v)", c.name, err)
+			continue
+		}
+		if output := buffer.String(); output != string(expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, string(expected))
+		}
+		releaseBuffer(buffer)
+		if size, err := encodedSize(c.value); err != nil || size != len(expected) {
+			t.Errorf("case (%v) size: (%v, %v) is not the expected: (%v)", c.name, size, err, len(expected))
+		}
+	}
+
+	if _, err := encodeJSON(map[string]interface{}{"invalid": func() {}}); err == nil {
+		t.Errorf("case (invalid) the value which cannot be encoded should fail")
+	}
+}
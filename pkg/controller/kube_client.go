@@ -0,0 +1,68 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var (
+	// KubeAPIQPS is the rate of the requests sent to the kubernetes api server, the client-go default is 5
+	KubeAPIQPS float32 = 50
+	// KubeAPIBurst is the burst of the requests sent to the kubernetes api server, the client-go default is 10
+	KubeAPIBurst = 100
+	// KubeAPIContentType is the content type of the requests of the typed kubernetes client,
+	// e.g. application/vnd.kubernetes.protobuf, empty uses json, the dynamic and the metadata clients always use json
+	KubeAPIContentType = ""
+	// WatchTimeout is how long every watch of the informers lasts before it is established again,
+	// 0 keeps the client-go default which is random between 5 and 10 minutes
+	WatchTimeout time.Duration
+)
+
+// ValidateKubeAPIContentType checks the content type of the kubernetes client
+func ValidateKubeAPIContentType(contentType string) error {
+	switch contentType {
+	case "", runtime.ContentTypeJSON, runtime.ContentTypeProtobuf:
+		return nil
+	}
+	return fmt.Errorf("invalid kubernetes api content type %q, it should be %v or %v",
+		contentType, runtime.ContentTypeJSON, runtime.ContentTypeProtobuf)
+}
+
+// kubeConfig returns the config of the kubernetes clients with the rate limits and the content type
+func kubeConfig() (*rest.Config, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", "")
+	if err != nil {
+		return nil, err
+	}
+	config.QPS = KubeAPIQPS
+	config.Burst = KubeAPIBurst
+	if KubeAPIContentType != "" {
+		config.ContentType = KubeAPIContentType
+	}
+	return config, nil
+}
+
+// tuneListWatch lists the objects of the informer by pages and sets the timeout of its watches
+func tuneListWatch(lw *cache.ListWatch) *cache.ListWatch {
+	paged := paginate(lw)
+	return &cache.ListWatch{
+		ListFunc: paged.ListFunc,
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			if WatchTimeout > 0 {
+				timeout := int64(WatchTimeout.Seconds())
+				opts.TimeoutSeconds = &timeout
+			}
+			return lw.Watch(opts)
+		},
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestValidateKubeAPIContentType(t *testing.T) {
+	testCaseList := []struct {
+		name        string
+		contentType string
+		hasErr      bool
+	}{
+		{"default", "", false},
+
+		{"json", "application/json", false},
+
+		{"protobuf", "application/vnd.kubernetes.protobuf", false},
+
+		{"yaml", "application/yaml", true},
+	}
+
+	for _, c := range testCaseList {
+		if err := ValidateKubeAPIContentType(c.contentType); (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+	}
+}
+
+func TestTuneListWatch(t *testing.T) {
+	defer func(timeout time.Duration) { WatchTimeout = timeout }(WatchTimeout)
+
+	var watched metav1.ListOptions
+	lw := tuneListWatch(&cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) { return nil, nil },
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			watched = opts
+			return watch.NewFake(), nil
+		},
+	})
+	reflectorTimeout := int64(420)
+
+	testCaseList := []struct {
+		name     string
+		timeout  time.Duration
+		expected int64
+	}{
+		{"client-go default", 0, reflectorTimeout},
+
+		{"configured", 30 * time.Minute, 1800},
+	}
+
+	for _, c := range testCaseList {
+		WatchTimeout = c.timeout
+		timeout := reflectorTimeout
+		if _, err := lw.Watch(metav1.ListOptions{ResourceVersion: "10", TimeoutSeconds: &timeout}); err != nil {
+			t.Errorf("case (%v) error: (%v)", c.name, err)
+			continue
+		}
+		if *watched.TimeoutSeconds != c.expected || watched.ResourceVersion != "10" {
+			t.Errorf("case (%v) output: (%v, %v) is not the expected: (%v, 10)", c.name, *watched.TimeoutSeconds,
+				watched.ResourceVersion, c.expected)
+		}
+	}
+}
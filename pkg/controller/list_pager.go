@@ -0,0 +1,59 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/pager"
+)
+
+// ListPageSize is the number of objects got by every list request of the dashboard sources, 0 lists all of them at once
+var ListPageSize int64 = 500
+
+// pagedList lists the objects by pages of ListPageSize and returns them in one list.
+// The informers list at resource version 0 which the watch cache serves in full whatever the limit is,
+// so such a list is read by pages from etcd instead; the lists at a given resource version are kept as they are
+// since the informer lists them from the watch cache on purpose.
+func pagedList(ctx context.Context, opts metav1.ListOptions,
+	list func(opts metav1.ListOptions) (runtime.Object, error)) (runtime.Object, error) {
+	if ListPageSize <= 0 || (opts.ResourceVersion != "" && opts.ResourceVersion != "0") {
+		return list(opts)
+	}
+	if opts.ResourceVersion == "0" {
+		opts.ResourceVersion = ""
+	}
+	opts.Limit = ListPageSize
+	p := pager.New(pager.SimplePageFunc(list))
+	p.PageSize = ListPageSize
+	obj, _, err := p.List(ctx, opts)
+	return obj, err
+}
+
+// eachListItem calls fn for every object listed by pages
+func eachListItem(ctx context.Context, opts metav1.ListOptions, list func(opts metav1.ListOptions) (runtime.Object, error),
+	fn func(obj runtime.Object)) error {
+	obj, err := pagedList(ctx, opts, list)
+	if err != nil {
+		return err
+	}
+	return meta.EachListItem(obj, func(obj runtime.Object) error {
+		fn(obj)
+		return nil
+	})
+}
+
+// paginate lists the objects of the list watch by pages, the watch is not changed
+func paginate(lw *cache.ListWatch) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			return pagedList(context.TODO(), opts, lw.List)
+		},
+		WatchFunc: lw.Watch,
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestPagedList(t *testing.T) {
+	defer func(size int64) { ListPageSize = size }(ListPageSize)
+
+	configmaps := []corev1.ConfigMap{}
+	for i := 0; i < 5; i++ {
+		configmaps = append(configmaps, corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: strconv.Itoa(i)}})
+	}
+	requests := []metav1.ListOptions{}
+	// list serves the configmaps by pages of the limit, the continue token is the index of the next configmap
+	list := func(opts metav1.ListOptions) (runtime.Object, error) {
+		requests = append(requests, opts)
+		start, _ := strconv.Atoi(opts.Continue)
+		end := len(configmaps)
+		result := &corev1.ConfigMapList{}
+		if opts.Limit > 0 && start+int(opts.Limit) < end {
+			end = start + int(opts.Limit)
+			result.Continue = strconv.Itoa(end)
+		}
+		result.Items = configmaps[start:end]
+		return result, nil
+	}
+
+	testCaseList := []struct {
+		name            string
+		pageSize        int64
+		resourceVersion string
+		requests        int
+		firstVersion    string
+	}{
+		{"initial list", 2, "0", 3, ""},
+
+		{"latest", 2, "", 3, ""},
+
+		{"exact page", 5, "0", 1, ""},
+
+		{"relist from the watch cache", 2, "10", 1, "10"},
+
+		{"disabled", 0, "0", 1, "0"},
+	}
+
+	for _, c := range testCaseList {
+		ListPageSize = c.pageSize
+		requests = nil
+		obj, err := pagedList(context.TODO(), metav1.ListOptions{ResourceVersion: c.resourceVersion}, list)
+		if err != nil {
+			t.Errorf("case (%v) error: (%v)", c.name, err)
+			continue
+		}
+		if output := meta.LenList(obj); output != len(configmaps) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, len(configmaps))
+		}
+		if len(requests) != c.requests {
+			t.Errorf("case (%v) requests: (%v) is not the expected: (%v)", c.name, len(requests), c.requests)
+		}
+		if requests[0].ResourceVersion != c.firstVersion {
+			t.Errorf("case (%v) resource version: (%v) is not the expected: (%v)", c.name, requests[0].ResourceVersion, c.firstVersion)
+		}
+	}
+}
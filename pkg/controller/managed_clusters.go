@@ -0,0 +1,238 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
+)
+
+const (
+	// managedClustersKey is the annotation to apply the dashboards of the configmap once per managed cluster,
+	// the value is the label selector of the managed clusters, empty means all of them
+	managedClustersKey = "observability.open-cluster-management.io/dashboard-managed-clusters"
+	// managedClusterKey is the managed cluster of a configmap expanded from a per cluster one,
+	// it is exposed to the folder templates as .ManagedCluster
+	managedClusterKey = "observability.open-cluster-management.io/managed-cluster"
+	// managedClusterPlaceholder in the dashboards is replaced with the name of the managed cluster
+	managedClusterPlaceholder = "${MANAGED_CLUSTER}"
+	// managedClusterFolder is the folder of the per cluster dashboards without a folder annotation
+	managedClusterFolder = "{{ .ManagedCluster }}"
+	// clusterVariable is the template variable which is set to the managed cluster
+	clusterVariable = "cluster"
+)
+
+var (
+	// WatchManagedClusters enables the per cluster dashboards of the configmaps with the dashboard-managed-clusters annotation
+	WatchManagedClusters = false
+
+	managedClusterResource = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1", Resource: "managedclusters"}
+)
+
+var managedClusters = struct {
+	sync.Mutex
+	// labels are the labels of the managed clusters keyed by the cluster name
+	labels map[string]map[string]string
+	// applied are the clusters which the per cluster configmaps were applied for keyed by the target and the configmap
+	applied map[string][]string
+}{labels: map[string]map[string]string{}, applied: map[string][]string{}}
+
+func isPerClusterConfigmap(obj interface{}) bool {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok || cm == nil {
+		return false
+	}
+	_, ok = cm.GetAnnotations()[managedClustersKey]
+	return ok
+}
+
+// matchedManagedClusters returns the names of the managed clusters selected by the configmap ordered by name
+func matchedManagedClusters(cm *corev1.ConfigMap) ([]string, error) {
+	selector, err := labels.Parse(cm.GetAnnotations()[managedClustersKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v annotation: %v", managedClustersKey, err)
+	}
+	managedClusters.Lock()
+	defer managedClusters.Unlock()
+	names := []string{}
+	for name, clusterLabels := range managedClusters.labels {
+		if selector.Matches(labels.Set(clusterLabels)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// clusterConfigmap returns the configmap of the dashboards of the managed cluster
+func clusterConfigmap(cm *corev1.ConfigMap, cluster string) *corev1.ConfigMap {
+	// the data is shared with the configmap since the derived configmaps only differ in the annotations
+	derived := &corev1.ConfigMap{TypeMeta: cm.TypeMeta, ObjectMeta: *cm.ObjectMeta.DeepCopy(),
+		Data: cm.Data, BinaryData: cm.BinaryData}
+	if derived.Annotations == nil {
+		derived.Annotations = map[string]string{}
+	}
+	delete(derived.Annotations, managedClustersKey)
+	derived.Annotations[managedClusterKey] = cluster
+	if derived.Annotations[customFolderKey] == "" {
+		derived.Annotations[customFolderKey] = managedClusterFolder
+	}
+	return derived
+}
+
+// managedClusterOf returns the managed cluster of the configmap, empty means it is not a per cluster one
+func managedClusterOf(cm *corev1.ConfigMap) string {
+	return cm.GetAnnotations()[managedClusterKey]
+}
+
+// clusterDashboardUID makes the uid of the dashboard unique for every managed cluster
+func clusterDashboardUID(uid string, cluster string) string {
+	clusterUID, _ := util.GenerateUID(uid, cluster)
+	return clusterUID
+}
+
+// substituteManagedCluster replaces the managed cluster placeholders of the dashboard
+func substituteManagedCluster(cm *corev1.ConfigMap, value string) string {
+	cluster := managedClusterOf(cm)
+	if cluster == "" {
+		return value
+	}
+	// the placeholders are in json strings so the value needs to be escaped
+	escaped, _ := json.Marshal(cluster)
+	return strings.ReplaceAll(value, managedClusterPlaceholder, string(escaped[1:len(escaped)-1]))
+}
+
+// setClusterVariable selects the managed cluster in the cluster variable of the dashboard
+func setClusterVariable(cm *corev1.ConfigMap, dashboard map[string]interface{}) {
+	cluster := managedClusterOf(cm)
+	if cluster == "" {
+		return
+	}
+	for _, variable := range templateVariables(dashboard) {
+		if variable["name"] == clusterVariable {
+			variable["current"] = map[string]interface{}{"selected": true, "text": cluster, "value": cluster}
+		}
+	}
+}
+
+// appliedClustersKey identifies the per cluster configmap in the grafana target of the context
+func appliedClustersKey(ctx context.Context, cm *corev1.ConfigMap) string {
+	return targetName(ctx) + "|" + configmapKey(cm)
+}
+
+// updateManagedClusters applies the dashboards of the per cluster configmap for every selected managed cluster,
+// the dashboards of the clusters which are no longer selected are deleted
+func updateManagedClusters(ctx context.Context, old interface{}, cm *corev1.ConfigMap, overwrite bool) error {
+	clusters, err := matchedManagedClusters(cm)
+	if err != nil {
+		return err
+	}
+	oldCM, _ := old.(*corev1.ConfigMap)
+	var syncErr error
+	clusterCtx := withDashboardData(ctx, cm)
+	for _, cluster := range clusters {
+		var oldCluster interface{}
+		if oldCM != nil && isPerClusterConfigmap(oldCM) {
+			oldCluster = clusterConfigmap(oldCM, cluster)
+		}
+		if err := updateDashboard(clusterCtx, oldCluster, clusterConfigmap(cm, cluster), overwrite); err != nil {
+			syncErr = fmt.Errorf("%v: %v", cluster, err)
+		}
+	}
+
+	key := appliedClustersKey(ctx, cm)
+	managedClusters.Lock()
+	previous := managedClusters.applied[key]
+	managedClusters.applied[key] = clusters
+	managedClusters.Unlock()
+	selected := map[string]bool{}
+	for _, cluster := range clusters {
+		selected[cluster] = true
+	}
+	for _, cluster := range previous {
+		if !selected[cluster] {
+			klog.InfoS("the managed cluster is no longer selected", "configmap", klog.KObj(cm), "cluster", cluster)
+			deleteDashboard(ctx, clusterConfigmap(cm, cluster))
+		}
+	}
+	return syncErr
+}
+
+// deleteManagedClusters deletes the dashboards of the per cluster configmap for all the clusters it was applied for,
+// it returns the first failure
+func deleteManagedClusters(ctx context.Context, cm *corev1.ConfigMap) error {
+	key := appliedClustersKey(ctx, cm)
+	managedClusters.Lock()
+	clusters := managedClusters.applied[key]
+	delete(managedClusters.applied, key)
+	managedClusters.Unlock()
+	if matched, err := matchedManagedClusters(cm); err == nil {
+		clusters = append(clusters, matched...)
+	}
+	deleted := map[string]bool{}
+	var deleteErr error
+	for _, cluster := range clusters {
+		if !deleted[cluster] {
+			deleted[cluster] = true
+			if err := deleteDashboard(ctx, clusterConfigmap(cm, cluster)); err != nil && deleteErr == nil {
+				deleteErr = fmt.Errorf("cluster %v: %v", cluster, err)
+			}
+		}
+	}
+	return deleteErr
+}
+
+// setManagedCluster records the labels of the managed cluster, the per cluster configmaps are applied again once
+// the clusters are changed since their hashes cover the selected clusters
+func setManagedCluster(obj interface{}, deleted bool) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		if tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown); isTombstone {
+			u, ok = tombstone.Obj.(*unstructured.Unstructured)
+		}
+		if !ok {
+			return
+		}
+	}
+	managedClusters.Lock()
+	current, exists := managedClusters.labels[u.GetName()]
+	changed := deleted == exists
+	if deleted {
+		delete(managedClusters.labels, u.GetName())
+	} else {
+		changed = changed || !labels.Equals(current, u.GetLabels())
+		managedClusters.labels[u.GetName()] = u.GetLabels()
+	}
+	managedClusters.Unlock()
+	if changed {
+		klog.InfoS("the managed cluster is changed", "cluster", u.GetName(), "deleted", deleted)
+		go redeliverAll()
+	}
+}
+
+func newManagedClusterInformer(client dynamic.Interface) cache.SharedIndexInformer {
+	informer := handleWatchErrors(managedClusterResource.GroupResource().String(), dynamicinformer.NewFilteredDynamicInformer(client,
+		managedClusterResource, "", ResyncPeriod, cache.Indexers{}, nil).Informer())
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { setManagedCluster(obj, false) },
+		UpdateFunc: func(old, new interface{}) { setManagedCluster(new, false) },
+		DeleteFunc: func(obj interface{}) { setManagedCluster(obj, true) },
+	})
+	return informer
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestManagedClusterDashboards(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func() {
+		managedClusters.labels = map[string]map[string]string{}
+		managedClusters.applied = map[string][]string{}
+	}()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test",
+			Labels:      map[string]string{"grafana-custom-dashboard": "true"},
+			Annotations: map[string]string{managedClustersKey: "env=prod"}},
+		Data: map[string]string{
+			"a.json": `{"uid": "a", "title": "Nodes of ${MANAGED_CLUSTER}",
+				"templating": {"list": [{"name": "cluster", "current": {"text": "local-cluster", "value": "local-cluster"}}]}}`,
+		},
+	}
+
+	testCaseList := []struct {
+		name     string
+		clusters map[string]map[string]string
+		expected []string
+	}{
+		{"no cluster", map[string]map[string]string{}, []string{}},
+
+		{"selected clusters", map[string]map[string]string{
+			"spoke-1": {"env": "prod"}, "spoke-2": {"env": "prod"}, "spoke-3": {"env": "dev"}},
+			[]string{"spoke-1", "spoke-2"}},
+
+		{"unselected cluster", map[string]map[string]string{
+			"spoke-1": {"env": "prod"}, "spoke-2": {"env": "dev"}, "spoke-3": {"env": "dev"}},
+			[]string{"spoke-1"}},
+	}
+
+	for _, c := range testCaseList {
+		managedClusters.labels = c.clusters
+		if err := updateDashboard(context.TODO(), cm, cm, false); err != nil {
+			t.Errorf("case (%v) failed to update the dashboards: %v", c.name, err)
+		}
+		folders := map[float64]string{}
+		for _, folder := range fake.folders[""] {
+			folders[folder.ID] = folder.Title
+		}
+		output := []string{}
+		for uid, saved := range fake.dashboards[""] {
+			cluster := folders[saved.folderID]
+			if uid != clusterDashboardUID("a", cluster) {
+				t.Errorf("case (%v) uid: (%v) is not the expected: (%v)", c.name, uid, clusterDashboardUID("a", cluster))
+			}
+			if title := saved.dashboard["title"]; title != "Nodes of "+cluster {
+				t.Errorf("case (%v) title: (%v) is not the expected: (%v)", c.name, title, "Nodes of "+cluster)
+			}
+			variable := templateVariables(saved.dashboard)[0]
+			if current := variable["current"].(map[string]interface{})["value"]; current != cluster {
+				t.Errorf("case (%v) cluster variable: (%v) is not the expected: (%v)", c.name, current, cluster)
+			}
+			output = append(output, cluster)
+		}
+		sort.Strings(output)
+		if !reflect.DeepEqual(output, c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+
+	deleteDashboard(context.TODO(), cm)
+	if len(fake.dashboards[""]) != 0 {
+		t.Errorf("case (deleted) output: (%v) is not the expected: (%v)", len(fake.dashboards[""]), 0)
+	}
+}
+
+func TestManagedClusterFolder(t *testing.T) {
+	testCaseList := []struct {
+		name        string
+		annotations map[string]string
+		expected    string
+	}{
+		{"cluster folder", map[string]string{managedClustersKey: ""}, "spoke-1"},
+
+		{"folder annotation", map[string]string{managedClustersKey: "", customFolderKey: "Fleet/{{ .ManagedCluster }}"}, "Fleet/spoke-1"},
+	}
+
+	for _, c := range testCaseList {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: c.annotations}}
+		if output := getDashboardCustomFolderTitle(clusterConfigmap(cm, "spoke-1")); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
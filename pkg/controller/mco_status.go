@@ -0,0 +1,150 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// dashboardsSyncedCondition is the condition on the MultiClusterObservability resource
+	// which reports whether all the dashboards are applied to grafana
+	dashboardsSyncedCondition = "GrafanaDashboardsSynced"
+	dashboardsSyncedReason    = "DashboardsSynced"
+	dashboardsFailedReason    = "DashboardsFailed"
+)
+
+var (
+	// ReportMCOStatus writes the GrafanaDashboardsSynced condition onto the MultiClusterObservability resource
+	ReportMCOStatus = false
+	// MCOName is the MultiClusterObservability resource to report to, empty means the only one in the cluster
+	MCOName = ""
+	// MCOStatusInterval is how often the condition is refreshed
+	MCOStatusInterval = time.Minute
+
+	mcoResource = schema.GroupVersionResource{
+		Group:    "observability.open-cluster-management.io",
+		Version:  "v1beta2",
+		Resource: "multiclusterobservabilities",
+	}
+)
+
+// dashboardCounts counts the dashboards of all the sources by the result of their last sync
+func dashboardCounts() (int, int) {
+	synced, failed := 0, 0
+	for _, state := range allSyncStates() {
+		for _, status := range state.listStatuses() {
+			if status.Health == healthProgressing {
+				continue
+			}
+			if status.Synced {
+				synced += len(status.Dashboards)
+				continue
+			}
+			// the dashboards applied before the failure are still in grafana
+			synced += len(status.UIDs)
+			failed += len(status.Dashboards) - len(status.UIDs)
+		}
+	}
+	return synced, failed
+}
+
+// dashboardsCondition returns the condition for the counts of the synced and failed dashboards
+func dashboardsCondition(synced int, failed int) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    dashboardsSyncedCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  dashboardsSyncedReason,
+		Message: fmt.Sprintf("%v dashboards synced, %v failed", synced, failed),
+	}
+	if failed > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = dashboardsFailedReason
+	}
+	return condition
+}
+
+// runMCOStatusReporter refreshes the condition on the MultiClusterObservability resource until ctx is done
+func runMCOStatusReporter(ctx context.Context, client dynamic.Interface, interval time.Duration) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := reportMCOStatus(ctx, client); err != nil {
+			klog.ErrorS(err, "failed to report the dashboard status to the MultiClusterObservability resource")
+		}
+	}, interval)
+}
+
+// reportMCOStatus updates the status of the MultiClusterObservability resource when the condition is changed
+func reportMCOStatus(ctx context.Context, client dynamic.Interface) error {
+	mco, err := getMCO(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	conditions := []metav1.Condition{}
+	existing, _, err := unstructured.NestedSlice(mco.Object, "status", "conditions")
+	if err != nil {
+		return fmt.Errorf("failed to read the conditions of %v: %v", mco.GetName(), err)
+	}
+	for _, item := range existing {
+		u, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		condition := metav1.Condition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u, &condition); err != nil {
+			return fmt.Errorf("failed to read the conditions of %v: %v", mco.GetName(), err)
+		}
+		conditions = append(conditions, condition)
+	}
+
+	updated := append([]metav1.Condition{}, conditions...)
+	meta.SetStatusCondition(&updated, dashboardsCondition(dashboardCounts()))
+	if reflect.DeepEqual(conditions, updated) {
+		return nil
+	}
+
+	items := []interface{}{}
+	for i := range updated {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&updated[i])
+		if err != nil {
+			return err
+		}
+		items = append(items, u)
+	}
+	if err := unstructured.SetNestedSlice(mco.Object, items, "status", "conditions"); err != nil {
+		return err
+	}
+	_, err = client.Resource(mcoResource).UpdateStatus(ctx, mco, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update the status of %v: %v", mco.GetName(), err)
+	}
+	klog.V(4).InfoS("the dashboard status is reported", "multiclusterobservability", mco.GetName())
+	return nil
+}
+
+func getMCO(ctx context.Context, client dynamic.Interface) (*unstructured.Unstructured, error) {
+	if MCOName != "" {
+		return client.Resource(mcoResource).Get(ctx, MCOName, metav1.GetOptions{})
+	}
+	list, err := client.Resource(mcoResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Items) != 1 {
+		return nil, fmt.Errorf("found %v MultiClusterObservability resources, set the name to report to", len(list.Items))
+	}
+	return &list.Items[0], nil
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+)
+
+func TestDashboardsCondition(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		synced   int
+		failed   int
+		expected metav1.ConditionStatus
+		reason   string
+	}{
+		{"all synced", 3, 0, metav1.ConditionTrue, dashboardsSyncedReason},
+
+		{"no dashboards", 0, 0, metav1.ConditionTrue, dashboardsSyncedReason},
+
+		{"failed", 2, 1, metav1.ConditionFalse, dashboardsFailedReason},
+	}
+
+	for _, c := range testCaseList {
+		condition := dashboardsCondition(c.synced, c.failed)
+		if condition.Status != c.expected || condition.Reason != c.reason {
+			t.Errorf("case (%v) output: (%v %v) is not the expected: (%v %v)", c.name, condition.Status,
+				condition.Reason, c.expected, c.reason)
+		}
+	}
+}
+
+func TestDashboardCounts(t *testing.T) {
+	synced, failed := dashboardCounts()
+	state := newSyncState("counts-test")
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Data:       map[string]string{"a.json": "{}", "b.json": "{}", "c.json": "{}"},
+	}
+	state.markFailed(cm, &appliedDashboards{uids: map[string]string{"a.json": "a"}}, fmt.Errorf("grafana is down"))
+	defer state.forget(cm)
+
+	s, f := dashboardCounts()
+	if s-synced != 1 || f-failed != 2 {
+		t.Errorf("case (partial failure) output: (%v %v) is not the expected: (1 2)", s-synced, f-failed)
+	}
+}
+
+func TestReportMCOStatus(t *testing.T) {
+	mco := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "observability.open-cluster-management.io/v1beta2",
+		"kind":       "MultiClusterObservability",
+		"metadata":   map[string]interface{}{"name": "observability"},
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{
+					"type":               "Ready",
+					"status":             "True",
+					"reason":             "Ready",
+					"message":            "Observability components are deployed and running",
+					"lastTransitionTime": "2021-01-01T00:00:00Z",
+				},
+			},
+		},
+	}}
+	client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), mco)
+
+	if err := reportMCOStatus(context.TODO(), client); err != nil {
+		t.Fatalf("failed to report the status: %v", err)
+	}
+	updated, err := client.Resource(mcoResource).Get(context.TODO(), "observability", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get the MultiClusterObservability: %v", err)
+	}
+	items, _, _ := unstructured.NestedSlice(updated.Object, "status", "conditions")
+	conditions := []metav1.Condition{}
+	for _, item := range items {
+		condition := metav1.Condition{}
+		runtime.DefaultUnstructuredConverter.FromUnstructured(item.(map[string]interface{}), &condition)
+		conditions = append(conditions, condition)
+	}
+	if !meta.IsStatusConditionTrue(conditions, "Ready") {
+		t.Errorf("case (existing) the other conditions should be kept: %v", conditions)
+	}
+	expected := dashboardsCondition(dashboardCounts())
+	condition := meta.FindStatusCondition(conditions, dashboardsSyncedCondition)
+	if condition == nil || condition.Status != expected.Status || condition.Message != expected.Message {
+		t.Errorf("case (dashboards) output: (%v) is not the expected: (%v)", condition, expected)
+	}
+
+	// the unchanged condition is not written again
+	client.ClearActions()
+	if err := reportMCOStatus(context.TODO(), client); err != nil {
+		t.Fatalf("failed to report the status: %v", err)
+	}
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "update" {
+			t.Errorf("case (unchanged) the status should not be updated again")
+		}
+	}
+
+	MCOName = "missing"
+	defer func() { MCOName = "" }()
+	if err := reportMCOStatus(context.TODO(), client); err == nil {
+		t.Errorf("case (missing) reporting to a missing MultiClusterObservability should fail")
+	}
+}
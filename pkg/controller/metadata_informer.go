@@ -0,0 +1,155 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// MetadataInformer watches the metadata of the configmaps only and gets the configmap once its event is handled,
+// so that the loader does not keep the content of all the configmaps in memory,
+// the deleted configmaps are deleted from grafana by the dashboard uids of their last sync
+var MetadataInformer = false
+
+// metadataEventHandler turns the metadata of the configmaps into the configmaps for the dashboard handler
+type metadataEventHandler struct {
+	handler    cache.ResourceEventHandler
+	state      *syncState
+	coreClient corev1client.CoreV1Interface
+	lock       sync.Mutex
+	// perCluster are the per cluster configmaps which are kept in full, their dashboards are deleted for every cluster
+	// by the content
+	perCluster map[string]*corev1.ConfigMap
+}
+
+func newMetadataInformer(ctx context.Context, metadataClient metadata.Interface,
+	coreClient corev1client.CoreV1Interface) cache.SharedIndexInformer {
+	watchedNS := os.Getenv("POD_NAMESPACE")
+	configmaps := metadataClient.Resource(corev1.SchemeGroupVersion.WithResource("configmaps")).Namespace(watchedNS)
+	watchlist := &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			return configmaps.List(context.TODO(), configmapListOptions(opts))
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			return configmaps.Watch(context.TODO(), configmapListOptions(opts))
+		},
+	}
+	informer := handleWatchErrors("configmaps", cache.NewSharedIndexInformer(
+		stripMetadata(tuneListWatch(watchlist)),
+		&metav1.PartialObjectMetadata{},
+		ResyncPeriod,
+		cache.Indexers{},
+	))
+
+	handler := newMetadataEventHandler(newDashboardEventHandler(ctx, appliedState, configmapFromObject), appliedState, coreClient)
+	informer.AddEventHandler(handler)
+	registerInformerResync(informer, handler)
+	return informer
+}
+
+func newMetadataEventHandler(handler cache.ResourceEventHandler, state *syncState,
+	coreClient corev1client.CoreV1Interface) *metadataEventHandler {
+	return &metadataEventHandler{handler: handler, state: state, coreClient: coreClient,
+		perCluster: map[string]*corev1.ConfigMap{}}
+}
+
+// metadataConfigmap returns the configmap with the metadata only, it is checked before the configmap is fetched
+func metadataConfigmap(obj interface{}) *corev1.ConfigMap {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	meta, ok := obj.(*metav1.PartialObjectMetadata)
+	if !ok || meta == nil {
+		return nil
+	}
+	return &corev1.ConfigMap{ObjectMeta: *meta.ObjectMeta.DeepCopy()}
+}
+
+// fetch gets the configmap of the metadata, nil means it is not a dashboard or it cannot be got,
+// the configmaps which fail are got again on the next resync
+func (h *metadataEventHandler) fetch(obj interface{}) *corev1.ConfigMap {
+	meta := metadataConfigmap(obj)
+	if !isDesiredDashboardConfigmap(meta) || !ownsNamespace(meta.GetNamespace()) {
+		return nil
+	}
+	cm, err := h.coreClient.ConfigMaps(meta.GetNamespace()).Get(context.TODO(), meta.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		klog.ErrorS(err, "failed to get the configmap, it is handled on the next resync", "configmap", klog.KObj(meta))
+		return nil
+	}
+	cm = stripObject(cm).(*corev1.ConfigMap)
+	if isPerClusterConfigmap(cm) {
+		h.lock.Lock()
+		h.perCluster[configmapKey(cm)] = cm
+		h.lock.Unlock()
+	}
+	return cm
+}
+
+// lastApplied returns the configmap with the dashboards of its last sync, the data is the uid of every applied dashboard
+// so that the dashboards of the deleted configmap and the ones moved to another org can be deleted
+func (h *metadataEventHandler) lastApplied(obj interface{}) *corev1.ConfigMap {
+	cm := metadataConfigmap(obj)
+	if cm == nil {
+		return nil
+	}
+	h.lock.Lock()
+	full, ok := h.perCluster[configmapKey(cm)]
+	h.lock.Unlock()
+	if ok {
+		return full
+	}
+	cm.Data = map[string]string{}
+	for key, uid := range h.state.appliedUIDs(configmapKey(cm)) {
+		// the uid is not resolved again from the references
+		dataKey := strings.TrimSuffix(strings.TrimSuffix(key, grafanaComSuffix), remoteSuffix)
+		cm.Data[dataKey] = fmt.Sprintf("{\"uid\": %q}", uid)
+		if folder, ok := cm.Annotations[dashboardFolderKeyPrefix+key]; ok && dataKey != key {
+			cm.Annotations[dashboardFolderKeyPrefix+dataKey] = folder
+		}
+	}
+	return cm
+}
+
+func (h *metadataEventHandler) OnAdd(obj interface{}) {
+	if cm := h.fetch(obj); cm != nil {
+		h.handler.OnAdd(cm)
+	}
+}
+
+func (h *metadataEventHandler) OnUpdate(old, new interface{}) {
+	// the per cluster configmap of the last sync is kept until the new one is got
+	last := h.lastApplied(old)
+	if cm := h.fetch(new); cm != nil {
+		h.handler.OnUpdate(last, cm)
+	}
+}
+
+func (h *metadataEventHandler) OnDelete(obj interface{}) {
+	cm := h.lastApplied(obj)
+	if cm == nil {
+		return
+	}
+	h.lock.Lock()
+	delete(h.perCluster, configmapKey(cm))
+	h.lock.Unlock()
+	h.handler.OnDelete(cm)
+}
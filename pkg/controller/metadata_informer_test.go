@@ -0,0 +1,64 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMetadataEventHandler(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+
+	dashboard := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "large", Namespace: "metadata-test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+		Data: map[string]string{"a.json": `{"uid": "large", "title": "Large", "panels": []}`},
+	}
+	unrelated := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "kube-root-ca.crt", Namespace: "metadata-test"}}
+	kubeClient := kubefake.NewSimpleClientset(dashboard, unrelated)
+	state := newSyncState("metadata-test")
+	defer state.forget(dashboard)
+	handler := newMetadataEventHandler(newDashboardEventHandler(context.TODO(), state, func(obj interface{}) interface{} {
+		return obj
+	}), state, kubeClient.CoreV1())
+	metadataOf := func(cm *corev1.ConfigMap) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{ObjectMeta: cm.ObjectMeta}
+	}
+
+	testCaseList := []struct {
+		name   string
+		event  func()
+		gets   int
+		exists bool
+	}{
+		{"unrelated", func() { handler.OnAdd(metadataOf(unrelated)) }, 0, false},
+
+		{"added", func() { handler.OnAdd(metadataOf(dashboard)) }, 1, true},
+
+		{"deleted", func() { handler.OnDelete(metadataOf(dashboard)) }, 0, false},
+	}
+
+	for _, c := range testCaseList {
+		kubeClient.ClearActions()
+		c.event()
+		gets := 0
+		for _, action := range kubeClient.Actions() {
+			if action.GetVerb() == "get" {
+				gets++
+			}
+		}
+		if gets != c.gets {
+			t.Errorf("case (%v) gets: (%v) is not the expected: (%v)", c.name, gets, c.gets)
+		}
+		if _, output := fake.dashboards[""]["large"]; output != c.exists {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.exists)
+		}
+	}
+}
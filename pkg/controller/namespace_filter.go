@@ -0,0 +1,93 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"fmt"
+	"path"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// IncludeNamespaces are the namespaces the dashboards are loaded from, empty means all of them,
+	// the names can be patterns such as "team-*"
+	IncludeNamespaces = []string{}
+	// ExcludeNamespaces are the namespaces the dashboards are never loaded from, they win over the included ones
+	ExcludeNamespaces = []string{}
+	// NamespaceSelector is the label selector of the namespaces the dashboards are loaded from
+	NamespaceSelector = ""
+
+	namespaceSelector = labels.Everything()
+)
+
+// namespaceLabels are the labels of the namespaces keyed by the namespace name
+var namespaceLabels = struct {
+	sync.Mutex
+	labels map[string]map[string]string
+}{labels: map[string]map[string]string{}}
+
+// ValidateNamespaceFilter checks the namespace patterns and parses the namespace selector
+func ValidateNamespaceFilter() error {
+	for _, pattern := range append(append([]string{}, IncludeNamespaces...), ExcludeNamespaces...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid namespace pattern %q: %v", pattern, err)
+		}
+	}
+	selector, err := labels.Parse(NamespaceSelector)
+	if err != nil {
+		return fmt.Errorf("invalid namespace selector %q: %v", NamespaceSelector, err)
+	}
+	namespaceSelector = selector
+	return nil
+}
+
+func matchNamespace(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isAllowedNamespace checks whether the dashboards can be loaded from the namespace,
+// the dashboards without a namespace, e.g. from git or the filesystem, are always allowed
+func isAllowedNamespace(namespace string) bool {
+	if namespace == "" {
+		return true
+	}
+	if matchNamespace(ExcludeNamespaces, namespace) {
+		return false
+	}
+	if len(IncludeNamespaces) > 0 && !matchNamespace(IncludeNamespaces, namespace) {
+		return false
+	}
+	if NamespaceSelector == "" {
+		return true
+	}
+	namespaceLabels.Lock()
+	defer namespaceLabels.Unlock()
+	return namespaceSelector.Matches(labels.Set(namespaceLabels.labels[namespace]))
+}
+
+// setNamespaceLabels records the labels of the namespace, the dashboards are applied again
+// once they are changed on an existing namespace so that the newly selected namespaces are loaded
+func setNamespaceLabels(ns *corev1.Namespace, deleted bool, resync bool) {
+	namespaceLabels.Lock()
+	changed := !labels.Equals(namespaceLabels.labels[ns.Name], ns.GetLabels())
+	if deleted {
+		delete(namespaceLabels.labels, ns.Name)
+	} else {
+		namespaceLabels.labels[ns.Name] = ns.GetLabels()
+	}
+	namespaceLabels.Unlock()
+	if changed && resync && NamespaceSelector != "" {
+		klog.InfoS("the labels of the namespace are changed", "namespace", ns.Name, "allowed", isAllowedNamespace(ns.Name))
+		go resyncAll()
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAllowedNamespace(t *testing.T) {
+	defer func() {
+		IncludeNamespaces, ExcludeNamespaces, NamespaceSelector = []string{}, []string{}, ""
+		ValidateNamespaceFilter()
+		namespaceLabels.labels = map[string]map[string]string{}
+	}()
+	setNamespaceLabels(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a",
+		Labels: map[string]string{"grafana-dashboards": "enabled"}}}, false, false)
+	setNamespaceLabels(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}}, false, false)
+
+	testCaseList := []struct {
+		name      string
+		include   []string
+		exclude   []string
+		selector  string
+		namespace string
+		expected  bool
+	}{
+		{"no filter", []string{}, []string{}, "", "openshift-monitoring", true},
+
+		{"no namespace", []string{"team-*"}, []string{}, "", "", true},
+
+		{"included", []string{"team-*", "monitoring"}, []string{}, "", "team-a", true},
+
+		{"not included", []string{"team-*", "monitoring"}, []string{}, "", "openshift-monitoring", false},
+
+		{"excluded", []string{}, []string{"openshift-*"}, "", "openshift-monitoring", false},
+
+		{"excluded over included", []string{"team-*"}, []string{"team-b"}, "", "team-b", false},
+
+		{"selected", []string{}, []string{}, "grafana-dashboards=enabled", "team-a", true},
+
+		{"not selected", []string{}, []string{}, "grafana-dashboards=enabled", "team-b", false},
+
+		{"unknown namespace", []string{}, []string{}, "grafana-dashboards=enabled", "team-c", false},
+	}
+
+	for _, c := range testCaseList {
+		IncludeNamespaces, ExcludeNamespaces, NamespaceSelector = c.include, c.exclude, c.selector
+		if err := ValidateNamespaceFilter(); err != nil {
+			t.Fatalf("case (%v) invalid filter: %v", c.name, err)
+		}
+		if output := isAllowedNamespace(c.namespace); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+
+	NamespaceSelector = "grafana-dashboards in (enabled"
+	if err := ValidateNamespaceFilter(); err == nil {
+		t.Errorf("case (invalid selector) output: (%v) is not the expected: (%v)", err, "an error")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// NamespaceFolders puts the dashboards without a folder annotation into the folder of their namespace,
+// which is the dashboard-folder annotation of the namespace or the namespace name
+var NamespaceFolders = false
+
+// namespaceFolders are the dashboard-folder annotations of the namespaces keyed by the namespace name
+var namespaceFolders = struct {
+	sync.Mutex
+	titles map[string]string
+}{titles: map[string]string{}}
+
+// namespaceFolderTitle returns the folder of the namespace, empty means the namespace is not known, e.g. a git source
+func namespaceFolderTitle(namespace string) string {
+	namespaceFolders.Lock()
+	defer namespaceFolders.Unlock()
+	if title := namespaceFolders.titles[namespace]; title != "" {
+		return title
+	}
+	return namespace
+}
+
+// setNamespaceFolder records the folder annotation of the namespace, the dashboards are applied again
+// once it is changed on an existing namespace
+func setNamespaceFolder(ns *corev1.Namespace, deleted bool, resync bool) {
+	title := ns.GetAnnotations()[customFolderKey]
+	namespaceFolders.Lock()
+	changed := namespaceFolders.titles[ns.Name] != title
+	if deleted || title == "" {
+		delete(namespaceFolders.titles, ns.Name)
+	} else {
+		namespaceFolders.titles[ns.Name] = title
+	}
+	namespaceFolders.Unlock()
+	if changed && resync {
+		klog.InfoS("the folder of the namespace is changed", "namespace", ns.Name, "folder", namespaceFolderTitle(ns.Name))
+		go resyncAll()
+	}
+}
+
+// newNamespaceInformer watches the folder annotations and the labels of the namespaces
+func newNamespaceInformer(coreClient corev1client.CoreV1Interface) cache.SharedIndexInformer {
+	watchlist := &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			return coreClient.Namespaces().List(context.TODO(), opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			return coreClient.Namespaces().Watch(context.TODO(), opts)
+		},
+	}
+	informer := handleWatchErrors("namespaces", cache.NewSharedIndexInformer(watchlist, &corev1.Namespace{}, ResyncPeriod, cache.Indexers{}))
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			setNamespaceFolder(obj.(*corev1.Namespace), false, false)
+			setNamespaceLabels(obj.(*corev1.Namespace), false, false)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			setNamespaceFolder(new.(*corev1.Namespace), false, NamespaceFolders)
+			setNamespaceLabels(new.(*corev1.Namespace), false, true)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if ns, ok := obj.(*corev1.Namespace); ok {
+				setNamespaceFolder(ns, true, false)
+				setNamespaceLabels(ns, true, false)
+			}
+		},
+	})
+	return informer
+}
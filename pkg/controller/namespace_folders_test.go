@@ -0,0 +1,50 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNamespaceFolders(t *testing.T) {
+	defer func() {
+		NamespaceFolders = false
+		namespaceFolders.titles = map[string]string{}
+	}()
+	setNamespaceFolder(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b",
+		Annotations: map[string]string{customFolderKey: "Team B"}}}, false, false)
+	setNamespaceFolder(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-c",
+		Annotations: map[string]string{customFolderKey: "{{ .Namespace }} dashboards"}}}, false, false)
+
+	testCaseList := []struct {
+		name        string
+		enabled     bool
+		namespace   string
+		annotations map[string]string
+		expected    string
+	}{
+		{"disabled", false, "team-a", nil, defaultCustomFolder},
+
+		{"namespace name", true, "team-a", nil, "team-a"},
+
+		{"namespace annotation", true, "team-b", nil, "Team B"},
+
+		{"namespace template", true, "team-c", nil, "team-c dashboards"},
+
+		{"configmap annotation", true, "team-b", map[string]string{customFolderKey: "SLOs"}, "SLOs"},
+
+		{"no namespace", true, "", nil, defaultCustomFolder},
+	}
+
+	for _, c := range testCaseList {
+		NamespaceFolders = c.enabled
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: c.namespace, Annotations: c.annotations}}
+		if output := getDashboardFolderTitle(cm, "a.json"); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
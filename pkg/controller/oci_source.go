@@ -0,0 +1,138 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/oci"
+)
+
+var (
+	// OCIArtifact is the oci artifact to load the dashboards from, empty means disabled
+	OCIArtifact = ""
+	// OCIPollInterval is how often the oci artifact is checked for a new digest
+	OCIPollInterval = 5 * time.Minute
+	// OCIInsecure uses plain http to pull the oci artifact
+	OCIInsecure = false
+	// OCIUsername and OCIPassword are the optional credentials of the registry
+	OCIUsername = ""
+	OCIPassword = ""
+)
+
+// ociSource syncs the dashboard files of an oci artifact pushed by oras,
+// the files are extracted to a directory which follows the same layout as the filesystem source
+type ociSource struct {
+	ref      *oci.Reference
+	client   *oci.Client
+	dir      string
+	interval time.Duration
+	files    *filesystemSource
+	// syncedDigest is the manifest digest which was synced successfully last time
+	syncedDigest string
+}
+
+func newOCISource(artifact string, client *oci.Client, interval time.Duration) (*ociSource, error) {
+	ref, err := oci.ParseReference(artifact)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := ioutil.TempDir("", "grafana-dashboards")
+	if err != nil {
+		return nil, err
+	}
+
+	files := newFilesystemSource(dir, interval)
+	files.state.source = "oci:" + ref.String()
+	return &ociSource{
+		ref:      ref,
+		client:   client,
+		dir:      dir,
+		interval: interval,
+		files:    files,
+	}, nil
+}
+
+// Run pulls the oci artifact periodically until ctx is done
+func (o *ociSource) Run(ctx context.Context) {
+	wait.Until(func() { o.poll(ctx) }, o.interval, ctx.Done())
+}
+
+func (o *ociSource) poll(ctx context.Context) {
+	if !inFlightSyncs.start() {
+		return
+	}
+	defer inFlightSyncs.done()
+	err := o.sync(ctx)
+	if err != nil {
+		klog.Errorf("failed to sync oci artifact %v: %v", o.ref, err)
+		metrics.OCISyncFailures.WithLabelValues(o.ref.String()).Inc()
+	}
+}
+
+func (o *ociSource) sync(ctx context.Context) error {
+	manifest, err := o.client.Resolve(o.ref)
+	if err != nil {
+		return err
+	}
+	// the files of the same digest are applied again after a resync
+	if manifest.Digest == o.syncedDigest && o.files.isSynced() {
+		return nil
+	}
+
+	klog.Infof("sync oci artifact %v at digest %v", o.ref, manifest.Digest)
+	files, err := o.client.Files(o.ref, manifest)
+	if err != nil {
+		return err
+	}
+	err = o.extract(files)
+	if err != nil {
+		return err
+	}
+	err = o.files.sync(ctx)
+	if err != nil {
+		return err
+	}
+
+	if o.syncedDigest != "" {
+		metrics.OCILastSyncedDigest.Delete(prometheus.Labels{"artifact": o.ref.String(), "digest": o.syncedDigest})
+	}
+	metrics.OCILastSyncedDigest.WithLabelValues(o.ref.String(), manifest.Digest).Set(1)
+	metrics.OCILastSyncTimestamp.WithLabelValues(o.ref.String()).SetToCurrentTime()
+	o.syncedDigest = manifest.Digest
+	return nil
+}
+
+// extract replaces the content of the directory with the files of the artifact
+func (o *ociSource) extract(files map[string][]byte) error {
+	entries, err := ioutil.ReadDir(o.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(o.dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	for name, content := range files {
+		path := filepath.Join(o.dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
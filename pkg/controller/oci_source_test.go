@@ -0,0 +1,69 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/oci"
+)
+
+func TestOCISource(t *testing.T) {
+	dashboard := "{\"title\": \"a\"}"
+	sum := sha256.Sum256([]byte(dashboard))
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	manifest := fmt.Sprintf(`{"mediaType": "application/vnd.oci.image.manifest.v1+json", "layers": [
+		{"mediaType": "application/json", "digest": "%v", "size": %v,
+		 "annotations": {"org.opencontainers.image.title": "SLOs/a.json"}}]}`, digest, len(dashboard))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v2/dashboards/manifests/v1":
+			w.Write([]byte(manifest))
+		case "/v2/dashboards/blobs/" + digest:
+			w.Write([]byte(dashboard))
+		default:
+			// the grafana api
+			w.Write([]byte("[]"))
+		}
+	}))
+	defer server.Close()
+
+	originalURI := grafanaURI
+	grafanaURI = server.URL
+	DryRun = true
+	retry = 1
+	defer func() {
+		grafanaURI = originalURI
+		DryRun = false
+	}()
+
+	source, err := newOCISource(strings.TrimPrefix(server.URL, "http://")+"/dashboards:v1",
+		oci.NewClient(true, "", ""), time.Second)
+	if err != nil {
+		t.Fatalf("failed to create oci source: %v", err)
+	}
+	defer os.RemoveAll(source.dir)
+
+	if err := source.sync(context.TODO()); err != nil {
+		t.Fatalf("failed to sync oci source: %v", err)
+	}
+	if source.syncedDigest == "" || len(source.files.loaded) != 1 {
+		t.Fatalf("the digest %v with %v dashboards is not synced", source.syncedDigest, len(source.files.loaded))
+	}
+	for _, cm := range source.files.loaded {
+		if getDashboardCustomFolderTitle(cm) != "SLOs" {
+			t.Errorf("the folder %v is not the expected SLOs", getDashboardCustomFolderTitle(cm))
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// sourceNamespaceTagPrefix, sourceNameTagPrefix and sourceKeyTagPrefix are the tags of the configmap data key
+	// which the dashboard is applied from
+	sourceNamespaceTagPrefix = "source-namespace:"
+	sourceNameTagPrefix      = "source-name:"
+	sourceKeyTagPrefix       = "source-key:"
+
+	// folderOwnershipMarker starts the description of the folders created by the loader
+	folderOwnershipMarker = "Managed by grafana-dashboard-loader"
+)
+
+// OwnershipTags tags the dashboards with the namespace, the name and the data key of their configmaps
+// and describes the folders created by the loader, so the loader managed content can be told apart in grafana
+var OwnershipTags = true
+
+var ownershipTagPrefixes = []string{sourceNamespaceTagPrefix, sourceNameTagPrefix, sourceKeyTagPrefix}
+
+// setOwnershipTags tags the dashboard with its source, the source tags in the dashboard json are replaced
+func setOwnershipTags(dashboard map[string]interface{}, cm *corev1.ConfigMap, key string) {
+	removeOwnershipTags(dashboard)
+	tags, _ := dashboard["tags"].([]interface{})
+	dashboard["tags"] = append(tags, sourceNamespaceTagPrefix+cm.GetNamespace(), sourceNameTagPrefix+cm.GetName(),
+		sourceKeyTagPrefix+key)
+}
+
+// removeOwnershipTags drops the source tags, e.g. from the exported dashboards which are applied from other sources
+func removeOwnershipTags(dashboard map[string]interface{}) {
+	current, ok := dashboard["tags"].([]interface{})
+	if !ok {
+		return
+	}
+	tags := []interface{}{}
+	for _, tag := range current {
+		if s, ok := tag.(string); ok && isOwnershipTag(s) {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	dashboard["tags"] = tags
+}
+
+func isOwnershipTag(tag string) bool {
+	for _, prefix := range ownershipTagPrefixes {
+		if strings.HasPrefix(tag, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// folderDescription returns the description of the folder created for the configmap of the context,
+// empty means the folder is created without description
+func folderDescription(ctx context.Context) string {
+	if !OwnershipTags {
+		return ""
+	}
+	if source := auditSource(ctx); source != "" {
+		return fmt.Sprintf("%v, created for configmap %v", folderOwnershipMarker, source)
+	}
+	return folderOwnershipMarker
+}
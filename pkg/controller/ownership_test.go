@@ -0,0 +1,67 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetOwnershipTags(t *testing.T) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "slo", Namespace: "team-a"}}
+	testCaseList := []struct {
+		name      string
+		dashboard map[string]interface{}
+		expected  interface{}
+	}{
+		{
+			"no tags",
+			map[string]interface{}{},
+			[]interface{}{"source-namespace:team-a", "source-name:slo", "source-key:slo.json"},
+		},
+
+		{
+			"exported from another configmap",
+			map[string]interface{}{"tags": []interface{}{"acm", "source-namespace:team-b", "source-name:other", "source-key:a.json"}},
+			[]interface{}{"acm", "source-namespace:team-a", "source-name:slo", "source-key:slo.json"},
+		},
+	}
+
+	for _, c := range testCaseList {
+		setOwnershipTags(c.dashboard, cm, "slo.json")
+		output := c.dashboard["tags"]
+		if !reflect.DeepEqual(output, c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestFolderDescription(t *testing.T) {
+	defer func(enabled bool) { OwnershipTags = enabled }(OwnershipTags)
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "slo", Namespace: "team-a"}}
+	testCaseList := []struct {
+		name     string
+		enabled  bool
+		ctx      context.Context
+		expected string
+	}{
+		{"configmap", true, withAuditSource(context.TODO(), cm), folderOwnershipMarker + ", created for configmap team-a/slo"},
+
+		{"no configmap", true, context.TODO(), folderOwnershipMarker},
+
+		{"disabled", false, withAuditSource(context.TODO(), cm), ""},
+	}
+
+	for _, c := range testCaseList {
+		OwnershipTags = c.enabled
+		if output := folderDescription(c.ctx); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
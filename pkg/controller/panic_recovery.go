@@ -0,0 +1,49 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+const (
+	panicOpApply  = "apply"
+	panicOpDelete = "delete"
+	panicOpEvent  = "event"
+)
+
+// recoverSyncPanic turns a panic while the configmap is applied or deleted into the error of the configmap,
+// so that a malformed configmap fails on its own instead of crashing the loader, it is deferred directly
+func recoverSyncPanic(op string, obj interface{}, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	metrics.SyncPanics.WithLabelValues(op).Inc()
+	ref := klog.ObjectRef{}
+	if cm, ok := obj.(*corev1.ConfigMap); ok && cm != nil {
+		ref = klog.KObj(cm)
+	}
+	klog.ErrorS(nil, "recovered from a panic", "op", op, "configmap", ref, "panic", r, "stack", string(debug.Stack()))
+	*err = fmt.Errorf("the loader failed to %v the dashboards: %v", op, r)
+}
+
+// recoverEventPanic logs the panic while an event of the source object is handled, the next events are still handled,
+// it is deferred directly
+func recoverEventPanic(obj interface{}) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	metrics.SyncPanics.WithLabelValues(panicOpEvent).Inc()
+	klog.ErrorS(nil, "recovered from a panic while the event is handled", "object", fmt.Sprintf("%T", obj), "panic", r,
+		"stack", string(debug.Stack()))
+	recordEvent(obj, corev1.EventTypeWarning, reasonDashboardApplyFailed, "The loader failed to handle the object: %v", r)
+}
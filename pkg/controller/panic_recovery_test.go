@@ -0,0 +1,108 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+// panickingGrafanaClient panics on saving or deleting the dashboard with the uid
+type panickingGrafanaClient struct {
+	*fakeGrafanaClient
+	uid string
+}
+
+func (c *panickingGrafanaClient) SaveDashboard(ctx context.Context, orgID string, dashboard map[string]interface{},
+	folderID float64, overwrite bool) (SavedDashboard, error) {
+	if dashboard["uid"] == c.uid {
+		var folder map[string]interface{}
+		_ = folder["id"].(float64)
+	}
+	return c.fakeGrafanaClient.SaveDashboard(ctx, orgID, dashboard, folderID, overwrite)
+}
+
+func (c *panickingGrafanaClient) DeleteDashboard(ctx context.Context, orgID string, uid string) error {
+	if uid == c.uid {
+		panic("malformed")
+	}
+	return c.fakeGrafanaClient.DeleteDashboard(ctx, orgID, uid)
+}
+
+func TestRecoverSyncPanic(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	grafanaClient = &panickingGrafanaClient{fakeGrafanaClient: fake, uid: "malformed"}
+
+	state := newSyncState("panic-test")
+	testCaseList := []struct {
+		name  string
+		uid   string
+		op    string
+		sync  func(cm *corev1.ConfigMap) error
+		err   bool
+		saved bool
+	}{
+		{"applied", "valid", panicOpApply, func(cm *corev1.ConfigMap) error {
+			return syncDashboard(context.TODO(), state, nil, cm)
+		}, false, true},
+
+		{"apply panicked", "malformed", panicOpApply, func(cm *corev1.ConfigMap) error {
+			return syncDashboard(context.TODO(), state, nil, cm)
+		}, true, false},
+
+		{"delete panicked", "malformed", panicOpDelete, func(cm *corev1.ConfigMap) error {
+			fake.dashboards[""]["malformed"] = fakeDashboard{}
+			return deleteDashboard(context.TODO(), cm)
+		}, true, true},
+	}
+
+	for _, c := range testCaseList {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: c.uid, Namespace: "panic-test",
+				Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+			Data: map[string]string{"a.json": `{"uid": "` + c.uid + `", "title": "` + c.uid + `", "panels": []}`},
+		}
+		defer state.forget(cm)
+		defer pendingWork.done(state.source, cm)
+		panics := testutil.ToFloat64(metrics.SyncPanics.WithLabelValues(c.op))
+		err := c.sync(cm)
+		if (err != nil) != c.err || err != nil && !strings.Contains(err.Error(), "the loader failed to "+c.op) {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.err)
+		}
+		if _, output := fake.dashboards[""][c.uid]; output != c.saved {
+			t.Errorf("case (%v) saved: (%v) is not the expected: (%v)", c.name, output, c.saved)
+		}
+		expected := 0.0
+		if c.err {
+			expected = 1
+		}
+		if output := testutil.ToFloat64(metrics.SyncPanics.WithLabelValues(c.op)) - panics; output != expected {
+			t.Errorf("case (%v) metrics output: (%v) is not the expected: (%v)", c.name, output, expected)
+		}
+	}
+	if statuses := state.listStatuses(); len(statuses) != 2 || statuses[0].Synced == statuses[1].Synced {
+		t.Errorf("case (statuses) only the malformed configmap should fail: %v", statuses)
+	}
+}
+
+func TestRecoverEventPanic(t *testing.T) {
+	handler := newDashboardEventHandler(context.TODO(), newSyncState("panic-event-test"), func(obj interface{}) interface{} {
+		panic("cannot convert")
+	})
+	panics := testutil.ToFloat64(metrics.SyncPanics.WithLabelValues(panicOpEvent))
+	handler.OnAdd(&corev1.ConfigMap{})
+	handler.OnUpdate(&corev1.ConfigMap{}, &corev1.ConfigMap{})
+	handler.OnDelete(&corev1.ConfigMap{})
+	if output := testutil.ToFloat64(metrics.SyncPanics.WithLabelValues(panicOpEvent)) - panics; output != 3 {
+		t.Errorf("case (event) metrics output: (%v) is not the expected: (%v)", output, 3)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// stockDashboardsNamespace and stockDashboardsPrefix select the configmaps of the dashboards
+	// shipped by the multicluster observability operator
+	stockDashboardsNamespace = "open-cluster-management-observability"
+	stockDashboardsPrefix    = "grafana-dashboard-acm"
+)
+
+var (
+	// ProtectedDashboardUIDs are the uids of the dashboards which the loader never deletes
+	ProtectedDashboardUIDs []string
+	// ProtectedFolders are the titles or uids of the folders which the loader never saves dashboards into
+	// and never deletes together with their dashboards, e.g. a hand-curated folder
+	ProtectedFolders []string
+	// ProtectStockDashboards protects the dashboards applied from the stock multicluster observability configmaps,
+	// so deleting a mislabeled configmap which resolves to the same uids does not wipe out the shipped dashboards
+	ProtectStockDashboards = true
+)
+
+var (
+	stockUIDsLock sync.Mutex
+	// stockUIDs are the uids of the applied stock dashboards keyed by the org and uid
+	stockUIDs = map[string]bool{}
+)
+
+// isStockConfigmap checks whether the configmap is shipped by the multicluster observability operator
+func isStockConfigmap(cm *corev1.ConfigMap) bool {
+	return cm.GetNamespace() == stockDashboardsNamespace && strings.HasPrefix(cm.GetName(), stockDashboardsPrefix)
+}
+
+// recordStockDashboard remembers the uid of the dashboard applied from a stock configmap
+func recordStockDashboard(orgID string, uid string, cm *corev1.ConfigMap) {
+	if !ProtectStockDashboards || !isStockConfigmap(cm) {
+		return
+	}
+	stockUIDsLock.Lock()
+	defer stockUIDsLock.Unlock()
+	stockUIDs[orgID+"/"+uid] = true
+}
+
+// isProtectedDashboard checks whether the dashboard must not be deleted, the folder title is the folder of the configmap,
+// the dashboard is also protected when it is in a protected folder in grafana, e.g. it was moved there by hand
+func isProtectedDashboard(ctx context.Context, orgID string, uid string, folderTitle string) bool {
+	for _, protected := range ProtectedDashboardUIDs {
+		if protected == uid {
+			return true
+		}
+	}
+	if folderTitle != "" && isProtectedFolderName(folderTitle) {
+		return true
+	}
+	stockUIDsLock.Lock()
+	stock := stockUIDs[orgID+"/"+uid]
+	stockUIDsLock.Unlock()
+	if stock || len(ProtectedFolders) == 0 {
+		return stock
+	}
+	_, meta, err := clientFor(ctx).GetDashboardWithMeta(ctx, orgID, uid)
+	switch {
+	case grafanaStatus(err) == http.StatusNotFound:
+		return false
+	case err != nil:
+		// the dashboard which cannot be checked is kept
+		klog.ErrorS(err, "failed to get the dashboard to check whether its folder is protected", "uid", uid)
+		return true
+	}
+	return meta.FolderID != 0 && isProtectedFolder(ctx, orgID, meta.FolderID)
+}
+
+// checkFolderWritable refuses to save the dashboards into a protected folder, the folder id is 0 before it is created
+func checkFolderWritable(ctx context.Context, orgID string, folderTitle string, folderID float64) error {
+	if folderTitle == "" || len(ProtectedFolders) == 0 {
+		return nil
+	}
+	if isProtectedFolderName(folderTitle) || (folderID != 0 && isProtectedFolder(ctx, orgID, folderID)) {
+		return fmt.Errorf("the folder %v is protected, the loader does not save dashboards into it", folderTitle)
+	}
+	return nil
+}
+
+func isProtectedFolderName(name string) bool {
+	for _, protected := range ProtectedFolders {
+		if protected == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isProtectedFolder checks whether the folder must not be deleted by its title or uid
+func isProtectedFolder(ctx context.Context, orgID string, folderID float64) bool {
+	if len(ProtectedFolders) == 0 {
+		return false
+	}
+	folder, err := clientFor(ctx).GetFolder(ctx, orgID, folderID)
+	if err != nil {
+		// the folder which cannot be checked is kept
+		klog.ErrorS(err, "failed to get the folder to check whether it is protected", "folder", folderID)
+		return true
+	}
+	return isProtectedFolderName(folder.Title) || isProtectedFolderName(folder.UID)
+}
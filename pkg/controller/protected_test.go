@@ -0,0 +1,124 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestProtectedDashboards(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func(uids []string) {
+		ProtectedDashboardUIDs = uids
+		stockUIDsLock.Lock()
+		stockUIDs = map[string]bool{}
+		stockUIDsLock.Unlock()
+	}(ProtectedDashboardUIDs)
+	ProtectedDashboardUIDs = []string{"pinned"}
+
+	stock := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "grafana-dashboard-acm-clusters-overview", Namespace: stockDashboardsNamespace},
+		Data:       map[string]string{"overview.json": `{"uid": "overview", "title": "Overview"}`},
+	}
+	if err := updateDashboard(context.TODO(), nil, stock, false); err != nil {
+		t.Fatalf("failed to apply the stock dashboard: %v", err)
+	}
+	// the uid is not owned by the stock configmap any more, e.g. after a restart, so only the protection keeps it
+	releaseDashboardUIDs(context.TODO(), "", stock, nil)
+
+	testCaseList := []struct {
+		name        string
+		annotations map[string]string
+		uid         string
+		expected    bool
+	}{
+		{"stock", nil, "overview", true},
+
+		{"pinned", nil, "pinned", true},
+
+		{"not protected", nil, "custom", false},
+	}
+
+	for _, c := range testCaseList {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "mislabeled", Namespace: "test", Annotations: c.annotations},
+			Data:       map[string]string{"a.json": `{"uid": "` + c.uid + `", "title": "` + c.name + `"}`},
+		}
+		if c.uid != "overview" {
+			if err := updateDashboard(context.TODO(), nil, cm, false); err != nil {
+				t.Fatalf("case (%v) failed to apply the dashboard: %v", c.name, err)
+			}
+		}
+		if err := deleteDashboard(context.TODO(), cm); err != nil {
+			t.Errorf("case (%v) error: (%v)", c.name, err)
+		}
+		if _, ok := fake.dashboards[""][c.uid]; ok != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, ok, c.expected)
+		}
+	}
+
+}
+
+func TestProtectedFolders(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func(folders []string) { ProtectedFolders = folders }(ProtectedFolders)
+	ProtectedFolders = []string{"Executive", "folder-Curated"}
+
+	executive, _ := fake.CreateFolder(context.TODO(), "", "Executive")
+	curated, _ := fake.CreateFolder(context.TODO(), "", "Curated")
+	// the hand-made dashboard in the protected folder has the uid of a configmap dashboard
+	fake.dashboards[""] = map[string]fakeDashboard{"moved": {id: 100, folderID: executive.ID,
+		dashboard: map[string]interface{}{"uid": "moved", "title": "Moved"}}}
+
+	testCaseList := []struct {
+		name     string
+		folder   string
+		uid      string
+		hasErr   bool
+		expected bool
+	}{
+		{"protected by title", "Executive", "executive", true, false},
+
+		{"protected by uid", "Curated", "curated", true, false},
+
+		{"not protected", "SLOs", "slo", false, true},
+	}
+
+	for _, c := range testCaseList {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: c.uid, Namespace: "test", Annotations: map[string]string{customFolderKey: c.folder}},
+			Data:       map[string]string{"a.json": `{"uid": "` + c.uid + `", "title": "` + c.name + `"}`},
+		}
+		if err := updateDashboard(context.TODO(), nil, cm, false); (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+		if _, ok := fake.dashboards[""][c.uid]; ok != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, ok, c.expected)
+		}
+	}
+
+	moved := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "moved", Namespace: "test"},
+		Data:       map[string]string{"a.json": `{"uid": "moved", "title": "Moved"}`},
+	}
+	if err := deleteDashboard(context.TODO(), moved); err != nil {
+		t.Errorf("failed to delete the configmap: %v", err)
+	}
+	if _, ok := fake.dashboards[""]["moved"]; !ok {
+		t.Errorf("the dashboard in the protected folder is deleted")
+	}
+
+	fake.dashboards[""] = map[string]fakeDashboard{}
+	for _, folder := range []Folder{executive, curated} {
+		if deleteCustomFolder(context.TODO(), "", folder.ID) {
+			t.Errorf("the protected folder %v is deleted", folder.Title)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// publicDashboardKey is the annotation to enable ("true") or disable ("false") the public share of the dashboards,
+	// the public share is left untouched without the annotation
+	publicDashboardKey = "observability.open-cluster-management.io/public-dashboard"
+	// publicDashboardSettingsKey is the annotation with the json settings of the public share,
+	// e.g. '{"timeSelectionEnabled": true, "annotationsEnabled": false}'
+	publicDashboardSettingsKey = "observability.open-cluster-management.io/public-dashboard-settings"
+)
+
+// getPublicDashboardConfig returns the public dashboard config to apply, nil means the public share is not managed
+func getPublicDashboardConfig(cm *corev1.ConfigMap) (map[string]interface{}, error) {
+	var enabled bool
+	switch strings.ToLower(cm.GetAnnotations()[publicDashboardKey]) {
+	case "true":
+		enabled = true
+	case "false":
+		enabled = false
+	default:
+		return nil, nil
+	}
+
+	config := map[string]interface{}{}
+	if settings := cm.GetAnnotations()[publicDashboardSettingsKey]; settings != "" {
+		err := json.Unmarshal([]byte(settings), &config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid annotation %v: %v", publicDashboardSettingsKey, err)
+		}
+	}
+	config["isEnabled"] = enabled
+	return config, nil
+}
+
+// syncPublicDashboard creates or updates the public share of the dashboard
+func syncPublicDashboard(ctx context.Context, orgID string, uid string, cm *corev1.ConfigMap) error {
+	config, err := getPublicDashboardConfig(cm)
+	if err != nil || config == nil {
+		return err
+	}
+
+	publicUID, err := clientFor(ctx).GetPublicDashboardUID(ctx, orgID, uid)
+	if err != nil {
+		return fmt.Errorf("failed to get the public dashboard: %v", err)
+	}
+	if publicUID == "" && config["isEnabled"] == false {
+		// nothing to disable
+		return nil
+	}
+
+	err = clientFor(ctx).SavePublicDashboard(ctx, orgID, uid, publicUID, config)
+	if err != nil {
+		return fmt.Errorf("failed to update the public dashboard: %v", err)
+	}
+	klog.Infof("public dashboard of %v is updated with isEnabled=%v", uid, config["isEnabled"])
+	return nil
+}
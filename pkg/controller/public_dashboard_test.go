@@ -0,0 +1,159 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetPublicDashboardConfig(t *testing.T) {
+	testCaseList := []struct {
+		name        string
+		annotations map[string]string
+		expected    map[string]interface{}
+		expectedErr bool
+	}{
+
+		{
+			"no annotation",
+			map[string]string{},
+			nil,
+			false,
+		},
+
+		{
+			"enabled",
+			map[string]string{publicDashboardKey: "true"},
+			map[string]interface{}{"isEnabled": true},
+			false,
+		},
+
+		{
+			"disabled with settings",
+			map[string]string{
+				publicDashboardKey:         "false",
+				publicDashboardSettingsKey: "{\"timeSelectionEnabled\": true}",
+			},
+			map[string]interface{}{"isEnabled": false, "timeSelectionEnabled": true},
+			false,
+		},
+
+		{
+			"invalid settings",
+			map[string]string{
+				publicDashboardKey:         "true",
+				publicDashboardSettingsKey: "timeSelectionEnabled",
+			},
+			nil,
+			true,
+		},
+	}
+
+	for _, c := range testCaseList {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Namespace:   "test",
+				Annotations: c.annotations,
+			},
+		}
+		output, err := getPublicDashboardConfig(cm)
+		if (err != nil) != c.expectedErr {
+			t.Errorf("case (%v) error: (%v) is not expected", c.name, err)
+		}
+		o, _ := json.Marshal(output)
+		e, _ := json.Marshal(c.expected)
+		if string(o) != string(e) {
+			t.Errorf("case (%v) output: (%s) is not the expected: (%s)", c.name, o, e)
+		}
+	}
+}
+
+func TestSyncPublicDashboard(t *testing.T) {
+	testCaseList := []struct {
+		name           string
+		enabled        string
+		existing       bool
+		expectedMethod string
+		expectedPath   string
+	}{
+
+		{
+			"create public dashboard",
+			"true",
+			false,
+			http.MethodPost,
+			"/api/dashboards/uid/test/public-dashboards",
+		},
+
+		{
+			"update public dashboard",
+			"false",
+			true,
+			http.MethodPatch,
+			"/api/dashboards/uid/test/public-dashboards/public",
+		},
+
+		{
+			"nothing to disable",
+			"false",
+			false,
+			"",
+			"",
+		},
+	}
+
+	defer func(uri string, r int) {
+		grafanaURI = uri
+		retry = r
+	}(grafanaURI, retry)
+	retry = 1
+
+	for _, c := range testCaseList {
+		method, path := "", ""
+		config := map[string]interface{}{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodGet {
+				if !c.existing {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Write([]byte("{\"uid\": \"public\", \"isEnabled\": true}"))
+				return
+			}
+			method, path = req.Method, req.URL.Path
+			body, _ := ioutil.ReadAll(req.Body)
+			json.Unmarshal(body, &config)
+			w.Write([]byte("{}"))
+		}))
+		grafanaURI = server.URL
+
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Namespace:   "test",
+				Annotations: map[string]string{publicDashboardKey: c.enabled},
+			},
+		}
+		err := syncPublicDashboard(context.TODO(), "", "test", cm)
+		server.Close()
+		if err != nil {
+			t.Errorf("case (%v) failed to sync public dashboard: %v", c.name, err)
+		}
+		if method != c.expectedMethod || path != c.expectedPath {
+			t.Errorf("case (%v) output: (%v %v) is not the expected: (%v %v)", c.name, method, path, c.expectedMethod, c.expectedPath)
+		}
+		if method != "" && config["isEnabled"] != (c.enabled == "true") {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, config["isEnabled"], c.enabled)
+		}
+	}
+}
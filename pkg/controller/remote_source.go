@@ -0,0 +1,71 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
+)
+
+// remoteSuffix marks the data keys which refer to a dashboard served over https, e.g.
+//
+//	big-dashboard.remote: '{"url": "https://example.com/big-dashboard.json", "sha256": "9f86d0..."}'
+const remoteSuffix = ".remote"
+
+// remoteReference refers to a dashboard by its url and checksum
+type remoteReference struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// the checksum identifies the content so the downloaded dashboards are cached by it
+var remoteCache = newDownloadCache()
+
+func isRemoteReference(key string) bool {
+	return strings.HasSuffix(key, remoteSuffix)
+}
+
+// resolveRemoteDashboard downloads the referred dashboard and verifies its checksum
+func resolveRemoteDashboard(ctx context.Context, value string) (string, error) {
+	ref := remoteReference{}
+	err := json.Unmarshal([]byte(value), &ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid remote reference: %v", err)
+	}
+	u, err := url.Parse(ref.URL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("invalid remote reference: an https url is required")
+	}
+	checksum := strings.ToLower(ref.SHA256)
+	if len(checksum) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid remote reference: a sha256 checksum is required")
+	}
+
+	dashboard, ok := remoteCache.get(checksum)
+	if ok {
+		return dashboard, nil
+	}
+
+	klog.Infof("download dashboard from %v", redactURL(ref.URL))
+	body, err := util.Download(ctx, ref.URL, activeSettings().retry, maxDecompressedSize)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	if hex.EncodeToString(sum[:]) != checksum {
+		return "", fmt.Errorf("the checksum of %v does not match %v", redactURL(ref.URL), checksum)
+	}
+
+	remoteCache.add(checksum, string(body))
+	return string(body), nil
+}
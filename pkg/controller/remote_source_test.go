@@ -0,0 +1,85 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveRemoteDashboard(t *testing.T) {
+	dashboard := "{\"title\": \"remote\"}"
+	sum := sha256.Sum256([]byte(dashboard))
+	checksum := hex.EncodeToString(sum[:])
+
+	downloads := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		downloads++
+		w.Write([]byte(dashboard))
+	}))
+	defer server.Close()
+
+	// trust the certificate of the test server
+	originalTransport := http.DefaultTransport
+	http.DefaultTransport = server.Client().Transport
+	retry = 1
+	defer func() {
+		http.DefaultTransport = originalTransport
+	}()
+
+	testCaseList := []struct {
+		name   string
+		value  string
+		hasErr bool
+	}{
+
+		{
+			"http url",
+			fmt.Sprintf("{\"url\": \"http://example.com/a.json\", \"sha256\": \"%v\"}", checksum),
+			true,
+		},
+
+		{
+			"missing checksum",
+			fmt.Sprintf("{\"url\": \"%v/a.json\"}", server.URL),
+			true,
+		},
+
+		{
+			"mismatched checksum",
+			fmt.Sprintf("{\"url\": \"%v/a.json\", \"sha256\": \"%064d\"}", server.URL, 0),
+			true,
+		},
+
+		{
+			"valid reference",
+			fmt.Sprintf("{\"url\": \"%v/a.json\", \"sha256\": \"%v\"}", server.URL, checksum),
+			false,
+		},
+
+		{
+			"cached reference",
+			fmt.Sprintf("{\"url\": \"%v/a.json\", \"sha256\": \"%v\"}", server.URL, checksum),
+			false,
+		},
+	}
+
+	for _, c := range testCaseList {
+		output, err := resolveRemoteDashboard(context.TODO(), c.value)
+		if (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+		if !c.hasErr && output != dashboard {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, dashboard)
+		}
+	}
+	if downloads != 2 {
+		t.Errorf("the dashboard is downloaded %v times instead of twice", downloads)
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+// retryQueueKey is the data key of the pending work in the retry queue configmap
+const retryQueueKey = "queue.json"
+
+const (
+	workApply  = "apply"
+	workDelete = "delete"
+)
+
+var (
+	// RetryQueueConfigmap is the configmap in the namespace of the loader to persist the pending work into,
+	// so that a restart does not forget the dashboards which still need to be applied or deleted,
+	// empty keeps the pending work in memory only
+	RetryQueueConfigmap = ""
+	// RetryQueueInterval is how often the pending work is retried
+	RetryQueueInterval = time.Minute
+
+	pendingWork = newRetryQueue()
+)
+
+// workItem is a configmap whose last apply or delete failed
+type workItem struct {
+	Op     string `json:"op"`
+	Source string `json:"source"`
+	// Configmap is the configmap to delete the dashboards of, the content of the secrets is not kept
+	// since the queue is persisted into a plain configmap, their dashboards are deleted by the uids
+	Configmap *corev1.ConfigMap `json:"configmap"`
+	// UIDs are the uids of the applied dashboards keyed by the data key to delete them by,
+	// they are kept for the applies as well in case the configmap is deleted while the loader is down
+	UIDs  map[string]string `json:"uids,omitempty"`
+	Error string            `json:"error,omitempty"`
+	Since time.Time         `json:"since"`
+}
+
+// retryQueue is the pending work keyed by the source and the configmap
+type retryQueue struct {
+	sync.Mutex
+	items map[string]workItem
+	// changed is signalled once the queue is changed to persist it
+	changed chan struct{}
+}
+
+func newRetryQueue() *retryQueue {
+	return &retryQueue{items: map[string]workItem{}, changed: make(chan struct{}, 1)}
+}
+
+func workKey(source string, cm *corev1.ConfigMap) string {
+	return source + "/" + configmapKey(cm)
+}
+
+// queuedConfigmap keeps only what is needed to apply or delete the dashboards of the configmap,
+// the content of a secret is dropped together with the last applied configuration which repeats it
+func queuedConfigmap(source string, cm *corev1.ConfigMap) *corev1.ConfigMap {
+	queued := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        cm.GetName(),
+			Namespace:   cm.GetNamespace(),
+			Labels:      cm.GetLabels(),
+			Annotations: cm.GetAnnotations(),
+		},
+		Data:       cm.Data,
+		BinaryData: cm.BinaryData,
+	}
+	if source != appliedSecretState.source {
+		return queued
+	}
+	queued.Annotations = map[string]string{}
+	for key, value := range cm.GetAnnotations() {
+		if key != corev1.LastAppliedConfigAnnotation {
+			queued.Annotations[key] = value
+		}
+	}
+	queued.Data, queued.BinaryData = nil, nil
+	return queued
+}
+
+// add records the failure of the configmap with the uids of its applied dashboards,
+// the time of the first failure is kept while the operation is the same
+func (q *retryQueue) add(op string, source string, cm *corev1.ConfigMap, uids map[string]string, err error) {
+	q.Lock()
+	defer q.Unlock()
+	key := workKey(source, cm)
+	since := time.Now()
+	if existing, ok := q.items[key]; ok && existing.Op == op {
+		since = existing.Since
+	}
+	q.items[key] = workItem{Op: op, Source: source, Configmap: queuedConfigmap(source, cm), UIDs: uids, Error: err.Error(), Since: since}
+	q.updated()
+}
+
+// done drops the configmap once it is applied or deleted
+func (q *retryQueue) done(source string, cm *corev1.ConfigMap) {
+	q.Lock()
+	defer q.Unlock()
+	if _, ok := q.items[workKey(source, cm)]; !ok {
+		return
+	}
+	delete(q.items, workKey(source, cm))
+	q.updated()
+}
+
+// load adds the persisted items which are not in the queue yet
+func (q *retryQueue) load(items []workItem) {
+	q.Lock()
+	defer q.Unlock()
+	for _, item := range items {
+		if item.Configmap == nil {
+			continue
+		}
+		if _, ok := q.items[workKey(item.Source, item.Configmap)]; !ok {
+			// the content of the secrets persisted by the former versions is not kept
+			item.Configmap = queuedConfigmap(item.Source, item.Configmap)
+			q.items[workKey(item.Source, item.Configmap)] = item
+		}
+	}
+	q.updated()
+}
+
+// list returns the items ordered by the source and the configmap
+func (q *retryQueue) list() []workItem {
+	q.Lock()
+	defer q.Unlock()
+	keys := []string{}
+	for key := range q.items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	items := []workItem{}
+	for _, key := range keys {
+		items = append(items, q.items[key])
+	}
+	return items
+}
+
+// updated sets the gauges and signals the change without blocking, the caller holds the lock
+func (q *retryQueue) updated() {
+	counts := map[string]int{workApply: 0, workDelete: 0}
+	for _, item := range q.items {
+		counts[item.Op]++
+	}
+	for op, count := range counts {
+		metrics.RetryQueueItems.WithLabelValues(op).Set(float64(count))
+	}
+	select {
+	case q.changed <- struct{}{}:
+	default:
+	}
+}
+
+// syncDeletion deletes the dashboards of the configmap, the failed deletion is queued to be retried
+func syncDeletion(ctx context.Context, state *syncState, cm *corev1.ConfigMap) error {
+	uids := previousUIDs(state, cm)
+	err := checkFreezeWindows(time.Now())
+	if err == nil {
+		err = deleteFromTargets(withPreviousUIDs(ctx, uids), cm)
+	}
+	state.forget(cm)
+	dependencies.forget(state, cm)
+	if isFrozen(err) {
+		klog.InfoS("the deletion is deferred", "configmap", klog.KObj(cm), "reason", err)
+		pendingWork.add(workDelete, state.source, cm, uids, err)
+		return err
+	}
+	if err != nil {
+		klog.ErrorS(err, "failed to delete the dashboards, it is retried later", "configmap", klog.KObj(cm))
+		pendingWork.add(workDelete, state.source, cm, uids, err)
+		return err
+	}
+	pendingWork.done(state.source, cm)
+	return nil
+}
+
+// retryPendingWork deletes the dashboards of the queued deletions again, the queued applies are left to the resync
+// of their sources except the configmaps which are deleted in the meantime, e.g. while the loader was down
+func retryPendingWork(ctx context.Context, coreClient corev1client.CoreV1Interface) {
+	if checkFreezeWindows(time.Now()) != nil {
+		return
+	}
+	for _, item := range pendingWork.list() {
+		cm := item.Configmap
+		if !ownsNamespace(cm.GetNamespace()) {
+			continue
+		}
+		if item.Op == workApply {
+			if item.Source != appliedState.source || coreClient == nil {
+				continue
+			}
+			current, err := coreClient.ConfigMaps(cm.GetNamespace()).Get(ctx, cm.GetName(), metav1.GetOptions{})
+			if err == nil && !isDesiredDashboardConfigmap(current) {
+				// the configmap is not a dashboard configmap any longer, so it is not applied again
+				pendingWork.done(item.Source, cm)
+				continue
+			}
+			if !errors.IsNotFound(err) {
+				continue
+			}
+			klog.InfoS("the configmap which failed to be applied is deleted, its dashboards are deleted", "configmap", klog.KObj(cm))
+		}
+		if err := deleteFromTargets(withPreviousUIDs(ctx, item.UIDs), cm); err != nil {
+			klog.ErrorS(err, "failed to delete the dashboards again", "configmap", klog.KObj(cm), "since", item.Since)
+			pendingWork.add(workDelete, item.Source, cm, item.UIDs, err)
+			continue
+		}
+		klog.InfoS("the dashboards are deleted by the retry", "configmap", klog.KObj(cm))
+		pendingWork.done(item.Source, cm)
+	}
+}
+
+// restoreRetryQueue loads the work which was pending when the loader stopped
+func restoreRetryQueue(ctx context.Context, coreClient corev1client.CoreV1Interface, namespace string, name string) error {
+	cm, err := coreClient.ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	items := []workItem{}
+	if content, ok := cm.Data[retryQueueKey]; ok {
+		if err := json.Unmarshal([]byte(content), &items); err != nil {
+			return err
+		}
+	}
+	pendingWork.load(items)
+	klog.InfoS("the pending work is restored", "configmap", klog.KObj(cm), "items", len(items))
+	return nil
+}
+
+// writeRetryQueue persists the pending work into the configmap once it is changed
+func writeRetryQueue(ctx context.Context, coreClient corev1client.CoreV1Interface, namespace string, name string) error {
+	content, err := json.Marshal(pendingWork.list())
+	if err != nil {
+		return err
+	}
+	if err := checkConfigmapSize(len(content)); err != nil {
+		return err
+	}
+
+	cm, err := coreClient.ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string]string{retryQueueKey: string(content)},
+		}
+		_, err = coreClient.ConfigMaps(namespace).Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data[retryQueueKey] == string(content) {
+		return nil
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[retryQueueKey] = string(content)
+	_, err = coreClient.ConfigMaps(namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// runRetryQueue retries the pending work every interval and persists it after every change until ctx is done
+func runRetryQueue(ctx context.Context, coreClient corev1client.CoreV1Interface, namespace string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if RetryQueueConfigmap != "" {
+			if err := writeRetryQueue(ctx, coreClient, namespace, RetryQueueConfigmap); err != nil {
+				klog.ErrorS(err, "failed to write the retry queue", "configmap", klog.KRef(namespace, RetryQueueConfigmap))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-pendingWork.changed:
+		case <-ticker.C:
+			retryPendingWork(ctx, coreClient)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func queuedOps() []string {
+	ops := []string{}
+	for _, item := range pendingWork.list() {
+		ops = append(ops, item.Op)
+	}
+	return ops
+}
+
+func TestRetryQueue(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func(queue *retryQueue) { pendingWork = queue }(pendingWork)
+	pendingWork = newRetryQueue()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "queued", Namespace: "test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+		Data: map[string]string{"a.json": `{"uid": "queued", "title": "queued", "panels": []}`},
+	}
+	state := newSyncState("configmap")
+	defer state.forget(cm)
+	unavailable := &GrafanaAPIError{"POST", "/api/dashboards/db", 503, nil}
+
+	testCaseList := []struct {
+		name     string
+		run      func()
+		expected []string
+		saved    bool
+	}{
+		{"apply failed", func() {
+			fake.saveErrs = map[string]error{"queued": unavailable}
+			syncDashboard(context.TODO(), state, nil, cm)
+		}, []string{workApply}, false},
+
+		{"applied", func() {
+			fake.saveErrs = nil
+			syncDashboard(context.TODO(), state, nil, cm)
+		}, []string{}, true},
+
+		{"delete failed", func() {
+			fake.deleteErrs = map[string]error{"queued": unavailable}
+			syncDeletion(context.TODO(), state, cm)
+		}, []string{workDelete}, true},
+
+		{"restored", func() {
+			kubeClient := kubefake.NewSimpleClientset()
+			if err := writeRetryQueue(context.TODO(), kubeClient.CoreV1(), "test", "loader-queue"); err != nil {
+				t.Fatalf("failed to write the retry queue: %v", err)
+			}
+			pendingWork = newRetryQueue()
+			if err := restoreRetryQueue(context.TODO(), kubeClient.CoreV1(), "test", "loader-queue"); err != nil {
+				t.Fatalf("failed to restore the retry queue: %v", err)
+			}
+		}, []string{workDelete}, true},
+
+		{"delete retried", func() {
+			fake.deleteErrs = nil
+			retryPendingWork(context.TODO(), kubefake.NewSimpleClientset().CoreV1())
+		}, []string{}, false},
+
+		{"apply of the existing configmap", func() {
+			syncDashboard(context.TODO(), state, nil, cm)
+			fake.saveErrs = map[string]error{"queued": unavailable}
+			state.reset()
+			syncDashboard(context.TODO(), state, nil, cm)
+			retryPendingWork(context.TODO(), kubefake.NewSimpleClientset(cm).CoreV1())
+		}, []string{workApply}, true},
+
+		{"apply of the deleted configmap", func() {
+			retryPendingWork(context.TODO(), kubefake.NewSimpleClientset().CoreV1())
+		}, []string{}, false},
+	}
+
+	for _, c := range testCaseList {
+		c.run()
+		fake.saveErrs, fake.deleteErrs = nil, nil
+		if output := queuedOps(); len(output) != len(c.expected) || len(output) > 0 && output[0] != c.expected[0] {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+		if _, output := fake.dashboards[""]["queued"]; output != c.saved {
+			t.Errorf("case (%v) saved: (%v) is not the expected: (%v)", c.name, output, c.saved)
+		}
+	}
+}
+
+func TestRetryQueueSecret(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func(queue *retryQueue) { pendingWork = queue }(pendingWork)
+	pendingWork = newRetryQueue()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "sensitive", Namespace: "test",
+			Annotations: map[string]string{corev1.LastAppliedConfigAnnotation: `{"data": "sensitive"}`}},
+		Data: map[string]string{"a.json": `{"uid": "sensitive", "title": "sensitive", "panels": []}`},
+	}
+	fake.dashboards[""] = map[string]fakeDashboard{"sensitive": {id: 1}}
+	fake.deleteErrs = map[string]error{"sensitive": &GrafanaAPIError{"DELETE", "/api/dashboards/uid/sensitive", 503, nil}}
+	pendingWork.add(workDelete, appliedSecretState.source, cm, map[string]string{"a.json": "sensitive"}, fmt.Errorf("unavailable"))
+
+	kubeClient := kubefake.NewSimpleClientset()
+	if err := writeRetryQueue(context.TODO(), kubeClient.CoreV1(), "test", "loader-queue"); err != nil {
+		t.Fatalf("failed to write the retry queue: %v", err)
+	}
+	queue, _ := kubeClient.CoreV1().ConfigMaps("test").Get(context.TODO(), "loader-queue", metav1.GetOptions{})
+	if content := queue.Data[retryQueueKey]; strings.Contains(content, "panels") ||
+		strings.Contains(content, corev1.LastAppliedConfigAnnotation) {
+		t.Errorf("case (persisted) the content of the secret is persisted: %v", content)
+	}
+
+	fake.deleteErrs = nil
+	retryPendingWork(context.TODO(), kubeClient.CoreV1())
+	if _, ok := fake.dashboards[""]["sensitive"]; ok || len(pendingWork.list()) != 0 {
+		t.Errorf("case (retried) the dashboard of the secret is not deleted by its uid")
+	}
+}
+
+func TestRetryQueueUnreachableGrafana(t *testing.T) {
+	defer func(client GrafanaClient, count int) { grafanaClient, retry = client, count }(grafanaClient, retry)
+	defer func(queue *retryQueue) { pendingWork = queue }(pendingWork)
+	pendingWork = newRetryQueue()
+	retry = 1
+
+	// the closed listener refuses the connections which is reported as 404 without a body
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	grafanaClient = &httpGrafanaClient{url: server.URL}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "unreachable", Namespace: "test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+		Data: map[string]string{"a.json": `{"uid": "unreachable", "title": "unreachable", "panels": []}`},
+	}
+	state := newSyncState("configmap")
+	if err := syncDeletion(context.TODO(), state, cm); err == nil {
+		t.Errorf("the deletion should fail while grafana is unreachable")
+	}
+	if output := queuedOps(); len(output) != 1 || output[0] != workDelete {
+		t.Errorf("case (unreachable) output: (%v) is not the expected: (%v)", output, []string{workDelete})
+	}
+}
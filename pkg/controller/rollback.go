@@ -0,0 +1,265 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+const (
+	// dashboardRollbackKeyPrefix followed by a data key is the annotation to hold the dashboard at a version of its grafana history,
+	// the value is "previous" for the version before the one applied last by the loader, or the version number,
+	// the configmap content is applied again once the annotation is removed
+	dashboardRollbackKeyPrefix = "dashboard-rollback.observability.open-cluster-management.io/"
+	rollbackPrevious           = "previous"
+)
+
+// rollbackRecord is a version restored by the loader and the version which the restore created
+type rollbackRecord struct {
+	from    float64
+	version float64
+}
+
+var (
+	versionsLock sync.Mutex
+	// appliedVersions are the versions saved by the loader keyed by the target, the org and the uid
+	appliedVersions = map[desiredUID]float64{}
+	// rolledBack are the last rollbacks keyed by the target, the org and the uid,
+	// so the held dashboard is not restored again by every sync
+	rolledBack = map[desiredUID]rollbackRecord{}
+)
+
+// versionKey is the key of the applied version in the status, the versions of the targets are told apart by the target name
+func versionKey(ctx context.Context, key string) string {
+	if target := targetName(ctx); target != "" {
+		return target + "/" + key
+	}
+	return key
+}
+
+// recordAppliedVersion remembers the version of the dashboard applied for the data key
+func recordAppliedVersion(ctx context.Context, orgID string, key string, uid string, version float64) {
+	if version == 0 {
+		return
+	}
+	versionsLock.Lock()
+	appliedVersions[desiredUID{targetName(ctx), orgID, uid}] = version
+	versionsLock.Unlock()
+	recordStatusVersion(ctx, key, version)
+}
+
+func recordStatusVersion(ctx context.Context, key string, version float64) {
+	applied, ok := ctx.Value(appliedDashboardsKey{}).(*appliedDashboards)
+	if !ok {
+		return
+	}
+	applied.Lock()
+	defer applied.Unlock()
+	applied.versions[versionKey(ctx, key)] = version
+}
+
+// appliedVersion returns the version applied last by the loader, it is read from the status annotation
+// when the dashboard is not applied since the loader started, 0 means unknown
+func appliedVersion(ctx context.Context, orgID string, cm *corev1.ConfigMap, key string, uid string) float64 {
+	versionsLock.Lock()
+	version := appliedVersions[desiredUID{targetName(ctx), orgID, uid}]
+	versionsLock.Unlock()
+	if version != 0 || cm == nil {
+		return version
+	}
+	versions := map[string]float64{}
+	if value, ok := cm.GetAnnotations()[appliedVersionsKey]; ok {
+		if err := json.Unmarshal([]byte(value), &versions); err != nil {
+			klog.ErrorS(err, "invalid annotation, the applied versions are unknown", "configmap", klog.KObj(cm),
+				"annotation", appliedVersionsKey)
+		}
+	}
+	return versions[versionKey(ctx, key)]
+}
+
+// invalidRollbackError is returned when there is no version to restore for the rollback
+type invalidRollbackError struct {
+	spec   string
+	reason string
+}
+
+func (e *invalidRollbackError) Error() string {
+	return fmt.Sprintf("invalid rollback %q: %v", e.spec, e.reason)
+}
+
+// rollbackTarget resolves the version to restore, the newest version stands for the applied one when it is unknown
+func rollbackTarget(spec string, applied float64, versions []DashboardVersion) (float64, error) {
+	if spec != rollbackPrevious {
+		version, err := strconv.Atoi(spec)
+		if err != nil || version < 1 {
+			return 0, &invalidRollbackError{spec, "it is " + rollbackPrevious + " or a version number"}
+		}
+		return float64(version), nil
+	}
+	if applied == 0 {
+		applied = newestVersion(versions).Version
+	}
+	previous := 0.0
+	for _, version := range versions {
+		if version.Version < applied && version.Version > previous {
+			previous = version.Version
+		}
+	}
+	if previous == 0 {
+		return 0, &invalidRollbackError{spec, fmt.Sprintf("there is no version before version %v", applied)}
+	}
+	return previous, nil
+}
+
+func newestVersion(versions []DashboardVersion) DashboardVersion {
+	newest := DashboardVersion{}
+	for _, version := range versions {
+		if version.Version > newest.Version {
+			newest = version
+		}
+	}
+	return newest
+}
+
+// isRestoredFrom checks whether the newest version of the dashboard is the restore of the version,
+// grafana describes the versions created by the restores so it is known after the loader restarts
+func isRestoredFrom(id desiredUID, newest DashboardVersion, from float64) bool {
+	versionsLock.Lock()
+	record, ok := rolledBack[id]
+	versionsLock.Unlock()
+	if ok && record.from == from && record.version == newest.Version {
+		return true
+	}
+	return newest.Message == fmt.Sprintf("Restored from version %v", from)
+}
+
+// rollbackDashboard restores the dashboard to the version of the spec, "previous" or a version number
+func rollbackDashboard(ctx context.Context, orgID string, uid string, spec string, applied float64) (SavedDashboard, float64, error) {
+	versions, err := clientFor(ctx).ListDashboardVersions(ctx, orgID, uid)
+	if err != nil {
+		return SavedDashboard{}, 0, fmt.Errorf("failed to list the versions of the dashboard %v: %v", uid, err)
+	}
+	from, err := rollbackTarget(spec, applied, versions)
+	if err != nil {
+		return SavedDashboard{}, 0, err
+	}
+	saved, err := restoreDashboardVersion(ctx, orgID, uid, from)
+	return saved, from, err
+}
+
+func restoreDashboardVersion(ctx context.Context, orgID string, uid string, from float64) (SavedDashboard, error) {
+	saved, err := clientFor(ctx).RestoreDashboardVersion(ctx, orgID, uid, from)
+	if err != nil {
+		return SavedDashboard{}, fmt.Errorf("failed to restore the version %v of the dashboard %v: %v", from, uid, err)
+	}
+	versionsLock.Lock()
+	rolledBack[desiredUID{targetName(ctx), orgID, uid}] = rollbackRecord{from, saved.Version}
+	versionsLock.Unlock()
+	klog.InfoS("the dashboard is rolled back", "uid", uid, "from version", from, "version", saved.Version, "org", orgID)
+	metrics.DashboardsRolledBack.Inc()
+	return saved, nil
+}
+
+// holdRolledBackDashboard keeps the dashboard of the data key at the version of the rollback annotation
+// instead of applying the configmap content, the dashboard is only restored when it is not at that version yet
+func holdRolledBackDashboard(ctx context.Context, orgID string, cm *corev1.ConfigMap, key string, uid string,
+	folderTitle string, spec string) error {
+	applied := appliedVersion(ctx, orgID, cm, key, uid)
+	versions, err := clientFor(ctx).ListDashboardVersions(ctx, orgID, uid)
+	if err != nil {
+		return fmt.Errorf("failed to list the versions of the dashboard %v: %v", uid, err)
+	}
+	if applied == 0 {
+		// the newest version is taken as the applied one once, so previous does not move back with every restore
+		applied = newestVersion(versions).Version
+		recordAppliedVersion(ctx, orgID, key, uid, applied)
+	}
+	from, err := rollbackTarget(spec, applied, versions)
+	if err != nil {
+		return err
+	}
+	if isRestoredFrom(desiredUID{targetName(ctx), orgID, uid}, newestVersion(versions), from) {
+		klog.V(2).InfoS("the dashboard is held at the rolled back version", "configmap", klog.KObj(cm), "key", key,
+			"uid", uid, "from version", from)
+	} else {
+		saved, err := restoreDashboardVersion(ctx, orgID, uid, from)
+		if err != nil {
+			return err
+		}
+		recordEvent(cm, corev1.EventTypeNormal, reasonDashboardRolledBack,
+			"The dashboard %v is rolled back to version %v as version %v", key, from, saved.Version)
+	}
+	recordManagedDashboard(orgID, uid, folderTitle)
+	recordAppliedDashboard(ctx, key, uid, folderTitle)
+	// the held dashboard keeps the version applied from the configmap so previous stays the same version
+	if applied != 0 {
+		recordStatusVersion(ctx, key, applied)
+	}
+	return nil
+}
+
+// rollbackRequest is the body of the rollback admin api, the version is "previous" or the version number,
+// the empty target is the default grafana and the empty org is the default org
+type rollbackRequest struct {
+	Target  string `json:"target"`
+	OrgID   string `json:"org"`
+	UID     string `json:"uid"`
+	Version string `json:"version"`
+}
+
+// handleRollback restores a dashboard at once, unlike the annotation the dashboard is not held,
+// so the next change of its configmap applies the configmap content again
+func handleRollback(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rollback := rollbackRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&rollback); err != nil || rollback.UID == "" {
+		http.Error(w, "the body is the json of the uid and the version to restore", http.StatusBadRequest)
+		return
+	}
+	if rollback.Version == "" {
+		rollback.Version = rollbackPrevious
+	}
+	ctx := req.Context()
+	if rollback.Target != "" {
+		var found *grafanaTarget
+		for _, target := range grafanaTargets() {
+			if target.settings.name == rollback.Target {
+				found = target
+				break
+			}
+		}
+		if found == nil {
+			http.Error(w, fmt.Sprintf("unknown grafana target %v", rollback.Target), http.StatusNotFound)
+			return
+		}
+		ctx = withGrafanaTarget(ctx, found)
+	}
+	klog.InfoS("rollback is triggered by the admin api", "target", rollback.Target, "uid", rollback.UID,
+		"version", rollback.Version, "org", rollback.OrgID)
+	saved, from, err := rollbackDashboard(ctx, rollback.OrgID, rollback.UID, rollback.Version,
+		appliedVersion(ctx, rollback.OrgID, nil, "", rollback.UID))
+	if err != nil {
+		klog.ErrorS(err, "failed to roll back the dashboard", "uid", rollback.UID)
+		code := http.StatusBadGateway
+		if _, ok := err.(*invalidRollbackError); ok {
+			code = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"uid": saved.UID, "from": from, "version": saved.Version})
+}
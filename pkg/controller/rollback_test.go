@@ -0,0 +1,165 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// useEmptyVersions makes the applied versions and the rollbacks known in the test only
+func useEmptyVersions() func() {
+	versionsLock.Lock()
+	defer versionsLock.Unlock()
+	applied, rollbacks := appliedVersions, rolledBack
+	appliedVersions, rolledBack = map[desiredUID]float64{}, map[desiredUID]rollbackRecord{}
+	return func() {
+		versionsLock.Lock()
+		defer versionsLock.Unlock()
+		appliedVersions, rolledBack = applied, rollbacks
+	}
+}
+
+func TestRollbackTarget(t *testing.T) {
+	versions := []DashboardVersion{{Version: 4}, {Version: 3}, {Version: 1}}
+
+	testCaseList := []struct {
+		name     string
+		spec     string
+		applied  float64
+		hasErr   bool
+		expected float64
+	}{
+		{"version number", "2", 4, false, 2},
+
+		{"previous", rollbackPrevious, 4, false, 3},
+
+		{"previous skips the missing versions", rollbackPrevious, 3, false, 1},
+
+		{"previous of the unknown applied version", rollbackPrevious, 0, false, 3},
+
+		{"no previous version", rollbackPrevious, 1, true, 0},
+
+		{"invalid", "last", 4, true, 0},
+
+		{"invalid version number", "0", 4, true, 0},
+	}
+
+	for _, c := range testCaseList {
+		output, err := rollbackTarget(c.spec, c.applied, versions)
+		if (err != nil) != c.hasErr || output != c.expected {
+			t.Errorf("case (%v) output: (%v, %v) is not the expected: (%v, %v)", c.name, output, err, c.expected, c.hasErr)
+		}
+	}
+}
+
+func TestHoldRolledBackDashboard(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer useEmptyVersions()()
+	fake.dashboards[""] = map[string]fakeDashboard{"slo": {id: 1, dashboard: map[string]interface{}{"uid": "slo", "title": "SLO"}}}
+	fake.versions["/slo"] = []DashboardVersion{{Version: 1}, {Version: 2}, {Version: 3}}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "slo", Namespace: "test",
+			Labels: map[string]string{generalFolderKey: "true"},
+			Annotations: map[string]string{
+				appliedVersionsKey:                      `{"slo.json": 3}`,
+				dashboardRollbackKeyPrefix + "slo.json": rollbackPrevious,
+			}},
+		Data: map[string]string{"slo.json": `{"uid": "slo", "title": "SLO v4"}`},
+	}
+
+	testCaseList := []struct {
+		name     string
+		spec     string
+		expected []string
+	}{
+		{"previous", rollbackPrevious, []string{"", "", "", "Restored from version 2"}},
+
+		{"held", rollbackPrevious, []string{"", "", "", "Restored from version 2"}},
+
+		{"another version", "1", []string{"", "", "", "Restored from version 2", "Restored from version 1"}},
+	}
+
+	for _, c := range testCaseList {
+		cm.Annotations[dashboardRollbackKeyPrefix+"slo.json"] = c.spec
+		ctx, applied := withAppliedDashboards(context.TODO())
+		if err := updateDashboard(ctx, nil, cm, false); err != nil {
+			t.Errorf("case (%v) error: (%v) is not the expected: (nil)", c.name, err)
+		}
+		output := []string{}
+		for _, version := range fake.versions["/slo"] {
+			output = append(output, version.Message)
+		}
+		if strings.Join(output, ",") != strings.Join(c.expected, ",") {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+		if fake.dashboards[""]["slo"].dashboard["title"] != "SLO" {
+			t.Errorf("case (%v) the configmap content is applied to the held dashboard", c.name)
+		}
+		if applied.uids["slo.json"] != "slo" || applied.versions["slo.json"] != 3 {
+			t.Errorf("case (%v) applied: (%v, %v) is not the expected: (slo, 3)", c.name, applied.uids, applied.versions)
+		}
+	}
+
+	// the configmap content is applied again once the annotation is removed
+	delete(cm.Annotations, dashboardRollbackKeyPrefix+"slo.json")
+	if err := updateDashboard(context.TODO(), nil, cm, false); err != nil {
+		t.Errorf("case (released) error: (%v) is not the expected: (nil)", err)
+	}
+	if fake.dashboards[""]["slo"].dashboard["title"] != "SLO v4" {
+		t.Errorf("case (released) the configmap content is not applied")
+	}
+}
+
+func TestRollbackAPI(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer useEmptyVersions()()
+	defer func(token string) { AdminToken = token }(AdminToken)
+	AdminToken = "secret"
+	fake.versions["/slo"] = []DashboardVersion{{Version: 1}, {Version: 2}}
+	handler := newAdminHandler()
+
+	testCaseList := []struct {
+		name     string
+		method   string
+		body     string
+		expected int
+	}{
+		{"GET", "GET", "", http.StatusMethodNotAllowed},
+
+		{"no uid", "POST", `{"version": "1"}`, http.StatusBadRequest},
+
+		{"invalid version", "POST", `{"uid": "slo", "version": "last"}`, http.StatusBadRequest},
+
+		{"unknown dashboard", "POST", `{"uid": "unknown"}`, http.StatusBadGateway},
+
+		{"unknown target", "POST", `{"target": "unknown", "uid": "slo"}`, http.StatusNotFound},
+
+		{"previous", "POST", `{"uid": "slo"}`, http.StatusOK},
+
+		{"version number", "POST", `{"uid": "slo", "version": "2"}`, http.StatusOK},
+	}
+
+	for _, c := range testCaseList {
+		req := httptest.NewRequest(c.method, "/api/v1/dashboards/rollback", strings.NewReader(c.body))
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, w.Code, c.expected)
+		}
+	}
+	if expected := 4; len(fake.versions["/slo"]) != expected {
+		t.Errorf("case (restores) output: (%v) is not the expected: (%v)", fake.versions["/slo"], expected)
+	}
+}
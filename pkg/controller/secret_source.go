@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	// WatchSecrets enables loading dashboards from secrets besides configmaps
+	WatchSecrets = false
+
+	// secrets are tracked separately since a secret may have the same name as a configmap
+	appliedSecretState = newSyncState("secret")
+)
+
+// secretToConfigmap converts the secret into a configmap with the same metadata,
+// every entry of data and stringData is treated as a dashboard json,
+// the gzip-compressed entries are moved into binaryData
+func secretToConfigmap(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	secret, ok := obj.(*corev1.Secret)
+	if !ok || secret == nil {
+		return nil
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: *secret.ObjectMeta.DeepCopy(),
+		Data:       map[string]string{},
+		BinaryData: map[string][]byte{},
+	}
+	for key, value := range secret.Data {
+		if isGzipDashboard(key, value) {
+			cm.BinaryData[key] = value
+			continue
+		}
+		cm.Data[key] = string(value)
+	}
+	// stringData takes precedence over data which is the same as the apiserver does
+	for key, value := range secret.StringData {
+		cm.Data[key] = value
+	}
+	return cm
+}
+
+func newSecretInformer(ctx context.Context, coreClient corev1client.CoreV1Interface) cache.SharedIndexInformer {
+	// get watched namespace
+	watchedNS := os.Getenv("POD_NAMESPACE")
+	watchlist := &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			return coreClient.Secrets(watchedNS).List(context.TODO(), opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			return coreClient.Secrets(watchedNS).Watch(context.TODO(), opts)
+		},
+	}
+	secretInformer := handleWatchErrors("secrets", cache.NewSharedIndexInformer(
+		stripMetadata(tuneListWatch(watchlist)),
+		&corev1.Secret{},
+		ResyncPeriod,
+		cache.Indexers{},
+	))
+
+	handler := newDashboardEventHandler(ctx, appliedSecretState, secretToConfigmap)
+	secretInformer.AddEventHandler(handler)
+	registerInformerResync(secretInformer, handler)
+
+	return secretInformer
+}
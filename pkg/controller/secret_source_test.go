@@ -0,0 +1,96 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestSecretToConfigmap(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		obj      interface{}
+		expected map[string]string
+		desired  bool
+	}{
+
+		{
+			"invalid secret",
+			&corev1.ConfigMap{},
+			nil,
+			false,
+		},
+
+		{
+			"data and stringData",
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "test",
+					Labels:    map[string]string{"grafana-custom-dashboard": "true"},
+				},
+				Data: map[string][]byte{
+					"a.json": []byte("{\"title\": \"a\"}"),
+					"b.json": []byte("{\"title\": \"b\"}"),
+				},
+				StringData: map[string]string{"b.json": "{\"title\": \"c\"}"},
+			},
+			map[string]string{"a.json": "{\"title\": \"a\"}", "b.json": "{\"title\": \"c\"}"},
+			true,
+		},
+
+		{
+			"no label",
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "test",
+				},
+				Data: map[string][]byte{"a.json": []byte("{}")},
+			},
+			map[string]string{"a.json": "{}"},
+			false,
+		},
+
+		{
+			"tombstone",
+			cache.DeletedFinalStateUnknown{Key: "test/test", Obj: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "test",
+					Labels:    map[string]string{"grafana-custom-dashboard": "true"},
+				},
+				Data: map[string][]byte{"a.json": []byte("{}")},
+			}},
+			map[string]string{"a.json": "{}"},
+			true,
+		},
+	}
+
+	for _, c := range testCaseList {
+		output := secretToConfigmap(c.obj)
+		if isDesiredDashboardConfigmap(output) != c.desired {
+			t.Errorf("case (%v) desired: (%v) is not the expected: (%v)", c.name, !c.desired, c.desired)
+		}
+		if c.expected == nil {
+			if output != nil {
+				t.Errorf("case (%v) output: (%v) is not the expected: (nil)", c.name, output)
+			}
+			continue
+		}
+		cm := output.(*corev1.ConfigMap)
+		if cm.Name != "test" || cm.Namespace != "test" {
+			t.Errorf("case (%v) metadata is not kept: %v/%v", c.name, cm.Namespace, cm.Name)
+		}
+		for key, value := range c.expected {
+			if cm.Data[key] != value {
+				t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, cm.Data[key], value)
+			}
+		}
+	}
+}
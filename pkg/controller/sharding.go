@@ -0,0 +1,195 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"k8s.io/klog/v2"
+)
+
+// shardLeaseLabel marks the leases of the loader replicas which share the namespaces
+const shardLeaseLabel = "observability.open-cluster-management.io/dashboard-loader-shard"
+
+var (
+	// Sharding spreads the watched namespaces across the loader replicas by consistent hashing,
+	// every replica holds a lease and only applies the dashboards of the namespaces it owns
+	Sharding = false
+	// ShardIdentity is the name of the replica, empty means the hostname, i.e. the pod name
+	ShardIdentity = ""
+	// ShardLeaseNamespace is where the leases of the replicas are, empty means the namespace of the loader
+	ShardLeaseNamespace = ""
+	// ShardLeaseDuration is how long the namespaces of a replica are kept after its lease is last renewed
+	ShardLeaseDuration = 30 * time.Second
+)
+
+// shards are the live replicas ordered by name
+var shards = struct {
+	sync.Mutex
+	identity string
+	members  []string
+	// renewed is when the lease of the current replica is last renewed
+	renewed time.Time
+}{}
+
+// shardOwner returns the member owning the key by rendezvous hashing, so that only the keys of a joining
+// or leaving member are moved
+func shardOwner(key string, members []string) string {
+	owner := ""
+	var highest uint64
+	for _, member := range members {
+		// fnv does not mix the similar names of the replicas well enough
+		sum := sha256.Sum256([]byte(member + "/" + key))
+		if weight := binary.BigEndian.Uint64(sum[:8]); owner == "" || weight > highest {
+			owner, highest = member, weight
+		}
+	}
+	return owner
+}
+
+// ownsNamespace checks whether the current replica applies the dashboards of the namespace,
+// the sources without a namespace, e.g. git, are owned by a single replica as well,
+// and nothing is owned once the lease may be expired for the other replicas
+func ownsNamespace(namespace string) bool {
+	if !Sharding {
+		return true
+	}
+	shards.Lock()
+	defer shards.Unlock()
+	if shards.identity == "" || time.Since(shards.renewed) >= ShardLeaseDuration {
+		return false
+	}
+	return shardOwner(namespace, shards.members) == shards.identity
+}
+
+// setShardMembers records the live replicas, the dashboards are re-delivered once they are changed
+// so that the replica picks up the namespaces moved to it
+func setShardMembers(members []string) {
+	sort.Strings(members)
+	shards.Lock()
+	changed := !reflect.DeepEqual(shards.members, members)
+	initial := shards.members == nil
+	shards.members = members
+	shards.Unlock()
+	if changed && !initial {
+		klog.InfoS("the loader replicas are changed", "members", members)
+		go redeliverAll()
+	}
+}
+
+func shardLeaseName(identity string) string {
+	return "grafana-dashboard-loader-" + identity
+}
+
+// renewShardLease creates or renews the lease of the current replica
+func renewShardLease(ctx context.Context, client coordinationv1client.LeaseInterface, identity string) error {
+	now := metav1.NewMicroTime(time.Now())
+	seconds := int32(ShardLeaseDuration.Seconds())
+	lease, err := client.Get(ctx, shardLeaseName(identity), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: shardLeaseName(identity), Labels: map[string]string{shardLeaseLabel: "true"}},
+			Spec: coordinationv1.LeaseSpec{HolderIdentity: &identity, LeaseDurationSeconds: &seconds,
+				AcquireTime: &now, RenewTime: &now},
+		}
+		_, err = client.Create(ctx, lease, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	lease.Spec.HolderIdentity = &identity
+	lease.Spec.LeaseDurationSeconds = &seconds
+	lease.Spec.RenewTime = &now
+	_, err = client.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// liveShardMembers returns the holders of the leases which are not expired
+func liveShardMembers(ctx context.Context, client coordinationv1client.LeaseInterface, now time.Time) ([]string, error) {
+	leases, err := client.List(ctx, metav1.ListOptions{LabelSelector: shardLeaseLabel + "=true"})
+	if err != nil {
+		return nil, err
+	}
+	members := []string{}
+	for _, lease := range leases.Items {
+		spec := lease.Spec
+		if spec.HolderIdentity == nil || spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		if spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second).After(now) {
+			members = append(members, *spec.HolderIdentity)
+		}
+	}
+	return members, nil
+}
+
+// refreshShards renews the lease of the current replica and reads the live replicas
+func refreshShards(ctx context.Context, client coordinationv1client.LeaseInterface, identity string) error {
+	renewed := time.Now()
+	if err := renewShardLease(ctx, client, identity); err != nil {
+		return fmt.Errorf("failed to renew the lease of %v: %v", identity, err)
+	}
+	shards.Lock()
+	shards.renewed = renewed
+	shards.Unlock()
+	members, err := liveShardMembers(ctx, client, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to list the leases of the loader replicas: %v", err)
+	}
+	setShardMembers(members)
+	return nil
+}
+
+// startSharding joins the replicas and keeps the lease renewed until ctx is done, the lease is released
+// then so that the other replicas take over the namespaces at once, it returns once the first members are known
+func startSharding(ctx context.Context, coordinationClient coordinationv1client.CoordinationV1Interface) {
+	identity := ShardIdentity
+	if identity == "" {
+		identity, _ = os.Hostname()
+	}
+	namespace := ShardLeaseNamespace
+	if namespace == "" {
+		namespace = os.Getenv("POD_NAMESPACE")
+	}
+	client := coordinationClient.Leases(namespace)
+	shards.Lock()
+	shards.identity = identity
+	shards.Unlock()
+
+	wait.PollImmediateUntil(ShardLeaseDuration/3, func() (bool, error) {
+		if err := refreshShards(ctx, client, identity); err != nil {
+			klog.ErrorS(err, "failed to join the loader replicas", "identity", identity)
+			return false, nil
+		}
+		return true, nil
+	}, ctx.Done())
+	klog.InfoS("the loader replicas are joined", "identity", identity)
+
+	go func() {
+		wait.UntilWithContext(ctx, func(ctx context.Context) {
+			if err := refreshShards(ctx, client, identity); err != nil {
+				klog.ErrorS(err, "failed to refresh the loader replicas", "identity", identity)
+			}
+		}, ShardLeaseDuration/3)
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := client.Delete(releaseCtx, shardLeaseName(identity), metav1.DeleteOptions{}); err != nil {
+			klog.ErrorS(err, "failed to release the lease", "identity", identity)
+		}
+	}()
+}
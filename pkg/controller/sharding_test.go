@@ -0,0 +1,96 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestShardOwner(t *testing.T) {
+	members := []string{"loader-0", "loader-1", "loader-2"}
+	scaled := append(append([]string{}, members...), "loader-3")
+	owned := map[string]int{}
+	for i := 0; i < 300; i++ {
+		namespace := fmt.Sprintf("team-%v", i)
+		owner := shardOwner(namespace, members)
+		owned[owner]++
+		if moved := shardOwner(namespace, scaled); moved != owner && moved != "loader-3" {
+			t.Errorf("case (scaled) %v is moved from %v to %v instead of the new replica", namespace, owner, moved)
+		}
+	}
+	for _, member := range members {
+		if owned[member] < 50 {
+			t.Errorf("case (balance) output: (%v) is not balanced", owned)
+		}
+	}
+	if owner := shardOwner("team-a", nil); owner != "" {
+		t.Errorf("case (no member) output: (%v) is not the expected: (%v)", owner, "")
+	}
+}
+
+func TestOwnsNamespace(t *testing.T) {
+	defer func() {
+		Sharding = false
+		shards.identity, shards.members, shards.renewed = "", nil, time.Time{}
+	}()
+	members := []string{"loader-0", "loader-1"}
+	namespace := "team-a"
+	owner := shardOwner(namespace, members)
+
+	testCaseList := []struct {
+		name     string
+		sharding bool
+		identity string
+		renewed  time.Time
+		expected bool
+	}{
+		{"no sharding", false, "", time.Time{}, true},
+
+		{"owner", true, owner, time.Now(), true},
+
+		{"other replica", true, "loader-9", time.Now(), false},
+
+		{"expired lease", true, owner, time.Now().Add(-time.Minute), false},
+	}
+
+	for _, c := range testCaseList {
+		Sharding = c.sharding
+		shards.identity, shards.members, shards.renewed = c.identity, members, c.renewed
+		if output := ownsNamespace(namespace); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestRefreshShards(t *testing.T) {
+	defer func() { shards.identity, shards.members, shards.renewed = "", nil, time.Time{} }()
+	lease := func(identity string, renewed time.Time) *coordinationv1.Lease {
+		seconds := int32(30)
+		renewTime := metav1.NewMicroTime(renewed)
+		return &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: shardLeaseName(identity), Namespace: "loader",
+				Labels: map[string]string{shardLeaseLabel: "true"}},
+			Spec: coordinationv1.LeaseSpec{HolderIdentity: &identity, LeaseDurationSeconds: &seconds, RenewTime: &renewTime},
+		}
+	}
+	kubeClient := fakekube.NewSimpleClientset(lease("loader-1", time.Now()), lease("loader-2", time.Now().Add(-time.Hour)))
+	client := kubeClient.CoordinationV1().Leases("loader")
+
+	for i := 0; i < 2; i++ {
+		if err := refreshShards(context.TODO(), client, "loader-0"); err != nil {
+			t.Fatalf("case (refresh %v) failed to refresh the replicas: %v", i, err)
+		}
+		if expected := []string{"loader-0", "loader-1"}; !reflect.DeepEqual(shards.members, expected) {
+			t.Errorf("case (refresh %v) output: (%v) is not the expected: (%v)", i, shards.members, expected)
+		}
+	}
+}
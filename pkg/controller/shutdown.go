@@ -0,0 +1,86 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// ShutdownGracePeriod is how long the in-flight syncs may take to finish once the loader is stopped,
+// the ones still running are cancelled and retried after the restart, it is kept below the termination
+// grace period of the pod
+var ShutdownGracePeriod = 20 * time.Second
+
+// shutdownFlushTimeout limits the last write of the retry queue after the syncs are drained or cancelled
+const shutdownFlushTimeout = 5 * time.Second
+
+// syncTracker counts the syncs in flight, no sync is started any more once it is draining
+type syncTracker struct {
+	sync.Mutex
+	wg       sync.WaitGroup
+	draining bool
+}
+
+var inFlightSyncs = &syncTracker{}
+
+// start registers a sync, it returns false once the loader is shutting down so that the sync is skipped,
+// the skipped objects are applied again after the restart
+func (t *syncTracker) start() bool {
+	t.Lock()
+	defer t.Unlock()
+	if t.draining {
+		return false
+	}
+	t.wg.Add(1)
+	return true
+}
+
+func (t *syncTracker) done() {
+	t.wg.Done()
+}
+
+// drain stops new syncs and waits for the in-flight ones until the timeout, it returns whether all of them finished
+func (t *syncTracker) drain(timeout time.Duration) bool {
+	t.Lock()
+	t.draining = true
+	t.Unlock()
+	finished := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// shutDown drains the in-flight syncs, cancels the ones which do not finish within the grace period
+// so that they are recorded as pending work, and persists the pending work for the next start
+func shutDown(cancelWork context.CancelFunc, coreClient corev1client.CoreV1Interface) {
+	klog.InfoS("the loader is stopping, waiting for the in-flight syncs", "gracePeriod", ShutdownGracePeriod)
+	if !inFlightSyncs.drain(ShutdownGracePeriod) {
+		klog.InfoS("the in-flight syncs are cancelled after the grace period, they are retried after the restart")
+		cancelWork()
+		inFlightSyncs.drain(shutdownFlushTimeout)
+	}
+	cancelWork()
+	if RetryQueueConfigmap != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownFlushTimeout)
+		defer cancel()
+		namespace := os.Getenv("POD_NAMESPACE")
+		if err := writeRetryQueue(ctx, coreClient, namespace, RetryQueueConfigmap); err != nil {
+			klog.ErrorS(err, "failed to write the retry queue", "configmap", klog.KRef(namespace, RetryQueueConfigmap))
+		}
+	}
+	klog.Info("the loader is stopped")
+}
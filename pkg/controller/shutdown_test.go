@@ -0,0 +1,71 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSyncTrackerDrain(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		duration time.Duration
+		expected bool
+	}{
+		{"idle", 0, true},
+
+		{"finished in time", 10 * time.Millisecond, true},
+
+		{"still running", time.Second, false},
+	}
+
+	for _, c := range testCaseList {
+		tracker := &syncTracker{}
+		if c.duration > 0 {
+			tracker.start()
+			go func(d time.Duration) {
+				time.Sleep(d)
+				tracker.done()
+			}(c.duration)
+		}
+		if output := tracker.drain(100 * time.Millisecond); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+		if tracker.start() {
+			t.Errorf("case (%v) no sync should be started once the tracker is draining", c.name)
+		}
+	}
+}
+
+func TestShutDown(t *testing.T) {
+	defer func(tracker *syncTracker, grace time.Duration, name string) {
+		inFlightSyncs, ShutdownGracePeriod, RetryQueueConfigmap = tracker, grace, name
+	}(inFlightSyncs, ShutdownGracePeriod, RetryQueueConfigmap)
+	inFlightSyncs, ShutdownGracePeriod, RetryQueueConfigmap = &syncTracker{}, 20*time.Millisecond, "loader-queue"
+	defer os.Setenv("POD_NAMESPACE", os.Getenv("POD_NAMESPACE"))
+	os.Setenv("POD_NAMESPACE", "test")
+
+	// the in-flight sync only stops once its requests are cancelled
+	workCtx, cancelWork := context.WithCancel(context.Background())
+	inFlightSyncs.start()
+	go func() {
+		<-workCtx.Done()
+		inFlightSyncs.done()
+	}()
+
+	kubeClient := kubefake.NewSimpleClientset()
+	shutDown(cancelWork, kubeClient.CoreV1())
+	if workCtx.Err() == nil {
+		t.Errorf("case (cancelled) the in-flight sync is not cancelled after the grace period")
+	}
+	if _, err := kubeClient.CoreV1().ConfigMaps("test").Get(context.TODO(), "loader-queue", metav1.GetOptions{}); err != nil {
+		t.Errorf("case (persisted) the retry queue is not written on shutdown: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	// SidecarLabel is the label of the k8s-sidecar dashboards to load as well, empty means disabled
+	SidecarLabel = ""
+	// SidecarLabelValue is the value of SidecarLabel to match, empty matches any value
+	SidecarLabelValue = "1"
+	// SidecarFolderAnnotation is the annotation of the k8s-sidecar dashboards for the folder
+	SidecarFolderAnnotation = "grafana_folder"
+)
+
+// isSidecarDashboard checks whether the configmap follows the k8s-sidecar label convention
+func isSidecarDashboard(cm *corev1.ConfigMap) bool {
+	s := activeSettings()
+	if s.sidecarLabel == "" {
+		return false
+	}
+	value, ok := cm.GetLabels()[s.sidecarLabel]
+	return ok && (s.sidecarLabelValue == "" || value == s.sidecarLabelValue)
+}
+
+// getSidecarFolderTitle returns the folder of the k8s-sidecar dashboard,
+// the sidecar annotation is a directory so the last element is the folder like the grafana file provider does
+func getSidecarFolderTitle(cm *corev1.ConfigMap) string {
+	if !isSidecarDashboard(cm) {
+		return ""
+	}
+	folder := strings.TrimRight(cm.GetAnnotations()[activeSettings().sidecarFolderAnnotation], "/")
+	if folder == "" {
+		return ""
+	}
+	return path.Base(folder)
+}
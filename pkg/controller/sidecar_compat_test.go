@@ -0,0 +1,92 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSidecarDashboard(t *testing.T) {
+	SidecarLabel = "grafana_dashboard"
+	defer func() {
+		SidecarLabel = ""
+	}()
+
+	testCaseList := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		desired     bool
+		folder      string
+	}{
+
+		{
+			"no label",
+			nil,
+			nil,
+			false,
+			"Custom",
+		},
+
+		{
+			"unmatched label value",
+			map[string]string{"grafana_dashboard": "0"},
+			nil,
+			false,
+			"Custom",
+		},
+
+		{
+			"no folder annotation",
+			map[string]string{"grafana_dashboard": "1"},
+			nil,
+			true,
+			"Custom",
+		},
+
+		{
+			"folder annotation",
+			map[string]string{"grafana_dashboard": "1"},
+			map[string]string{"grafana_folder": "Kubernetes"},
+			true,
+			"Kubernetes",
+		},
+
+		{
+			"folder directory annotation",
+			map[string]string{"grafana_dashboard": "1"},
+			map[string]string{"grafana_folder": "/tmp/dashboards/Kubernetes/"},
+			true,
+			"Kubernetes",
+		},
+
+		{
+			"loader folder annotation",
+			map[string]string{"grafana_dashboard": "1"},
+			map[string]string{"grafana_folder": "Kubernetes", customFolderKey: "SLOs"},
+			true,
+			"SLOs",
+		},
+	}
+
+	for _, c := range testCaseList {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Namespace:   "test",
+				Labels:      c.labels,
+				Annotations: c.annotations,
+			},
+		}
+		if output := isDesiredDashboardConfigmap(cm); output != c.desired {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.desired)
+		}
+		if output := getDashboardCustomFolderTitle(cm); output != c.folder {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.folder)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// statusAnnotationPrefix is the prefix of the annotations which are written by the loader
+	statusAnnotationPrefix = "status.observability.open-cluster-management.io/"
+	// lastSyncedKey is the time when all the dashboards of the configmap were applied last time
+	lastSyncedKey = statusAnnotationPrefix + "dashboard-last-synced"
+	// appliedUIDsKey is the json of the uids of the applied dashboards keyed by the data key
+	appliedUIDsKey = statusAnnotationPrefix + "dashboard-uids"
+	// appliedVersionsKey is the json of the grafana versions of the applied dashboards keyed by the data key
+	appliedVersionsKey = statusAnnotationPrefix + "dashboard-versions"
+	// lastErrorKey is the error of the last sync, it is removed once the sync succeeds
+	lastErrorKey = statusAnnotationPrefix + "dashboard-last-error"
+	// healthKey is Progressing, Healthy or Degraded, it is the field which the health checks of argo cd read
+	healthKey = statusAnnotationPrefix + "dashboard-health"
+	// lintKey is the json of the lint findings keyed by the data key, it is removed once there is no finding
+	lintKey = statusAnnotationPrefix + "dashboard-lint"
+	// targetsKey is the json of the sync result of every grafana target, e.g. {"prod": "Synced", "dev": "<error>"}
+	targetsKey = statusAnnotationPrefix + "dashboard-targets"
+
+	healthProgressing = "Progressing"
+	healthHealthy     = "Healthy"
+	healthDegraded    = "Degraded"
+)
+
+// isStatusAnnotation checks whether the annotation is written by the loader
+func isStatusAnnotation(key string) bool {
+	return strings.HasPrefix(key, statusAnnotationPrefix)
+}
+
+type appliedDashboardsKey struct{}
+
+// appliedDashboards collects the uids, folders and versions of the dashboards applied during a sync keyed by the data key,
+// together with the lint findings of the dashboards no matter whether they are applied
+type appliedDashboards struct {
+	sync.Mutex
+	uids     map[string]string
+	folders  map[string]string
+	versions map[string]float64
+	lint     map[string][]string
+}
+
+// withAppliedDashboards returns the context to collect the applied dashboards into the returned collector
+func withAppliedDashboards(ctx context.Context) (context.Context, *appliedDashboards) {
+	applied := &appliedDashboards{uids: map[string]string{}, folders: map[string]string{}, versions: map[string]float64{},
+		lint: map[string][]string{}}
+	return context.WithValue(ctx, appliedDashboardsKey{}, applied), applied
+}
+
+func recordAppliedDashboard(ctx context.Context, key string, uid string, folder string) {
+	applied, ok := ctx.Value(appliedDashboardsKey{}).(*appliedDashboards)
+	if !ok {
+		return
+	}
+	applied.Lock()
+	defer applied.Unlock()
+	applied.uids[key] = uid
+	applied.folders[key] = folder
+}
+
+func recordLintFindings(ctx context.Context, key string, findings []lintFinding) {
+	applied, ok := ctx.Value(appliedDashboardsKey{}).(*appliedDashboards)
+	if !ok || len(findings) == 0 {
+		return
+	}
+	applied.Lock()
+	defer applied.Unlock()
+	for _, finding := range findings {
+		applied.lint[key] = append(applied.lint[key], finding.String())
+	}
+}
+
+// newStatusAnnotationWriter patches the sync status onto the configmap as annotations
+func newStatusAnnotationWriter(coreClient corev1client.CoreV1Interface) func(context.Context, *corev1.ConfigMap, dashboardStatus) {
+	return func(ctx context.Context, cm *corev1.ConfigMap, status dashboardStatus) {
+		annotations := statusAnnotations(cm, status)
+		if len(annotations) == 0 {
+			return
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": annotations},
+		})
+		if err != nil {
+			klog.ErrorS(err, "failed to build the status patch", "configmap", klog.KObj(cm))
+			return
+		}
+		_, err = coreClient.ConfigMaps(cm.Namespace).Patch(ctx, cm.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if err != nil {
+			klog.ErrorS(err, "failed to write the status annotations", "configmap", klog.KObj(cm))
+		}
+	}
+}
+
+// statusAnnotations returns the annotations to patch, nil value removes the annotation:
+// a failed sync only updates the uids and the error so that the patch does not trigger the same failed sync again
+func statusAnnotations(cm *corev1.ConfigMap, status dashboardStatus) map[string]interface{} {
+	current := cm.GetAnnotations()
+	annotations := map[string]interface{}{}
+	if status.Health != current[healthKey] {
+		annotations[healthKey] = status.Health
+	}
+	if status.Health == healthProgressing {
+		return annotations
+	}
+
+	uids := ""
+	if len(status.UIDs) > 0 {
+		b, _ := json.Marshal(status.UIDs)
+		uids = string(b)
+	}
+	if _, ok := current[appliedUIDsKey]; ok && uids == "" {
+		annotations[appliedUIDsKey] = nil
+	} else if uids != current[appliedUIDsKey] {
+		annotations[appliedUIDsKey] = uids
+	}
+
+	versions := ""
+	if len(status.Versions) > 0 {
+		b, _ := json.Marshal(status.Versions)
+		versions = string(b)
+	}
+	if _, ok := current[appliedVersionsKey]; ok && versions == "" {
+		annotations[appliedVersionsKey] = nil
+	} else if versions != current[appliedVersionsKey] {
+		annotations[appliedVersionsKey] = versions
+	}
+
+	lint := ""
+	if len(status.Lint) > 0 {
+		b, _ := json.Marshal(status.Lint)
+		lint = string(b)
+	}
+	if _, ok := current[lintKey]; ok && lint == "" {
+		annotations[lintKey] = nil
+	} else if lint != current[lintKey] {
+		annotations[lintKey] = lint
+	}
+
+	targets := ""
+	if len(status.Targets) > 0 {
+		results := map[string]string{}
+		for name, target := range status.Targets {
+			results[name] = "Synced"
+			if !target.Synced {
+				results[name] = target.Error
+			}
+		}
+		b, _ := json.Marshal(results)
+		targets = string(b)
+	}
+	if _, ok := current[targetsKey]; ok && targets == "" {
+		annotations[targetsKey] = nil
+	} else if targets != current[targetsKey] {
+		annotations[targetsKey] = targets
+	}
+
+	if status.Synced {
+		annotations[lastSyncedKey] = status.LastSyncTime.UTC().Format(time.RFC3339)
+		if _, ok := current[lastErrorKey]; ok {
+			annotations[lastErrorKey] = nil
+		}
+	} else if status.Error != current[lastErrorKey] {
+		annotations[lastErrorKey] = status.Error
+	}
+	return annotations
+}
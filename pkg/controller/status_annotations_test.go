@@ -0,0 +1,144 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestStatusAnnotations(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test",
+			Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+		Data: map[string]string{"a.json": `{"uid": "a", "title": "a"}`, "b.json": `{"uid": "b", "title": "b"}`},
+	}
+	kubeClient := kubefake.NewSimpleClientset(cm)
+	state := newSyncState("status-test")
+	state.statusWriter = newStatusAnnotationWriter(kubeClient.CoreV1())
+	get := func() *corev1.ConfigMap {
+		current, err := kubeClient.CoreV1().ConfigMaps("test").Get(context.TODO(), "test", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get the configmap: %v", err)
+		}
+		return current
+	}
+
+	// the rejected dashboard fails the sync while the other one is applied
+	fake.saveErrs = map[string]error{"b": &GrafanaAPIError{"POST", "/api/dashboards/db", 500, nil}}
+	if err := syncDashboard(context.TODO(), state, nil, cm); err == nil {
+		t.Fatalf("the sync with a rejected dashboard should fail")
+	}
+	failed := get()
+	testCaseList := []struct {
+		name     string
+		key      string
+		expected string
+	}{
+		{"uids", appliedUIDsKey, `{"a.json":"a"}`},
+
+		{"versions", appliedVersionsKey, `{"a.json":1}`},
+
+		{"not synced", lastSyncedKey, ""},
+	}
+	for _, c := range testCaseList {
+		if failed.Annotations[c.key] != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, failed.Annotations[c.key], c.expected)
+		}
+	}
+	if failed.Annotations[lastErrorKey] == "" {
+		t.Errorf("case (error) the error of the failed sync is not written")
+	}
+
+	// the same failure is not written again so that the patch does not trigger another sync
+	kubeClient.ClearActions()
+	syncDashboard(context.TODO(), state, nil, failed)
+	for _, action := range kubeClient.Actions() {
+		if action.GetVerb() == "patch" {
+			t.Errorf("case (same error) the configmap should not be patched again")
+		}
+	}
+
+	fake.saveErrs = nil
+	fixed := get()
+	if err := syncDashboard(context.TODO(), state, nil, fixed); err != nil {
+		t.Fatalf("failed to sync the fixed configmap: %v", err)
+	}
+	synced := get()
+	if synced.Annotations[appliedUIDsKey] != `{"a.json":"a","b.json":"b"}` || synced.Annotations[lastSyncedKey] == "" {
+		t.Errorf("case (synced) output: (%v) is not the expected", synced.Annotations)
+	}
+	if _, ok := synced.Annotations[lastErrorKey]; ok {
+		t.Errorf("case (synced) the error should be removed: %v", synced.Annotations)
+	}
+
+	// the status annotations do not change the hash
+	if !state.isSynced(synced) {
+		t.Errorf("case (hash) the configmap with the new status annotations should be synced")
+	}
+}
+
+func TestStatusAnnotationsHealth(t *testing.T) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test",
+		Annotations: map[string]string{lastErrorKey: "grafana is down", healthKey: healthDegraded}}}
+	testCaseList := []struct {
+		name     string
+		status   dashboardStatus
+		expected map[string]interface{}
+	}{
+		{
+			"progressing",
+			dashboardStatus{Health: healthProgressing, Error: "grafana is down"},
+			map[string]interface{}{healthKey: healthProgressing},
+		},
+
+		{
+			"degraded with the same error",
+			dashboardStatus{Health: healthDegraded, Error: "grafana is down"},
+			map[string]interface{}{},
+		},
+
+		{
+			"healthy",
+			dashboardStatus{Health: healthHealthy, Synced: true},
+			map[string]interface{}{healthKey: healthHealthy, lastErrorKey: nil},
+		},
+	}
+	for _, c := range testCaseList {
+		output := statusAnnotations(cm, c.status)
+		delete(output, lastSyncedKey)
+		if !reflect.DeepEqual(output, c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestMarkProgressing(t *testing.T) {
+	state := newSyncState("progressing-test")
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Data: map[string]string{"a.json": "{}"}}
+	defer state.forget(cm)
+
+	if status, changed := state.markProgressing(cm); !changed || status.Health != healthProgressing {
+		t.Errorf("case (new) the new configmap should be progressing: %v", status)
+	}
+	state.markFailed(cm, nil, fmt.Errorf("grafana is down"))
+	if status, changed := state.markProgressing(cm); changed || status.Health != healthDegraded {
+		t.Errorf("case (retry) the retried configmap should stay degraded: %v", status)
+	}
+	changed := cm.DeepCopy()
+	changed.Data["a.json"] = `{"title": "a"}`
+	if status, ok := state.markProgressing(changed); !ok || status.Health != healthProgressing || status.Error == "" {
+		t.Errorf("case (changed) the changed configmap should be progressing with the last error kept: %v", status)
+	}
+}
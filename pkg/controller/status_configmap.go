@@ -0,0 +1,111 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// statusConfigmapKey is the data key of the summary in the status configmap
+const statusConfigmapKey = "status.yaml"
+
+var (
+	// StatusConfigmap is the configmap in the namespace of the loader to summarize the managed dashboards into,
+	// empty disables the summary
+	StatusConfigmap = ""
+
+	// statusChanged is signalled once the status of any source is changed
+	statusChanged = make(chan struct{}, 1)
+)
+
+// statusSummary is the content of the status configmap
+type statusSummary struct {
+	Synced     int                   `json:"synced"`
+	Failed     int                   `json:"failed"`
+	Dashboards []summarizedDashboard `json:"dashboards"`
+}
+
+type summarizedDashboard struct {
+	Namespace string `json:"namespace"`
+	reportedDashboard
+}
+
+// notifyStatusChanged asks for the status configmap to be refreshed, it never blocks
+func notifyStatusChanged() {
+	select {
+	case statusChanged <- struct{}{}:
+	default:
+	}
+}
+
+// runStatusConfigmapWriter refreshes the status configmap after every sync until ctx is done,
+// it is also refreshed every resync period in case a write was lost
+func runStatusConfigmapWriter(ctx context.Context, coreClient corev1client.CoreV1Interface, namespace string) {
+	ticker := time.NewTicker(ResyncPeriod)
+	defer ticker.Stop()
+	for {
+		if err := writeStatusConfigmap(ctx, coreClient, namespace, StatusConfigmap); err != nil {
+			klog.ErrorS(err, "failed to write the status configmap", "configmap", klog.KRef(namespace, StatusConfigmap))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-statusChanged:
+		case <-ticker.C:
+		}
+	}
+}
+
+func summarizeStatus() statusSummary {
+	summary := statusSummary{Dashboards: []summarizedDashboard{}}
+	summary.Synced, summary.Failed = dashboardCounts()
+	for namespace, dashboards := range reportedDashboards() {
+		for _, d := range dashboards {
+			summary.Dashboards = append(summary.Dashboards, summarizedDashboard{namespace, d})
+		}
+	}
+	sort.SliceStable(summary.Dashboards, func(i, j int) bool {
+		return summary.Dashboards[i].Namespace < summary.Dashboards[j].Namespace
+	})
+	return summary
+}
+
+// writeStatusConfigmap creates or updates the status configmap once the summary is changed
+func writeStatusConfigmap(ctx context.Context, coreClient corev1client.CoreV1Interface, namespace string, name string) error {
+	content, err := yaml.Marshal(summarizeStatus())
+	if err != nil {
+		return err
+	}
+
+	cm, err := coreClient.ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string]string{statusConfigmapKey: string(content)},
+		}
+		_, err = coreClient.ConfigMaps(namespace).Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data[statusConfigmapKey] == string(content) {
+		return nil
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[statusConfigmapKey] = string(content)
+	_, err = coreClient.ConfigMaps(namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func TestStatusConfigmap(t *testing.T) {
+	state := newSyncState("summary-test")
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "summary-test"},
+		Data:       map[string]string{"a.json": "{}"},
+	}
+	state.markFailed(cm, nil, fmt.Errorf("grafana is down"))
+	defer state.forget(cm)
+
+	kubeClient := kubefake.NewSimpleClientset()
+	if err := writeStatusConfigmap(context.TODO(), kubeClient.CoreV1(), "test", "loader-status"); err != nil {
+		t.Fatalf("failed to write the status configmap: %v", err)
+	}
+	written, err := kubeClient.CoreV1().ConfigMaps("test").Get(context.TODO(), "loader-status", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get the status configmap: %v", err)
+	}
+	summary := statusSummary{}
+	if err := yaml.Unmarshal([]byte(written.Data[statusConfigmapKey]), &summary); err != nil {
+		t.Fatalf("the status is not valid yaml: %v", err)
+	}
+	found := false
+	for _, d := range summary.Dashboards {
+		if d.Namespace == "summary-test" && d.Name == "test" && d.Key == "a.json" && d.Error == "grafana is down" {
+			found = true
+		}
+	}
+	if !found || summary.Failed == 0 {
+		t.Errorf("case (failed) the failed dashboard is not summarized: %v", written.Data[statusConfigmapKey])
+	}
+
+	// the unchanged summary is not written again
+	kubeClient.ClearActions()
+	if err := writeStatusConfigmap(context.TODO(), kubeClient.CoreV1(), "test", "loader-status"); err != nil {
+		t.Fatalf("failed to write the status configmap: %v", err)
+	}
+	for _, action := range kubeClient.Actions() {
+		if action.GetVerb() == "update" {
+			t.Errorf("case (unchanged) the status configmap should not be updated again")
+		}
+	}
+
+	state.markSynced(cm, nil)
+	if err := writeStatusConfigmap(context.TODO(), kubeClient.CoreV1(), "test", "loader-status"); err != nil {
+		t.Fatalf("failed to write the status configmap: %v", err)
+	}
+	updated, _ := kubeClient.CoreV1().ConfigMaps("test").Get(context.TODO(), "loader-status", metav1.GetOptions{})
+	if updated.Data[statusConfigmapKey] == written.Data[statusConfigmapKey] {
+		t.Errorf("case (synced) the status configmap is not refreshed")
+	}
+}
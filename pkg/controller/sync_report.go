@@ -0,0 +1,194 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// ReportSyncStatus maintains a DashboardSyncReport resource in every namespace of the dashboard sources
+	ReportSyncStatus = false
+	// SyncReportName is the name of the DashboardSyncReport resources
+	SyncReportName = "grafana-dashboard-loader"
+	// SyncReportInterval is how often the DashboardSyncReport resources are refreshed
+	SyncReportInterval = time.Minute
+
+	syncReportResource = schema.GroupVersionResource{
+		Group:    "observability.open-cluster-management.io",
+		Version:  "v1alpha1",
+		Resource: "dashboardsyncreports",
+	}
+)
+
+// reportedDashboard is a managed dashboard in the status of the DashboardSyncReport
+type reportedDashboard struct {
+	Source       string `json:"source"`
+	Name         string `json:"name"`
+	Key          string `json:"key"`
+	Folder       string `json:"folder,omitempty"`
+	UID          string `json:"uid,omitempty"`
+	Synced       bool   `json:"synced"`
+	Health       string `json:"health"`
+	LastSyncTime string `json:"lastSyncTime"`
+	Error        string `json:"error,omitempty"`
+}
+
+// reportHealth is Degraded once any dashboard is degraded, then Progressing once any dashboard is being applied
+func reportHealth(dashboards []reportedDashboard) string {
+	health := healthHealthy
+	for _, d := range dashboards {
+		switch d.Health {
+		case healthDegraded:
+			return healthDegraded
+		case healthProgressing:
+			health = healthProgressing
+		}
+	}
+	return health
+}
+
+// syncReporter writes the DashboardSyncReport resources
+type syncReporter struct {
+	client dynamic.Interface
+	// reported are the namespaces which have a report, they are emptied once the dashboards are gone
+	reported map[string]bool
+	mutex    sync.Mutex
+}
+
+func newSyncReporter(client dynamic.Interface) *syncReporter {
+	return &syncReporter{client: client, reported: map[string]bool{}}
+}
+
+// Run refreshes the reports every interval until ctx is done
+func (r *syncReporter) Run(ctx context.Context, interval time.Duration) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.report(ctx); err != nil {
+			klog.ErrorS(err, "failed to write the dashboard sync reports")
+		}
+	}, interval)
+}
+
+// reportedDashboards groups the dashboards of all the sources by namespace,
+// the sources without a namespace are reported in the namespace of the loader
+func reportedDashboards() map[string][]reportedDashboard {
+	reports := map[string][]reportedDashboard{}
+	for _, state := range allSyncStates() {
+		for _, status := range state.listStatuses() {
+			namespace := status.Namespace
+			if namespace == "" {
+				namespace = os.Getenv("POD_NAMESPACE")
+			}
+			for _, key := range status.Dashboards {
+				reports[namespace] = append(reports[namespace], reportedDashboard{
+					Source:       status.Source,
+					Name:         status.Name,
+					Key:          key,
+					Folder:       status.Folders[key],
+					UID:          status.UIDs[key],
+					Synced:       status.Synced,
+					Health:       status.Health,
+					LastSyncTime: status.LastSyncTime.UTC().Format(time.RFC3339),
+					Error:        status.Error,
+				})
+			}
+		}
+	}
+	for _, dashboards := range reports {
+		sort.Slice(dashboards, func(i, j int) bool {
+			if dashboards[i].Source != dashboards[j].Source {
+				return dashboards[i].Source < dashboards[j].Source
+			}
+			if dashboards[i].Name != dashboards[j].Name {
+				return dashboards[i].Name < dashboards[j].Name
+			}
+			return dashboards[i].Key < dashboards[j].Key
+		})
+	}
+	return reports
+}
+
+func (r *syncReporter) report(ctx context.Context) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	reports := reportedDashboards()
+	for namespace := range r.reported {
+		if _, ok := reports[namespace]; !ok {
+			reports[namespace] = []reportedDashboard{}
+		}
+	}
+
+	var lastErr error
+	for namespace, dashboards := range reports {
+		if namespace == "" {
+			continue
+		}
+		if err := r.writeReport(ctx, namespace, dashboards); err != nil {
+			klog.ErrorS(err, "failed to write the dashboard sync report", "namespace", namespace)
+			lastErr = err
+			continue
+		}
+		if len(dashboards) == 0 {
+			delete(r.reported, namespace)
+		} else {
+			r.reported[namespace] = true
+		}
+	}
+	return lastErr
+}
+
+// writeReport creates the report of the namespace when it is missing and updates its status once the dashboards are changed
+func (r *syncReporter) writeReport(ctx context.Context, namespace string, dashboards []reportedDashboard) error {
+	client := r.client.Resource(syncReportResource).Namespace(namespace)
+	report, err := client.Get(ctx, SyncReportName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		report = &unstructured.Unstructured{}
+		report.SetAPIVersion(syncReportResource.GroupVersion().String())
+		report.SetKind("DashboardSyncReport")
+		report.SetNamespace(namespace)
+		report.SetName(SyncReportName)
+		report, err = client.Create(ctx, report, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	items := []interface{}{}
+	for i := range dashboards {
+		item, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&dashboards[i])
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+	status := map[string]interface{}{"dashboards": items, "health": reportHealth(dashboards)}
+	existing, _, err := unstructured.NestedMap(report.Object, "status")
+	if err != nil {
+		return fmt.Errorf("failed to read the status of the report: %v", err)
+	}
+	if reflect.DeepEqual(existing, status) {
+		return nil
+	}
+
+	if err := unstructured.SetNestedMap(report.Object, status, "status"); err != nil {
+		return err
+	}
+	_, err = client.UpdateStatus(ctx, report, metav1.UpdateOptions{})
+	return err
+}
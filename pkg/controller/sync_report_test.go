@@ -0,0 +1,88 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+)
+
+func TestSyncReport(t *testing.T) {
+	state := newSyncState("report-test")
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "report-test"},
+		Data:       map[string]string{"a.json": "{}", "b.json": "{}"},
+	}
+	applied := &appliedDashboards{uids: map[string]string{"a.json": "a"}, folders: map[string]string{"a.json": "Custom"}}
+	state.markFailed(cm, applied, fmt.Errorf("grafana is down"))
+	defer state.forget(cm)
+
+	client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+	reporter := newSyncReporter(client)
+	if err := reporter.report(context.TODO()); err != nil {
+		t.Fatalf("failed to write the reports: %v", err)
+	}
+	getDashboards := func() []interface{} {
+		report, err := client.Resource(syncReportResource).Namespace("report-test").Get(context.TODO(), SyncReportName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get the report: %v", err)
+		}
+		dashboards, _, _ := unstructured.NestedSlice(report.Object, "status", "dashboards")
+		return dashboards
+	}
+
+	dashboards := getDashboards()
+	testCaseList := []struct {
+		name     string
+		index    int
+		field    string
+		expected interface{}
+	}{
+		{"applied uid", 0, "uid", "a"},
+
+		{"applied folder", 0, "folder", "Custom"},
+
+		{"error", 0, "error", "grafana is down"},
+
+		{"not applied", 1, "uid", nil},
+
+		{"not applied key", 1, "key", "b.json"},
+	}
+	if len(dashboards) != 2 {
+		t.Fatalf("the report should list 2 dashboards: %v", dashboards)
+	}
+	for _, c := range testCaseList {
+		output := dashboards[c.index].(map[string]interface{})[c.field]
+		if output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+
+	// the unchanged report is not written again
+	client.ClearActions()
+	if err := reporter.report(context.TODO()); err != nil {
+		t.Fatalf("failed to write the reports: %v", err)
+	}
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "update" {
+			t.Errorf("case (unchanged) the report should not be updated again")
+		}
+	}
+
+	// the report is emptied once the dashboards are deleted
+	state.forget(cm)
+	if err := reporter.report(context.TODO()); err != nil {
+		t.Fatalf("failed to write the reports: %v", err)
+	}
+	if dashboards := getDashboards(); len(dashboards) != 0 {
+		t.Errorf("case (deleted) the report should be empty: %v", dashboards)
+	}
+}
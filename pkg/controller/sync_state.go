@@ -0,0 +1,344 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+// syncState records the content hash of the configmaps which were applied to grafana successfully,
+// together with the result of the last sync of every configmap
+type syncState struct {
+	sync.Mutex
+	// source is the kind of the source, e.g. configmap, secret or git:<repository>
+	source   string
+	hashes   map[string]string
+	statuses map[string]dashboardStatus
+	// attempts are the hashes of the configmaps which were applied last time no matter the result
+	attempts map[string]string
+	// targets are the results of the last sync to every grafana target keyed by the configmap and the target name
+	targets map[string]map[string]targetStatus
+	// statusWriter publishes the status of the configmap after every sync, nil means the status is kept in memory only
+	statusWriter func(ctx context.Context, cm *corev1.ConfigMap, status dashboardStatus)
+}
+
+// dashboardStatus is the result of the last sync of a configmap
+type dashboardStatus struct {
+	Source     string   `json:"source"`
+	Namespace  string   `json:"namespace"`
+	Name       string   `json:"name"`
+	Dashboards []string `json:"dashboards"`
+	// UIDs are the uids of the applied dashboards keyed by the data key
+	UIDs map[string]string `json:"uids,omitempty"`
+	// Folders are the folders of the applied dashboards keyed by the data key
+	Folders map[string]string `json:"folders,omitempty"`
+	// Versions are the grafana versions of the applied dashboards keyed by the data key
+	Versions map[string]float64 `json:"versions,omitempty"`
+	// Lint are the lint findings of the dashboards keyed by the data key
+	Lint         map[string][]string `json:"lint,omitempty"`
+	Synced       bool                `json:"synced"`
+	LastSyncTime time.Time           `json:"lastSyncTime"`
+	Error        string              `json:"error,omitempty"`
+	// Health is Progressing while the dashboards are applied, then Healthy or Degraded by the result
+	Health string `json:"health"`
+	// Targets are the results of the grafana targets keyed by the target name, empty without targets
+	Targets map[string]targetStatus `json:"targets,omitempty"`
+}
+
+var (
+	syncStatesLock sync.Mutex
+	// syncStates are the states of all the sources
+	syncStates []*syncState
+)
+
+var appliedState = newSyncState("configmap")
+
+func newSyncState(source string) *syncState {
+	state := &syncState{
+		source:   source,
+		hashes:   map[string]string{},
+		statuses: map[string]dashboardStatus{},
+		attempts: map[string]string{},
+		targets:  map[string]map[string]targetStatus{},
+	}
+	syncStatesLock.Lock()
+	syncStates = append(syncStates, state)
+	syncStatesLock.Unlock()
+	return state
+}
+
+func allSyncStates() []*syncState {
+	syncStatesLock.Lock()
+	defer syncStatesLock.Unlock()
+	return append([]*syncState{}, syncStates...)
+}
+
+func configmapKey(cm *corev1.ConfigMap) string {
+	return cm.GetNamespace() + "/" + cm.GetName()
+}
+
+// configmapHash covers everything which decides how the dashboards are applied,
+// the labels and annotations select the folder so they are part of the hash,
+// while the status annotations written by the loader itself are not,
+// and the selected managed clusters are part of the hash of a per cluster configmap
+func configmapHash(cm *corev1.ConfigMap) string {
+	annotations := map[string]string{}
+	for key, value := range cm.GetAnnotations() {
+		if !isStatusAnnotation(key) {
+			annotations[key] = value
+		}
+	}
+	content := struct {
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+		Data        map[string]string `json:"data"`
+		BinaryData  map[string][]byte `json:"binaryData"`
+		Clusters    []string          `json:"managedClusters,omitempty"`
+	}{cm.GetLabels(), annotations, cm.Data, cm.BinaryData, nil}
+	if isPerClusterConfigmap(cm) {
+		content.Clusters, _ = matchedManagedClusters(cm)
+	}
+
+	// json.Marshal sorts the map keys so the output is stable
+	b, err := json.Marshal(content)
+	if err != nil {
+		return ""
+	}
+	hasher := fnv.New128a()
+	_, err = hasher.Write(b)
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// isSynced checks whether the configmap is unchanged since it was applied last time,
+// and that it was applied to all the current grafana targets it is routed to
+func (s *syncState) isSynced(cm *corev1.ConfigMap) bool {
+	s.Lock()
+	defer s.Unlock()
+	hash, ok := s.hashes[configmapKey(cm)]
+	if !ok || hash == "" || hash != configmapHash(cm) {
+		return false
+	}
+	for _, name := range targetNames() {
+		if !isRoutedTo(cm, name) {
+			continue
+		}
+		if target, ok := s.targets[configmapKey(cm)][name]; !ok || target.hash != hash {
+			return false
+		}
+	}
+	return true
+}
+
+// isKeySynced checks whether the last sync of the configmap with the key succeeded
+func (s *syncState) isKeySynced(key string) bool {
+	s.Lock()
+	defer s.Unlock()
+	status, ok := s.statuses[key]
+	return ok && status.Synced
+}
+
+// appliedUIDs returns the uids of the dashboards of the last sync of the configmap with the key keyed by the data key
+func (s *syncState) appliedUIDs(key string) map[string]string {
+	s.Lock()
+	defer s.Unlock()
+	uids := map[string]string{}
+	for dataKey, uid := range s.statuses[key].UIDs {
+		uids[dataKey] = uid
+	}
+	return uids
+}
+
+// markProgressing records that the configmap is being applied, it returns false when the same content was applied before
+// so that retrying a failed configmap does not flip its health back and forth
+func (s *syncState) markProgressing(cm *corev1.ConfigMap) (dashboardStatus, bool) {
+	s.Lock()
+	defer s.Unlock()
+	hash := configmapHash(cm)
+	if s.attempts[configmapKey(cm)] == hash {
+		return s.statuses[configmapKey(cm)], false
+	}
+	s.attempts[configmapKey(cm)] = hash
+	status, ok := s.statuses[configmapKey(cm)]
+	if !ok {
+		status = s.newStatus(cm, nil, nil)
+		status.Synced = false
+	}
+	status.Health = healthProgressing
+	s.statuses[configmapKey(cm)] = status
+	notifyStatusChanged()
+	return status, true
+}
+
+func (s *syncState) markSynced(cm *corev1.ConfigMap, applied *appliedDashboards) dashboardStatus {
+	s.Lock()
+	defer s.Unlock()
+	s.hashes[configmapKey(cm)] = configmapHash(cm)
+	status := s.newStatus(cm, applied, nil)
+	s.statuses[configmapKey(cm)] = status
+	s.updateGauges()
+	notifyStatusChanged()
+	return status
+}
+
+// markFailed records the error of the sync, the configmap is applied again on the next resync
+func (s *syncState) markFailed(cm *corev1.ConfigMap, applied *appliedDashboards, err error) dashboardStatus {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.hashes, configmapKey(cm))
+	status := s.newStatus(cm, applied, err)
+	s.statuses[configmapKey(cm)] = status
+	s.updateGauges()
+	notifyStatusChanged()
+	return status
+}
+
+func (s *syncState) newStatus(cm *corev1.ConfigMap, applied *appliedDashboards, err error) dashboardStatus {
+	status := dashboardStatus{
+		Source:       s.source,
+		Namespace:    cm.GetNamespace(),
+		Name:         cm.GetName(),
+		Dashboards:   []string{},
+		Synced:       err == nil,
+		LastSyncTime: time.Now(),
+		Health:       healthHealthy,
+	}
+	if targets := s.targets[configmapKey(cm)]; len(targets) > 0 {
+		status.Targets = map[string]targetStatus{}
+		for name, target := range targets {
+			status.Targets[name] = target
+		}
+	}
+	if applied != nil {
+		status.UIDs = applied.uids
+		status.Folders = applied.folders
+		if len(applied.versions) > 0 {
+			status.Versions = applied.versions
+		}
+		if len(applied.lint) > 0 {
+			status.Lint = applied.lint
+		}
+	}
+	for key := range cm.Data {
+		status.Dashboards = append(status.Dashboards, key)
+	}
+	for key := range cm.BinaryData {
+		status.Dashboards = append(status.Dashboards, key)
+	}
+	sort.Strings(status.Dashboards)
+	if err != nil {
+		status.Error = err.Error()
+		status.Health = healthDegraded
+	}
+	return status
+}
+
+func (s *syncState) forget(cm *corev1.ConfigMap) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.hashes, configmapKey(cm))
+	delete(s.statuses, configmapKey(cm))
+	delete(s.attempts, configmapKey(cm))
+	delete(s.targets, configmapKey(cm))
+	s.updateGauges()
+	notifyStatusChanged()
+}
+
+// updateGauges sets the gauges of the source, the caller holds the lock
+func (s *syncState) updateGauges() {
+	retrying := 0
+	for _, status := range s.statuses {
+		if !status.Synced {
+			retrying++
+		}
+	}
+	metrics.ConfigmapsMatched.WithLabelValues(s.source).Set(float64(len(s.statuses)))
+	metrics.SyncsRetrying.WithLabelValues(s.source).Set(float64(retrying))
+}
+
+// reset drops all the hashes so that every configmap is applied again, the statuses are kept
+func (s *syncState) reset() {
+	s.Lock()
+	defer s.Unlock()
+	s.hashes = map[string]string{}
+	for _, targets := range s.targets {
+		for name, target := range targets {
+			target.hash = ""
+			targets[name] = target
+		}
+	}
+}
+
+// resetTarget drops the hashes of the target so that every configmap is applied to it again
+func (s *syncState) resetTarget(target string) {
+	s.Lock()
+	defer s.Unlock()
+	for _, targets := range s.targets {
+		if status, ok := targets[target]; ok {
+			status.hash = ""
+			targets[target] = status
+		}
+	}
+}
+
+// isTargetSynced checks whether the configmap is unchanged since it was applied to the target last time
+func (s *syncState) isTargetSynced(cm *corev1.ConfigMap, target string) bool {
+	s.Lock()
+	defer s.Unlock()
+	status, ok := s.targets[configmapKey(cm)][target]
+	return ok && status.Synced && status.hash != "" && status.hash == configmapHash(cm)
+}
+
+// hasTarget checks whether the configmap was applied to the target
+func (s *syncState) hasTarget(cm *corev1.ConfigMap, target string) bool {
+	s.Lock()
+	defer s.Unlock()
+	_, ok := s.targets[configmapKey(cm)][target]
+	return ok
+}
+
+// forgetTarget drops the result of the target once the configmap is no longer applied to it
+func (s *syncState) forgetTarget(cm *corev1.ConfigMap, target string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.targets[configmapKey(cm)], target)
+}
+
+// markTargetSynced records the result of the sync of the configmap to the target
+func (s *syncState) markTargetSynced(cm *corev1.ConfigMap, target string, err error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.targets[configmapKey(cm)] == nil {
+		s.targets[configmapKey(cm)] = map[string]targetStatus{}
+	}
+	status := targetStatus{Synced: err == nil, LastSyncTime: time.Now()}
+	if err != nil {
+		status.Error = err.Error()
+	} else {
+		status.hash = configmapHash(cm)
+	}
+	s.targets[configmapKey(cm)][target] = status
+}
+
+func (s *syncState) listStatuses() []dashboardStatus {
+	s.Lock()
+	defer s.Unlock()
+	statuses := []dashboardStatus{}
+	for _, status := range s.statuses {
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+func TestSyncState(t *testing.T) {
+	state := newSyncState("configmap")
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+			Labels:    map[string]string{"grafana-custom-dashboard": "true"},
+		},
+		Data: map[string]string{"test.json": "{}"},
+	}
+
+	if state.isSynced(cm) {
+		t.Fatalf("configmap %v should not be synced before applied", cm.Name)
+	}
+
+	state.markSynced(cm, nil)
+	if !state.isSynced(cm) {
+		t.Fatalf("configmap %v should be synced after applied", cm.Name)
+	}
+
+	// a resync delivers a copy of the same configmap
+	if !state.isSynced(cm.DeepCopy()) {
+		t.Fatalf("unchanged configmap %v should be synced", cm.Name)
+	}
+
+	changed := cm.DeepCopy()
+	changed.Annotations = map[string]string{customFolderKey: "test"}
+	if state.isSynced(changed) {
+		t.Fatalf("configmap %v with new annotations should not be synced", cm.Name)
+	}
+
+	changed = cm.DeepCopy()
+	changed.Data["test.json"] = "{\"title\": \"test\"}"
+	if state.isSynced(changed) {
+		t.Fatalf("configmap %v with new data should not be synced", cm.Name)
+	}
+
+	state.markFailed(cm, nil, fmt.Errorf("grafana is down"))
+	if state.isSynced(cm) {
+		t.Fatalf("configmap %v should not be synced after failed", cm.Name)
+	}
+	statuses := state.listStatuses()
+	if len(statuses) != 1 || statuses[0].Synced || statuses[0].Error != "grafana is down" {
+		t.Fatalf("the failed sync of configmap %v is not recorded: %v", cm.Name, statuses)
+	}
+
+	state.markSynced(cm, nil)
+	state.reset()
+	if state.isSynced(cm) {
+		t.Fatalf("configmap %v should not be synced after reset", cm.Name)
+	}
+	if len(state.listStatuses()) != 1 {
+		t.Fatalf("the status of configmap %v should be kept after reset", cm.Name)
+	}
+
+	state.forget(cm)
+	if state.isSynced(cm) {
+		t.Fatalf("configmap %v should not be synced after deleted", cm.Name)
+	}
+	if len(state.listStatuses()) != 0 {
+		t.Fatalf("the status of configmap %v should be dropped after deleted", cm.Name)
+	}
+}
+
+func TestSyncStateGauges(t *testing.T) {
+	state := newSyncState("gauge-test")
+	synced := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "synced", Namespace: "test"}}
+	failed := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "failed", Namespace: "test"}}
+	state.markSynced(synced, nil)
+	state.markFailed(failed, nil, fmt.Errorf("grafana is down"))
+
+	recordManagedDashboard("gauge-test", "a", "Custom")
+	recordManagedDashboard("gauge-test", "b", "Custom")
+	recordManagedDashboard("gauge-test", "c", "")
+	defer func() {
+		for _, uid := range []string{"a", "b", "c"} {
+			forgetManagedDashboard("gauge-test", uid)
+		}
+	}()
+	managed := len(listManagedDashboards())
+
+	testCaseList := []struct {
+		name     string
+		gauge    prometheus.Collector
+		expected float64
+	}{
+		{"matched", metrics.ConfigmapsMatched.WithLabelValues("gauge-test"), 2},
+
+		{"retrying", metrics.SyncsRetrying.WithLabelValues("gauge-test"), 1},
+
+		{"managed", metrics.DashboardsManaged, float64(managed)},
+	}
+	for _, c := range testCaseList {
+		if output := testutil.ToFloat64(c.gauge); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+
+	folders := testutil.ToFloat64(metrics.CustomFolders)
+	forgetManagedDashboard("gauge-test", "a")
+	if output := testutil.ToFloat64(metrics.CustomFolders); output != folders {
+		t.Errorf("case (folder in use) output: (%v) is not the expected: (%v)", output, folders)
+	}
+	forgetManagedDashboard("gauge-test", "b")
+	if output := testutil.ToFloat64(metrics.CustomFolders); output != folders-1 {
+		t.Errorf("case (folder emptied) output: (%v) is not the expected: (%v)", output, folders-1)
+	}
+
+	state.forget(failed)
+	if output := testutil.ToFloat64(metrics.SyncsRetrying.WithLabelValues("gauge-test")); output != 0 {
+		t.Errorf("case (deleted) output: (%v) is not the expected: (0)", output)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"hash/fnv"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// SyncWorkers is the number of the dashboard objects synced at the same time, the events of the same object
+// are always handled by the same worker in the order the informer delivers them, 1 syncs the objects one by one
+var SyncWorkers = 1
+
+// syncWorkerQueueLength is the number of the events a worker may have waiting before the informer is blocked
+const syncWorkerQueueLength = 100
+
+// syncWorkers hands the events over to the workers keyed by the object
+type syncWorkers struct {
+	ctx     context.Context
+	handler cache.ResourceEventHandler
+	queues  []chan func()
+}
+
+// parallelSyncs returns the handler which handles the events of the different objects by the workers in parallel,
+// the workers stop once the context is done
+func parallelSyncs(ctx context.Context, workers int, handler cache.ResourceEventHandler) cache.ResourceEventHandler {
+	if workers <= 1 {
+		return handler
+	}
+	w := &syncWorkers{ctx: ctx, handler: handler, queues: make([]chan func(), workers)}
+	for i := range w.queues {
+		w.queues[i] = make(chan func(), syncWorkerQueueLength)
+		go w.run(w.queues[i])
+	}
+	return w
+}
+
+func (w *syncWorkers) run(queue chan func()) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case event := <-queue:
+			event()
+		}
+	}
+}
+
+// dispatch queues the event to the worker of the object, the events which cannot be keyed go to the first worker
+func (w *syncWorkers) dispatch(obj interface{}, event func()) {
+	worker := 0
+	if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(key))
+		worker = int(hash.Sum32() % uint32(len(w.queues)))
+	} else {
+		klog.ErrorS(err, "failed to key the event, it is handled by the first worker")
+	}
+	select {
+	case <-w.ctx.Done():
+	case w.queues[worker] <- event:
+	}
+}
+
+func (w *syncWorkers) OnAdd(obj interface{}) {
+	w.dispatch(obj, func() { w.handler.OnAdd(obj) })
+}
+
+func (w *syncWorkers) OnUpdate(old, new interface{}) {
+	w.dispatch(new, func() { w.handler.OnUpdate(old, new) })
+}
+
+func (w *syncWorkers) OnDelete(obj interface{}) {
+	w.dispatch(obj, func() { w.handler.OnDelete(obj) })
+}
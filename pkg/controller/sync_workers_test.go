@@ -0,0 +1,70 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestParallelSyncs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	lock := sync.Mutex{}
+	versions := map[string][]string{}
+	running, maxRunning := 0, 0
+	wg := sync.WaitGroup{}
+	handler := parallelSyncs(ctx, 4, cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, new interface{}) {
+			defer wg.Done()
+			cm := new.(*corev1.ConfigMap)
+			lock.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			lock.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			lock.Lock()
+			running--
+			versions[cm.Name] = append(versions[cm.Name], cm.ResourceVersion)
+			lock.Unlock()
+		},
+	})
+
+	for v := 1; v <= 3; v++ {
+		for i := 0; i < 8; i++ {
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: strconv.Itoa(i), Namespace: "test",
+				ResourceVersion: strconv.Itoa(v)}}
+			wg.Add(1)
+			handler.OnUpdate(cm, cm)
+		}
+	}
+	wg.Wait()
+
+	if maxRunning <= 1 {
+		t.Errorf("case (parallel) output: (%v) is not the expected: (more than 1)", maxRunning)
+	}
+	for name, output := range versions {
+		if expected := []string{"1", "2", "3"}; !reflect.DeepEqual(output, expected) {
+			t.Errorf("case (ordered %v) output: (%v) is not the expected: (%v)", name, output, expected)
+		}
+	}
+
+	// a single worker handles the events at once in the informer goroutine
+	handled := false
+	parallelSyncs(ctx, 1, cache.ResourceEventHandlerFuncs{AddFunc: func(obj interface{}) { handled = true }}).OnAdd(nil)
+	if !handled {
+		t.Errorf("case (single worker) the event is not handled at once")
+	}
+}
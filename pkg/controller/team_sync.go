@@ -0,0 +1,256 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	rbacv1client "k8s.io/client-go/kubernetes/typed/rbac/v1"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// TeamSync maintains a grafana team per namespace with the users bound to TeamRoles in the namespace,
+	// the team is granted TeamPermission on the folders of the dashboards of the namespace
+	TeamSync = false
+	// TeamSyncInterval is how often the teams are synced with the role bindings
+	TeamSyncInterval = 5 * time.Minute
+	// TeamRoles are the roles or cluster roles whose subjects in the namespace are the members of the team
+	TeamRoles = []string{"admin", "edit"}
+	// TeamPermission is the permission of the team on the folders, it is View, Edit or Admin
+	TeamPermission = "Edit"
+
+	openshiftGroupResource = schema.GroupVersionResource{Group: "user.openshift.io", Version: "v1", Resource: "groups"}
+)
+
+var folderPermissionLevels = map[string]int{"View": 1, "Edit": 2, "Admin": 4}
+
+// ValidateTeamPermission checks that the permission is a grafana folder permission
+func ValidateTeamPermission(permission string) error {
+	if _, ok := folderPermissionLevels[permission]; !ok {
+		return fmt.Errorf("invalid team permission %q, it is View, Edit or Admin", permission)
+	}
+	return nil
+}
+
+// teamFolders returns the folders of the applied dashboards keyed by their namespace
+func teamFolders(statuses []dashboardStatus) map[string][]string {
+	folders := map[string]map[string]bool{}
+	for _, status := range statuses {
+		if status.Namespace == "" {
+			continue
+		}
+		if folders[status.Namespace] == nil {
+			folders[status.Namespace] = map[string]bool{}
+		}
+		for _, folder := range status.Folders {
+			if folder != "" {
+				folders[status.Namespace][folder] = true
+			}
+		}
+	}
+	titles := map[string][]string{}
+	for namespace, set := range folders {
+		titles[namespace] = []string{}
+		for folder := range set {
+			titles[namespace] = append(titles[namespace], folder)
+		}
+		sort.Strings(titles[namespace])
+	}
+	return titles
+}
+
+// namespaceMembers returns the users bound to the team roles in the namespace ordered by name,
+// the groups are expanded with the openshift groups when the client is given
+func namespaceMembers(ctx context.Context, rbacClient rbacv1client.RbacV1Interface, dynamicClient dynamic.Interface,
+	namespace string) ([]string, error) {
+	bindings, err := rbacClient.RoleBindings(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the role bindings of %v: %v", namespace, err)
+	}
+	roles := stringSet(TeamRoles)
+	users := map[string]bool{}
+	for _, binding := range bindings.Items {
+		if !roles[binding.RoleRef.Name] {
+			continue
+		}
+		for _, subject := range binding.Subjects {
+			switch subject.Kind {
+			case rbacv1.UserKind:
+				users[subject.Name] = true
+			case rbacv1.GroupKind:
+				for _, user := range groupUsers(ctx, dynamicClient, subject.Name) {
+					users[user] = true
+				}
+			}
+		}
+	}
+	members := []string{}
+	for user := range users {
+		members = append(members, user)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// groupUsers returns the users of the openshift group, the members of the groups are unknown on other clusters
+func groupUsers(ctx context.Context, client dynamic.Interface, name string) []string {
+	if client == nil {
+		return nil
+	}
+	group, err := client.Resource(openshiftGroupResource).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		klog.V(4).InfoS("the members of the group are unknown", "group", name, "error", err)
+		return nil
+	}
+	users, _, _ := unstructured.NestedStringSlice(group.Object, "users")
+	return users
+}
+
+// syncTeam makes the users the members of the team and grants the team the permission on the folders
+func syncTeam(ctx context.Context, orgID string, name string, users []string, folders []string) error {
+	client := clientFor(ctx)
+	teamID, err := client.GetTeamID(ctx, orgID, name)
+	if err != nil {
+		return fmt.Errorf("failed to get the team %v: %v", name, err)
+	}
+	if teamID == 0 {
+		if teamID, err = client.CreateTeam(ctx, orgID, name); err != nil {
+			return fmt.Errorf("failed to create the team %v: %v", name, err)
+		}
+		klog.InfoS("the grafana team is created", "team", name, "org", orgID)
+	}
+
+	members := []TeamMember{}
+	if teamID != dryRunTeamID {
+		if members, err = client.ListTeamMembers(ctx, orgID, teamID); err != nil {
+			return fmt.Errorf("failed to list the members of the team %v: %v", name, err)
+		}
+	}
+	desired := stringSet(users)
+	current := map[string]bool{}
+	for _, member := range members {
+		current[member.Login] = true
+		if desired[member.Login] {
+			continue
+		}
+		if err := client.RemoveTeamMember(ctx, orgID, teamID, member.UserID); err != nil {
+			return fmt.Errorf("failed to remove %v from the team %v: %v", member.Login, name, err)
+		}
+		klog.InfoS("the user is removed from the grafana team", "team", name, "user", member.Login, "org", orgID)
+	}
+	for _, user := range users {
+		if current[user] {
+			continue
+		}
+		userID, err := client.GetUserID(ctx, user)
+		if err != nil {
+			return fmt.Errorf("failed to get the user %v: %v", user, err)
+		}
+		if userID == 0 {
+			klog.V(2).InfoS("the user is not added to the grafana team since it never logged into grafana", "team", name, "user", user)
+			continue
+		}
+		if err := client.AddTeamMember(ctx, orgID, teamID, userID); err != nil {
+			return fmt.Errorf("failed to add %v to the team %v: %v", user, name, err)
+		}
+		klog.InfoS("the user is added to the grafana team", "team", name, "user", user, "org", orgID)
+	}
+
+	existing, err := client.ListFolders(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to list the folders: %v", err)
+	}
+	titles := stringSet(folders)
+	for _, folder := range existing {
+		if !titles[folder.Title] {
+			continue
+		}
+		if err := grantFolder(ctx, orgID, folder.UID, teamID); err != nil {
+			return fmt.Errorf("failed to grant the team %v the folder %v: %v", name, folder.Title, err)
+		}
+	}
+	return nil
+}
+
+// grantFolder grants the team TeamPermission on the folder, the other permissions of the folder are kept
+func grantFolder(ctx context.Context, orgID string, folderUID string, teamID float64) error {
+	client := clientFor(ctx)
+	permissions, err := client.GetFolderPermissions(ctx, orgID, folderUID)
+	if err != nil {
+		return err
+	}
+	level := folderPermissionLevels[TeamPermission]
+	updated := []FolderPermission{}
+	for _, permission := range permissions {
+		if permission.TeamID == teamID {
+			if permission.Permission == level {
+				return nil
+			}
+			continue
+		}
+		updated = append(updated, permission)
+	}
+	updated = append(updated, FolderPermission{TeamID: teamID, Permission: level})
+	klog.InfoS("the grafana team is granted the folder", "team", teamID, "folder", folderUID, "permission", TeamPermission, "org", orgID)
+	return client.UpdateFolderPermissions(ctx, orgID, folderUID, updated)
+}
+
+// syncTeams syncs the team of every namespace with applied dashboards in all the grafana targets
+func syncTeams(ctx context.Context, rbacClient rbacv1client.RbacV1Interface, dynamicClient dynamic.Interface) error {
+	folders := teamFolders(appliedState.listStatuses())
+	namespaces := []string{}
+	for namespace := range folders {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	targetCtxs := []context.Context{ctx}
+	if targets := grafanaTargets(); len(targets) > 0 {
+		targetCtxs = []context.Context{}
+		for _, target := range targets {
+			targetCtxs = append(targetCtxs, withGrafanaTarget(ctx, target))
+		}
+	}
+	problems := []string{}
+	for _, namespace := range namespaces {
+		users, err := namespaceMembers(ctx, rbacClient, dynamicClient, namespace)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		for _, targetCtx := range targetCtxs {
+			orgID, err := dashboardOrgID(targetCtx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: namespace}})
+			if err == nil {
+				err = syncTeam(targetCtx, orgID, namespace, users, folders[namespace])
+			}
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%v: %v", namespace, err))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("failed to sync the teams %v", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func runTeamSync(ctx context.Context, rbacClient rbacv1client.RbacV1Interface, dynamicClient dynamic.Interface, interval time.Duration) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := syncTeams(ctx, rbacClient, dynamicClient); err != nil {
+			klog.ErrorS(err, "failed to sync the grafana teams")
+		}
+	}, interval)
+}
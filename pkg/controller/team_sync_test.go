@@ -0,0 +1,118 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNamespaceMembers(t *testing.T) {
+	binding := func(name string, role string, subjects ...rbacv1.Subject) *rbacv1.RoleBinding {
+		return &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a"},
+			RoleRef: rbacv1.RoleRef{Kind: "ClusterRole", Name: role}, Subjects: subjects}
+	}
+	kubeClient := fakekube.NewSimpleClientset(
+		binding("admins", "admin", rbacv1.Subject{Kind: rbacv1.UserKind, Name: "alice"}),
+		binding("editors", "edit", rbacv1.Subject{Kind: rbacv1.GroupKind, Name: "sre"},
+			rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: "default", Namespace: "team-a"}),
+		binding("viewers", "view", rbacv1.Subject{Kind: rbacv1.UserKind, Name: "carol"}),
+	)
+	group := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "user.openshift.io/v1", "kind": "Group",
+		"metadata": map[string]interface{}{"name": "sre"}, "users": []interface{}{"bob", "alice"}}}
+	dynamicClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), group)
+
+	testCaseList := []struct {
+		name     string
+		roles    []string
+		groups   bool
+		expected []string
+	}{
+		{"default roles", []string{"admin", "edit"}, true, []string{"alice", "bob"}},
+
+		{"no openshift groups", []string{"admin", "edit"}, false, []string{"alice"}},
+
+		{"view role", []string{"view"}, true, []string{"carol"}},
+	}
+
+	defer func(roles []string) { TeamRoles = roles }(TeamRoles)
+	for _, c := range testCaseList {
+		TeamRoles = c.roles
+		client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+		if c.groups {
+			client = dynamicClient
+		}
+		output, err := namespaceMembers(context.TODO(), kubeClient.RbacV1(), client, "team-a")
+		if err != nil || !reflect.DeepEqual(output, c.expected) {
+			t.Errorf("case (%v) output: (%v, %v) is not the expected: (%v)", c.name, output, err, c.expected)
+		}
+	}
+}
+
+func TestSyncTeam(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	fake.users = map[string]float64{"alice": 101, "bob": 102, "mallory": 103}
+	slos, _ := fake.CreateFolder(context.TODO(), "", "SLOs")
+	other, _ := fake.CreateFolder(context.TODO(), "", "Other")
+	fake.permissions[""] = map[string][]FolderPermission{slos.UID: {{Role: "Viewer", Permission: 1}}}
+
+	testCaseList := []struct {
+		name     string
+		users    []string
+		expected []string
+	}{
+		{"created team", []string{"alice", "bob", "dave"}, []string{"alice", "bob"}},
+
+		{"removed member", []string{"bob"}, []string{"bob"}},
+
+		{"manually added member", []string{"bob"}, []string{"bob"}},
+	}
+
+	for _, c := range testCaseList {
+		if c.name == "manually added member" {
+			fake.AddTeamMember(context.TODO(), "", fake.teams[""]["team-a"], 103)
+		}
+		if err := syncTeam(context.TODO(), "", "team-a", c.users, []string{"SLOs"}); err != nil {
+			t.Errorf("case (%v) failed to sync the team: %v", c.name, err)
+		}
+		teamID := fake.teams[""]["team-a"]
+		output := []string{}
+		for _, member := range fake.members[teamID] {
+			output = append(output, member.Login)
+		}
+		if !reflect.DeepEqual(output, c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+		expected := []FolderPermission{{Role: "Viewer", Permission: 1}, {TeamID: teamID, Permission: 2}}
+		if permissions := fake.permissions[""][slos.UID]; !reflect.DeepEqual(permissions, expected) {
+			t.Errorf("case (%v) permissions: (%v) is not the expected: (%v)", c.name, permissions, expected)
+		}
+		if permissions := fake.permissions[""][other.UID]; len(permissions) != 0 {
+			t.Errorf("case (%v) the other folder is granted: (%v)", c.name, permissions)
+		}
+	}
+}
+
+func TestTeamFolders(t *testing.T) {
+	statuses := []dashboardStatus{
+		{Namespace: "team-a", Folders: map[string]string{"a.json": "SLOs", "b.json": "Team A", "c.json": "SLOs"}},
+		{Namespace: "team-a", Folders: map[string]string{"d.json": "Alerts"}},
+		{Namespace: "team-b"},
+		{Name: "git", Folders: map[string]string{"e.json": "Git"}},
+	}
+	expected := map[string][]string{"team-a": {"Alerts", "SLOs", "Team A"}, "team-b": {}}
+	if output := teamFolders(statuses); !reflect.DeepEqual(output, expected) {
+		t.Errorf("case (team folders) output: (%v) is not the expected: (%v)", output, expected)
+	}
+}
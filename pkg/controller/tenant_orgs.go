@@ -0,0 +1,90 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// TenantOrgs applies the dashboards of every namespace into the grafana organization of the namespace,
+	// which is created when it does not exist
+	TenantOrgs = false
+	// TenantOrgNames are the names of the organizations keyed by the namespace, the default is the namespace name
+	TenantOrgNames = map[string]string{}
+	// TenantFolder is the folder of the dashboards without a folder annotation in the organization of the tenant,
+	// it can be a template as well
+	TenantFolder = "{{ .Namespace }}"
+)
+
+// tenantOrgIDs are the resolved organizations keyed by the grafana target and the organization name
+var tenantOrgIDs = struct {
+	sync.Mutex
+	ids map[string]string
+}{ids: map[string]string{}}
+
+func isTenantConfigmap(cm *corev1.ConfigMap) bool {
+	return TenantOrgs && cm != nil && cm.GetNamespace() != ""
+}
+
+// tenantOrgName returns the name of the organization of the namespace
+func tenantOrgName(namespace string) string {
+	if name := TenantOrgNames[namespace]; name != "" {
+		return name
+	}
+	return namespace
+}
+
+// tenantOrgID returns the organization of the tenant of the configmap in the grafana target of the context,
+// the organization is created on demand
+func tenantOrgID(ctx context.Context, cm *corev1.ConfigMap) (string, error) {
+	name := tenantOrgName(cm.GetNamespace())
+	key := targetName(ctx) + "|" + name
+	tenantOrgIDs.Lock()
+	defer tenantOrgIDs.Unlock()
+	if orgID := tenantOrgIDs.ids[key]; orgID != "" {
+		return orgID, nil
+	}
+
+	orgID, err := clientFor(ctx).GetOrgID(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the grafana organization %q: %v", name, err)
+	}
+	if orgID == "" {
+		if DryRun {
+			return "", fmt.Errorf("the grafana organization %q does not exist and is not created under dry-run mode", name)
+		}
+		orgID, err = clientFor(ctx).CreateOrg(ctx, name)
+		if err != nil {
+			return "", fmt.Errorf("failed to create the grafana organization %q: %v", name, err)
+		}
+		klog.InfoS("the grafana organization of the tenant is created", "namespace", cm.GetNamespace(), "org", name, "id", orgID)
+	}
+	tenantOrgIDs.ids[key] = orgID
+	return orgID, nil
+}
+
+// dashboardOrgID returns the organization of the configmap, the organization annotation overrides the tenant one
+func dashboardOrgID(ctx context.Context, obj interface{}) (string, error) {
+	orgID, err := getDashboardOrgID(obj)
+	if err != nil || orgID != "" {
+		return orgID, err
+	}
+	if cm, ok := obj.(*corev1.ConfigMap); ok && isTenantConfigmap(cm) {
+		return tenantOrgID(ctx, cm)
+	}
+	return "", nil
+}
+
+// resetTenantOrgs forgets the resolved organizations, e.g. once they may be deleted in grafana
+func resetTenantOrgs() {
+	tenantOrgIDs.Lock()
+	defer tenantOrgIDs.Unlock()
+	tenantOrgIDs.ids = map[string]string{}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTenantOrgs(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	defer func() {
+		TenantOrgs, TenantOrgNames = false, map[string]string{}
+		resetTenantOrgs()
+	}()
+	TenantOrgs, TenantOrgNames = true, map[string]string{"team-b": "Team B"}
+	fake.orgs["existing"] = "7"
+
+	testCaseList := []struct {
+		name        string
+		namespace   string
+		annotations map[string]string
+		org         string
+		folder      string
+		expected    string
+	}{
+		{"created org", "team-a", nil, "team-a", "team-a", "3"},
+
+		{"named org", "team-b", nil, "Team B", "team-b", "4"},
+
+		{"existing org", "existing", map[string]string{customFolderKey: "SLOs"}, "existing", "SLOs", "7"},
+
+		{"org annotation", "team-c", map[string]string{dashboardOrgIDKey: "5"}, "", "team-c", "5"},
+	}
+
+	for _, c := range testCaseList {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: c.namespace, Annotations: c.annotations,
+				Labels: map[string]string{"grafana-custom-dashboard": "true"}},
+			Data: map[string]string{"a.json": `{"uid": "` + c.namespace + `", "title": "a", "panels": []}`},
+		}
+		if err := updateDashboard(context.TODO(), nil, cm, false); err != nil {
+			t.Errorf("case (%v) failed to update the dashboards: %v", c.name, err)
+		}
+		if c.org != "" && fake.orgs[c.org] != c.expected {
+			t.Errorf("case (%v) org: (%v) is not the expected: (%v)", c.name, fake.orgs[c.org], c.expected)
+		}
+		saved, ok := fake.dashboards[c.expected][c.namespace]
+		if !ok {
+			t.Fatalf("case (%v) the dashboard is not saved in the org %v", c.name, c.expected)
+		}
+		if folder, _ := fake.GetFolder(context.TODO(), c.expected, saved.folderID); folder.Title != c.folder {
+			t.Errorf("case (%v) folder: (%v) is not the expected: (%v)", c.name, folder.Title, c.folder)
+		}
+		deleteDashboard(context.TODO(), cm)
+		if _, ok := fake.dashboards[c.expected][c.namespace]; ok {
+			t.Errorf("case (%v) the dashboard is not deleted from the org %v", c.name, c.expected)
+		}
+	}
+	if len(fake.orgs) != 3 {
+		t.Errorf("case (orgs) output: (%v) is not the expected: (%v)", fake.orgs, 3)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+// desiredUID is a dashboard uid in an org of a grafana target
+type desiredUID struct {
+	target string
+	orgID  string
+	uid    string
+}
+
+// uidOwner is the data key of a configmap which a dashboard uid is desired by
+type uidOwner struct {
+	configmap string
+	key       string
+}
+
+func (o uidOwner) String() string {
+	return fmt.Sprintf("%v key %v", o.configmap, o.key)
+}
+
+// uidConflictError is returned when the uid of the dashboard is desired by another data key
+type uidConflictError struct {
+	uid   string
+	owner uidOwner
+}
+
+func (e *uidConflictError) Error() string {
+	return fmt.Sprintf("the dashboard uid %v is already used by configmap %v, the dashboard is not saved", e.uid, e.owner)
+}
+
+var (
+	desiredUIDsLock sync.Mutex
+	// desiredUIDs are the owners of the dashboard uids, the first data key which resolves to a uid
+	// owns it until its configmap is deleted or the data key resolves to another uid
+	desiredUIDs = map[desiredUID]uidOwner{}
+)
+
+type uidIndexBypassKey struct{}
+
+// withoutUIDIndex returns the context to save the dashboards whatever data keys own their uids,
+// e.g. to restore a backup of the dashboards which the configmaps own
+func withoutUIDIndex(ctx context.Context) context.Context {
+	return context.WithValue(ctx, uidIndexBypassKey{}, true)
+}
+
+func bypassesUIDIndex(ctx context.Context) bool {
+	bypassed, _ := ctx.Value(uidIndexBypassKey{}).(bool)
+	return bypassed
+}
+
+// ownerOf returns the owner of the data key, the per cluster configmaps of the same configmap are different owners
+func ownerOf(cm *corev1.ConfigMap, key string) uidOwner {
+	name := configmapKey(cm)
+	if cluster := managedClusterOf(cm); cluster != "" {
+		name += " for cluster " + cluster
+	}
+	return uidOwner{name, key}
+}
+
+// claimDashboardUID makes the data key the owner of the uid unless another data key owns it already
+func claimDashboardUID(ctx context.Context, orgID string, uid string, cm *corev1.ConfigMap, key string) error {
+	if bypassesUIDIndex(ctx) {
+		return nil
+	}
+	id, owner := desiredUID{targetName(ctx), orgID, uid}, ownerOf(cm, key)
+	desiredUIDsLock.Lock()
+	current, ok := desiredUIDs[id]
+	if !ok {
+		desiredUIDs[id] = owner
+	}
+	desiredUIDsLock.Unlock()
+	if !ok || current == owner {
+		return nil
+	}
+	metrics.DashboardUIDConflicts.Inc()
+	recordEvent(cm, corev1.EventTypeWarning, reasonDashboardUIDConflict,
+		"The dashboard %v is not saved since its uid %v is already used by configmap %v", key, uid, current)
+	return &uidConflictError{uid: uid, owner: current}
+}
+
+// checkDashboardUIDOwner returns the conflict error when the uid is owned by another data key,
+// the dashboard of the data key is not deleted then since it is the dashboard of the owner
+func checkDashboardUIDOwner(ctx context.Context, orgID string, uid string, cm *corev1.ConfigMap, key string) error {
+	desiredUIDsLock.Lock()
+	defer desiredUIDsLock.Unlock()
+	current, ok := desiredUIDs[desiredUID{targetName(ctx), orgID, uid}]
+	if !ok || current == ownerOf(cm, key) {
+		return nil
+	}
+	return &uidConflictError{uid: uid, owner: current}
+}
+
+// releaseDashboardUIDs releases the uids of the configmap in the org except the kept ones,
+// the kept ones are the uids claimed by the latest sync of the configmap keyed by the data key and nil releases all of them
+func releaseDashboardUIDs(ctx context.Context, orgID string, cm *corev1.ConfigMap, kept map[string]string) {
+	if bypassesUIDIndex(ctx) {
+		return
+	}
+	target, name := targetName(ctx), ownerOf(cm, "").configmap
+	desiredUIDsLock.Lock()
+	defer desiredUIDsLock.Unlock()
+	for id, owner := range desiredUIDs {
+		if owner.configmap == name && id.target == target && id.orgID == orgID && kept[owner.key] != id.uid {
+			delete(desiredUIDs, id)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDashboardUIDConflicts(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+
+	first := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "first", Namespace: "test"},
+		Data:       map[string]string{"a.json": `{"uid": "shared", "title": "first"}`},
+	}
+	second := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "second", Namespace: "test"},
+		Data:       map[string]string{"b.json": `{"uid": "shared", "title": "second"}`},
+	}
+	renamed := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "first", Namespace: "test"},
+		Data:       map[string]string{"a.json": `{"uid": "renamed", "title": "first"}`},
+	}
+
+	testCaseList := []struct {
+		name     string
+		apply    func() error
+		hasErr   bool
+		expected string
+	}{
+		{"first owns the uid", func() error { return updateDashboard(context.TODO(), nil, first, false) }, false, "first"},
+
+		{"second conflicts", func() error { return updateDashboard(context.TODO(), nil, second, false) }, true, "first"},
+
+		{"first is applied again", func() error { return updateDashboard(context.TODO(), nil, first, false) }, false, "first"},
+
+		{"second does not delete the dashboard of first", func() error { return deleteDashboard(context.TODO(), second) },
+			false, "first"},
+
+		{"first changes its uid", func() error { return updateDashboard(context.TODO(), first, renamed, false) }, false, "first"},
+
+		{"second owns the released uid", func() error { return updateDashboard(context.TODO(), nil, second, false) },
+			false, "second"},
+	}
+
+	for _, c := range testCaseList {
+		if err := c.apply(); (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+		output, _ := fake.dashboards[""]["shared"].dashboard["title"].(string)
+		if output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestReleaseDashboardUIDs(t *testing.T) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}}
+	other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "test"}}
+
+	testCaseList := []struct {
+		name     string
+		orgID    string
+		kept     map[string]string
+		expected map[string]bool
+	}{
+		{"stale ones", "1", map[string]string{"a.json": "a"}, map[string]bool{"1/a": false, "1/b": true, "2/a": false}},
+
+		{"all", "1", nil, map[string]bool{"1/a": true, "1/b": true, "2/a": false}},
+
+		{"another org", "2", nil, map[string]bool{"1/a": false, "1/b": false, "2/a": true}},
+	}
+
+	for _, c := range testCaseList {
+		restoreUIDs := useEmptyUIDIndex()
+		for _, id := range []desiredUID{{"", "1", "a"}, {"", "1", "b"}, {"", "2", "a"}} {
+			if err := claimDashboardUID(context.TODO(), id.orgID, id.uid, cm, id.uid+".json"); err != nil {
+				t.Fatalf("failed to claim uid %v: %v", id.uid, err)
+			}
+		}
+		releaseDashboardUIDs(context.TODO(), c.orgID, cm, c.kept)
+		for id, expected := range c.expected {
+			orgID, uid := id[:1], id[2:]
+			if released := claimDashboardUID(context.TODO(), orgID, uid, other, uid+".json") == nil; released != expected {
+				t.Errorf("case (%v) uid %v released: (%v) is not the expected: (%v)", c.name, id, released, expected)
+			}
+		}
+		restoreUIDs()
+	}
+}
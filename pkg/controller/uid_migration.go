@@ -0,0 +1,132 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+// uidStrategyLegacy is the name strategy of the former versions which generated the uid with dots as it was
+const uidStrategyLegacy = "legacy"
+
+// MigrateUIDsFrom are the uid strategies which the dashboards may have been applied by before,
+// the dashboard found under the uid of a former strategy is given the current uid in place,
+// so it keeps its id, folder and version history and no duplicate is left behind after the strategy changes
+var MigrateUIDsFrom = []string{uidStrategyLegacy}
+
+var (
+	migratedUIDsLock sync.Mutex
+	// migratedUIDs are the former uids which are checked already keyed by the target, the org and the uid,
+	// so every former uid costs one request per process at most
+	migratedUIDs = map[desiredUID]bool{}
+)
+
+// ValidateMigrateUIDsFrom checks the former uid strategies
+func ValidateMigrateUIDsFrom(strategies []string) error {
+	for _, strategy := range strategies {
+		if strategy == uidStrategyLegacy {
+			continue
+		}
+		if err := ValidateUIDStrategy(strategy); err != nil {
+			return fmt.Errorf("invalid former uid strategy: %v", err)
+		}
+	}
+	return nil
+}
+
+// legacyGenerateUID is the uid generated by the former versions, it was only hashed when it was too long
+func legacyGenerateUID(namespace string, name string) string {
+	uid := namespace + "-" + name
+	if len(uid) > 40 {
+		hasher := fnv.New128a()
+		_, _ = hasher.Write([]byte(uid))
+		uid = hex.EncodeToString(hasher.Sum(nil))
+	}
+	return uid
+}
+
+// formerDashboardUIDs returns the uids which the former strategies resolve for the data key except the current uid,
+// the uid pinned by the annotation does not depend on the strategy so it has no former uid
+func formerDashboardUIDs(cm *corev1.ConfigMap, key string, dashboard map[string]interface{}, uid string) []string {
+	if cm.GetAnnotations()[dashboardUIDKeyPrefix+key] != "" {
+		return nil
+	}
+	formers := []string{}
+	seen := map[string]bool{uid: true}
+	for _, strategy := range MigrateUIDsFrom {
+		var former string
+		if strategy == uidStrategyLegacy {
+			if embedded, _ := dashboard["uid"].(string); embedded != "" {
+				continue
+			}
+			former = legacyGenerateUID(cm.GetName(), cm.GetNamespace())
+		} else {
+			var err error
+			if former, err = strategyUID(strategy, cm, key, dashboard); err != nil {
+				continue
+			}
+		}
+		if cluster := managedClusterOf(cm); cluster != "" {
+			former = clusterDashboardUID(former, cluster)
+		}
+		if !seen[former] {
+			seen[former] = true
+			formers = append(formers, former)
+		}
+	}
+	return formers
+}
+
+// migrateFormerUID finds the dashboard under a former uid and sets its id to the dashboard to save,
+// so that grafana updates it with the current uid instead of creating a duplicate, the migrated uid is returned
+func migrateFormerUID(ctx context.Context, orgID string, cm *corev1.ConfigMap, key string, dashboard map[string]interface{},
+	formers []string) string {
+	for _, former := range formers {
+		id := desiredUID{targetName(ctx), orgID, former}
+		migratedUIDsLock.Lock()
+		checked := migratedUIDs[id]
+		migratedUIDsLock.Unlock()
+		if checked {
+			continue
+		}
+		if err := checkDashboardUIDOwner(ctx, orgID, former, cm, key); err != nil {
+			klog.V(2).InfoS("the dashboard under the former uid is not migrated", "configmap", klog.KObj(cm), "key", key,
+				"reason", err)
+			continue
+		}
+		stored, _, err := clientFor(ctx).GetDashboardWithMeta(ctx, orgID, former)
+		if grafanaStatus(err) == http.StatusNotFound {
+			migratedUIDsLock.Lock()
+			migratedUIDs[id] = true
+			migratedUIDsLock.Unlock()
+			continue
+		}
+		if err != nil {
+			klog.ErrorS(err, "failed to get the dashboard under the former uid", "configmap", klog.KObj(cm), "key", key,
+				"uid", former)
+			continue
+		}
+		dashboard["id"] = stored["id"]
+		return former
+	}
+	return ""
+}
+
+// recordMigratedUID records the dashboard which is saved under the current uid in place of the former one
+func recordMigratedUID(orgID string, cm *corev1.ConfigMap, key string, former string, uid string) {
+	klog.InfoS("the dashboard is migrated from the former uid", "configmap", klog.KObj(cm), "key", key,
+		"former uid", former, "uid", uid, "org", orgID)
+	metrics.DashboardUIDsMigrated.Inc()
+	forgetManagedDashboard(orgID, former)
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
+)
+
+// useEmptyMigratedUIDs makes the former uids checked in the test only
+func useEmptyMigratedUIDs() func() {
+	migratedUIDsLock.Lock()
+	defer migratedUIDsLock.Unlock()
+	original := migratedUIDs
+	migratedUIDs = map[desiredUID]bool{}
+	return func() {
+		migratedUIDsLock.Lock()
+		defer migratedUIDsLock.Unlock()
+		migratedUIDs = original
+	}
+}
+
+func TestFormerDashboardUIDs(t *testing.T) {
+	defer func(strategies []string) { MigrateUIDsFrom = strategies }(MigrateUIDsFrom)
+	current, _ := util.GenerateUID("slo.v2", "test")
+
+	testCaseList := []struct {
+		name        string
+		strategies  []string
+		annotations map[string]string
+		dashboard   map[string]interface{}
+		expected    []string
+	}{
+		{"legacy", []string{uidStrategyLegacy}, nil, map[string]interface{}{}, []string{"slo.v2-test"}},
+
+		{"legacy with embedded uid", []string{uidStrategyLegacy}, nil, map[string]interface{}{"uid": "slo"}, []string{}},
+
+		{"embedded", []string{uidStrategyEmbedded}, nil, map[string]interface{}{"uid": "slo"}, []string{"slo"}},
+
+		{"same as current", []string{uidStrategyName}, nil, map[string]interface{}{}, []string{}},
+
+		{"pinned", []string{uidStrategyLegacy}, map[string]string{dashboardUIDKeyPrefix + "slo.json": "pinned"},
+			map[string]interface{}{}, nil},
+
+		{"none", []string{}, nil, map[string]interface{}{}, []string{}},
+	}
+
+	for _, c := range testCaseList {
+		MigrateUIDsFrom = c.strategies
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "slo.v2", Namespace: "test", Annotations: c.annotations}}
+		output := formerDashboardUIDs(cm, "slo.json", c.dashboard, current)
+		if !reflect.DeepEqual(output, c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestMigrateFormerUID(t *testing.T) {
+	fake, restore := useFakeGrafanaClient()
+	defer restore()
+	grafanaClient = &titleGrafanaClient{fake}
+	defer useEmptyMigratedUIDs()()
+	defer func(strategies []string) { MigrateUIDsFrom = strategies }(MigrateUIDsFrom)
+	current, _ := util.GenerateUID("slo.v2", "test")
+
+	testCaseList := []struct {
+		name       string
+		strategies []string
+		existing   string
+		hasErr     bool
+		expected   []string
+		expectedID float64
+	}{
+		{"migrated", []string{uidStrategyLegacy}, "slo.v2-test", false, []string{current}, 100},
+
+		{"not found", []string{uidStrategyLegacy}, "other", false, []string{current, "other"}, 101},
+
+		{"not migrated", []string{}, "slo.v2-test", true, []string{"slo.v2-test"}, 0},
+	}
+
+	for _, c := range testCaseList {
+		MigrateUIDsFrom = c.strategies
+		fake.nextID = 100
+		fake.dashboards = map[string]map[string]fakeDashboard{"": {c.existing: {id: 100, folderID: 0,
+			dashboard: map[string]interface{}{"uid": c.existing, "title": "SLO"}}}}
+		if c.existing == "other" {
+			fake.dashboards[""][c.existing].dashboard["title"] = "Other"
+		}
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "slo.v2", Namespace: "test",
+				Labels: map[string]string{generalFolderKey: "true"}},
+			Data: map[string]string{"slo.json": `{"title": "SLO"}`},
+		}
+		if err := updateDashboard(context.TODO(), nil, cm, false); (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+		for _, uid := range c.expected {
+			if _, ok := fake.dashboards[""][uid]; !ok {
+				t.Errorf("case (%v) the dashboard (%v) is not found", c.name, uid)
+			}
+		}
+		if len(fake.dashboards[""]) != len(c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, fake.dashboards[""], c.expected)
+		}
+		if d, ok := fake.dashboards[""][current]; ok && d.id != c.expectedID {
+			t.Errorf("case (%v) the dashboard id: (%v) is not the expected: (%v)", c.name, d.id, c.expectedID)
+		}
+		deleteDashboard(context.TODO(), cm)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/util"
+)
+
+const (
+	// uidStrategyName uses the embedded uid, or the one generated from the name and the namespace of the configmap
+	// which all the dashboards of the configmap share
+	uidStrategyName = "name"
+	// uidStrategyHash hashes the namespace, the name and the data key, so every dashboard has its own stable uid
+	// whatever uid it embeds
+	uidStrategyHash = "hash"
+	// uidStrategyEmbedded passes the embedded uid through, the dashboard without uid is not applied
+	uidStrategyEmbedded = "embedded"
+
+	// dashboardUIDStrategyKey is the annotation to use another uid strategy for the dashboards of the configmap
+	dashboardUIDStrategyKey = "observability.open-cluster-management.io/dashboard-uid-strategy"
+)
+
+// UIDStrategy is how the uid of the dashboards is chosen unless the configmap says otherwise,
+// the uid pinned by the annotation of a data key always takes precedence
+var UIDStrategy = uidStrategyName
+
+// ValidateUIDStrategy checks the uid strategy
+func ValidateUIDStrategy(strategy string) error {
+	switch strategy {
+	case uidStrategyName, uidStrategyHash, uidStrategyEmbedded:
+		return nil
+	}
+	return fmt.Errorf("invalid uid strategy %q, it should be %v, %v or %v", strategy,
+		uidStrategyName, uidStrategyHash, uidStrategyEmbedded)
+}
+
+// configmapUIDStrategy returns the uid strategy of the configmap
+func configmapUIDStrategy(cm *corev1.ConfigMap) (string, error) {
+	strategy, ok := cm.GetAnnotations()[dashboardUIDStrategyKey]
+	if !ok {
+		return UIDStrategy, nil
+	}
+	if err := ValidateUIDStrategy(strategy); err != nil {
+		return "", fmt.Errorf("invalid annotation %v: %v", dashboardUIDStrategyKey, err)
+	}
+	return strategy, nil
+}
+
+// strategyUID returns the uid of the dashboard by the strategy
+func strategyUID(strategy string, cm *corev1.ConfigMap, key string, dashboard map[string]interface{}) (string, error) {
+	embedded, _ := dashboard["uid"].(string)
+	switch strategy {
+	case uidStrategyHash:
+		hasher := fnv.New128a()
+		if _, err := hasher.Write([]byte(cm.GetNamespace() + "/" + cm.GetName() + "/" + key)); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(hasher.Sum(nil)), nil
+	case uidStrategyEmbedded:
+		if embedded == "" {
+			return "", fmt.Errorf("%v has no uid while the uid strategy is %v", key, uidStrategyEmbedded)
+		}
+		return embedded, validateEmbeddedUID(key, embedded)
+	}
+	if embedded != "" {
+		return embedded, validateEmbeddedUID(key, embedded)
+	}
+	return util.GenerateUID(cm.GetName(), cm.GetNamespace())
+}
+
+// validateEmbeddedUID rejects the embedded uid which grafana would reject with a bare 400
+func validateEmbeddedUID(key string, uid string) error {
+	if err := util.ValidateUID(uid); err != nil {
+		return fmt.Errorf("the embedded uid of %v is invalid: %v, fix the uid in the dashboard or pin another one with annotation %v",
+			key, err, dashboardUIDKeyPrefix+key)
+	}
+	return nil
+}
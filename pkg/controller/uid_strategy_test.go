@@ -0,0 +1,86 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateUIDStrategy(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		strategy string
+		hasErr   bool
+	}{
+		{"name", uidStrategyName, false},
+
+		{"hash", uidStrategyHash, false},
+
+		{"embedded", uidStrategyEmbedded, false},
+
+		{"unknown", "random", true},
+	}
+
+	for _, c := range testCaseList {
+		if err := ValidateUIDStrategy(c.strategy); (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+	}
+}
+
+func TestGetDashboardUIDByStrategy(t *testing.T) {
+	defer func(strategy string) { UIDStrategy = strategy }(UIDStrategy)
+
+	hashed, _ := strategyUID(uidStrategyHash, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}},
+		"test.json", nil)
+	testCaseList := []struct {
+		name        string
+		strategy    string
+		annotations map[string]string
+		dashboard   map[string]interface{}
+		expected    string
+		hasErr      bool
+	}{
+		{"name generated", uidStrategyName, nil, map[string]interface{}{}, "test-test", false},
+
+		{"name embedded", uidStrategyName, nil, map[string]interface{}{"uid": "embedded"}, "embedded", false},
+
+		{"hash", uidStrategyHash, nil, map[string]interface{}{"uid": "embedded"}, hashed, false},
+
+		{"embedded", uidStrategyEmbedded, nil, map[string]interface{}{"uid": "embedded"}, "embedded", false},
+
+		{"embedded missing", uidStrategyEmbedded, nil, map[string]interface{}{}, "", true},
+
+		{"overridden by configmap", uidStrategyEmbedded, map[string]string{dashboardUIDStrategyKey: uidStrategyName},
+			map[string]interface{}{}, "test-test", false},
+
+		{"invalid configmap strategy", uidStrategyName, map[string]string{dashboardUIDStrategyKey: "random"},
+			map[string]interface{}{}, "", true},
+
+		{"embedded too long", uidStrategyName, nil, map[string]interface{}{"uid": strings.Repeat("a", 41)}, "", true},
+
+		{"embedded with a dot", uidStrategyEmbedded, nil, map[string]interface{}{"uid": "team.a"}, "", true},
+
+		{"hash ignores the invalid embedded uid", uidStrategyHash, nil, map[string]interface{}{"uid": "team.a"}, hashed, false},
+
+		{"invalid pinned", uidStrategyName, map[string]string{dashboardUIDKeyPrefix + "test.json": "team/a"},
+			map[string]interface{}{}, "", true},
+
+		{"pinned", uidStrategyEmbedded, map[string]string{dashboardUIDKeyPrefix + "test.json": "pinned"},
+			map[string]interface{}{}, "pinned", false},
+	}
+
+	for _, c := range testCaseList {
+		UIDStrategy = c.strategy
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Annotations: c.annotations}}
+		output, err := getDashboardUID(cm, "test.json", c.dashboard)
+		if output != c.expected || (err != nil) != c.hasErr {
+			t.Errorf("case (%v) output: (%v, %v) is not the expected: (%v, %v)", c.name, output, err, c.expected, c.hasErr)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+// WatchFailureThreshold is how long the watch of a resource may keep failing before it is reported
+// by a warning event on the loader pod, which is named by the POD_NAME env, and the watch_failing metric
+var WatchFailureThreshold = 5 * time.Minute
+
+// watchRecoveryGap is longer than the maximum backoff of the reflector, the watch is established again
+// once no failure is seen for so long
+const watchRecoveryGap = time.Minute
+
+// watchHealth tracks the consecutive failures of the watch of a resource
+type watchHealth struct {
+	resource string
+	// first and last are the times of the first and the last failure in a row
+	first     time.Time
+	last      time.Time
+	escalated bool
+}
+
+var (
+	watchHealthLock sync.Mutex
+	watchHealths    = map[string]*watchHealth{}
+)
+
+// watchFailureReason classifies the error of the list and watch
+func watchFailureReason(err error) string {
+	switch {
+	case apierrors.IsResourceExpired(err) || apierrors.IsGone(err):
+		return "expired"
+	case err == io.EOF:
+		return "closed"
+	case err == io.ErrUnexpectedEOF:
+		return "disconnected"
+	default:
+		return "error"
+	}
+}
+
+// failed records the failure and escalates once the failures last longer than the threshold
+func (h *watchHealth) failed(err error, now time.Time) {
+	watchHealthLock.Lock()
+	defer watchHealthLock.Unlock()
+	if h.last.IsZero() || now.Sub(h.last) > watchRecoveryGap {
+		h.first = now
+	}
+	h.last = now
+	if h.escalated || now.Sub(h.first) < WatchFailureThreshold {
+		return
+	}
+	h.escalated = true
+	metrics.WatchFailing.WithLabelValues(h.resource).Set(1)
+	klog.ErrorS(err, "the watch cannot be established, the dashboards of the resource are not kept in sync",
+		"resource", h.resource, "since", h.first)
+	if pod := loaderPod(); pod != nil {
+		recordEvent(pod, corev1.EventTypeWarning, reasonWatchFailing, "The watch of %v has been failing since %v: %v",
+			h.resource, h.first.Format(time.RFC3339), err)
+	}
+}
+
+// refresh clears the escalation once no failure is seen for the recovery gap
+func (h *watchHealth) refresh(now time.Time) {
+	watchHealthLock.Lock()
+	defer watchHealthLock.Unlock()
+	if !h.escalated || now.Sub(h.last) <= watchRecoveryGap {
+		return
+	}
+	h.escalated = false
+	metrics.WatchFailing.WithLabelValues(h.resource).Set(0)
+	klog.InfoS("the watch is established again", "resource", h.resource, "failedSince", h.first)
+}
+
+// loaderPod returns the reference of the loader pod for the events, nil without the POD_NAME env
+func loaderPod() *corev1.ObjectReference {
+	if os.Getenv("POD_NAME") == "" {
+		return nil
+	}
+	return &corev1.ObjectReference{Kind: "Pod", APIVersion: "v1", Name: os.Getenv("POD_NAME"), Namespace: os.Getenv("POD_NAMESPACE")}
+}
+
+// handleWatchErrors makes the informer of the resource record its list and watch failures,
+// the default handler still logs them
+func handleWatchErrors(resource string, informer cache.SharedIndexInformer) cache.SharedIndexInformer {
+	watchHealthLock.Lock()
+	health, ok := watchHealths[resource]
+	if !ok {
+		health = &watchHealth{resource: resource}
+		watchHealths[resource] = health
+	}
+	watchHealthLock.Unlock()
+	metrics.WatchFailing.WithLabelValues(resource).Set(0)
+	err := informer.SetWatchErrorHandler(func(r *cache.Reflector, err error) {
+		reason := watchFailureReason(err)
+		metrics.WatchErrors.WithLabelValues(resource, reason).Inc()
+		cache.DefaultWatchErrorHandler(r, err)
+		// the expired and the closed watches are established again at once
+		if reason != "expired" && reason != "closed" {
+			health.failed(err, time.Now())
+		}
+	})
+	if err != nil {
+		klog.ErrorS(err, "failed to handle the watch errors", "resource", resource)
+	}
+	return informer
+}
+
+// runWatchHealth clears the escalations of the watches which are established again until ctx is done
+func runWatchHealth(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		watchHealthLock.Lock()
+		healths := []*watchHealth{}
+		for _, health := range watchHealths {
+			healths = append(healths, health)
+		}
+		watchHealthLock.Unlock()
+		for _, health := range healths {
+			health.refresh(time.Now())
+		}
+	}, watchRecoveryGap)
+}
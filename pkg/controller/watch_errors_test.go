@@ -0,0 +1,79 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+func TestWatchFailureReason(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"expired", apierrors.NewResourceExpired("too old resource version"), "expired"},
+
+		{"gone", apierrors.NewGone("gone"), "expired"},
+
+		{"closed", io.EOF, "closed"},
+
+		{"disconnected", io.ErrUnexpectedEOF, "disconnected"},
+
+		{"forbidden", apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "", errors.New("denied")), "error"},
+	}
+
+	for _, c := range testCaseList {
+		if output := watchFailureReason(c.err); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestWatchHealth(t *testing.T) {
+	defer func(threshold time.Duration) { WatchFailureThreshold = threshold }(WatchFailureThreshold)
+	WatchFailureThreshold = 90 * time.Second
+	health := &watchHealth{resource: "watch-test"}
+	start := time.Now()
+	err := errors.New("connection refused")
+
+	testCaseList := []struct {
+		name     string
+		failed   bool
+		after    time.Duration
+		expected float64
+	}{
+		{"first failure", true, 0, 0},
+
+		{"failing in a row", true, 45 * time.Second, 0},
+
+		{"failing for long", true, 90 * time.Second, 1},
+
+		{"still failing", false, 120 * time.Second, 1},
+
+		{"established again", false, 90*time.Second + 2*watchRecoveryGap, 0},
+
+		{"new failure", true, 90*time.Second + 3*watchRecoveryGap, 0},
+	}
+
+	for _, c := range testCaseList {
+		if c.failed {
+			health.failed(err, start.Add(c.after))
+		} else {
+			health.refresh(start.Add(c.after))
+		}
+		if output := testutil.ToFloat64(metrics.WatchFailing.WithLabelValues("watch-test")); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
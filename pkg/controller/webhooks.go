@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	webhookEventApplyFailed = "DashboardApplyFailed"
+	webhookEventDeleted     = "DashboardDeleted"
+	// webhookEventDrift is sent when the dashboard was changed in grafana since the loader applied it
+	webhookEventDrift = "DashboardDriftDetected"
+)
+
+var (
+	// WebhookURLs receive the notifications as json
+	WebhookURLs = []string{}
+	// SlackWebhookURLs receive the notifications as slack incoming webhook messages
+	SlackWebhookURLs = []string{}
+	// WebhookTimeout limits the time to deliver a notification to every webhook
+	WebhookTimeout = 10 * time.Second
+)
+
+// notification is the payload of the generic webhooks
+type notification struct {
+	Event     string    `json:"event"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Key       string    `json:"key,omitempty"`
+	UID       string    `json:"uid,omitempty"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"time"`
+}
+
+// slackText is the message of the notification for slack
+func (n notification) slackText() string {
+	text := fmt.Sprintf("*%v* %v/%v", n.Event, n.Namespace, n.Name)
+	if n.Key != "" {
+		text += fmt.Sprintf(" key `%v`", n.Key)
+	}
+	if n.UID != "" {
+		text += fmt.Sprintf(" uid `%v`", n.UID)
+	}
+	return text + ": " + n.Message
+}
+
+// notifyWebhooks sends the notification about the dashboards of the configmap to all the webhooks in the background,
+// the failed deliveries are logged and not retried
+func notifyWebhooks(event string, cm *corev1.ConfigMap, key string, uid string, message string) {
+	if len(WebhookURLs) == 0 && len(SlackWebhookURLs) == 0 {
+		return
+	}
+	n := notification{
+		Event:     event,
+		Namespace: cm.GetNamespace(),
+		Name:      cm.GetName(),
+		Key:       key,
+		UID:       uid,
+		Message:   message,
+		Time:      time.Now().UTC(),
+	}
+	for _, url := range WebhookURLs {
+		go sendWebhook(url, n)
+	}
+	slack := map[string]string{"text": n.slackText()}
+	for _, url := range SlackWebhookURLs {
+		go sendWebhook(url, slack)
+	}
+}
+
+func sendWebhook(url string, payload interface{}) {
+	if err := postWebhook(url, payload); err != nil {
+		klog.ErrorS(err, "failed to send the webhook notification")
+	}
+}
+
+func postWebhook(url string, payload interface{}) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), WebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// the url is not logged since it carries the token of the slack webhooks
+		return fmt.Errorf("the webhook responded with %v", resp.StatusCode)
+	}
+	return nil
+}
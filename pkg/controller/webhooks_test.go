@@ -0,0 +1,79 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package controller
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNotifyWebhooks(t *testing.T) {
+	received := make(chan map[string]interface{}, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		payload := map[string]interface{}{}
+		json.Unmarshal(body, &payload)
+		payload["path"] = r.URL.Path
+		received <- payload
+	}))
+	defer server.Close()
+
+	WebhookURLs = []string{server.URL + "/generic"}
+	SlackWebhookURLs = []string{server.URL + "/slack"}
+	defer func() {
+		WebhookURLs = []string{}
+		SlackWebhookURLs = []string{}
+	}()
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}}
+	notifyWebhooks(webhookEventDeleted, cm, "a.json", "a", "the dashboard is deleted from grafana")
+
+	payloads := map[string]map[string]interface{}{}
+	for i := 0; i < 2; i++ {
+		select {
+		case payload := <-received:
+			payloads[payload["path"].(string)] = payload
+		case <-time.After(5 * time.Second):
+			t.Fatalf("the notifications are not delivered: %v", payloads)
+		}
+	}
+
+	testCaseList := []struct {
+		name     string
+		output   interface{}
+		expected interface{}
+	}{
+		{"generic event", payloads["/generic"]["event"], webhookEventDeleted},
+
+		{"generic uid", payloads["/generic"]["uid"], "a"},
+
+		{"generic configmap", payloads["/generic"]["name"], "test"},
+	}
+	for _, c := range testCaseList {
+		if c.output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, c.output, c.expected)
+		}
+	}
+	if text, _ := payloads["/slack"]["text"].(string); !strings.Contains(text, webhookEventDeleted) || !strings.Contains(text, "test/test") {
+		t.Errorf("case (slack) output: (%v) does not tell the event and the configmap", text)
+	}
+}
+
+func TestPostWebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	if err := postWebhook(server.URL, map[string]string{"text": "test"}); err == nil {
+		t.Errorf("the rejected notification should fail")
+	}
+}
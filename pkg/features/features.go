@@ -0,0 +1,38 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package features
+
+import (
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/component-base/featuregate"
+)
+
+const (
+	// EmptyFolderCleanup deletes the custom folders once their last dashboard is removed
+	EmptyFolderCleanup featuregate.Feature = "EmptyFolderCleanup"
+	// DashboardSchemaValidation rejects the dashboards which do not match the bundled grafana schema before they are saved
+	DashboardSchemaValidation featuregate.Feature = "DashboardSchemaValidation"
+)
+
+// DefaultMutableFeatureGate is set by the --feature-gates flag
+var DefaultMutableFeatureGate featuregate.MutableFeatureGate = featuregate.NewFeatureGate()
+
+// DefaultFeatureGate is the read only view of DefaultMutableFeatureGate
+var DefaultFeatureGate featuregate.FeatureGate = DefaultMutableFeatureGate
+
+// defaultFeatureGates are all the known features, a new destructive or experimental behavior
+// is added as alpha and disabled by default so that it can be enabled per environment
+var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
+	EmptyFolderCleanup:        {Default: true, PreRelease: featuregate.Beta},
+	DashboardSchemaValidation: {Default: false, PreRelease: featuregate.Alpha},
+}
+
+func init() {
+	runtime.Must(DefaultMutableFeatureGate.Add(defaultFeatureGates))
+}
+
+// Enabled checks whether the feature is enabled
+func Enabled(feature featuregate.Feature) bool {
+	return DefaultFeatureGate.Enabled(feature)
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package features
+
+import (
+	"testing"
+)
+
+func TestFeatureGates(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		value    string
+		expected bool
+		err      bool
+	}{
+		{"default", "", true, false},
+
+		{"disabled", "EmptyFolderCleanup=false", false, false},
+
+		{"all beta disabled", "AllBeta=false", false, false},
+
+		{"unknown feature", "NestedFolders=true", true, true},
+
+		{"invalid value", "EmptyFolderCleanup=maybe", true, true},
+	}
+
+	for _, c := range testCaseList {
+		gate := DefaultMutableFeatureGate.DeepCopy()
+		err := gate.Set(c.value)
+		if (err != nil) != c.err {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.err)
+		}
+		if gate.Enabled(EmptyFolderCleanup) != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, gate.Enabled(EmptyFolderCleanup), c.expected)
+		}
+	}
+}
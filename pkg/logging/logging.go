@@ -0,0 +1,132 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
+)
+
+// Format is the format of the logs, text or json
+var Format = "text"
+
+// Setup switches klog to the log format, the verbosity is still decided by the klog -v flag
+func Setup() error {
+	switch Format {
+	case "text":
+		return nil
+	case "json":
+		klog.SetLogger(NewJSONLogger(os.Stderr))
+		return nil
+	default:
+		return fmt.Errorf("unknown log format %v, only text and json are supported", Format)
+	}
+}
+
+// jsonLogger writes every log as a json line with the timestamp, level, message and the key values
+type jsonLogger struct {
+	lock   *sync.Mutex
+	w      io.Writer
+	level  int
+	name   string
+	values []interface{}
+}
+
+// NewJSONLogger returns the logger which writes the json lines into w
+func NewJSONLogger(w io.Writer) logr.Logger {
+	return &jsonLogger{lock: &sync.Mutex{}, w: w}
+}
+
+// Enabled is always true since klog already checks the verbosity
+func (l *jsonLogger) Enabled() bool {
+	return true
+}
+
+func (l *jsonLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.write("info", nil, msg, keysAndValues)
+}
+
+func (l *jsonLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.write("error", err, msg, keysAndValues)
+}
+
+func (l *jsonLogger) V(level int) logr.Logger {
+	logger := *l
+	logger.level = level
+	return &logger
+}
+
+func (l *jsonLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	logger := *l
+	logger.values = append(append([]interface{}{}, l.values...), keysAndValues...)
+	return &logger
+}
+
+func (l *jsonLogger) WithName(name string) logr.Logger {
+	logger := *l
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	logger.name = name
+	return &logger
+}
+
+func (l *jsonLogger) write(level string, err error, msg string, keysAndValues []interface{}) {
+	entry := map[string]interface{}{
+		"ts":    time.Now().UTC().Format(time.RFC3339Nano),
+		"level": level,
+		"msg":   strings.TrimSuffix(msg, "\n"),
+	}
+	if l.level > 0 {
+		entry["v"] = l.level
+	}
+	if l.name != "" {
+		entry["logger"] = l.name
+	}
+	if err != nil {
+		entry["err"] = err.Error()
+	}
+	addKeysAndValues(entry, l.values)
+	addKeysAndValues(entry, keysAndValues)
+
+	b, marshalErr := json.Marshal(entry)
+	if marshalErr != nil {
+		b, _ = json.Marshal(map[string]interface{}{"ts": entry["ts"], "level": level, "msg": entry["msg"],
+			"err": fmt.Sprintf("failed to marshal the log: %v", marshalErr)})
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.w.Write(append(b, '\n'))
+}
+
+func addKeysAndValues(entry map[string]interface{}, keysAndValues []interface{}) {
+	// klog passes the key values of InfoS and ErrorS as one slice
+	if len(keysAndValues) == 1 {
+		if nested, ok := keysAndValues[0].([]interface{}); ok {
+			keysAndValues = nested
+		}
+	}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		var value interface{} = "(MISSING)"
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		switch v := value.(type) {
+		case error:
+			value = v.Error()
+		case fmt.Stringer:
+			value = v.String()
+		}
+		entry[key] = value
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"k8s.io/klog/v2"
+)
+
+func TestJSONLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewJSONLogger(buf)
+
+	testCaseList := []struct {
+		name     string
+		log      func()
+		expected map[string]interface{}
+	}{
+		{"info", func() { logger.Info("dashboard created/updated\n", "key", "test.json", "status", 200) },
+			map[string]interface{}{"level": "info", "msg": "dashboard created/updated", "key": "test.json", "status": 200.0}},
+
+		{"error", func() { logger.Error(fmt.Errorf("conflict"), "failed to create/update dashboard", "uid", "slo") },
+			map[string]interface{}{"level": "error", "msg": "failed to create/update dashboard", "err": "conflict", "uid": "slo"}},
+
+		{"klog key values", func() { logger.Info("dashboard deleted", []interface{}{"configmap", klog.KRef("ns", "cm")}) },
+			map[string]interface{}{"msg": "dashboard deleted", "configmap": "ns/cm"}},
+
+		{"verbosity and values", func() { logger.V(4).WithValues("source", "git").WithName("loader").Info("skip") },
+			map[string]interface{}{"msg": "skip", "v": 4.0, "source": "git", "logger": "loader"}},
+
+		{"missing value", func() { logger.Info("odd", "key") },
+			map[string]interface{}{"msg": "odd", "key": "(MISSING)"}},
+	}
+
+	for _, c := range testCaseList {
+		buf.Reset()
+		c.log()
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Errorf("case (%v) output: (%v) is not json: %v", c.name, buf.String(), err)
+			continue
+		}
+		if entry["ts"] == nil {
+			t.Errorf("case (%v) output: (%v) has no timestamp", c.name, entry)
+		}
+		for key, value := range c.expected {
+			if entry[key] != value {
+				t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, entry[key], value)
+			}
+		}
+	}
+}
+
+func TestSetup(t *testing.T) {
+	defer func(format string) { Format = format }(Format)
+	Format = "xml"
+	if err := Setup(); err == nil {
+		t.Errorf("the unknown log format should be rejected")
+	}
+}
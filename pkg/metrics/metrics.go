@@ -0,0 +1,427 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/klog/v2"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/version"
+)
+
+const namespace = "grafana_dashboard_loader"
+
+var (
+	// BuildInfo is always 1, the labels tell the version of the running loader
+	BuildInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "build_info",
+			Help:      "The version of the running loader, the value is always 1.",
+		},
+		[]string{"version", "commit", "goversion"},
+	)
+
+	// BackupLastSuccessTimestamp is the time of the last successful backup of the managed dashboards
+	BackupLastSuccessTimestamp = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "backup_last_success_timestamp_seconds",
+			Help:      "The unix time of the last successful backup of the managed dashboards.",
+		},
+	)
+
+	// BackupFailures counts the failed backups of the managed dashboards
+	BackupFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "backup_failures_total",
+			Help:      "The number of failed backups of the managed dashboards.",
+		},
+	)
+
+	// DashboardsRetained counts the deleted configmaps whose dashboards were left in grafana
+	DashboardsRetained = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dashboards_retained_total",
+			Help:      "The number of deleted dashboard configmaps whose dashboards were retained in grafana.",
+		},
+	)
+
+	// GrafanaConditionalHits counts the grafana GET requests answered with 304 Not Modified from the cached responses
+	GrafanaConditionalHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "grafana_conditional_hits_total",
+			Help:      "The number of grafana GET requests which were not modified and were served from the cached responses.",
+		},
+	)
+
+	// DashboardsUnchanged counts the dashboards which are not saved since the stored ones are the same
+	DashboardsUnchanged = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dashboards_unchanged_total",
+			Help:      "The number of dashboards which were not saved since the dashboards stored in grafana were the same.",
+		},
+	)
+
+	// DashboardUIDConflicts counts the dashboards which are not saved since their uid belongs to another data key
+	DashboardUIDConflicts = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dashboard_uid_conflicts_total",
+			Help:      "The number of dashboards which were not saved since another configmap or data key had the same dashboard uid.",
+		},
+	)
+
+	// DashboardsAdopted counts the existing dashboards which are taken over by the loader
+	DashboardsAdopted = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dashboards_adopted_total",
+			Help:      "The number of the existing dashboards with the same titles which were adopted instead of failing with name-exists.",
+		},
+	)
+
+	// DashboardsRolledBack counts the dashboards which are restored to a former version of their grafana history
+	DashboardsRolledBack = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dashboard_rollbacks_total",
+			Help:      "The number of the dashboards which were restored to a former grafana version by the rollback annotation or the admin api.",
+		},
+	)
+
+	// DashboardUIDsMigrated counts the dashboards which are given the current uid in place of the uid of a former strategy
+	DashboardUIDsMigrated = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dashboard_uids_migrated_total",
+			Help:      "The number of dashboards which were found under the uids of the former uid strategies and given the current uids.",
+		},
+	)
+
+	// DashboardsOversized counts the dashboards which are rejected by the size limit of grafana or the configmaps
+	DashboardsOversized = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dashboards_oversized_total",
+			Help:      "The number of the dashboards which are rejected as too large by the limit, grafana-request or configmap.",
+		},
+		[]string{"limit"},
+	)
+
+	// AngularPanels counts the angular panels found in the applied dashboards by the panel type
+	AngularPanels = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "angular_panels_total",
+			Help:      "The number of the angular panels found in the applied dashboards, they are not rendered by grafana 11 and later.",
+		},
+		[]string{"panel_type"},
+	)
+
+	// PolicyViolations counts the policy violations found in the applied dashboards by the policy mode
+	PolicyViolations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "policy_violations_total",
+			Help:      "The number of the banned panels, queries and variables found in the applied dashboards.",
+		},
+		[]string{"mode"},
+	)
+
+	// ConfigmapsMatched is the number of the dashboard configmaps currently matched by every source
+	ConfigmapsMatched = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "configmaps_matched",
+			Help:      "The number of the dashboard configmaps currently matched by the source.",
+		},
+		[]string{"source"},
+	)
+
+	// DashboardsManaged is the number of the dashboards saved to grafana by the loader
+	DashboardsManaged = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "dashboards_managed",
+			Help:      "The number of the dashboards saved to grafana by the loader.",
+		},
+	)
+
+	// CustomFolders is the number of the custom folders which hold the managed dashboards
+	CustomFolders = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "custom_folders",
+			Help:      "The number of the custom folders which hold the managed dashboards.",
+		},
+	)
+
+	// SyncsPending is the number of the configmaps which are being applied to grafana
+	SyncsPending = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "syncs_pending",
+			Help:      "The number of the dashboard configmaps which are being applied to grafana.",
+		},
+	)
+
+	// SyncsRetrying is the number of the configmaps whose last sync failed and which are applied again on the next resync
+	SyncsRetrying = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "syncs_retrying",
+			Help:      "The number of the dashboard configmaps whose last sync failed and which are applied again on the next resync.",
+		},
+		[]string{"source"},
+	)
+
+	// RetryQueueItems is the number of the configmaps which still need to be applied or deleted by the operation
+	RetryQueueItems = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "retry_queue_items",
+			Help:      "The number of the dashboard configmaps which still need to be applied or deleted after a failure.",
+		},
+		[]string{"op"},
+	)
+
+	// SyncPanics counts the panics which are recovered while a configmap is handled by the operation
+	SyncPanics = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sync_panics_total",
+			Help:      "The number of the panics which are recovered while a dashboard configmap is handled, the configmap is reported as failed.",
+		},
+		[]string{"op"},
+	)
+
+	// CoalescedUpdates counts the updates of the configmaps which are superseded by a later update within the debounce window
+	CoalescedUpdates = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "coalesced_updates_total",
+			Help:      "The number of the configmap updates which are not applied because a later update came within the debounce window.",
+		},
+	)
+
+	// WatchErrors counts the failures of the list and watch of the informers by the resource and the reason,
+	// the expired ones make the informer list the resource again
+	WatchErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "watch_errors_total",
+			Help:      "The number of the failed lists and watches of the informers, expired means the resource is listed again.",
+		},
+		[]string{"resource", "reason"},
+	)
+
+	// FreezeWindowActive is 1 while a freeze window defers the changes of the dashboards
+	FreezeWindowActive = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "freeze_window_active",
+			Help:      "Whether a freeze window is in effect, the dashboards are applied and deleted once it ends.",
+		},
+	)
+
+	// WatchFailing is 1 while the watch of the resource cannot be established for longer than the failure threshold
+	WatchFailing = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "watch_failing",
+			Help:      "Whether the watch of the resource has been failing for longer than the failure threshold.",
+		},
+		[]string{"resource"},
+	)
+
+	// GitLastSyncedCommit is 1 for the commit of the git source which was synced last time
+	GitLastSyncedCommit = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "git_last_synced_commit",
+			Help:      "The commit of the git source which was synced to grafana last time.",
+		},
+		[]string{"repository", "ref", "commit"},
+	)
+
+	// GitLastSyncTimestamp is the time of the last successful sync of the git source
+	GitLastSyncTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "git_last_sync_timestamp_seconds",
+			Help:      "The unix time of the last successful sync of the git source.",
+		},
+		[]string{"repository", "ref"},
+	)
+
+	// GitSyncFailures counts the failed syncs of the git source
+	GitSyncFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "git_sync_failures_total",
+			Help:      "The number of failed syncs of the git source.",
+		},
+		[]string{"repository", "ref"},
+	)
+
+	// OCILastSyncedDigest is 1 for the digest of the oci artifact which was synced last time
+	OCILastSyncedDigest = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "oci_last_synced_digest",
+			Help:      "The manifest digest of the oci artifact which was synced to grafana last time.",
+		},
+		[]string{"artifact", "digest"},
+	)
+
+	// OCILastSyncTimestamp is the time of the last successful sync of the oci artifact
+	OCILastSyncTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "oci_last_sync_timestamp_seconds",
+			Help:      "The unix time of the last successful sync of the oci artifact.",
+		},
+		[]string{"artifact"},
+	)
+
+	// OCISyncFailures counts the failed syncs of the oci artifact
+	OCISyncFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "oci_sync_failures_total",
+			Help:      "The number of failed syncs of the oci artifact.",
+		},
+		[]string{"artifact"},
+	)
+
+	// CircuitBreakerState is the state of the circuit breaker around the grafana api,
+	// 0 is closed, 1 is open and 2 is half-open
+	CircuitBreakerState = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "circuit_breaker_state",
+			Help:      "The state of the circuit breaker around the grafana api, 0 is closed, 1 is open and 2 is half-open.",
+		},
+	)
+
+	// CircuitBreakerTransitions counts the state changes of the circuit breaker
+	CircuitBreakerTransitions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "circuit_breaker_transitions_total",
+			Help:      "The number of state changes of the circuit breaker around the grafana api.",
+		},
+		[]string{"state"},
+	)
+
+	// GrafanaRequestDuration observes the duration of the requests to grafana
+	GrafanaRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "grafana_request_duration_seconds",
+			Help:      "The duration of the requests to grafana.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"method", "endpoint"},
+	)
+
+	// GrafanaRequests counts the requests to grafana by the response code, the code is "error" without a response
+	GrafanaRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "grafana_requests_total",
+			Help:      "The number of requests to grafana by the response code.",
+		},
+		[]string{"method", "endpoint", "code"},
+	)
+
+	// GrafanaRequestsInFlight is the number of requests to grafana waiting for the response
+	GrafanaRequestsInFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "grafana_requests_in_flight",
+			Help:      "The number of requests to grafana waiting for the response.",
+		},
+	)
+
+	// GrafanaResets counts the grafana instances which are found empty and got all the dashboards applied again
+	GrafanaResets = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "grafana_resets_total",
+			Help:      "The number of times grafana is found reinstalled without its database and all the dashboards are applied again.",
+		},
+		[]string{"target"},
+	)
+)
+
+func init() {
+	info := version.Get()
+	BuildInfo.WithLabelValues(info.Version, info.GitCommit, info.GoVersion).Set(1)
+	prometheus.MustRegister(
+		BuildInfo,
+		DashboardsRetained,
+		DashboardsUnchanged,
+		DashboardUIDConflicts,
+		DashboardsAdopted,
+		DashboardUIDsMigrated,
+		DashboardsRolledBack,
+		GrafanaConditionalHits,
+		DashboardsOversized,
+		AngularPanels,
+		PolicyViolations,
+		GitLastSyncedCommit,
+		GitLastSyncTimestamp,
+		GitSyncFailures,
+		OCILastSyncedDigest,
+		OCILastSyncTimestamp,
+		OCISyncFailures,
+		CircuitBreakerState,
+		CircuitBreakerTransitions,
+		GrafanaRequestDuration,
+		GrafanaRequests,
+		GrafanaRequestsInFlight,
+		GrafanaResets,
+		BackupLastSuccessTimestamp,
+		BackupFailures,
+		ConfigmapsMatched,
+		DashboardsManaged,
+		CustomFolders,
+		SyncsPending,
+		SyncsRetrying,
+		RetryQueueItems,
+		SyncPanics,
+		CoalescedUpdates,
+		WatchErrors,
+		WatchFailing,
+		FreezeWindowActive,
+	)
+}
+
+// Serve exposes the metrics on the /metrics endpoint and the version on the /version endpoint of the address
+func Serve(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/version", serveVersion)
+	err := http.ListenAndServe(addr, mux)
+	if err != nil {
+		klog.Error("failed to serve the metrics ", "error ", err)
+	}
+}
+
+func serveVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(version.Get()); err != nil {
+		klog.ErrorS(err, "failed to write the version")
+	}
+}
@@ -0,0 +1,341 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	imageManifestType    = "application/vnd.oci.image.manifest.v1+json"
+	artifactManifestType = "application/vnd.oci.artifact.manifest.v1+json"
+	// titleAnnotation is the file name of a layer pushed by oras
+	titleAnnotation = "org.opencontainers.image.title"
+	// maxBlobSize protects the loader from unexpectedly large artifacts
+	maxBlobSize = 64 << 20
+)
+
+// Descriptor describes a blob of the artifact
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an oci image manifest or an oras artifact manifest
+type Manifest struct {
+	MediaType string       `json:"mediaType"`
+	Layers    []Descriptor `json:"layers"`
+	Blobs     []Descriptor `json:"blobs"`
+	// Digest is the verified digest of the manifest
+	Digest string `json:"-"`
+}
+
+// Reference is a parsed reference like registry.example.com/dashboards:v1 or registry.example.com/dashboards@sha256:...
+type Reference struct {
+	Registry   string
+	Repository string
+	// Reference is the tag or the digest
+	Reference string
+}
+
+// ParseReference parses the artifact reference, the registry host is required
+func ParseReference(ref string) (*Reference, error) {
+	slash := strings.Index(ref, "/")
+	if slash <= 0 {
+		return nil, fmt.Errorf("invalid reference %v: the registry is required", ref)
+	}
+	r := &Reference{Registry: ref[:slash], Reference: "latest"}
+	repository := ref[slash+1:]
+	if at := strings.Index(repository, "@"); at >= 0 {
+		r.Reference = repository[at+1:]
+		repository = repository[:at]
+	} else if colon := strings.LastIndex(repository, ":"); colon > strings.LastIndex(repository, "/") {
+		r.Reference = repository[colon+1:]
+		repository = repository[:colon]
+	}
+	if repository == "" || r.Reference == "" {
+		return nil, fmt.Errorf("invalid reference %v", ref)
+	}
+	r.Repository = repository
+	return r, nil
+}
+
+func (r *Reference) isDigest() bool {
+	return strings.HasPrefix(r.Reference, "sha256:")
+}
+
+func (r *Reference) String() string {
+	if r.isDigest() {
+		return r.Registry + "/" + r.Repository + "@" + r.Reference
+	}
+	return r.Registry + "/" + r.Repository + ":" + r.Reference
+}
+
+// Client pulls artifacts from a registry with the distribution api
+type Client struct {
+	// Insecure uses plain http to talk to the registry
+	Insecure bool
+	Username string
+	Password string
+
+	httpClient *http.Client
+	token      string
+}
+
+// NewClient creates the registry client
+func NewClient(insecure bool, username, password string) *Client {
+	return &Client{
+		Insecure:   insecure,
+		Username:   username,
+		Password:   password,
+		httpClient: &http.Client{Timeout: time.Minute},
+	}
+}
+
+// Resolve gets the manifest of the reference and verifies its digest
+func (c *Client) Resolve(ref *Reference) (*Manifest, error) {
+	body, resp, err := c.get(ref, "/manifests/"+ref.Reference, imageManifestType+", "+artifactManifestType)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := digestOf(body)
+	if ref.isDigest() && digest != ref.Reference {
+		return nil, fmt.Errorf("the digest %v of the manifest does not match %v", digest, ref.Reference)
+	}
+	if header := resp.Header.Get("Docker-Content-Digest"); header != "" && header != digest {
+		return nil, fmt.Errorf("the digest %v of the manifest does not match %v", digest, header)
+	}
+
+	manifest := &Manifest{}
+	err = json.Unmarshal(body, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	manifest.Digest = digest
+	return manifest, nil
+}
+
+// Files downloads the blobs of the manifest and returns the files keyed by the relative path,
+// the tar blobs of the directories pushed by oras are extracted
+func (c *Client) Files(ref *Reference, manifest *Manifest) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	for _, layer := range append(manifest.Layers, manifest.Blobs...) {
+		title := layer.Annotations[titleAnnotation]
+		if title == "" {
+			continue
+		}
+		name, err := cleanPath(title)
+		if err != nil {
+			return nil, err
+		}
+		if layer.Size > maxBlobSize {
+			return nil, fmt.Errorf("the blob %v is larger than %v bytes", title, maxBlobSize)
+		}
+
+		body, _, err := c.get(ref, "/blobs/"+layer.Digest, "")
+		if err != nil {
+			return nil, err
+		}
+		if digestOf(body) != layer.Digest {
+			return nil, fmt.Errorf("the digest of blob %v does not match %v", title, layer.Digest)
+		}
+
+		if strings.HasSuffix(layer.MediaType, "tar+gzip") || strings.HasSuffix(layer.MediaType, ".tar") {
+			err = extract(body, name, strings.HasSuffix(layer.MediaType, "gzip"), files)
+			if err != nil {
+				return nil, fmt.Errorf("failed to extract blob %v: %v", title, err)
+			}
+			continue
+		}
+		files[name] = body
+	}
+	return files, nil
+}
+
+func (c *Client) get(ref *Reference, api string, accept string) ([]byte, *http.Response, error) {
+	scheme := "https"
+	if c.Insecure {
+		scheme = "http"
+	}
+	u := scheme + "://" + ref.Registry + "/v2/" + ref.Repository + api
+
+	resp, err := c.do(u, accept)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		err = c.authenticate(challenge, ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, err = c.do(u, accept)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("failed to get %v with %v", u, resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBlobSize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(body) > maxBlobSize {
+		return nil, nil, fmt.Errorf("the response of %v is larger than %v bytes", u, maxBlobSize)
+	}
+	return body, resp, nil
+}
+
+func (c *Client) do(u string, accept string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	} else if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	return c.httpClient.Do(req)
+}
+
+// authenticate gets a pull token for the bearer challenge of the registry
+func (c *Client) authenticate(challenge string, ref *Reference) error {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return fmt.Errorf("unauthorized to pull %v", ref)
+	}
+	params := parseChallenge(strings.TrimPrefix(challenge, "Bearer "))
+	if params["realm"] == "" {
+		return fmt.Errorf("invalid challenge from the registry: %v", challenge)
+	}
+	query := url.Values{}
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", "repository:"+ref.Repository+":pull")
+
+	req, err := http.NewRequest(http.MethodGet, params["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get the registry token with %v", resp.StatusCode)
+	}
+
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return err
+	}
+	c.token = token.Token
+	if c.token == "" {
+		c.token = token.AccessToken
+	}
+	if c.token == "" {
+		return fmt.Errorf("no token is returned by the registry")
+	}
+	return nil
+}
+
+// parseChallenge parses the parameters like realm="https://auth.example.com/token",service="registry"
+func parseChallenge(challenge string) map[string]string {
+	params := map[string]string{}
+	for _, param := range strings.Split(challenge, ",") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) == 2 {
+			params[kv[0]] = strings.Trim(kv[1], "\"")
+		}
+	}
+	return params
+}
+
+func digestOf(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// cleanPath rejects the paths escaping from the destination directory
+func cleanPath(name string) (string, error) {
+	cleaned := path.Clean("/" + name)[1:]
+	if cleaned == "" || cleaned != strings.TrimPrefix(name, "./") {
+		return "", fmt.Errorf("invalid file name %v in the artifact", name)
+	}
+	return cleaned, nil
+}
+
+func extract(body []byte, dir string, compressed bool, files map[string][]byte) error {
+	var reader io.Reader = bytes.NewReader(body)
+	if compressed {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	tr := tar.NewReader(reader)
+	total := int64(0)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name, err := cleanPath(header.Name)
+		if err != nil {
+			return err
+		}
+		// oras puts the directory itself into the tar
+		name = strings.TrimPrefix(name, dir+"/")
+		total += header.Size
+		if total > maxBlobSize {
+			return fmt.Errorf("the extracted files are larger than %v bytes", maxBlobSize)
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		files[path.Join(dir, name)] = content
+	}
+}
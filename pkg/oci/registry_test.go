@@ -0,0 +1,189 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		ref      string
+		expected Reference
+		hasErr   bool
+	}{
+
+		{
+			"no registry",
+			"dashboards",
+			Reference{},
+			true,
+		},
+
+		{
+			"default tag",
+			"quay.io/org/dashboards",
+			Reference{"quay.io", "org/dashboards", "latest"},
+			false,
+		},
+
+		{
+			"registry with port",
+			"localhost:5000/dashboards:v1",
+			Reference{"localhost:5000", "dashboards", "v1"},
+			false,
+		},
+
+		{
+			"digest",
+			"quay.io/dashboards@sha256:abc",
+			Reference{"quay.io", "dashboards", "sha256:abc"},
+			false,
+		},
+	}
+
+	for _, c := range testCaseList {
+		output, err := ParseReference(c.ref)
+		if (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+			continue
+		}
+		if err == nil && *output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, *output, c.expected)
+		}
+	}
+}
+
+func tarGzip(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// newFakeRegistry serves an artifact with a json file and a directory behind the bearer token auth
+func newFakeRegistry(t *testing.T, blobs map[string][]byte, manifest []byte) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/token" {
+			if req.URL.Query().Get("scope") != "repository:org/dashboards:pull" {
+				t.Errorf("unexpected scope %v", req.URL.Query().Get("scope"))
+			}
+			w.Write([]byte("{\"token\": \"secret\"}"))
+			return
+		}
+		if req.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", "Bearer realm=\""+server.URL+"/token\",service=\"registry\"")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case req.URL.Path == "/v2/org/dashboards/manifests/v1":
+			w.Write(manifest)
+		case strings.HasPrefix(req.URL.Path, "/v2/org/dashboards/blobs/"):
+			blob, ok := blobs[strings.TrimPrefix(req.URL.Path, "/v2/org/dashboards/blobs/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(blob)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func TestPull(t *testing.T) {
+	single := []byte("{\"title\": \"a\"}")
+	dir := tarGzip(t, map[string]string{"SLOs/b.json": "{\"title\": \"b\"}", "../evil.json": "{}"})
+	safeDir := tarGzip(t, map[string]string{"SLOs/b.json": "{\"title\": \"b\"}"})
+	tampered := "sha256:" + strings.Repeat("0", 64)
+	blobs := map[string][]byte{digestOf(single): single, digestOf(dir): dir, digestOf(safeDir): safeDir, tampered: single}
+
+	testCaseList := []struct {
+		name     string
+		layers   []Descriptor
+		expected map[string]string
+		hasErr   bool
+	}{
+
+		{
+			"files and directory",
+			[]Descriptor{
+				{MediaType: "application/json", Digest: digestOf(single), Size: int64(len(single)),
+					Annotations: map[string]string{titleAnnotation: "a.json"}},
+				{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: digestOf(safeDir), Size: int64(len(safeDir)),
+					Annotations: map[string]string{titleAnnotation: "SLOs"}},
+			},
+			map[string]string{"a.json": "{\"title\": \"a\"}", "SLOs/b.json": "{\"title\": \"b\"}"},
+			false,
+		},
+
+		{
+			"path traversal",
+			[]Descriptor{
+				{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: digestOf(dir), Size: int64(len(dir)),
+					Annotations: map[string]string{titleAnnotation: "SLOs"}},
+			},
+			nil,
+			true,
+		},
+
+		{
+			"mismatched digest",
+			[]Descriptor{
+				{MediaType: "application/json", Digest: tampered, Size: int64(len(single)),
+					Annotations: map[string]string{titleAnnotation: "a.json"}},
+			},
+			nil,
+			true,
+		},
+	}
+
+	for _, c := range testCaseList {
+		manifest, _ := json.Marshal(Manifest{MediaType: imageManifestType, Layers: c.layers})
+		server := newFakeRegistry(t, blobs, manifest)
+
+		ref, _ := ParseReference(strings.TrimPrefix(server.URL, "http://") + "/org/dashboards:v1")
+		client := NewClient(true, "", "")
+		resolved, err := client.Resolve(ref)
+		if err != nil {
+			t.Errorf("case (%v) failed to resolve: %v", c.name, err)
+			server.Close()
+			continue
+		}
+		if resolved.Digest != digestOf(manifest) {
+			t.Errorf("case (%v) digest: (%v) is not the expected: (%v)", c.name, resolved.Digest, digestOf(manifest))
+		}
+
+		files, err := client.Files(ref, resolved)
+		if (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+		if len(files) != len(c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, files, c.expected)
+		}
+		for name, content := range c.expected {
+			if string(files[name]) != content {
+				t.Errorf("case (%v) output: (%s) is not the expected: (%v)", c.name, files[name], content)
+			}
+		}
+		server.Close()
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+var (
+	// ClientCertFile is the client certificate presented to grafana, e.g. the tls.crt of a mounted secret
+	ClientCertFile = ""
+	// ClientKeyFile is the key of the client certificate, e.g. the tls.key of a mounted secret
+	ClientKeyFile = ""
+	// CAFile is the ca bundle to verify grafana, empty means the system roots
+	CAFile = ""
+)
+
+// certReloader loads the client certificate again once the files are changed,
+// so that a rotated secret is picked up without restarting the loader
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	lock        sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+func newCertReloader(certFile, keyFile string) *certReloader {
+	return &certReloader{certFile: certFile, keyFile: keyFile}
+}
+
+// GetClientCertificate is called on every tls handshake with grafana
+func (r *certReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	certModTime, err := modTime(r.certFile)
+	if err != nil {
+		return r.loaded(err)
+	}
+	keyModTime, err := modTime(r.keyFile)
+	if err != nil {
+		return r.loaded(err)
+	}
+	if r.cert != nil && certModTime.Equal(r.certModTime) && keyModTime.Equal(r.keyModTime) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		// the cert and key may be in the middle of an update
+		return r.loaded(err)
+	}
+	if r.cert != nil {
+		klog.Infof("client certificate %v is reloaded", r.certFile)
+	}
+	r.cert, r.certModTime, r.keyModTime = &cert, certModTime, keyModTime
+	return r.cert, nil
+}
+
+// loaded falls back to the certificate loaded last time
+func (r *certReloader) loaded(err error) (*tls.Certificate, error) {
+	if r.cert == nil {
+		return nil, fmt.Errorf("failed to load client certificate %v: %v", r.certFile, err)
+	}
+	klog.Errorf("failed to reload client certificate %v, keep using the loaded one: %v", r.certFile, err)
+	return r.cert, nil
+}
+
+func modTime(file string) (time.Time, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// newTLSConfig returns the tls config of the grafana connections, nil means the defaults
+func newTLSConfig(settings transportSettings) *tls.Config {
+	if settings.clientCertFile == "" && settings.caFile == "" {
+		return nil
+	}
+
+	config := &tls.Config{}
+	if settings.clientCertFile != "" {
+		config.GetClientCertificate = newCertReloader(settings.clientCertFile, settings.clientKeyFile).GetClientCertificate
+	}
+	if settings.caFile != "" {
+		ca, err := ioutil.ReadFile(settings.caFile)
+		if err != nil {
+			klog.Errorf("failed to read ca file %v, use the system roots: %v", settings.caFile, err)
+			return config
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			klog.Errorf("no certificate is found in ca file %v, use the system roots", settings.caFile)
+			return config
+		}
+		config.RootCAs = pool
+	}
+	return config
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestCert signs a certificate with the parent, the certificate is self-signed without the parent
+func newTestCert(t *testing.T, serial int64, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return cert, key,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestClientCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "client-cert")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, caKey, caPEM, _ := newTestCert(t, 1, nil, nil)
+	_, _, serverPEM, serverKeyPEM := newTestCert(t, 2, ca, caKey)
+	serverCert, _ := tls.X509KeyPair(serverPEM, serverKeyPEM)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	serial := int64(0)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		serial = req.TLS.PeerCertificates[0].SerialNumber.Int64()
+		w.Write([]byte("done"))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	writeClientCert := func(serial int64, modTime time.Time) {
+		_, _, certPEM, keyPEM := newTestCert(t, serial, ca, caKey)
+		ioutil.WriteFile(certFile, certPEM, 0600)
+		ioutil.WriteFile(keyFile, keyPEM, 0600)
+		os.Chtimes(certFile, modTime, modTime)
+		os.Chtimes(keyFile, modTime, modTime)
+	}
+	writeClientCert(10, time.Now().Add(-time.Minute))
+	ioutil.WriteFile(caFile, caPEM, 0600)
+
+	defer func(cert, key, ca string, idle time.Duration) {
+		ClientCertFile, ClientKeyFile, CAFile, IdleConnTimeout = cert, key, ca, idle
+	}(ClientCertFile, ClientKeyFile, CAFile, IdleConnTimeout)
+	ClientCertFile, ClientKeyFile, CAFile = certFile, keyFile, caFile
+
+	_, responseCode := SetRequest("GET", server.URL, nil, 1)
+	if responseCode != http.StatusOK || serial != 10 {
+		t.Fatalf("the request with client certificate responded %v with serial %v", responseCode, serial)
+	}
+
+	// the rotated certificate is presented on the next handshake
+	writeClientCert(11, time.Now())
+	getHTTPClient().CloseIdleConnections()
+	_, responseCode = SetRequest("GET", server.URL, nil, 1)
+	if responseCode != http.StatusOK || serial != 11 {
+		t.Fatalf("the request with rotated client certificate responded %v with serial %v", responseCode, serial)
+	}
+
+	// the loaded certificate is kept when the files are broken
+	ioutil.WriteFile(keyFile, []byte("broken"), 0600)
+	getHTTPClient().CloseIdleConnections()
+	_, responseCode = SetRequest("GET", server.URL, nil, 1)
+	if responseCode != http.StatusOK || serial != 11 {
+		t.Fatalf("the request with broken client certificate responded %v with serial %v", responseCode, serial)
+	}
+}
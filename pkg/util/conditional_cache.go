@@ -0,0 +1,98 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+// ConditionalCacheSize is the number of the grafana GET responses kept with their ETag or Last-Modified header,
+// they are got again by a conditional request which grafana answers with 304 Not Modified and no body
+// while they are unchanged, 0 disables the conditional requests
+var ConditionalCacheSize = 1000
+
+// cachedResponse is a GET response with the validators grafana sent with it
+type cachedResponse struct {
+	key          string
+	etag         string
+	lastModified string
+	body         []byte
+}
+
+// conditionalCache keeps the latest used responses
+type conditionalCache struct {
+	lock    sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+var conditionalResponses = &conditionalCache{order: list.New(), entries: map[string]*list.Element{}}
+
+func conditionalKey(url string, orgID string) string {
+	return orgID + " " + url
+}
+
+// prepare makes the GET request conditional when its response is cached
+func (c *conditionalCache) prepare(req *http.Request, url string, orgID string) {
+	if ConditionalCacheSize <= 0 {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	element, ok := c.entries[conditionalKey(url, orgID)]
+	if !ok {
+		return
+	}
+	cached := element.Value.(*cachedResponse)
+	if cached.etag != "" {
+		req.Header.Set("If-None-Match", cached.etag)
+	} else if cached.lastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.lastModified)
+	}
+}
+
+// update returns the cached body for a 304 response and caches a 200 response which has a validator
+func (c *conditionalCache) update(url string, orgID string, resp *http.Response, body []byte) ([]byte, int) {
+	if ConditionalCacheSize <= 0 {
+		return body, resp.StatusCode
+	}
+	key := conditionalKey(url, orgID)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	element, ok := c.entries[key]
+	switch {
+	case resp.StatusCode == http.StatusNotModified && ok:
+		metrics.GrafanaConditionalHits.Inc()
+		c.order.MoveToFront(element)
+		return element.Value.(*cachedResponse).body, http.StatusOK
+	case resp.StatusCode != http.StatusOK:
+		return body, resp.StatusCode
+	}
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		if ok {
+			c.order.Remove(element)
+			delete(c.entries, key)
+		}
+		return body, resp.StatusCode
+	}
+	cached := &cachedResponse{key: key, etag: etag, lastModified: lastModified, body: body}
+	if ok {
+		element.Value = cached
+		c.order.MoveToFront(element)
+	} else {
+		c.entries[key] = c.order.PushFront(cached)
+	}
+	for c.order.Len() > ConditionalCacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResponse).key)
+	}
+	return body, resp.StatusCode
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	version := "1"
+	conditional := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conditional = append(conditional, req.Header.Get("If-None-Match"))
+		switch req.URL.Path {
+		case "/api/dashboards/uid/test":
+			etag := "\"" + version + "\""
+			if req.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Write([]byte("version " + version))
+		default:
+			w.Write([]byte("no validator"))
+		}
+	}))
+	defer server.Close()
+	defer func(size int) { ConditionalCacheSize = size }(ConditionalCacheSize)
+	ConditionalCacheSize = 1
+
+	testCaseList := []struct {
+		name        string
+		path        string
+		prepare     func()
+		expected    string
+		conditional string
+		hit         float64
+	}{
+		{"first", "/api/dashboards/uid/test", func() {}, "version 1", "", 0},
+
+		{"not modified", "/api/dashboards/uid/test", func() {}, "version 1", "\"1\"", 1},
+
+		{"modified", "/api/dashboards/uid/test", func() { version = "2" }, "version 2", "\"1\"", 0},
+
+		{"no validator", "/api/search", func() {}, "no validator", "", 0},
+
+		{"kept while the other responses have no validator", "/api/dashboards/uid/test", func() {}, "version 2", "\"2\"", 1},
+	}
+
+	for _, c := range testCaseList {
+		c.prepare()
+		conditional = nil
+		before := testutil.ToFloat64(metrics.GrafanaConditionalHits)
+		body, status := SetOrgRequestContext(context.TODO(), "GET", server.URL+c.path, nil, 1, "1")
+		if status != http.StatusOK || string(body) != c.expected {
+			t.Errorf("case (%v) output: (%v, %s) is not the expected: (%v)", c.name, status, body, c.expected)
+		}
+		if len(conditional) != 1 || conditional[0] != c.conditional {
+			t.Errorf("case (%v) conditional: (%v) is not the expected: (%v)", c.name, conditional, c.conditional)
+		}
+		if hit := testutil.ToFloat64(metrics.GrafanaConditionalHits) - before; hit != c.hit {
+			t.Errorf("case (%v) hit: (%v) is not the expected: (%v)", c.name, hit, c.hit)
+		}
+	}
+}
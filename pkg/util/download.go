@@ -0,0 +1,63 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Download gets the content of an external url, unlike SetRequest it sends no grafana headers,
+// the download stops when the context is done and fails when the content is larger than limit bytes
+func Download(ctx context.Context, url string, retry int, limit int64) ([]byte, error) {
+	client := &http.Client{Timeout: time.Minute}
+	get := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		return client.Do(req)
+	}
+	resp, err := get()
+	times := 0
+	for {
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			break
+		}
+		if err == nil {
+			resp.Body.Close()
+			err = fmt.Errorf("unexpected status code %v", resp.StatusCode)
+		}
+		times++
+		if times >= retry {
+			return nil, fmt.Errorf("failed to download %v after retrying %v times: %v", url, retry, err)
+		}
+		klog.Error("failed to download. Retry in 5 seconds ", "url ", url, " error ", err)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to download %v: %v", url, ctx.Err())
+		case <-time.After(time.Second * 5):
+		}
+		resp, err = get()
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %v with %v", url, resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("the content of %v is larger than %v bytes", url, limit)
+	}
+	return body, nil
+}
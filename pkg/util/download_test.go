@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Forwarded-User") != "" {
+			t.Errorf("grafana headers should not be sent")
+		}
+		switch req.URL.Path {
+		case "/dashboard":
+			w.Write([]byte("{}"))
+		case "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	body, err := Download(context.TODO(), server.URL+"/dashboard", 1, 2)
+	if err != nil || string(body) != "{}" {
+		t.Fatalf("the body %s is not the expected {}: %v", body, err)
+	}
+
+	_, err = Download(context.TODO(), server.URL+"/notfound", 1, 2)
+	if err == nil {
+		t.Fatalf("download should fail on not found")
+	}
+
+	_, err = Download(context.TODO(), server.URL+"/dashboard", 1, 1)
+	if err == nil {
+		t.Fatalf("download should fail when the content is larger than the limit")
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	start := time.Now()
+	_, err = Download(ctx, server.URL+"/unavailable", 3, 2)
+	if err == nil || time.Since(start) > time.Second {
+		t.Fatalf("download should stop at once when the context is done: %v", err)
+	}
+}
@@ -4,24 +4,75 @@
 package util
 
 import (
+	"bytes"
+	"context"
 	"encoding/hex"
+	"fmt"
 	"hash/fnv"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"regexp"
 	"time"
 
-	"k8s.io/klog"
+	"k8s.io/klog/v2"
 )
 
+// RequestTimeout is the timeout of every single attempt to send a grafana request, 0 means no timeout
+var RequestTimeout = 30 * time.Second
+
 const (
 	defaultAdmin = "WHAT_YOU_ARE_DOING_IS_VOIDING_SUPPORT_0000000000000000000000000000000000000000000000000000000000000000"
 )
 
-// GenerateUID generates UID for customized dashboard
+var (
+	// AuthProxyUser is the user passed to the grafana auth proxy when no token or basic auth is set
+	AuthProxyUser = defaultAdmin
+	// BearerToken is sent as the grafana api token or service account token
+	BearerToken = ""
+	// BasicAuthUsername and BasicAuthPassword are the grafana basic auth credentials
+	BasicAuthUsername = ""
+	BasicAuthPassword = ""
+)
+
+// GrafanaAuth are the credentials of a grafana instance, they are used instead of the global ones
+// for the requests whose context carries them
+type GrafanaAuth struct {
+	ProxyUser         string
+	BearerToken       string
+	BasicAuthUsername string
+	BasicAuthPassword string
+}
+
+type grafanaAuthKey struct{}
+
+// WithGrafanaAuth returns the context to send the requests with the given credentials
+func WithGrafanaAuth(ctx context.Context, auth GrafanaAuth) context.Context {
+	return context.WithValue(ctx, grafanaAuthKey{}, auth)
+}
+
+// maxUIDLength is the longest dashboard uid grafana accepts
+const maxUIDLength = 40
+
+// uidPattern is the characters grafana accepts in the dashboard uids
+var uidPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ValidateUID checks the dashboard uid against the rules of grafana
+func ValidateUID(uid string) error {
+	if len(uid) > maxUIDLength {
+		return fmt.Errorf("the uid %q is longer than %v characters", uid, maxUIDLength)
+	}
+	if !uidPattern.MatchString(uid) {
+		return fmt.Errorf("the uid %q should only have letters, digits, - and _", uid)
+	}
+	return nil
+}
+
+// GenerateUID generates UID for customized dashboard,
+// the uid which grafana does not accept, e.g. too long or with dots, is hashed
 func GenerateUID(namespace string, name string) (string, error) {
 	uid := namespace + "-" + name
-	if len(uid) > 40 {
+	if ValidateUID(uid) != nil {
 		hasher := fnv.New128a()
 		_, err := hasher.Write([]byte(uid))
 		if err != nil {
@@ -32,43 +83,147 @@ func GenerateUID(namespace string, name string) (string, error) {
 	return uid, nil
 }
 
-// GetHTTPClient returns http client
-func getHTTPClient() *http.Client {
-	transport := &http.Transport{}
-	client := &http.Client{Transport: transport}
-	return client
-}
-
 // SetRequest ...
 func SetRequest(method string, url string, body io.Reader, retry int) ([]byte, int) {
-	req, _ := http.NewRequest(method, url, body)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Forwarded-User", defaultAdmin)
+	return SetOrgRequest(method, url, body, retry, "")
+}
 
-	resp, err := getHTTPClient().Do(req)
-	times := 0
+// SetOrgRequest sends the request in the context of the grafana organization, empty orgID means the default one
+func SetOrgRequest(method string, url string, body io.Reader, retry int, orgID string) ([]byte, int) {
+	return SetOrgRequestContext(context.Background(), method, url, body, retry, orgID)
+}
+
+// SetOrgRequestContext is SetOrgRequest which gives up once ctx is done,
+// every attempt is limited by RequestTimeout on top of ctx
+func SetOrgRequestContext(ctx context.Context, method string, url string, body io.Reader,
+	retry int, orgID string) ([]byte, int) {
+	// the body is buffered so that it can be sent again on retries,
+	// the bytes of a buffer are used as they are instead of being copied
+	var payload []byte
+	if buffer, ok := body.(*bytes.Buffer); ok {
+		payload = buffer.Bytes()
+	} else if body != nil {
+		var err error
+		payload, err = ioutil.ReadAll(body)
+		if err != nil {
+			klog.Error("failed to read request body ", "error ", err)
+			return nil, http.StatusNotFound
+		}
+	}
+
+	replicas, _ := ctx.Value(replicasKey{}).(*Replicas)
+	times, failovers := 0, 0
 	for {
-		if err == nil {
-			break
+		respBody, respStatusCode, wait, err := sendRequest(ctx, method, url, payload, orgID)
+		if err == nil && wait == 0 {
+			return respBody, respStatusCode
+		}
+		// the other replicas are tried at once before the request is retried
+		if err != nil && replicas != nil && failovers < replicas.Len()-1 && ctx.Err() == nil {
+			failovers++
+			url = replicas.failOver(url)
+			continue
+		}
+		failovers = 0
+		if err != nil {
+			klog.Error("failed to send HTTP request. Retry in 5 seconds ", "error ", err)
+			wait = time.Second * 5
+		} else {
+			klog.InfoS("grafana asked to retry the request later", "method", method, "url", url,
+				"status", respStatusCode, "retryAfter", wait)
 		}
-		klog.Error("failed to send HTTP request. Retry in 5 seconds ", "error ", err)
-		time.Sleep(time.Second * 5)
 		times++
 		if times == retry {
 			klog.Errorf("failed to send HTTP request after retrying %v times", retry)
-			break
+			if err == nil {
+				return respBody, respStatusCode
+			}
+			return nil, http.StatusNotFound
+		}
+		select {
+		case <-ctx.Done():
+			klog.Errorf("stop retrying HTTP request: %v", ctx.Err())
+			return nil, http.StatusNotFound
+		case <-time.After(wait):
 		}
-		resp, err = getHTTPClient().Do(req)
 	}
+}
 
-	if resp != nil {
-		defer resp.Body.Close()
-		respBody, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			klog.Info("failed to parse response body ", "error ", err)
-		}
-		return respBody, resp.StatusCode
-	} else {
-		return nil, http.StatusNotFound
+// sendRequest sends the request once, the returned duration is the wait asked by grafana before retrying
+func sendRequest(ctx context.Context, method string, url string, payload []byte,
+	orgID string) ([]byte, int, time.Duration, error) {
+	if err := waitForRateLimit(ctx); err != nil {
+		return nil, 0, 0, err
+	}
+	if timeout := CurrentSettings().RequestTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setAuthHeaders(req)
+	if orgID != "" {
+		req.Header.Set("X-Grafana-Org-Id", orgID)
+	}
+	if method == http.MethodGet {
+		conditionalResponses.prepare(req, url, orgID)
+	}
+
+	resp, err := getHTTPClient().Do(req)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer resp.Body.Close()
+	respBody, err := readBody(resp)
+	if err != nil {
+		klog.Info("failed to parse response body ", "error ", err)
+	}
+	recordRateLimitQuota(resp)
+	statusCode := resp.StatusCode
+	if method == http.MethodGet && err == nil {
+		respBody, statusCode = conditionalResponses.update(url, orgID, resp, respBody)
+	}
+	return respBody, statusCode, retryAfter(resp), nil
+}
+
+// maxPreallocatedBody is the largest content length the buffer of the response body is allocated for up front
+const maxPreallocatedBody = 64 << 20
+
+// readBody reads the response into a buffer of its content length,
+// so that a large dashboard is not read by growing the buffer again and again
+func readBody(resp *http.Response) ([]byte, error) {
+	if resp.ContentLength <= 0 || resp.ContentLength > maxPreallocatedBody {
+		return ioutil.ReadAll(resp.Body)
+	}
+	buffer := bytes.NewBuffer(make([]byte, 0, resp.ContentLength+bytes.MinRead))
+	_, err := buffer.ReadFrom(resp.Body)
+	return buffer.Bytes(), err
+}
+
+// setAuthHeaders authenticates the request with the token, the basic auth or the auth proxy in order,
+// the credentials of the request context take precedence over the global ones
+func setAuthHeaders(req *http.Request) {
+	global := CurrentSettings().Auth
+	auth, ok := req.Context().Value(grafanaAuthKey{}).(GrafanaAuth)
+	if !ok {
+		auth = global
+	} else if auth.ProxyUser == "" {
+		auth.ProxyUser = global.ProxyUser
+	}
+	switch {
+	case auth.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+auth.BearerToken)
+	case auth.BasicAuthUsername != "":
+		req.SetBasicAuth(auth.BasicAuthUsername, auth.BasicAuthPassword)
+	default:
+		req.Header.Set("X-Forwarded-User", auth.ProxyUser)
 	}
 }
@@ -4,7 +4,13 @@
 package util
 
 import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -21,6 +27,37 @@ func TestGenerateUID(t *testing.T) {
 		t.Fatalf("the uid %v should not equal to %v", uid, "4e20548bdba37201faabf30d1c419981")
 	}
 
+	uid, _ = GenerateUID("grafana.dashboards", "test")
+	if err := ValidateUID(uid); err != nil {
+		t.Fatalf("the uid %v with a dot is not hashed: %v", uid, err)
+	}
+
+}
+
+func TestValidateUID(t *testing.T) {
+	testCaseList := []struct {
+		name   string
+		uid    string
+		hasErr bool
+	}{
+		{"valid", "open-cluster_management-01", false},
+
+		{"40 characters", strings.Repeat("a", 40), false},
+
+		{"too long", strings.Repeat("a", 41), true},
+
+		{"dot", "grafana.dashboards", true},
+
+		{"slash", "team/dashboard", true},
+
+		{"empty", "", true},
+	}
+
+	for _, c := range testCaseList {
+		if err := ValidateUID(c.uid); (err != nil) != c.hasErr {
+			t.Errorf("case (%v) error: (%v) is not the expected: (%v)", c.name, err, c.hasErr)
+		}
+	}
 }
 
 func createFakeServer(t *testing.T) {
@@ -30,17 +67,100 @@ func createFakeServer(t *testing.T) {
 			w.Write([]byte("done"))
 		},
 	)
-	err := http.ListenAndServe(":3002", server3002)
+	// the server listens before it returns so the failure is reported by the test goroutine
+	listener, err := net.Listen("tcp", ":3002")
 	if err != nil {
 		t.Fatal("fail to create internal server at 3002")
 	}
+	go http.Serve(listener, server3002)
 }
 
 func TestSetRequest(t *testing.T) {
-	go createFakeServer(t)
+	createFakeServer(t)
 	time.Sleep(time.Second)
 	_, responseCode := SetRequest("GET", "http://127.0.0.1:3002", nil, 1)
 	if responseCode == http.StatusNotFound {
 		t.Fatalf("cannot send request to server: %v", responseCode)
 	}
 }
+
+func TestSetOrgRequestContext(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		body, _ := ioutil.ReadAll(req.Body)
+		if attempts == 1 {
+			// hang until the request is timed out
+			<-req.Context().Done()
+			return
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	defer func(timeout time.Duration) {
+		RequestTimeout = timeout
+	}(RequestTimeout)
+	RequestTimeout = 100 * time.Millisecond
+
+	// the hung attempt is timed out and the body is sent again by the retry
+	body, responseCode := SetOrgRequestContext(context.TODO(), "POST", server.URL, strings.NewReader("test"), 2, "")
+	if responseCode != http.StatusOK || string(body) != "test" || attempts != 2 {
+		t.Fatalf("the retry after the timeout responded %v %s after %v attempts", responseCode, body, attempts)
+	}
+
+	// the bytes of a buffer are sent as they are on every retry
+	attempts = 0
+	body, responseCode = SetOrgRequestContext(context.TODO(), "POST", server.URL, bytes.NewBufferString("buffered"), 2, "")
+	if responseCode != http.StatusOK || string(body) != "buffered" || attempts != 2 {
+		t.Fatalf("the retry of the buffered body responded %v %s after %v attempts", responseCode, body, attempts)
+	}
+
+	// no more retries once the context is done
+	attempts = 0
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	start := time.Now()
+	_, responseCode = SetOrgRequestContext(ctx, "GET", server.URL, nil, 10, "")
+	if responseCode != http.StatusNotFound || time.Since(start) > time.Second {
+		t.Fatalf("the cancelled request responded %v after %v", responseCode, time.Since(start))
+	}
+}
+
+func TestSetAuthHeaders(t *testing.T) {
+	defer func(token, username, password string) {
+		BearerToken, BasicAuthUsername, BasicAuthPassword = token, username, password
+	}(BearerToken, BasicAuthUsername, BasicAuthPassword)
+
+	testCaseList := []struct {
+		name          string
+		token         string
+		username      string
+		authorization string
+		forwardedUser string
+	}{
+		{"auth proxy", "", "", "", AuthProxyUser},
+
+		{"basic auth", "", "admin", "Basic YWRtaW46cGFzcw==", ""},
+
+		{"token", "secret", "admin", "Bearer secret", ""},
+	}
+
+	for _, c := range testCaseList {
+		BearerToken, BasicAuthUsername, BasicAuthPassword = c.token, c.username, "pass"
+		req := httptest.NewRequest("GET", "http://grafana/api/health", nil)
+		setAuthHeaders(req)
+		if req.Header.Get("Authorization") != c.authorization || req.Header.Get("X-Forwarded-User") != c.forwardedUser {
+			t.Errorf("case (%v) output: (%v, %v) is not the expected: (%v, %v)", c.name,
+				req.Header.Get("Authorization"), req.Header.Get("X-Forwarded-User"), c.authorization, c.forwardedUser)
+		}
+	}
+
+	// the credentials of the context are used instead of the global token
+	req := httptest.NewRequest("GET", "http://grafana/api/health", nil)
+	req = req.WithContext(WithGrafanaAuth(req.Context(), GrafanaAuth{BearerToken: "target"}))
+	setAuthHeaders(req)
+	if output := req.Header.Get("Authorization"); output != "Bearer target" {
+		t.Errorf("case (context auth) output: (%v) is not the expected: (%v)", output, "Bearer target")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/tracing"
+)
+
+// instrumentedTransport records the metrics and spans of the requests sent to grafana
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := normalizeEndpoint(req.URL.Path)
+	ctx, span := tracing.Start(req.Context(), "HTTP "+req.Method+" "+endpoint, trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	span.SetAttributes(semconv.HTTPMethodKey.String(req.Method), semconv.HTTPTargetKey.String(endpoint))
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	metrics.GrafanaRequestsInFlight.Inc()
+	defer metrics.GrafanaRequestsInFlight.Dec()
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	metrics.GrafanaRequestDuration.WithLabelValues(req.Method, endpoint).Observe(time.Since(start).Seconds())
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(resp.StatusCode))
+		span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(resp.StatusCode))
+	} else {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	metrics.GrafanaRequests.WithLabelValues(req.Method, endpoint, code).Inc()
+	return resp, err
+}
+
+// CloseIdleConnections is called by http.Client.CloseIdleConnections
+func (t *instrumentedTransport) CloseIdleConnections() {
+	if closer, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// normalizeEndpoint replaces the ids and uids in the path with placeholders to keep the metrics cardinality low,
+// e.g. /api/dashboards/uid/abc becomes /api/dashboards/uid/:uid
+func normalizeEndpoint(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 1; i < len(segments); i++ {
+		switch {
+		case segments[i-1] == "id":
+			segments[i] = ":id"
+		case segments[i-1] == "uid" || segments[i-1] == "public-dashboards":
+			segments[i] = ":uid"
+		case segments[i-1] == "folders" && segments[i] != "id":
+			segments[i] = ":uid"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
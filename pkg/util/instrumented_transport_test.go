@@ -0,0 +1,115 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/open-cluster-management/grafana-dashboard-loader/pkg/metrics"
+)
+
+func TestNormalizeEndpoint(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+
+		{
+			"folders",
+			"/api/folders",
+			"/api/folders",
+		},
+
+		{
+			"folder by id",
+			"/api/folders/id/12",
+			"/api/folders/id/:id",
+		},
+
+		{
+			"folder by uid",
+			"/api/folders/abc",
+			"/api/folders/:uid",
+		},
+
+		{
+			"dashboard by uid",
+			"/api/dashboards/uid/abc",
+			"/api/dashboards/uid/:uid",
+		},
+
+		{
+			"public dashboard",
+			"/api/dashboards/uid/abc/public-dashboards/def",
+			"/api/dashboards/uid/:uid/public-dashboards/:uid",
+		},
+
+		{
+			"save dashboard",
+			"/api/dashboards/db",
+			"/api/dashboards/db",
+		},
+	}
+
+	for _, c := range testCaseList {
+		output := normalizeEndpoint(c.path)
+		if output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestInstrumentedTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+	}))
+	defer server.Close()
+
+	counter := metrics.GrafanaRequests.WithLabelValues("POST", "/api/dashboards/db", "412")
+	before := testutil.ToFloat64(counter)
+	SetRequest("POST", server.URL+"/api/dashboards/db", nil, 1)
+	if testutil.ToFloat64(counter)-before != 1 {
+		t.Fatalf("the request is not counted")
+	}
+	if testutil.ToFloat64(metrics.GrafanaRequestsInFlight) != 0 {
+		t.Fatalf("no request should be in flight")
+	}
+}
+
+func TestInstrumentedTransportTracing(t *testing.T) {
+	traceparent := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		traceparent = req.Header.Get("traceparent")
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	originalProvider, originalPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(originalProvider)
+		otel.SetTextMapPropagator(originalPropagator)
+	}()
+
+	SetRequest("GET", server.URL+"/api/folders/id/3", nil, 1)
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "HTTP GET /api/folders/id/:id" {
+		t.Fatalf("the spans %v are not the expected", spans)
+	}
+	if traceparent == "" {
+		t.Fatalf("the trace context is not propagated to grafana")
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+var (
+	// RequestQPS is the rate of the requests sent to grafana, 0 means no limit
+	RequestQPS float32 = 20
+	// RequestBurst is the number of requests which can be sent to grafana at once
+	RequestBurst = 50
+	// MaxRetryAfter caps the wait asked by the Retry-After header of grafana
+	MaxRetryAfter = time.Minute
+)
+
+var (
+	limiterLock  sync.Mutex
+	limiter      flowcontrol.RateLimiter
+	limiterQPS   float32
+	limiterBurst int
+	// quotaReset is when the exhausted quota of a hosted grafana, e.g. grafana cloud, is refilled
+	quotaReset time.Time
+)
+
+// getRateLimiter returns the token bucket shared by all the requests,
+// it is rebuilt when RequestQPS or RequestBurst is changed
+func getRateLimiter() flowcontrol.RateLimiter {
+	settings := CurrentSettings()
+	limiterLock.Lock()
+	defer limiterLock.Unlock()
+	if settings.RequestQPS <= 0 {
+		return nil
+	}
+	if limiter == nil || limiterQPS != settings.RequestQPS || limiterBurst != settings.RequestBurst {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(settings.RequestQPS, settings.RequestBurst)
+		limiterQPS, limiterBurst = settings.RequestQPS, settings.RequestBurst
+	}
+	return limiter
+}
+
+// waitForRateLimit blocks until the request can be sent or ctx is done
+func waitForRateLimit(ctx context.Context) error {
+	limiterLock.Lock()
+	pause := time.Until(quotaReset)
+	limiterLock.Unlock()
+	if pause > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pause):
+		}
+	}
+	l := getRateLimiter()
+	if l == nil {
+		return nil
+	}
+	return l.Wait(ctx)
+}
+
+// retryAfter returns how long grafana asks to wait before the response is retried,
+// 0 means the response is final
+func retryAfter(resp *http.Response) time.Duration {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+
+	var wait time.Duration
+	header := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(header); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(header); err == nil {
+		wait = time.Until(date)
+	} else if reset, ok := rateLimitReset(resp.Header); ok && resp.StatusCode == http.StatusTooManyRequests {
+		wait = reset
+	} else if resp.StatusCode == http.StatusTooManyRequests {
+		// grafana does not always send the header when it rate limits
+		wait = 5 * time.Second
+	}
+
+	if wait < 0 {
+		wait = time.Second
+	}
+	if maximum := CurrentSettings().MaxRetryAfter; wait > maximum {
+		wait = maximum
+	}
+	return wait
+}
+
+// rateLimitReset returns how long it is until the quota is refilled by the X-RateLimit-Reset header of the
+// gateways in front of a hosted grafana, the header is either the unix time or the seconds to wait
+func rateLimitReset(header http.Header) (time.Duration, bool) {
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil || reset < 0 {
+		return 0, false
+	}
+	if reset > 1000000000 {
+		return time.Until(time.Unix(reset, 0)), true
+	}
+	return time.Duration(reset) * time.Second, true
+}
+
+// recordRateLimitQuota pauses all the requests until the quota is refilled once the response tells it is exhausted,
+// so that the requests are not rejected one by one
+func recordRateLimitQuota(resp *http.Response) {
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+	reset, ok := rateLimitReset(resp.Header)
+	if !ok || reset <= 0 {
+		return
+	}
+	if maximum := CurrentSettings().MaxRetryAfter; reset > maximum {
+		reset = maximum
+	}
+	limiterLock.Lock()
+	defer limiterLock.Unlock()
+	quotaReset = time.Now().Add(reset)
+}
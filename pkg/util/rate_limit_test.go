@@ -0,0 +1,159 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		statusCode int
+		header     string
+		expected   time.Duration
+	}{
+
+		{
+			"ok",
+			http.StatusOK,
+			"10",
+			0,
+		},
+
+		{
+			"too many requests with seconds",
+			http.StatusTooManyRequests,
+			"2",
+			2 * time.Second,
+		},
+
+		{
+			"too many requests without header",
+			http.StatusTooManyRequests,
+			"",
+			5 * time.Second,
+		},
+
+		{
+			"unavailable without header",
+			http.StatusServiceUnavailable,
+			"",
+			0,
+		},
+
+		{
+			"capped wait",
+			http.StatusServiceUnavailable,
+			"3600",
+			MaxRetryAfter,
+		},
+
+		{
+			"past date",
+			http.StatusTooManyRequests,
+			"Wed, 21 Oct 2015 07:28:00 GMT",
+			time.Second,
+		},
+	}
+
+	for _, c := range testCaseList {
+		resp := &http.Response{StatusCode: c.statusCode, Header: http.Header{}}
+		resp.Header.Set("Retry-After", c.header)
+		output := retryAfter(resp)
+		if output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("done"))
+	}))
+	defer server.Close()
+
+	defer func(qps float32, burst int) {
+		RequestQPS, RequestBurst = qps, burst
+	}(RequestQPS, RequestBurst)
+
+	// the 429 response is retried after the wait asked by grafana
+	start := time.Now()
+	body, responseCode := SetOrgRequestContext(context.TODO(), "GET", server.URL, nil, 2, "")
+	if responseCode != http.StatusOK || string(body) != "done" || time.Since(start) < time.Second {
+		t.Fatalf("the retry after 429 responded %v %s after %v", responseCode, body, time.Since(start))
+	}
+
+	// the last 429 response is returned once the retries are used up
+	attempts = 0
+	_, responseCode = SetOrgRequestContext(context.TODO(), "GET", server.URL, nil, 1, "")
+	if responseCode != http.StatusTooManyRequests {
+		t.Fatalf("the response %v is not the expected 429", responseCode)
+	}
+
+	// 5 requests with the burst of 1 wait for 4 tokens
+	RequestQPS, RequestBurst = 20, 1
+	start = time.Now()
+	for i := 0; i < 5; i++ {
+		SetOrgRequestContext(context.TODO(), "GET", server.URL, nil, 1, "")
+	}
+	if time.Since(start) < 150*time.Millisecond {
+		t.Fatalf("the requests are not rate limited, they took %v", time.Since(start))
+	}
+}
+
+func TestRateLimitReset(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		statusCode int
+		reset      string
+		remaining  string
+		expected   time.Duration
+		paused     bool
+	}{
+		{"seconds to wait", http.StatusTooManyRequests, "3", "0", 3 * time.Second, true},
+
+		{"quota left", http.StatusOK, "3", "10", 0, false},
+
+		{"quota exhausted", http.StatusOK, "3", "0", 0, true},
+
+		{"no reset", http.StatusTooManyRequests, "", "0", 5 * time.Second, false},
+	}
+
+	defer func() { quotaReset = time.Time{} }()
+	for _, c := range testCaseList {
+		quotaReset = time.Time{}
+		resp := &http.Response{StatusCode: c.statusCode, Header: http.Header{}}
+		resp.Header.Set("X-RateLimit-Reset", c.reset)
+		resp.Header.Set("X-RateLimit-Remaining", c.remaining)
+		recordRateLimitQuota(resp)
+		if output := retryAfter(resp); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		if paused := waitForRateLimit(ctx) != nil; paused != c.paused {
+			t.Errorf("case (%v) paused: (%v) is not the expected: (%v)", c.name, paused, c.paused)
+		}
+		cancel()
+	}
+
+	reset := time.Now().Add(time.Hour).Unix()
+	header := http.Header{}
+	header.Set("X-RateLimit-Reset", fmt.Sprint(reset))
+	if output, ok := rateLimitReset(header); !ok || output < 59*time.Minute || output > time.Hour {
+		t.Errorf("case (unix time) output: (%v) is not the expected: (%v)", output, time.Hour)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// Replicas are the base urls of the replicas of the same grafana instance in order of preference,
+// the requests go to the active replica and fail over to the next one once it cannot be connected
+type Replicas struct {
+	lock   sync.Mutex
+	urls   []string
+	active int
+}
+
+type replicasKey struct{}
+
+// NewReplicas returns the replicas of the urls, the first one is active
+func NewReplicas(urls []string) *Replicas {
+	return &Replicas{urls: urls}
+}
+
+// WithReplicas returns the context to fail over between the replicas on connection errors,
+// the urls of the requests start with the active replica
+func WithReplicas(ctx context.Context, replicas *Replicas) context.Context {
+	return context.WithValue(ctx, replicasKey{}, replicas)
+}
+
+// Active returns the base url of the replica the requests go to
+func (r *Replicas) Active() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.urls[r.active]
+}
+
+// Len returns the number of the replicas
+func (r *Replicas) Len() int {
+	return len(r.urls)
+}
+
+// failOver moves the request to url, which failed to connect, to the next replica and returns its url,
+// the request only follows the replica which another request has failed over to already
+func (r *Replicas) failOver(url string) string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i, base := range r.urls {
+		if !strings.HasPrefix(url, base+"/") {
+			continue
+		}
+		if i == r.active {
+			r.active = (r.active + 1) % len(r.urls)
+			klog.InfoS("grafana replica cannot be connected, failing over", "replica", base, "active", r.urls[r.active])
+		}
+		return r.urls[r.active] + strings.TrimPrefix(url, base)
+	}
+	return url
+}
+
+// Recover makes the most preferred replica which is healthy active again, the replicas ahead of the active one
+// are probed by their health api in order
+func (r *Replicas) Recover(ctx context.Context) {
+	r.lock.Lock()
+	active := r.active
+	r.lock.Unlock()
+	for i := 0; i < active; i++ {
+		_, code, _, err := sendRequest(ctx, "GET", r.urls[i]+"/api/health", nil, "")
+		if err != nil || code != http.StatusOK {
+			continue
+		}
+		r.lock.Lock()
+		if r.active == active {
+			r.active = i
+			klog.InfoS("grafana replica is healthy again, failing back", "replica", r.urls[i])
+		}
+		r.lock.Unlock()
+		return
+	}
+}
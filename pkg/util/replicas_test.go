@@ -0,0 +1,79 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReplicasFailOver(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	downURL := down.URL
+	down.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	otherURL := other.URL
+	other.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("done"))
+	}))
+	defer up.Close()
+
+	testCaseList := []struct {
+		name     string
+		urls     []string
+		code     int
+		expected string
+	}{
+		{"primary", []string{up.URL, downURL}, http.StatusOK, up.URL},
+
+		{"failed over", []string{downURL, up.URL}, http.StatusOK, up.URL},
+
+		{"all down", []string{downURL, otherURL}, http.StatusNotFound, otherURL},
+	}
+
+	for _, c := range testCaseList {
+		replicas := NewReplicas(c.urls)
+		ctx := WithReplicas(context.TODO(), replicas)
+		_, code := SetOrgRequestContext(ctx, "GET", replicas.Active()+"/api/health", nil, 1, "")
+		if code != c.code {
+			t.Errorf("case (%v) code: (%v) is not the expected: (%v)", c.name, code, c.code)
+		}
+		if output := replicas.Active(); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
+
+func TestReplicasRecover(t *testing.T) {
+	healthy := true
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer primary.Close()
+
+	testCaseList := []struct {
+		name     string
+		healthy  bool
+		expected string
+	}{
+		{"unhealthy", false, "http://secondary"},
+
+		{"healthy", true, primary.URL},
+	}
+
+	for _, c := range testCaseList {
+		replicas := NewReplicas([]string{primary.URL, "http://secondary"})
+		replicas.failOver(primary.URL + "/api/health")
+		healthy = c.healthy
+		replicas.Recover(context.TODO())
+		if output := replicas.Active(); output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
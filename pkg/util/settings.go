@@ -0,0 +1,45 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Settings are the request settings which a reloaded config file replaces while the requests are sent
+type Settings struct {
+	Auth           GrafanaAuth
+	RequestTimeout time.Duration
+	RequestQPS     float32
+	RequestBurst   int
+	MaxRetryAfter  time.Duration
+}
+
+// publishedSettings holds the *Settings of the config file, they are never changed once published
+var publishedSettings atomic.Value
+
+// PublishSettings replaces the settings read by the requests at once, nil goes back to the flags
+func PublishSettings(s *Settings) {
+	publishedSettings.Store(s)
+}
+
+// FlagSettings returns the settings set by the flags
+func FlagSettings() Settings {
+	return Settings{
+		Auth:           GrafanaAuth{AuthProxyUser, BearerToken, BasicAuthUsername, BasicAuthPassword},
+		RequestTimeout: RequestTimeout,
+		RequestQPS:     RequestQPS,
+		RequestBurst:   RequestBurst,
+		MaxRetryAfter:  MaxRetryAfter,
+	}
+}
+
+// CurrentSettings returns the published settings, or the flags before any settings are published
+func CurrentSettings() Settings {
+	if s, ok := publishedSettings.Load().(*Settings); ok && s != nil {
+		return *s
+	}
+	return FlagSettings()
+}
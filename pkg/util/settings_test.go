@@ -0,0 +1,34 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCurrentSettings(t *testing.T) {
+	defer PublishSettings(nil)
+	published := &Settings{Auth: GrafanaAuth{BearerToken: "token"}, RequestTimeout: time.Second}
+
+	testCaseList := []struct {
+		name     string
+		settings *Settings
+		expected string
+	}{
+		{"flags before any settings are published", nil, BearerToken},
+
+		{"published settings", published, "token"},
+
+		{"back to the flags", nil, BearerToken},
+	}
+
+	for _, c := range testCaseList {
+		PublishSettings(c.settings)
+		output := CurrentSettings().Auth.BearerToken
+		if output != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package util
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestGetHTTPClient(t *testing.T) {
+	defer func(idle int) {
+		MaxIdleConnsPerHost = idle
+	}(MaxIdleConnsPerHost)
+
+	first := getHTTPClient()
+	if getHTTPClient() != first {
+		t.Fatalf("the client should be shared by the requests")
+	}
+
+	MaxIdleConnsPerHost = 1
+	second := getHTTPClient()
+	if second == first {
+		t.Fatalf("the client should be rebuilt after the settings are changed")
+	}
+	transport := second.Transport.(*instrumentedTransport).next.(*http.Transport)
+	if transport.MaxIdleConnsPerHost != 1 {
+		t.Fatalf("the transport %v does not have the changed settings", transport.MaxIdleConnsPerHost)
+	}
+}
+
+func TestNewTransportHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("done"))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	testCaseList := []struct {
+		name         string
+		disableHTTP2 bool
+		expected     int
+	}{
+
+		{
+			"http2",
+			false,
+			2,
+		},
+
+		{
+			"http1",
+			true,
+			1,
+		},
+	}
+
+	for _, c := range testCaseList {
+		settings := currentTransportSettings()
+		settings.disableHTTP2 = c.disableHTTP2
+		transport := newTransport(settings)
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err != nil {
+			t.Fatalf("case (%v) failed to send request: %v", c.name, err)
+		}
+		resp.Body.Close()
+		if resp.ProtoMajor != c.expected {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, resp.ProtoMajor, c.expected)
+		}
+	}
+}
+
+func TestProxy(t *testing.T) {
+	proxied := ""
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		proxied = req.URL.String()
+		w.Write([]byte("{\"database\": \"ok\"}"))
+	}))
+	defer proxy.Close()
+
+	defer func(proxyURL, noProxy string) {
+		ProxyURL, NoProxy = proxyURL, noProxy
+	}(ProxyURL, NoProxy)
+	ProxyURL, NoProxy = proxy.URL, "internal.example"
+
+	_, responseCode := SetRequest("GET", "http://grafana.example/api/health", nil, 1)
+	if responseCode != http.StatusOK || proxied != "http://grafana.example/api/health" {
+		t.Fatalf("the request is not sent through the proxy: %v %v", responseCode, proxied)
+	}
+
+	proxyFunc := newProxyFunc(currentTransportSettings())
+	testCaseList := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+
+		{
+			"https grafana",
+			"https://grafana.example/api/health",
+			proxy.URL,
+		},
+
+		{
+			"no proxy host",
+			"http://internal.example/api/health",
+			"",
+		},
+	}
+
+	for _, c := range testCaseList {
+		target, _ := url.Parse(c.url)
+		output, err := proxyFunc(&http.Request{URL: target})
+		if err != nil {
+			t.Errorf("case (%v) failed to get the proxy: %v", c.name, err)
+		}
+		if (output == nil && c.expected != "") || (output != nil && output.String() != c.expected) {
+			t.Errorf("case (%v) output: (%v) is not the expected: (%v)", c.name, output, c.expected)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package version
+
+import (
+	"runtime"
+)
+
+var (
+	// Version is the release of the loader, it is set by -ldflags "-X ...version.Version=..." on build
+	Version = "unknown"
+	// GitCommit is the commit the loader is built from, it is set by -ldflags on build
+	GitCommit = "unknown"
+)
+
+// Info is the version of the running loader
+type Info struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the version of the running loader
+func Get() Info {
+	return Info{
+		Version:   Version,
+		GitCommit: GitCommit,
+		GoVersion: runtime.Version(),
+	}
+}
+
+func (i Info) String() string {
+	return i.Version + " (" + i.GitCommit + ", " + i.GoVersion + ")"
+}